@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/github/github-mcp-server/internal/ghmcp"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// exitCode is returned by commands that failed without an error left to report, having printed what went wrong,
+// making the process exit with the code.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+var callCmd = &cobra.Command{
+	Use:   "call <tool>",
+	Short: "Call a tool",
+	Long: `Call a tool once, through the same server, middleware and handlers the calls of agents go through, and print its result.
+
+The exit status is 1 when the tool reports an error, whose content is printed to standard error.`,
+	Example: `  github-mcp-server call get_issue --arg owner=github --arg repo=github-mcp-server --arg issue_number=1
+  github-mcp-server call list_issues --args-json '{"owner": "github", "repo": "github-mcp-server", "labels": ["bug"]}'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		stdioServerConfig, err := getStdioServerConfig()
+		if err != nil {
+			return err
+		}
+		callArgs, err := cmd.Flags().GetStringArray("arg")
+		if err != nil {
+			return err
+		}
+
+		// The usage is only printed when the arguments are wrong
+		cmd.SilenceUsage = true
+		isError, err := ghmcp.RunCall(ghmcp.CallConfig{
+			StdioServerConfig: stdioServerConfig,
+			Tool:              args[0],
+			ArgsJSON:          viper.GetString("call_args_json"),
+			Args:              callArgs,
+			Format:            viper.GetString("call_format"),
+			Out:               os.Stdout,
+			ErrOut:            os.Stderr,
+		})
+		if err != nil {
+			return err
+		}
+		if isError {
+			cmd.SilenceErrors = true
+			return exitCode(1)
+		}
+		return nil
+	},
+}
+
+func init() {
+	callCmd.Flags().StringArray("arg", nil, "An argument of the call as key=value, converted to the type the tool's input schema gives it, which may be repeated")
+	callCmd.Flags().String("args-json", "", "The arguments of the call as a JSON object, which --arg adds to")
+	callCmd.Flags().String("format", ghmcp.FormatText, "Output format, \"text\" for the content of the result or \"json\" for the result as the server sends it")
+
+	_ = viper.BindPFlag("call_args_json", callCmd.Flags().Lookup("args-json"))
+	_ = viper.BindPFlag("call_format", callCmd.Flags().Lookup("format"))
+
+	rootCmd.AddCommand(callCmd)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+
+	"github.com/github/github-mcp-server/internal/ghmcp"
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the connection to GitHub and the token",
+	Long: `Check that GitHub can be reached with the host, endpoint and network settings, that the token is valid and when it expires, how much of the rate limits is left, whether single sign-on blocks the token, and whether the token's scopes allow the tools of every enabled toolset, printing what to do about any problem found.
+
+The exit status is 1 when a check fails with an error.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		token := viper.GetString("personal_access_token")
+		appConfig := getGitHubAppConfig()
+		if token == "" && appConfig == nil {
+			store, err := auth.DefaultCredentialStore()
+			if err != nil {
+				return err
+			}
+			token, err = ghmcp.StoredToken(store, viper.GetString("host"))
+			if err != nil {
+				return err
+			}
+		}
+		enabledToolsets, err := getEnabledToolsets()
+		if err != nil {
+			return err
+		}
+		tools, excludeTools, err := getToolFilter()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+		ok, err := ghmcp.RunDoctor(ghmcp.DoctorConfig{
+			Version:         version,
+			Host:            viper.GetString("host"),
+			Endpoints:       getEndpoints(),
+			Network:         getNetworkConfig(),
+			Token:           token,
+			GitHubApp:       appConfig,
+			EnabledToolsets: enabledToolsets,
+			Tools:           tools,
+			ExcludeTools:    excludeTools,
+			ReadOnly:        viper.GetBool("read-only"),
+			Format:          viper.GetString("doctor_format"),
+			Out:             os.Stdout,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			cmd.SilenceErrors = true
+			return exitCode(1)
+		}
+		return nil
+	},
+}
+
+func init() {
+	doctorCmd.Flags().String("format", ghmcp.FormatText, "Output format, \"text\" or \"json\"")
+
+	_ = viper.BindPFlag("doctor_format", doctorCmd.Flags().Lookup("format"))
+
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -66,6 +66,19 @@ func generateReadmeDocs(readmePath string) error {
 	// Create toolset group with mock clients
 	tsg := github.DefaultToolsetGroup(false, mockGetClient, mockGetGQLClient, mockGetRawClient, t)
 
+	// Document only the tools the server would offer with the configured --tools and --exclude-tools
+	tools, excludeTools, err := getToolFilter()
+	if err != nil {
+		return err
+	}
+	if len(tools) > 0 || len(excludeTools) > 0 {
+		filter, err := toolsets.NewToolFilter(tools, excludeTools)
+		if err != nil {
+			return err
+		}
+		tsg.SetToolFilter(filter)
+	}
+
 	// Generate toolsets documentation
 	toolsetsDoc := generateToolsetsDoc(tsg)
 
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+
+	"github.com/github/github-mcp-server/internal/ghmcp"
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	loginCmd = &cobra.Command{
+		Use:   "login",
+		Short: "Authenticate with GitHub",
+		Long:  `Authenticate with GitHub using the OAuth device flow, storing the token for the stdio server to use when GITHUB_PERSONAL_ACCESS_TOKEN is not set.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			store, err := auth.DefaultCredentialStore()
+			if err != nil {
+				return err
+			}
+
+			var scopes []string
+			if err := viper.UnmarshalKey("oauth_scopes", &scopes); err != nil {
+				return err
+			}
+
+			return ghmcp.RunLogin(ghmcp.LoginConfig{
+				Version:   version,
+				Host:      viper.GetString("host"),
+				Endpoints: getEndpoints(),
+				Network:   getNetworkConfig(),
+				ClientID:  viper.GetString("oauth_client_id"),
+				Scopes:    scopes,
+				Store:     store,
+				Out:       os.Stderr,
+			})
+		},
+	}
+
+	logoutCmd = &cobra.Command{
+		Use:   "logout",
+		Short: "Remove stored GitHub credentials",
+		Long:  `Remove the token stored by login for the GitHub host.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			store, err := auth.DefaultCredentialStore()
+			if err != nil {
+				return err
+			}
+			return ghmcp.RunLogout(store, viper.GetString("host"), os.Stdout)
+		},
+	}
+
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show stored GitHub credentials",
+		Long:  `Show the hosts login has stored tokens for, and check that each token is still valid.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			store, err := auth.DefaultCredentialStore()
+			if err != nil {
+				return err
+			}
+			return ghmcp.RunAuthStatus(store, version, viper.GetString("host"), getEndpoints(), getNetworkConfig(), os.Stdout)
+		},
+	}
+)
+
+func init() {
+	loginCmd.Flags().String("client-id", "", "Client ID of the OAuth app to authorize")
+	loginCmd.Flags().StringSlice("scopes", ghmcp.DefaultLoginScopes, "OAuth scopes to request")
+
+	_ = viper.BindPFlag("oauth_client_id", loginCmd.Flags().Lookup("client-id"))
+	_ = viper.BindPFlag("oauth_scopes", loginCmd.Flags().Lookup("scopes"))
+
+	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+	rootCmd.AddCommand(statusCmd)
+}
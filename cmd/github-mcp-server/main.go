@@ -4,10 +4,24 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/github/github-mcp-server/internal/config"
 	"github.com/github/github-mcp-server/internal/ghmcp"
+	"github.com/github/github-mcp-server/pkg/audit"
+	"github.com/github/github-mcp-server/pkg/auth"
 	"github.com/github/github-mcp-server/pkg/github"
+	"github.com/github/github-mcp-server/pkg/httpcache"
+	"github.com/github/github-mcp-server/pkg/limits"
+	mcplog "github.com/github/github-mcp-server/pkg/log"
+	"github.com/github/github-mcp-server/pkg/network"
+	"github.com/github/github-mcp-server/pkg/ratelimit"
+	"github.com/github/github-mcp-server/pkg/scopes"
+	"github.com/github/github-mcp-server/pkg/tracing"
+	"github.com/github/github-mcp-server/pkg/translations"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -24,6 +38,9 @@ var (
 		Short:   "GitHub MCP Server",
 		Long:    `A GitHub MCP server that handles various tools and resources.`,
 		Version: fmt.Sprintf("Version: %s\nCommit: %s\nBuild Date: %s", version, commit, date),
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return loadConfigFile()
+		},
 	}
 
 	stdioCmd = &cobra.Command{
@@ -31,32 +48,39 @@ var (
 		Short: "Start stdio server",
 		Long:  `Start a server that communicates via standard input/output streams using JSON-RPC messages.`,
 		RunE: func(_ *cobra.Command, _ []string) error {
+			stdioServerConfig, err := getStdioServerConfig()
+			if err != nil {
+				return err
+			}
+			return ghmcp.RunStdioServer(stdioServerConfig)
+		},
+	}
+
+	httpCmd = &cobra.Command{
+		Use:   "http",
+		Short: "Start HTTP server",
+		Long:  `Start a server that communicates via the MCP streamable HTTP transport, with the HTTP+SSE transport available for older clients.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			// The token is optional here, as clients can authenticate each request with their own.
 			token := viper.GetString("personal_access_token")
-			if token == "" {
-				return errors.New("GITHUB_PERSONAL_ACCESS_TOKEN not set")
+
+			ownerTokens, err := getOwnerTokens()
+			if err != nil {
+				return err
 			}
 
-			// If you're wondering why we're not using viper.GetStringSlice("toolsets"),
-			// it's because viper doesn't handle comma-separated values correctly for env
-			// vars when using GetStringSlice.
-			// https://github.com/spf13/viper/issues/380
-			var enabledToolsets []string
-			if err := viper.UnmarshalKey("toolsets", &enabledToolsets); err != nil {
-				return fmt.Errorf("failed to unmarshal toolsets: %w", err)
+			localServerConfig, err := getLocalServerConfig(token, getGitHubAppConfig(), ownerTokens)
+			if err != nil {
+				return err
 			}
 
-			stdioServerConfig := ghmcp.StdioServerConfig{
-				Version:              version,
-				Host:                 viper.GetString("host"),
-				Token:                token,
-				EnabledToolsets:      enabledToolsets,
-				DynamicToolsets:      viper.GetBool("dynamic_toolsets"),
-				ReadOnly:             viper.GetBool("read-only"),
-				ExportTranslations:   viper.GetBool("export-translations"),
-				EnableCommandLogging: viper.GetBool("enable-command-logging"),
-				LogFilePath:          viper.GetString("log-file"),
+			httpServerConfig := ghmcp.HTTPServerConfig{
+				LocalServerConfig: localServerConfig,
+				ListenAddress:     viper.GetString("listen_address"),
+				BasePath:          viper.GetString("base_path"),
+				ShutdownTimeout:   viper.GetDuration("shutdown_timeout"),
 			}
-			return ghmcp.RunStdioServer(stdioServerConfig)
+			return ghmcp.RunHTTPServer(httpServerConfig)
 		},
 	}
 )
@@ -68,25 +92,123 @@ func init() {
 	rootCmd.SetVersionTemplate("{{.Short}}\n{{.Version}}\n")
 
 	// Add global flags that will be shared by all commands
+	rootCmd.PersistentFlags().String("config", "", "Path to a YAML or JSON configuration file")
 	rootCmd.PersistentFlags().StringSlice("toolsets", github.DefaultTools, "An optional comma separated list of groups of tools to allow, defaults to enabling all")
+	rootCmd.PersistentFlags().StringSlice("tools", nil, "An optional comma separated list of tools or glob patterns (e.g. get_*) to offer from the enabled toolsets, defaults to all of them")
+	rootCmd.PersistentFlags().StringSlice("exclude-tools", nil, "An optional comma separated list of tools or glob patterns not to offer, even when their toolset is enabled")
+	rootCmd.PersistentFlags().StringSlice("repos", nil, "An optional comma separated list of owner/repo patterns (e.g. myorg/*,!myorg/secrets-*) restricting the repositories tools may access")
+	rootCmd.PersistentFlags().StringSlice("confirm-tools", nil, "An optional comma separated list of tools or glob patterns, or \"destructive\" for all destructive tools, whose calls the user must approve through elicitation")
+	rootCmd.PersistentFlags().Bool("allow-unconfirmed", false, "Let calls to the tools in --confirm-tools go ahead without approval when the client does not support elicitation, instead of refusing them")
 	rootCmd.PersistentFlags().Bool("dynamic-toolsets", false, "Enable dynamic toolsets")
+	rootCmd.PersistentFlags().Duration("rate-limit-max-wait", ratelimit.DefaultMaxWait, "The longest a request to GitHub is held back waiting for a rate limit to reset, or before being retried")
+	rootCmd.PersistentFlags().Int("max-retries", ratelimit.DefaultMaxRetries, "How many times idempotent requests to GitHub are retried after being rate limited or failing with a server error")
+	rootCmd.PersistentFlags().Int("max-concurrent-calls", 0, "How many tool calls may run at once, any number if 0")
+	rootCmd.PersistentFlags().Int("max-queued-calls", limits.DefaultMaxQueued, "How many tool calls may wait for others to complete before further calls are refused")
+	rootCmd.PersistentFlags().StringSlice("tool-max-concurrent-calls", nil, "An optional comma separated list of tool=N pairs limiting calls to each tool matching the name or glob pattern (e.g. get_job_logs=2) to N at once")
+	rootCmd.PersistentFlags().Duration("call-timeout", limits.DefaultTimeout, "How long a tool call may take, including the time it waits to run, without limit if 0")
+	rootCmd.PersistentFlags().StringSlice("tool-timeouts", nil, "An optional comma separated list of tool=duration pairs overriding --call-timeout for the tools matching the name or glob pattern (e.g. search_*=30s)")
 	rootCmd.PersistentFlags().Bool("read-only", false, "Restrict the server to read-only operations")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Make write tools return the requests they would make to change data on GitHub instead of making them")
+	rootCmd.PersistentFlags().String("scope-check", scopes.ModeHide, "What to do with the tools the OAuth scopes of a classic token do not allow: \"hide\" them, \"annotate\" their description, or \"off\"")
 	rootCmd.PersistentFlags().String("log-file", "", "Path to log file")
+	rootCmd.PersistentFlags().String("log-format", mcplog.FormatText, "Format of the log, \"text\" or \"json\" for one JSON object per line")
 	rootCmd.PersistentFlags().Bool("enable-command-logging", false, "When enabled, the server will log all command requests and responses to the log file")
+	rootCmd.PersistentFlags().StringSlice("log-redact-fields", nil, "An optional comma separated list of JSON fields whose values are masked in logged commands, in addition to the likes of secret and token")
+	rootCmd.PersistentFlags().StringArray("log-redact-patterns", nil, "A regular expression matching secrets to mask in logged commands, in addition to GitHub tokens, bearer tokens and private keys, which may be repeated")
+	rootCmd.PersistentFlags().Bool("log-resource-contents", false, "Log the contents of resources, such as files, in logged commands instead of masking them")
+	rootCmd.PersistentFlags().String("record", "", "Directory to record every request made to GitHub, and its response, into as cassette files, without credentials")
+	rootCmd.PersistentFlags().String("replay", "", "Directory of cassette files recorded with --record to answer requests from instead of GitHub, failing requests that were not recorded")
 	rootCmd.PersistentFlags().Bool("export-translations", false, "Save translations to a JSON file")
+	rootCmd.PersistentFlags().String("cache-store", "", "Cache responses from GitHub and revalidate them with conditional requests, in \"memory\" or on \"disk\"")
+	rootCmd.PersistentFlags().String("cache-dir", "", "Directory to store cached responses in with --cache-store=disk, defaulting to the user cache directory")
+	rootCmd.PersistentFlags().Int64("cache-max-size", httpcache.DefaultMaxBytes>>20, "Maximum size of the response cache in megabytes")
+	rootCmd.PersistentFlags().Duration("cache-ttl", httpcache.DefaultTTL, "How long cached responses are kept after they were last confirmed current")
+	rootCmd.PersistentFlags().String("metrics-address", "", "Address to serve Prometheus metrics on at /metrics (e.g. localhost:9090), disabled if empty")
+	rootCmd.PersistentFlags().String("trace-exporter", "", "Export OpenTelemetry traces of tool calls and GitHub requests with \"otlp\" or to a \"file\", disabled if empty")
+	rootCmd.PersistentFlags().String("trace-endpoint", "", "URL of the OTLP/HTTP collector to send traces to, defaulting to OTEL_EXPORTER_OTLP_ENDPOINT or http://localhost:4318")
+	rootCmd.PersistentFlags().String("trace-file", "", "Path of the file to append traces to with --trace-exporter=file")
+	rootCmd.PersistentFlags().String("audit-log", "", "Path of a file to append a JSON record of every call to a write tool to, disabled if empty")
+	rootCmd.PersistentFlags().Int64("audit-log-max-size", audit.DefaultMaxBytes>>20, "Size in megabytes the audit log is rotated at")
+	rootCmd.PersistentFlags().Int("audit-log-max-backups", 0, "How many rotated audit logs to keep, 0 keeps all of them")
 	rootCmd.PersistentFlags().String("gh-host", "", "Specify the GitHub hostname (for GitHub Enterprise etc.)")
+	rootCmd.PersistentFlags().String("api-url", "", "URL of the GitHub REST API, overriding the one derived from --gh-host")
+	rootCmd.PersistentFlags().String("graphql-url", "", "URL of the GitHub GraphQL API, overriding the one derived from --gh-host")
+	rootCmd.PersistentFlags().String("upload-url", "", "URL of the GitHub uploads API, overriding the one derived from --gh-host")
+	rootCmd.PersistentFlags().String("raw-url", "", "URL raw file contents are fetched from, overriding the one derived from --gh-host")
+	rootCmd.PersistentFlags().String("ca-cert", "", "Path to a PEM bundle of certificate authorities to trust, in addition to the system ones, when connecting to GitHub")
+	rootCmd.PersistentFlags().String("client-cert", "", "Path to a PEM encoded client certificate to present to GitHub, for mutual TLS")
+	rootCmd.PersistentFlags().String("client-key", "", "Path to the PEM encoded private key of --client-cert")
+	rootCmd.PersistentFlags().StringSlice("owner-tokens", nil, "An optional comma separated list of owner=ENV_VAR pairs, authenticating calls acting on the repositories of an owner, or glob pattern (e.g. myorg-*), with the token in the environment variable")
+	rootCmd.PersistentFlags().Int64("app-id", 0, "Authenticate as the GitHub App with this ID instead of with a personal access token")
+	rootCmd.PersistentFlags().String("app-private-key-file", "", "Path to the PEM encoded private key of the GitHub App")
+	rootCmd.PersistentFlags().Int64("app-installation-id", 0, "ID of the GitHub App installation to authenticate as")
+	rootCmd.PersistentFlags().String("app-installation-owner", "", "User or organization to look up the GitHub App installation for, if no installation ID is given")
 
 	// Bind flag to viper
+	_ = viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config"))
 	_ = viper.BindPFlag("toolsets", rootCmd.PersistentFlags().Lookup("toolsets"))
+	_ = viper.BindPFlag("tools", rootCmd.PersistentFlags().Lookup("tools"))
+	_ = viper.BindPFlag("exclude_tools", rootCmd.PersistentFlags().Lookup("exclude-tools"))
+	_ = viper.BindPFlag("repos", rootCmd.PersistentFlags().Lookup("repos"))
+	_ = viper.BindPFlag("confirm_tools", rootCmd.PersistentFlags().Lookup("confirm-tools"))
+	_ = viper.BindPFlag("allow_unconfirmed", rootCmd.PersistentFlags().Lookup("allow-unconfirmed"))
 	_ = viper.BindPFlag("dynamic_toolsets", rootCmd.PersistentFlags().Lookup("dynamic-toolsets"))
+	_ = viper.BindPFlag("rate_limit_max_wait", rootCmd.PersistentFlags().Lookup("rate-limit-max-wait"))
+	_ = viper.BindPFlag("max_retries", rootCmd.PersistentFlags().Lookup("max-retries"))
+	_ = viper.BindPFlag("max_concurrent_calls", rootCmd.PersistentFlags().Lookup("max-concurrent-calls"))
+	_ = viper.BindPFlag("max_queued_calls", rootCmd.PersistentFlags().Lookup("max-queued-calls"))
+	_ = viper.BindPFlag("tool_max_concurrent_calls", rootCmd.PersistentFlags().Lookup("tool-max-concurrent-calls"))
+	_ = viper.BindPFlag("call_timeout", rootCmd.PersistentFlags().Lookup("call-timeout"))
+	_ = viper.BindPFlag("tool_timeouts", rootCmd.PersistentFlags().Lookup("tool-timeouts"))
 	_ = viper.BindPFlag("read-only", rootCmd.PersistentFlags().Lookup("read-only"))
+	_ = viper.BindPFlag("dry_run", rootCmd.PersistentFlags().Lookup("dry-run"))
+	_ = viper.BindPFlag("scope_check", rootCmd.PersistentFlags().Lookup("scope-check"))
 	_ = viper.BindPFlag("log-file", rootCmd.PersistentFlags().Lookup("log-file"))
+	_ = viper.BindPFlag("log_format", rootCmd.PersistentFlags().Lookup("log-format"))
 	_ = viper.BindPFlag("enable-command-logging", rootCmd.PersistentFlags().Lookup("enable-command-logging"))
+	_ = viper.BindPFlag("log_redact_fields", rootCmd.PersistentFlags().Lookup("log-redact-fields"))
+	_ = viper.BindPFlag("log_redact_patterns", rootCmd.PersistentFlags().Lookup("log-redact-patterns"))
+	_ = viper.BindPFlag("log_resource_contents", rootCmd.PersistentFlags().Lookup("log-resource-contents"))
+	_ = viper.BindPFlag("record", rootCmd.PersistentFlags().Lookup("record"))
+	_ = viper.BindPFlag("replay", rootCmd.PersistentFlags().Lookup("replay"))
 	_ = viper.BindPFlag("export-translations", rootCmd.PersistentFlags().Lookup("export-translations"))
+	_ = viper.BindPFlag("cache_store", rootCmd.PersistentFlags().Lookup("cache-store"))
+	_ = viper.BindPFlag("cache_dir", rootCmd.PersistentFlags().Lookup("cache-dir"))
+	_ = viper.BindPFlag("cache_max_size", rootCmd.PersistentFlags().Lookup("cache-max-size"))
+	_ = viper.BindPFlag("cache_ttl", rootCmd.PersistentFlags().Lookup("cache-ttl"))
+	_ = viper.BindPFlag("metrics_address", rootCmd.PersistentFlags().Lookup("metrics-address"))
+	_ = viper.BindPFlag("trace_exporter", rootCmd.PersistentFlags().Lookup("trace-exporter"))
+	_ = viper.BindPFlag("trace_endpoint", rootCmd.PersistentFlags().Lookup("trace-endpoint"))
+	_ = viper.BindPFlag("trace_file", rootCmd.PersistentFlags().Lookup("trace-file"))
+	_ = viper.BindPFlag("audit_log", rootCmd.PersistentFlags().Lookup("audit-log"))
+	_ = viper.BindPFlag("audit_log_max_size", rootCmd.PersistentFlags().Lookup("audit-log-max-size"))
+	_ = viper.BindPFlag("audit_log_max_backups", rootCmd.PersistentFlags().Lookup("audit-log-max-backups"))
 	_ = viper.BindPFlag("host", rootCmd.PersistentFlags().Lookup("gh-host"))
+	_ = viper.BindPFlag("api_url", rootCmd.PersistentFlags().Lookup("api-url"))
+	_ = viper.BindPFlag("graphql_url", rootCmd.PersistentFlags().Lookup("graphql-url"))
+	_ = viper.BindPFlag("upload_url", rootCmd.PersistentFlags().Lookup("upload-url"))
+	_ = viper.BindPFlag("raw_url", rootCmd.PersistentFlags().Lookup("raw-url"))
+	_ = viper.BindPFlag("ca_cert", rootCmd.PersistentFlags().Lookup("ca-cert"))
+	_ = viper.BindPFlag("client_cert", rootCmd.PersistentFlags().Lookup("client-cert"))
+	_ = viper.BindPFlag("client_key", rootCmd.PersistentFlags().Lookup("client-key"))
+	_ = viper.BindPFlag("owner_tokens", rootCmd.PersistentFlags().Lookup("owner-tokens"))
+	_ = viper.BindPFlag("app_id", rootCmd.PersistentFlags().Lookup("app-id"))
+	_ = viper.BindPFlag("app_private_key_file", rootCmd.PersistentFlags().Lookup("app-private-key-file"))
+	_ = viper.BindPFlag("app_installation_id", rootCmd.PersistentFlags().Lookup("app-installation-id"))
+	_ = viper.BindPFlag("app_installation_owner", rootCmd.PersistentFlags().Lookup("app-installation-owner"))
+
+	// Add HTTP server flags
+	httpCmd.Flags().String("listen-address", "localhost:8082", "Address for the HTTP server to listen on")
+	httpCmd.Flags().String("base-path", "", "Path prefix for all HTTP endpoints, for use behind a reverse proxy")
+	httpCmd.Flags().Duration("shutdown-timeout", 10*time.Second, "How long to wait for in-flight requests to complete on shutdown")
+
+	_ = viper.BindPFlag("listen_address", httpCmd.Flags().Lookup("listen-address"))
+	_ = viper.BindPFlag("base_path", httpCmd.Flags().Lookup("base-path"))
+	_ = viper.BindPFlag("shutdown_timeout", httpCmd.Flags().Lookup("shutdown-timeout"))
 
 	// Add subcommands
 	rootCmd.AddCommand(stdioCmd)
+	rootCmd.AddCommand(httpCmd)
 }
 
 func initConfig() {
@@ -96,8 +218,428 @@ func initConfig() {
 
 }
 
+// loadConfigFile merges the settings from the file passed to --config into viper, below flags and environment
+// variables in precedence.
+func loadConfigFile() error {
+	path := viper.GetString("config")
+	if path == "" {
+		return nil
+	}
+
+	tsg := github.DefaultToolsetGroup(false, mockGetClient, mockGetGQLClient, mockGetRawClient, translations.NullTranslationHelper)
+	opts := config.Options{}
+	for name, toolset := range tsg.Toolsets {
+		opts.Toolsets = append(opts.Toolsets, name)
+		for _, tool := range toolset.GetAvailableTools() {
+			opts.Tools = append(opts.Tools, tool.Tool.Name)
+		}
+	}
+	sort.Strings(opts.Toolsets)
+
+	settings, err := config.Load(path, opts)
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return viper.MergeConfigMap(settings)
+}
+
+// getStdioServerConfig returns the configuration of the stdio server set via flag, environment or config file,
+// along with the credentials to use.
+func getStdioServerConfig() (ghmcp.StdioServerConfig, error) {
+	token := viper.GetString("personal_access_token")
+	appConfig := getGitHubAppConfig()
+	if token == "" && appConfig == nil {
+		// Fall back to the token stored by the login command
+		store, err := auth.DefaultCredentialStore()
+		if err != nil {
+			return ghmcp.StdioServerConfig{}, err
+		}
+		token, err = ghmcp.StoredToken(store, viper.GetString("host"))
+		if err != nil {
+			return ghmcp.StdioServerConfig{}, err
+		}
+	}
+	ownerTokens, err := getOwnerTokens()
+	if err != nil {
+		return ghmcp.StdioServerConfig{}, err
+	}
+	// Replayed sessions need no credentials, as nothing is sent to GitHub
+	if token == "" && appConfig == nil && len(ownerTokens) == 0 && viper.GetString("replay") == "" {
+		return ghmcp.StdioServerConfig{}, errors.New("GITHUB_PERSONAL_ACCESS_TOKEN not set, and not logged in with `github-mcp-server login`")
+	}
+
+	localServerConfig, err := getLocalServerConfig(token, appConfig, ownerTokens)
+	if err != nil {
+		return ghmcp.StdioServerConfig{}, err
+	}
+
+	logRedaction, err := getLogRedactionConfig()
+	if err != nil {
+		return ghmcp.StdioServerConfig{}, err
+	}
+
+	return ghmcp.StdioServerConfig{
+		LocalServerConfig:    localServerConfig,
+		EnableCommandLogging: viper.GetBool("enable-command-logging"),
+		LogRedaction:         logRedaction,
+	}, nil
+}
+
+// getLocalServerConfig returns the configuration shared by the stdio and HTTP servers set via flag, environment or
+// config file, with the given credentials.
+func getLocalServerConfig(token string, appConfig *auth.AppConfig, ownerTokens []auth.OwnerToken) (ghmcp.LocalServerConfig, error) {
+	enabledToolsets, err := getEnabledToolsets()
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	tools, excludeTools, err := getToolFilter()
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	hosts, err := getHosts()
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	repos, err := getStringSlice("repos")
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	confirmTools, err := getStringSlice("confirm_tools")
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	limitsConfig, err := getLimitsConfig()
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	cacheConfig, err := getCacheConfig()
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	tracingConfig, err := getTracingConfig()
+	if err != nil {
+		return ghmcp.LocalServerConfig{}, err
+	}
+
+	return ghmcp.LocalServerConfig{
+		ServerOptions: ghmcp.ServerOptions{
+			Version:          version,
+			Host:             viper.GetString("host"),
+			Endpoints:        getEndpoints(),
+			Hosts:            hosts,
+			Token:            token,
+			GitHubApp:        appConfig,
+			OwnerTokens:      ownerTokens,
+			EnabledToolsets:  enabledToolsets,
+			DynamicToolsets:  viper.GetBool("dynamic_toolsets"),
+			Tools:            tools,
+			ExcludeTools:     excludeTools,
+			Repos:            repos,
+			ConfirmTools:     confirmTools,
+			AllowUnconfirmed: viper.GetBool("allow_unconfirmed"),
+			RateLimitMaxWait: viper.GetDuration("rate_limit_max_wait"),
+			MaxRetries:       viper.GetInt("max_retries"),
+			Limits:           limitsConfig,
+			Cache:            cacheConfig,
+			ReadOnly:         viper.GetBool("read-only"),
+			DryRun:           viper.GetBool("dry_run"),
+			ScopeCheck:       viper.GetString("scope_check"),
+		},
+		Network:            getNetworkConfig(),
+		MetricsAddress:     viper.GetString("metrics_address"),
+		Tracing:            tracingConfig,
+		AuditLog:           getAuditLogConfig(),
+		ExportTranslations: viper.GetBool("export-translations"),
+		LogFilePath:        viper.GetString("log-file"),
+		LogFormat:          viper.GetString("log_format"),
+		Record:             viper.GetString("record"),
+		Replay:             viper.GetString("replay"),
+	}, nil
+}
+
+// getStringSlice returns the list of strings configured for key via flag, environment or config file.
+func getStringSlice(key string) ([]string, error) {
+	// If you're wondering why we're not using viper.GetStringSlice(key),
+	// it's because viper doesn't handle comma-separated values correctly for env
+	// vars when using GetStringSlice.
+	// https://github.com/spf13/viper/issues/380
+	var values []string
+	if err := viper.UnmarshalKey(key, &values); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", key, err)
+	}
+	return values, nil
+}
+
+// getEnabledToolsets returns the toolsets configured via flag, environment or config file.
+func getEnabledToolsets() ([]string, error) {
+	return getStringSlice("toolsets")
+}
+
+// getToolFilter returns the individual tools to offer and to exclude within the enabled toolsets.
+func getToolFilter() (tools []string, excludeTools []string, err error) {
+	if tools, err = getStringSlice("tools"); err != nil {
+		return nil, nil, err
+	}
+	if excludeTools, err = getStringSlice("exclude_tools"); err != nil {
+		return nil, nil, err
+	}
+	return tools, excludeTools, nil
+}
+
+// getLimitsConfig returns the limits on tool calls configured via flag, environment or config file.
+func getLimitsConfig() (limits.Config, error) {
+	cfg := limits.Config{
+		MaxConcurrent: viper.GetInt("max_concurrent_calls"),
+		MaxQueued:     viper.GetInt("max_queued_calls"),
+		Timeout:       viper.GetDuration("call_timeout"),
+	}
+
+	concurrency, err := getStringSlice("tool_max_concurrent_calls")
+	if err != nil {
+		return limits.Config{}, err
+	}
+	for _, value := range concurrency {
+		t, err := limits.ParseToolConcurrency(value)
+		if err != nil {
+			return limits.Config{}, err
+		}
+		cfg.Tools = append(cfg.Tools, t)
+	}
+
+	timeouts, err := getStringSlice("tool_timeouts")
+	if err != nil {
+		return limits.Config{}, err
+	}
+	for _, value := range timeouts {
+		t, err := limits.ParseToolTimeout(value)
+		if err != nil {
+			return limits.Config{}, err
+		}
+		cfg.Tools = append(cfg.Tools, t)
+	}
+	return cfg, nil
+}
+
+// getCacheConfig returns the response cache configured via flag, environment or config file, or nil if caching is
+// disabled.
+func getCacheConfig() (*httpcache.Config, error) {
+	cfg := &httpcache.Config{
+		MaxBytes: viper.GetInt64("cache_max_size") << 20,
+		TTL:      viper.GetDuration("cache_ttl"),
+	}
+
+	switch store := viper.GetString("cache_store"); store {
+	case "":
+		return nil, nil
+	case "memory":
+		return cfg, nil
+	case "disk":
+		cfg.Dir = viper.GetString("cache_dir")
+		if cfg.Dir == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to find user cache directory: %w", err)
+			}
+			cfg.Dir = filepath.Join(dir, "github-mcp-server", "http")
+		}
+		return cfg, nil
+	default:
+		return nil, fmt.Errorf("unknown cache store %q, expected memory or disk", store)
+	}
+}
+
+// getTracingConfig returns where traces are exported to as configured via flag, environment or config file, or nil
+// if tracing is disabled.
+func getTracingConfig() (*tracing.Config, error) {
+	cfg := &tracing.Config{
+		Exporter: viper.GetString("trace_exporter"),
+		Endpoint: viper.GetString("trace_endpoint"),
+		File:     viper.GetString("trace_file"),
+	}
+
+	switch cfg.Exporter {
+	case "":
+		return nil, nil
+	case tracing.ExporterOTLP:
+		return cfg, nil
+	case tracing.ExporterFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("--trace-file is required with --trace-exporter=%s", tracing.ExporterFile)
+		}
+		return cfg, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s or %s", cfg.Exporter, tracing.ExporterOTLP, tracing.ExporterFile)
+	}
+}
+
+// getAuditLogConfig returns the audit log configured via flag, environment or config file, or nil if there is none.
+func getAuditLogConfig() *audit.Config {
+	path := viper.GetString("audit_log")
+	if path == "" {
+		return nil
+	}
+	return &audit.Config{
+		Path:       path,
+		MaxBytes:   viper.GetInt64("audit_log_max_size") << 20,
+		MaxBackups: viper.GetInt("audit_log_max_backups"),
+	}
+}
+
+// getLogRedactionConfig returns the rules masking secrets in logged commands, in addition to the default ones,
+// configured via flag, environment or config file.
+func getLogRedactionConfig() (mcplog.RedactionConfig, error) {
+	fields, err := getStringSlice("log_redact_fields")
+	if err != nil {
+		return mcplog.RedactionConfig{}, err
+	}
+	// Patterns are not split on commas, which they may well contain
+	patterns := viper.GetStringSlice("log_redact_patterns")
+	return mcplog.RedactionConfig{
+		Fields:           fields,
+		Patterns:         patterns,
+		ResourceContents: viper.GetBool("log_resource_contents"),
+	}, nil
+}
+
+// getEndpoints returns the GitHub API URLs overridden via flag, environment or config file.
+func getEndpoints() ghmcp.Endpoints {
+	return ghmcp.Endpoints{
+		REST:    viper.GetString("api_url"),
+		GraphQL: viper.GetString("graphql_url"),
+		Upload:  viper.GetString("upload_url"),
+		Raw:     viper.GetString("raw_url"),
+	}
+}
+
+// getOwnerTokens returns the tokens of particular owners, configured as owner=ENV_VAR pairs via flag, environment or
+// config file, read from the environment variables named.
+func getOwnerTokens() ([]auth.OwnerToken, error) {
+	pairs, err := getStringSlice("owner_tokens")
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]auth.OwnerToken, 0, len(pairs))
+	for _, pair := range pairs {
+		owner, env, ok := strings.Cut(pair, "=")
+		if !ok || owner == "" || env == "" {
+			return nil, fmt.Errorf("invalid owner token %q, expected owner=ENV_VAR", pair)
+		}
+		token := os.Getenv(env)
+		if token == "" {
+			return nil, fmt.Errorf("environment variable %s, holding the token for owner %s, is not set", env, owner)
+		}
+		tokens = append(tokens, auth.OwnerToken{Owner: owner, Token: token})
+	}
+	return tokens, nil
+}
+
+// hostSettings are the settings of one of the hosts in the config file.
+type hostSettings struct {
+	Name       string   `mapstructure:"name"`
+	URL        string   `mapstructure:"url"`
+	Owners     []string `mapstructure:"owners"`
+	TokenEnv   string   `mapstructure:"token_env"`
+	APIURL     string   `mapstructure:"api_url"`
+	GraphQLURL string   `mapstructure:"graphql_url"`
+	UploadURL  string   `mapstructure:"upload_url"`
+	RawURL     string   `mapstructure:"raw_url"`
+}
+
+// getHosts returns the further GitHub hosts configured in the config file, each authenticated with the token in the
+// environment variable named by its token-env, or else the token stored by login for it.
+func getHosts() ([]ghmcp.HostConfig, error) {
+	var settings []hostSettings
+	if err := viper.UnmarshalKey("hosts", &settings); err != nil {
+		return nil, err
+	}
+
+	hosts := make([]ghmcp.HostConfig, 0, len(settings))
+	for _, s := range settings {
+		var token string
+		if s.TokenEnv != "" {
+			token = os.Getenv(s.TokenEnv)
+		}
+		if token == "" {
+			store, err := auth.DefaultCredentialStore()
+			if err != nil {
+				return nil, err
+			}
+			if token, err = ghmcp.StoredToken(store, s.URL); err != nil {
+				return nil, err
+			}
+		}
+		if token == "" {
+			return nil, fmt.Errorf("no token for host %s: set the environment variable named by its token-env, or log in with `github-mcp-server login --gh-host %s`", s.Name, s.URL)
+		}
+
+		hosts = append(hosts, ghmcp.HostConfig{
+			Name: s.Name,
+			URL:  s.URL,
+			Endpoints: ghmcp.Endpoints{
+				REST:    s.APIURL,
+				GraphQL: s.GraphQLURL,
+				Upload:  s.UploadURL,
+				Raw:     s.RawURL,
+			},
+			Token:  token,
+			Owners: s.Owners,
+		})
+	}
+	return hosts, nil
+}
+
+// getHostNames returns the further GitHub hosts configured in the config file with only their names and owners,
+// which are all describing the tools needs, so that no token is needed for them.
+func getHostNames() ([]ghmcp.HostConfig, error) {
+	var settings []hostSettings
+	if err := viper.UnmarshalKey("hosts", &settings); err != nil {
+		return nil, err
+	}
+
+	hosts := make([]ghmcp.HostConfig, 0, len(settings))
+	for _, s := range settings {
+		hosts = append(hosts, ghmcp.HostConfig{Name: s.Name, Owners: s.Owners})
+	}
+	return hosts, nil
+}
+
+// getNetworkConfig returns how to connect to GitHub as configured via flag, environment or config file.
+func getNetworkConfig() network.Config {
+	return network.Config{
+		CACertFile:     viper.GetString("ca_cert"),
+		ClientCertFile: viper.GetString("client_cert"),
+		ClientKeyFile:  viper.GetString("client_key"),
+	}
+}
+
+// getGitHubAppConfig returns the GitHub App configured via flag or environment, or nil if there is none.
+func getGitHubAppConfig() *auth.AppConfig {
+	appID := viper.GetInt64("app_id")
+	if appID == 0 {
+		return nil
+	}
+	return &auth.AppConfig{
+		AppID:             appID,
+		PrivateKeyPath:    viper.GetString("app_private_key_file"),
+		InstallationID:    viper.GetInt64("app_installation_id"),
+		InstallationOwner: viper.GetString("app_installation_owner"),
+	}
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
+		var code exitCode
+		if errors.As(err, &code) {
+			os.Exit(int(code))
+		}
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
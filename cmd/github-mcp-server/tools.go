@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+
+	"github.com/github/github-mcp-server/internal/ghmcp"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	toolsCmd = &cobra.Command{
+		Use:   "tools",
+		Short: "Inspect the tools offered to agents",
+		Long:  `Inspect the tools the server offers to agents with the current toolsets, read-only mode, tool selection, dynamic toolsets, dry runs, hosts and translations, without needing a token.`,
+	}
+
+	toolsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the tools offered",
+		Long:  `List the name, toolset, annotations and title of every tool the server offers, or of every tool it can offer with --all.`,
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := getToolsConfig()
+			if err != nil {
+				return err
+			}
+			cfg.All = viper.GetBool("tools_all")
+			return ghmcp.ListTools(cfg)
+		},
+	}
+
+	toolsDescribeCmd = &cobra.Command{
+		Use:   "describe <name>",
+		Short: "Describe a tool",
+		Long:  `Describe a tool in full, including the JSON schema of its input, whether or not its toolset is enabled.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cfg, err := getToolsConfig()
+			if err != nil {
+				return err
+			}
+			return ghmcp.DescribeTool(cfg, args[0])
+		},
+	}
+
+	toolsSchemaCmd = &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON schemas of the tools offered",
+		Long:  `Print the tools the server offers as JSON, as it lists them to clients in reply to tools/list. Tools the scope check hides or annotates, which depends on the token in use, are printed as they are.`,
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := getToolsConfig()
+			if err != nil {
+				return err
+			}
+			return ghmcp.PrintToolSchemas(cfg)
+		},
+	}
+)
+
+func init() {
+	toolsCmd.PersistentFlags().String("format", ghmcp.FormatTable, "Output format, \"table\" or \"json\"")
+	toolsListCmd.Flags().Bool("all", false, "List the tools of every toolset, including those not enabled")
+
+	_ = viper.BindPFlag("tools_format", toolsCmd.PersistentFlags().Lookup("format"))
+	_ = viper.BindPFlag("tools_all", toolsListCmd.Flags().Lookup("all"))
+
+	toolsCmd.AddCommand(toolsListCmd)
+	toolsCmd.AddCommand(toolsDescribeCmd)
+	toolsCmd.AddCommand(toolsSchemaCmd)
+	rootCmd.AddCommand(toolsCmd)
+}
+
+// getToolsConfig returns the tools selection configured via flag, environment or config file.
+func getToolsConfig() (ghmcp.ToolsConfig, error) {
+	enabledToolsets, err := getEnabledToolsets()
+	if err != nil {
+		return ghmcp.ToolsConfig{}, err
+	}
+	tools, excludeTools, err := getToolFilter()
+	if err != nil {
+		return ghmcp.ToolsConfig{}, err
+	}
+	hosts, err := getHostNames()
+	if err != nil {
+		return ghmcp.ToolsConfig{}, err
+	}
+	t, _ := translations.TranslationHelper()
+	return ghmcp.ToolsConfig{
+		EnabledToolsets: enabledToolsets,
+		Tools:           tools,
+		ExcludeTools:    excludeTools,
+		ReadOnly:        viper.GetBool("read-only"),
+		DynamicToolsets: viper.GetBool("dynamic_toolsets"),
+		DryRun:          viper.GetBool("dry_run"),
+		Hosts:           hosts,
+		Format:          viper.GetString("tools_format"),
+		Translator:      t,
+		Out:             os.Stdout,
+	}, nil
+}
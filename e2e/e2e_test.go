@@ -173,10 +173,12 @@ func setupMCPClient(t *testing.T, options ...clientOption) *mcpClient.Client {
 		}
 
 		ghServer, err := ghmcp.NewMCPServer(ghmcp.MCPServerConfig{
-			Token:           token,
-			EnabledToolsets: enabledToolsets,
-			Host:            getE2EHost(),
-			Translator:      translations.NullTranslationHelper,
+			ServerOptions: ghmcp.ServerOptions{
+				Token:           token,
+				EnabledToolsets: enabledToolsets,
+				Host:            getE2EHost(),
+			},
+			Translator: translations.NullTranslationHelper,
 		})
 		require.NoError(t, err, "expected to construct MCP server successfully")
 
@@ -496,10 +498,10 @@ func TestFileDeletion(t *testing.T) {
 	getFileContentsRequest := mcp.CallToolRequest{}
 	getFileContentsRequest.Params.Name = "get_file_contents"
 	getFileContentsRequest.Params.Arguments = map[string]any{
-		"owner":  currentOwner,
-		"repo":   repoName,
-		"path":   "test-file.txt",
-		"branch": "test-branch",
+		"owner": currentOwner,
+		"repo":  repoName,
+		"path":  "test-file.txt",
+		"ref":   "refs/heads/test-branch",
 	}
 
 	t.Logf("Getting file contents in %s/%s...", currentOwner, repoName)
@@ -688,10 +690,10 @@ func TestDirectoryDeletion(t *testing.T) {
 	getFileContentsRequest := mcp.CallToolRequest{}
 	getFileContentsRequest.Params.Name = "get_file_contents"
 	getFileContentsRequest.Params.Arguments = map[string]any{
-		"owner":  currentOwner,
-		"repo":   repoName,
-		"path":   "test-dir/test-file.txt",
-		"branch": "test-branch",
+		"owner": currentOwner,
+		"repo":  repoName,
+		"path":  "test-dir/test-file.txt",
+		"ref":   "refs/heads/test-branch",
 	}
 
 	t.Logf("Getting file contents in %s/%s...", currentOwner, repoName)
@@ -791,7 +793,7 @@ func TestDirectoryDeletion(t *testing.T) {
 func TestRequestCopilotReview(t *testing.T) {
 	t.Parallel()
 
-	if getE2EHost() != "" && getE2EHost() != "https://github.com" {
+	if !usingFake && getE2EHost() != "" && getE2EHost() != "https://github.com" {
 		t.Skip("Skipping test because the host does not support copilot reviews")
 	}
 
@@ -839,7 +841,7 @@ func TestRequestCopilotReview(t *testing.T) {
 	// Cleanup the repository after the test
 	t.Cleanup(func() {
 		// MCP Server doesn't support deletions, but we can use the GitHub Client
-		ghClient := gogithub.NewClient(nil).WithAuthToken(getE2EToken(t))
+		ghClient := getRESTClient(t)
 		t.Logf("Deleting repository %s/%s...", currentOwner, repoName)
 		_, err := ghClient.Repositories.Delete(context.Background(), currentOwner, repoName)
 		require.NoError(t, err, "expected to delete repository successfully")
@@ -925,7 +927,7 @@ func TestRequestCopilotReview(t *testing.T) {
 
 	// Finally, get requested reviews and see copilot is in there
 	// MCP Server doesn't support requesting reviews yet, but we can use the GitHub Client
-	ghClient := gogithub.NewClient(nil).WithAuthToken(getE2EToken(t))
+	ghClient := getRESTClient(t)
 	t.Logf("Getting reviews for pull request in %s/%s...", currentOwner, repoName)
 	reviewRequests, _, err := ghClient.PullRequests.ListReviewers(context.Background(), currentOwner, repoName, 1, nil)
 	require.NoError(t, err, "expected to get review requests successfully")
@@ -939,7 +941,7 @@ func TestRequestCopilotReview(t *testing.T) {
 func TestAssignCopilotToIssue(t *testing.T) {
 	t.Parallel()
 
-	if getE2EHost() != "" && getE2EHost() != "https://github.com" {
+	if !usingFake && getE2EHost() != "" && getE2EHost() != "https://github.com" {
 		t.Skip("Skipping test because the host does not support copilot being assigned to issues")
 	}
 
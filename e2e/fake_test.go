@@ -0,0 +1,43 @@
+//go:build e2e
+
+package e2e_test
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/github/github-mcp-server/internal/fakegithub"
+)
+
+// fakeHost is the value of GITHUB_MCP_SERVER_E2E_HOST that runs the tests against an in-process fake GitHub rather
+// than a real one, needing neither a token nor network access.
+const fakeHost = "fake"
+
+// usingFake reports whether the tests run against the fake GitHub.
+var usingFake bool
+
+func TestMain(m *testing.M) {
+	if os.Getenv("GITHUB_MCP_SERVER_E2E_HOST") != fakeHost {
+		os.Exit(m.Run())
+	}
+
+	const fakeToken = "fake-e2e-token"
+	ts := httptest.NewServer(fakegithub.New(fakegithub.Options{Token: fakeToken}))
+	usingFake = true
+
+	// The server runs in process, as the fake is not reachable from a container
+	for name, value := range map[string]string{
+		"GITHUB_MCP_SERVER_E2E_HOST":  ts.URL,
+		"GITHUB_MCP_SERVER_E2E_TOKEN": fakeToken,
+		"GITHUB_MCP_SERVER_E2E_DEBUG": "true",
+	} {
+		if err := os.Setenv(name, value); err != nil {
+			panic(err)
+		}
+	}
+
+	code := m.Run()
+	ts.Close()
+	os.Exit(code)
+}
@@ -0,0 +1,469 @@
+// Package config loads the server configuration file, which can hold any setting otherwise given by flag or
+// environment variable.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/confirm"
+	"github.com/github/github-mcp-server/pkg/limits"
+	mcplog "github.com/github/github-mcp-server/pkg/log"
+	"github.com/github/github-mcp-server/pkg/policy"
+	"github.com/github/github-mcp-server/pkg/scopes"
+	"github.com/github/github-mcp-server/pkg/tracing"
+	"gopkg.in/yaml.v3"
+)
+
+type kind int
+
+const (
+	kindString kind = iota
+	kindBool
+	kindInt
+	kindDuration
+	kindStringList
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "a boolean"
+	case kindInt:
+		return "an integer"
+	case kindDuration:
+		return "a duration (e.g. 30s)"
+	case kindStringList:
+		return "a list of strings"
+	default:
+		return "a string"
+	}
+}
+
+// entry is either a field holding a value, or a section holding further entries.
+type entry interface {
+	isEntry()
+}
+
+// field is a setting, stored under the same key the corresponding flag is bound to.
+type field struct {
+	key  string
+	kind kind
+	// check optionally validates each string value, or each element of a list
+	check func(opts Options, value string) error
+}
+
+type section map[string]entry
+
+// table maps names chosen by the user to entries described by the same section, such as the hosts. It is stored
+// under key as a list holding the settings of each entry, with the entry's name under "name".
+type table struct {
+	key     string
+	section section
+	// required lists the keys every entry must set
+	required []string
+}
+
+func (field) isEntry()   {}
+func (section) isEntry() {}
+func (table) isEntry()   {}
+
+// Options provides what the config is validated against.
+type Options struct {
+	// Toolsets are the names of the available toolsets
+	Toolsets []string
+
+	// Tools are the names of the available tools
+	Tools []string
+}
+
+func checkToolset(opts Options, value string) error {
+	if value == "all" || slices.Contains(opts.Toolsets, value) {
+		return nil
+	}
+	return fmt.Errorf("unknown toolset %q, expected one of: all, %s", value, strings.Join(opts.Toolsets, ", "))
+}
+
+func checkTool(opts Options, value string) error {
+	if _, err := path.Match(value, ""); err != nil {
+		return fmt.Errorf("invalid tool pattern %q: %w", value, err)
+	}
+	for _, tool := range opts.Tools {
+		if ok, _ := path.Match(value, tool); ok {
+			return nil
+		}
+	}
+	if strings.ContainsAny(value, `*?[\`) {
+		return fmt.Errorf("tool pattern %q does not match any tool", value)
+	}
+	return fmt.Errorf("unknown tool %q", value)
+}
+
+func checkConfirmTool(opts Options, value string) error {
+	if value == confirm.Destructive {
+		return nil
+	}
+	return checkTool(opts, value)
+}
+
+func checkRepoPattern(_ Options, value string) error {
+	_, err := policy.NewRepoScope([]string{value})
+	return err
+}
+
+func checkOwnerPattern(_ Options, value string) error {
+	if _, err := path.Match(value, ""); err != nil {
+		return fmt.Errorf("invalid owner pattern %q: %w", value, err)
+	}
+	return nil
+}
+
+func checkOwnerToken(opts Options, value string) error {
+	owner, env, ok := strings.Cut(value, "=")
+	if !ok || owner == "" || env == "" {
+		return fmt.Errorf("invalid owner token %q, expected owner=ENV_VAR", value)
+	}
+	return checkOwnerPattern(opts, owner)
+}
+
+func checkToolConcurrency(opts Options, value string) error {
+	t, err := limits.ParseToolConcurrency(value)
+	if err != nil {
+		return err
+	}
+	return checkTool(opts, t.Pattern)
+}
+
+func checkToolTimeout(opts Options, value string) error {
+	t, err := limits.ParseToolTimeout(value)
+	if err != nil {
+		return err
+	}
+	return checkTool(opts, t.Pattern)
+}
+
+func checkTraceExporter(_ Options, value string) error {
+	if value == tracing.ExporterOTLP || value == tracing.ExporterFile {
+		return nil
+	}
+	return fmt.Errorf("unknown trace exporter %q, expected %s or %s", value, tracing.ExporterOTLP, tracing.ExporterFile)
+}
+
+func checkScopeCheck(_ Options, value string) error {
+	if value == scopes.ModeHide || value == scopes.ModeAnnotate || value == scopes.ModeOff {
+		return nil
+	}
+	return fmt.Errorf("unknown scope check mode %q, expected %s, %s or %s", value, scopes.ModeHide, scopes.ModeAnnotate, scopes.ModeOff)
+}
+
+func checkLogFormat(_ Options, value string) error {
+	if value == mcplog.FormatText || value == mcplog.FormatJSON {
+		return nil
+	}
+	return fmt.Errorf("unknown log format %q, expected %s or %s", value, mcplog.FormatText, mcplog.FormatJSON)
+}
+
+func checkRedactPattern(_ Options, value string) error {
+	_, err := mcplog.NewRedactor(mcplog.RedactionConfig{Patterns: []string{value}})
+	return err
+}
+
+func checkCacheStore(_ Options, value string) error {
+	if value == "memory" || value == "disk" {
+		return nil
+	}
+	return fmt.Errorf("unknown cache store %q, expected memory or disk", value)
+}
+
+// schema describes every key the config file may contain.
+var schema = section{
+	"host":             field{key: "host", kind: kindString},
+	"toolsets":         field{key: "toolsets", kind: kindStringList, check: checkToolset},
+	"read-only":        field{key: "read-only", kind: kindBool},
+	"dry-run":          field{key: "dry_run", kind: kindBool},
+	"dynamic-toolsets": field{key: "dynamic_toolsets", kind: kindBool},
+	"owner-tokens":     field{key: "owner_tokens", kind: kindStringList, check: checkOwnerToken},
+	"scope-check":      field{key: "scope_check", kind: kindString, check: checkScopeCheck},
+	"record":           field{key: "record", kind: kindString},
+	"replay":           field{key: "replay", kind: kindString},
+	"urls": section{
+		"api":     field{key: "api_url", kind: kindString},
+		"graphql": field{key: "graphql_url", kind: kindString},
+		"upload":  field{key: "upload_url", kind: kindString},
+		"raw":     field{key: "raw_url", kind: kindString},
+	},
+	"tls": section{
+		"ca-cert":     field{key: "ca_cert", kind: kindString},
+		"client-cert": field{key: "client_cert", kind: kindString},
+		"client-key":  field{key: "client_key", kind: kindString},
+	},
+	"log": section{
+		"file":              field{key: "log-file", kind: kindString},
+		"format":            field{key: "log_format", kind: kindString, check: checkLogFormat},
+		"command-logging":   field{key: "enable-command-logging", kind: kindBool},
+		"redact-fields":     field{key: "log_redact_fields", kind: kindStringList},
+		"redact-patterns":   field{key: "log_redact_patterns", kind: kindStringList, check: checkRedactPattern},
+		"resource-contents": field{key: "log_resource_contents", kind: kindBool},
+	},
+	"tools": section{
+		"allow": field{key: "tools", kind: kindStringList, check: checkTool},
+		"deny":  field{key: "exclude_tools", kind: kindStringList, check: checkTool},
+	},
+	"policy": section{
+		"repos": field{key: "repos", kind: kindStringList, check: checkRepoPattern},
+	},
+	"confirm": section{
+		"tools":             field{key: "confirm_tools", kind: kindStringList, check: checkConfirmTool},
+		"allow-unconfirmed": field{key: "allow_unconfirmed", kind: kindBool},
+	},
+	"rate-limit": section{
+		"max-wait":    field{key: "rate_limit_max_wait", kind: kindDuration},
+		"max-retries": field{key: "max_retries", kind: kindInt},
+	},
+	"limits": section{
+		"max-concurrent-calls":      field{key: "max_concurrent_calls", kind: kindInt},
+		"max-queued-calls":          field{key: "max_queued_calls", kind: kindInt},
+		"tool-max-concurrent-calls": field{key: "tool_max_concurrent_calls", kind: kindStringList, check: checkToolConcurrency},
+		"call-timeout":              field{key: "call_timeout", kind: kindDuration},
+		"tool-timeouts":             field{key: "tool_timeouts", kind: kindStringList, check: checkToolTimeout},
+	},
+	"cache": section{
+		"store":    field{key: "cache_store", kind: kindString, check: checkCacheStore},
+		"dir":      field{key: "cache_dir", kind: kindString},
+		"max-size": field{key: "cache_max_size", kind: kindInt},
+		"ttl":      field{key: "cache_ttl", kind: kindDuration},
+	},
+	"metrics": section{
+		"address": field{key: "metrics_address", kind: kindString},
+	},
+	"tracing": section{
+		"exporter": field{key: "trace_exporter", kind: kindString, check: checkTraceExporter},
+		"endpoint": field{key: "trace_endpoint", kind: kindString},
+		"file":     field{key: "trace_file", kind: kindString},
+	},
+	"audit": section{
+		"file":        field{key: "audit_log", kind: kindString},
+		"max-size":    field{key: "audit_log_max_size", kind: kindInt},
+		"max-backups": field{key: "audit_log_max_backups", kind: kindInt},
+	},
+	"http": section{
+		"listen-address":   field{key: "listen_address", kind: kindString},
+		"base-path":        field{key: "base_path", kind: kindString},
+		"shutdown-timeout": field{key: "shutdown_timeout", kind: kindDuration},
+	},
+	"hosts": table{
+		key: "hosts",
+		section: section{
+			"url":       field{key: "url", kind: kindString},
+			"owners":    field{key: "owners", kind: kindStringList, check: checkOwnerPattern},
+			"token-env": field{key: "token_env", kind: kindString},
+			"urls": section{
+				"api":     field{key: "api_url", kind: kindString},
+				"graphql": field{key: "graphql_url", kind: kindString},
+				"upload":  field{key: "upload_url", kind: kindString},
+				"raw":     field{key: "raw_url", kind: kindString},
+			},
+		},
+		required: []string{"url"},
+	},
+	"app": section{
+		"id":                 field{key: "app_id", kind: kindInt},
+		"private-key-file":   field{key: "app_private_key_file", kind: kindString},
+		"installation-id":    field{key: "app_installation_id", kind: kindInt},
+		"installation-owner": field{key: "app_installation_owner", kind: kindString},
+	},
+}
+
+// Load reads and validates the YAML or JSON config file at path, returning its settings keyed by the name their
+// flags are bound to in viper. All problems found are reported, each pointing at the offending key.
+func Load(path string, opts Options) (map[string]any, error) {
+	data, err := os.ReadFile(path) //#nosec G304 -- the path is provided by the user running the server
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return Parse(path, data, opts)
+}
+
+// Parse validates the YAML or JSON config in data, which was read from path.
+func Parse(path string, data []byte, opts Options) (map[string]any, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	settings := map[string]any{}
+	if len(doc.Content) == 0 {
+		// An empty file configures nothing
+		return settings, nil
+	}
+
+	p := &parser{path: path, opts: opts, settings: settings}
+	p.section(doc.Content[0], "", schema)
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	return settings, nil
+}
+
+type parser struct {
+	path     string
+	opts     Options
+	settings map[string]any
+	errs     []error
+}
+
+func (p *parser) errorf(n *yaml.Node, key string, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if key != "" {
+		msg = key + ": " + msg
+	}
+	p.errs = append(p.errs, fmt.Errorf("%s:%d:%d: %s", p.path, n.Line, n.Column, msg))
+}
+
+func (p *parser) section(n *yaml.Node, prefix string, s section) {
+	if n.Kind != yaml.MappingNode {
+		p.errorf(n, prefix, "expected a mapping")
+		return
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keyNode, valueNode := n.Content[i], n.Content[i+1]
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		e, ok := s[keyNode.Value]
+		if !ok {
+			p.errorf(keyNode, "", "unknown key %q", key)
+			continue
+		}
+
+		switch e := e.(type) {
+		case section:
+			p.section(valueNode, key, e)
+		case field:
+			p.field(valueNode, key, e)
+		case table:
+			p.table(valueNode, key, e)
+		}
+	}
+}
+
+func (p *parser) table(n *yaml.Node, key string, t table) {
+	if n.Kind != yaml.MappingNode {
+		p.errorf(n, key, "expected a mapping")
+		return
+	}
+
+	entries := make([]map[string]any, 0, len(n.Content)/2)
+	errs := len(p.errs)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		nameNode, valueNode := n.Content[i], n.Content[i+1]
+		entryKey := key + "." + nameNode.Value
+
+		// Each entry is parsed into settings of its own
+		entry := &parser{path: p.path, opts: p.opts, settings: map[string]any{}}
+		entry.section(valueNode, entryKey, t.section)
+		p.errs = append(p.errs, entry.errs...)
+		if len(entry.errs) > 0 {
+			continue
+		}
+
+		for _, required := range t.required {
+			if _, ok := entry.settings[t.section[required].(field).key]; !ok {
+				p.errorf(valueNode, entryKey, "%s is required", required)
+			}
+		}
+		entry.settings["name"] = nameNode.Value
+		entries = append(entries, entry.settings)
+	}
+
+	if len(p.errs) == errs {
+		p.settings[t.key] = entries
+	}
+}
+
+func (p *parser) field(n *yaml.Node, key string, f field) {
+	if f.kind == kindStringList {
+		p.stringList(n, key, f)
+		return
+	}
+
+	if n.Kind != yaml.ScalarNode {
+		p.errorf(n, key, "expected %s", f.kind)
+		return
+	}
+
+	switch f.kind {
+	case kindString:
+		if n.Tag != "!!str" {
+			p.errorf(n, key, "expected %s, got %q", f.kind, n.Value)
+			return
+		}
+		if f.check != nil {
+			if err := f.check(p.opts, n.Value); err != nil {
+				p.errorf(n, key, "%v", err)
+				return
+			}
+		}
+		p.settings[f.key] = n.Value
+	case kindBool:
+		v, err := strconv.ParseBool(n.Value)
+		if n.Tag != "!!bool" || err != nil {
+			p.errorf(n, key, "expected %s, got %q", f.kind, n.Value)
+			return
+		}
+		p.settings[f.key] = v
+	case kindInt:
+		v, err := strconv.ParseInt(n.Value, 10, 64)
+		if n.Tag != "!!int" || err != nil {
+			p.errorf(n, key, "expected %s, got %q", f.kind, n.Value)
+			return
+		}
+		p.settings[f.key] = v
+	case kindDuration:
+		v, err := time.ParseDuration(n.Value)
+		if err != nil {
+			p.errorf(n, key, "expected %s, got %q", f.kind, n.Value)
+			return
+		}
+		p.settings[f.key] = v
+	}
+}
+
+func (p *parser) stringList(n *yaml.Node, key string, f field) {
+	if n.Kind != yaml.SequenceNode {
+		p.errorf(n, key, "expected %s", f.kind)
+		return
+	}
+
+	values := make([]string, 0, len(n.Content))
+	valid := true
+	for i, item := range n.Content {
+		itemKey := fmt.Sprintf("%s[%d]", key, i)
+		if item.Kind != yaml.ScalarNode || item.Tag != "!!str" {
+			p.errorf(item, itemKey, "expected a string")
+			valid = false
+			continue
+		}
+		if f.check != nil {
+			if err := f.check(p.opts, item.Value); err != nil {
+				p.errorf(item, itemKey, "%v", err)
+				valid = false
+				continue
+			}
+		}
+		values = append(values, item.Value)
+	}
+
+	if valid {
+		p.settings[f.key] = values
+	}
+}
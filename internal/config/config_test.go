@@ -0,0 +1,273 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOptions = Options{
+	Toolsets: []string{"issues", "pull_requests", "repos"},
+	Tools:    []string{"get_issue", "get_pull_request", "merge_pull_request"},
+}
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		expected      map[string]any
+		expectedError []string
+	}{
+		{
+			name: "full YAML config",
+			data: `
+host: https://github.example.com
+toolsets: [issues, pull_requests]
+read-only: true
+dry-run: true
+dynamic-toolsets: false
+log:
+  file: /tmp/server.log
+  command-logging: true
+tools:
+  allow: [get_issue, get_pull_request]
+  deny: [merge_pull_request]
+http:
+  listen-address: 0.0.0.0:8080
+  base-path: /github
+  shutdown-timeout: 30s
+app:
+  id: 123
+  private-key-file: /keys/app.pem
+  installation-id: 456
+  installation-owner: octo-org
+`,
+			expected: map[string]any{
+				"host":                   "https://github.example.com",
+				"toolsets":               []string{"issues", "pull_requests"},
+				"read-only":              true,
+				"dry_run":                true,
+				"dynamic_toolsets":       false,
+				"log-file":               "/tmp/server.log",
+				"enable-command-logging": true,
+				"tools":                  []string{"get_issue", "get_pull_request"},
+				"exclude_tools":          []string{"merge_pull_request"},
+				"listen_address":         "0.0.0.0:8080",
+				"base_path":              "/github",
+				"shutdown_timeout":       30 * time.Second,
+				"app_id":                 int64(123),
+				"app_private_key_file":   "/keys/app.pem",
+				"app_installation_id":    int64(456),
+				"app_installation_owner": "octo-org",
+			},
+		},
+		{
+			name:     "JSON config",
+			data:     `{"toolsets": ["all"], "read-only": true}`,
+			expected: map[string]any{"toolsets": []string{"all"}, "read-only": true},
+		},
+		{
+			name:     "empty config",
+			data:     ``,
+			expected: map[string]any{},
+		},
+		{
+			name: "unknown keys are reported with their position",
+			data: "host: github.com\nlog:\n  level: debug\n",
+			expectedError: []string{
+				`config.yaml:3:3: unknown key "log.level"`,
+			},
+		},
+		{
+			name: "values of the wrong type are rejected",
+			data: "read-only: yes please\nhttp:\n  shutdown-timeout: 10\ntoolsets: repos\n",
+			expectedError: []string{
+				`config.yaml:1:12: read-only: expected a boolean, got "yes please"`,
+				`config.yaml:3:21: http.shutdown-timeout: expected a duration (e.g. 30s), got "10"`,
+				`config.yaml:4:11: toolsets: expected a list of strings`,
+			},
+		},
+		{
+			name: "unknown toolsets and tools are rejected",
+			data: "toolsets: [repos, gists]\ntools:\n  deny: [delete_everything]\n",
+			expectedError: []string{
+				`config.yaml:1:19: toolsets[1]: unknown toolset "gists", expected one of: all, issues, pull_requests, repos`,
+				`config.yaml:3:10: tools.deny[0]: unknown tool "delete_everything"`,
+			},
+		},
+		{
+			name:     "tool patterns are accepted when they match a tool",
+			data:     "tools:\n  allow: [get_*]\n  deny: ['merge_*']\n",
+			expected: map[string]any{"tools": []string{"get_*"}, "exclude_tools": []string{"merge_*"}},
+		},
+		{
+			name: "tool patterns that match nothing or are malformed are rejected",
+			data: "tools:\n  allow: [delete_*, 'get_[']\n",
+			expectedError: []string{
+				`config.yaml:2:11: tools.allow[0]: tool pattern "delete_*" does not match any tool`,
+				`config.yaml:2:21: tools.allow[1]: invalid tool pattern "get_["`,
+			},
+		},
+		{
+			name:     "repository scope",
+			data:     "policy:\n  repos: [myorg/*, '!myorg/secrets-*']\n",
+			expected: map[string]any{"repos": []string{"myorg/*", "!myorg/secrets-*"}},
+		},
+		{
+			name:          "malformed repository patterns are rejected",
+			data:          "policy:\n  repos: [myorg]\n",
+			expectedError: []string{`config.yaml:2:11: policy.repos[0]: invalid repository pattern "myorg": expected owner/repo`},
+		},
+		{
+			name:     "confirmation",
+			data:     "confirm:\n  tools: [destructive, merge_*]\n  allow-unconfirmed: true\n",
+			expected: map[string]any{"confirm_tools": []string{"destructive", "merge_*"}, "allow_unconfirmed": true},
+		},
+		{
+			name:          "confirmation of unknown tools is rejected",
+			data:          "confirm:\n  tools: [delete_everything]\n",
+			expectedError: []string{`config.yaml:2:11: confirm.tools[0]: unknown tool "delete_everything"`},
+		},
+		{
+			name:     "limits",
+			data:     "limits:\n  max-concurrent-calls: 8\n  max-queued-calls: 20\n  tool-max-concurrent-calls: [get_*=2]\n  call-timeout: 2m\n  tool-timeouts: [merge_pull_request=10m]\n",
+			expected: map[string]any{"max_concurrent_calls": int64(8), "max_queued_calls": int64(20), "tool_max_concurrent_calls": []string{"get_*=2"}, "call_timeout": 2 * time.Minute, "tool_timeouts": []string{"merge_pull_request=10m"}},
+		},
+		{
+			name: "malformed tool limits are rejected",
+			data: "limits:\n  tool-max-concurrent-calls: [get_issue=0, delete_everything=1]\n  tool-timeouts: [get_issue]\n",
+			expectedError: []string{
+				`config.yaml:2:31: limits.tool-max-concurrent-calls[0]: invalid concurrency limit "0" for get_issue, expected a positive integer`,
+				`config.yaml:2:44: limits.tool-max-concurrent-calls[1]: unknown tool "delete_everything"`,
+				`config.yaml:3:19: limits.tool-timeouts[0]: invalid tool limit "get_issue", expected tool=duration`,
+			},
+		},
+		{
+			name:     "response cache",
+			data:     "cache:\n  store: disk\n  dir: /var/cache/github-mcp-server\n  max-size: 256\n  ttl: 2h\n",
+			expected: map[string]any{"cache_store": "disk", "cache_dir": "/var/cache/github-mcp-server", "cache_max_size": int64(256), "cache_ttl": 2 * time.Hour},
+		},
+		{
+			name:          "unknown cache stores are rejected",
+			data:          "cache:\n  store: redis\n",
+			expectedError: []string{`config.yaml:2:10: cache.store: unknown cache store "redis", expected memory or disk`},
+		},
+		{
+			name:     "log redaction",
+			data:     "log:\n  format: json\n  redact-fields: [content]\n  redact-patterns: ['AKIA[0-9A-Z]{16}']\n  resource-contents: true\n",
+			expected: map[string]any{"log_format": "json", "log_redact_fields": []string{"content"}, "log_redact_patterns": []string{"AKIA[0-9A-Z]{16}"}, "log_resource_contents": true},
+		},
+		{
+			name: "invalid log settings are rejected",
+			data: "log:\n  format: xml\n  redact-patterns: ['ghp_[']\n",
+			expectedError: []string{
+				`config.yaml:2:11: log.format: unknown log format "xml", expected text or json`,
+				`config.yaml:3:21: log.redact-patterns[0]: invalid redaction pattern "ghp_[": error parsing regexp: missing closing ]: ` + "`[`",
+			},
+		},
+		{
+			name:     "scope check",
+			data:     "scope-check: annotate\n",
+			expected: map[string]any{"scope_check": "annotate"},
+		},
+		{
+			name:          "unknown scope check modes are rejected",
+			data:          "scope-check: block\n",
+			expectedError: []string{`config.yaml:1:14: scope-check: unknown scope check mode "block", expected hide, annotate or off`},
+		},
+		{
+			name:     "cassettes",
+			data:     "record: testdata/session\nreplay: testdata/other\n",
+			expected: map[string]any{"record": "testdata/session", "replay": "testdata/other"},
+		},
+		{
+			name:     "tracing",
+			data:     "tracing:\n  exporter: otlp\n  endpoint: http://localhost:4318\n",
+			expected: map[string]any{"trace_exporter": "otlp", "trace_endpoint": "http://localhost:4318"},
+		},
+		{
+			name:          "unknown trace exporters are rejected",
+			data:          "tracing:\n  exporter: jaeger\n",
+			expectedError: []string{`config.yaml:2:13: tracing.exporter: unknown trace exporter "jaeger", expected otlp or file`},
+		},
+		{
+			name:     "audit log",
+			data:     "audit:\n  file: /var/log/github-mcp-server/audit.jsonl\n  max-size: 50\n  max-backups: 10\n",
+			expected: map[string]any{"audit_log": "/var/log/github-mcp-server/audit.jsonl", "audit_log_max_size": int64(50), "audit_log_max_backups": int64(10)},
+		},
+		{
+			name:     "endpoints and TLS",
+			data:     "urls:\n  api: https://ghes.example.com:8443/api/v3/\n  graphql: https://ghes.example.com:8443/api/graphql\ntls:\n  ca-cert: /etc/ssl/corp-ca.pem\n  client-cert: client.pem\n  client-key: client-key.pem\n",
+			expected: map[string]any{"api_url": "https://ghes.example.com:8443/api/v3/", "graphql_url": "https://ghes.example.com:8443/api/graphql", "ca_cert": "/etc/ssl/corp-ca.pem", "client_cert": "client.pem", "client_key": "client-key.pem"},
+		},
+		{
+			name: "hosts",
+			data: "hosts:\n  ghes:\n    url: https://ghes.example.com:8443\n    owners: [corp, corp-*]\n    token-env: GHES_TOKEN\n    urls:\n      graphql: https://ghes.example.com:8443/graphql\n  oss:\n    url: https://github.com\n",
+			expected: map[string]any{"hosts": []map[string]any{
+				{"name": "ghes", "url": "https://ghes.example.com:8443", "owners": []string{"corp", "corp-*"}, "token_env": "GHES_TOKEN", "graphql_url": "https://ghes.example.com:8443/graphql"},
+				{"name": "oss", "url": "https://github.com"},
+			}},
+		},
+		{
+			name: "hosts must have a URL and valid keys",
+			data: "hosts:\n  ghes:\n    owners: [corp]\n  other:\n    url: https://other.example.com\n    token: secret\n",
+			expectedError: []string{
+				`config.yaml:3:5: hosts.ghes: url is required`,
+				`config.yaml:6:5: unknown key "hosts.other.token"`,
+			},
+		},
+		{
+			name:     "owner tokens",
+			data:     "owner-tokens: [corp=CORP_TOKEN, corp-*=CORP_TOKEN]\n",
+			expected: map[string]any{"owner_tokens": []string{"corp=CORP_TOKEN", "corp-*=CORP_TOKEN"}},
+		},
+		{
+			name:          "malformed owner tokens are rejected",
+			data:          "owner-tokens: [corp, \"corp-[=CORP_TOKEN\"]\n",
+			expectedError: []string{`config.yaml:1:16: owner-tokens[0]: invalid owner token "corp", expected owner=ENV_VAR`, `owner-tokens[1]: invalid owner pattern "corp-["`},
+		},
+		{
+			name:     "metrics",
+			data:     "metrics:\n  address: localhost:9090\n",
+			expected: map[string]any{"metrics_address": "localhost:9090"},
+		},
+		{
+			name:          "the document must be a mapping",
+			data:          "- host\n",
+			expectedError: []string{`config.yaml:1:1: expected a mapping`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			settings, err := Parse("config.yaml", []byte(tc.data), testOptions)
+			if len(tc.expectedError) > 0 {
+				require.Error(t, err)
+				for _, msg := range tc.expectedError {
+					assert.Contains(t, err.Error(), msg)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, settings)
+		})
+	}
+}
+
+func Test_Load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("toolsets: [repos]\n"), 0600))
+
+	settings, err := Load(path, testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"toolsets": []string{"repos"}}, settings)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yml"), testOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read config file")
+}
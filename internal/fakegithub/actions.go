@@ -0,0 +1,515 @@
+package fakegithub
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workflows are the files under .github/workflows on the default branch. Dispatching one starts a run with a job
+// for each of those the file defines, which stays in progress until it is cancelled or CompleteWorkflowRun is
+// called, nothing being executed.
+
+const workflowsDir = ".github/workflows/"
+
+type workflow struct {
+	ID   int64
+	Path string
+	Name string
+	Jobs []string
+}
+
+type workflowRun struct {
+	ID         int64
+	Number     int
+	Attempt    int
+	Workflow   *workflow
+	HeadBranch string
+	HeadSHA    string
+	Event      string
+	Status     string
+	Conclusion string
+	Actor      *user
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Jobs       []*workflowJob
+}
+
+type workflowJob struct {
+	ID          int64
+	Name        string
+	Attempt     int
+	Status      string
+	Conclusion  string
+	StartedAt   time.Time
+	CompletedAt time.Time
+}
+
+func (s *Server) routeActions() {
+	const prefix = "/api/v3/repos/{owner}/{repo}/actions"
+	s.mux.HandleFunc("GET "+prefix+"/workflows", s.withRepo(s.listWorkflows))
+	s.mux.HandleFunc("GET "+prefix+"/workflows/{workflow}/runs", s.withRepo(s.listWorkflowRuns))
+	s.mux.HandleFunc("POST "+prefix+"/workflows/{workflow}/dispatches", s.withRepo(s.dispatchWorkflow))
+	s.mux.HandleFunc("GET "+prefix+"/runs", s.withRepo(s.listWorkflowRuns))
+	s.mux.HandleFunc("GET "+prefix+"/runs/{run}", s.withRun(s.getWorkflowRun))
+	s.mux.HandleFunc("POST "+prefix+"/runs/{run}/cancel", s.withRun(s.cancelWorkflowRun))
+	s.mux.HandleFunc("POST "+prefix+"/runs/{run}/rerun", s.withRun(s.rerunWorkflow))
+	s.mux.HandleFunc("POST "+prefix+"/runs/{run}/rerun-failed-jobs", s.withRun(s.rerunWorkflow))
+	s.mux.HandleFunc("GET "+prefix+"/runs/{run}/jobs", s.withRun(s.listWorkflowJobs))
+	s.mux.HandleFunc("GET "+prefix+"/runs/{run}/timing", s.withRun(s.getWorkflowRunUsage))
+	s.mux.HandleFunc("GET "+prefix+"/runs/{run}/artifacts", s.withRun(s.listWorkflowRunArtifacts))
+	s.mux.HandleFunc("GET "+prefix+"/runs/{run}/logs", s.withRun(s.getWorkflowRunLogs))
+	s.mux.HandleFunc("DELETE "+prefix+"/runs/{run}/logs", s.withRun(s.deleteWorkflowRunLogs))
+	s.mux.HandleFunc("GET "+prefix+"/jobs/{job}/logs", s.withRepo(s.getWorkflowJobLogs))
+	s.mux.HandleFunc("GET "+storagePrefix+"{owner}/{repo}/runs/{run}/logs.zip", s.withRun(s.downloadWorkflowRunLogs))
+	s.mux.HandleFunc("GET "+storagePrefix+"{owner}/{repo}/jobs/{job}/logs.txt", s.withRepo(s.downloadWorkflowJobLogs))
+}
+
+// CompleteWorkflowRun completes the run with runID of the repository owner/name, and its jobs in progress, with
+// conclusion, such as success or failure.
+func (s *Server) CompleteWorkflowRun(owner, name string, runID int64, conclusion string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, ok := s.repos[repoKey(owner, name)]
+	if !ok {
+		return fmt.Errorf("repository %s/%s not found", owner, name)
+	}
+	run := repo.run(runID)
+	if run == nil {
+		return fmt.Errorf("workflow run %d not found in %s/%s", runID, owner, name)
+	}
+	s.completeRun(run, conclusion)
+	return nil
+}
+
+func (s *Server) completeRun(run *workflowRun, conclusion string) {
+	now := s.now()
+	run.Status, run.Conclusion, run.UpdatedAt = "completed", conclusion, now
+	for _, job := range run.Jobs {
+		if job.Status != "completed" {
+			job.Status, job.Conclusion, job.CompletedAt = "completed", conclusion, now
+		}
+	}
+}
+
+// workflows returns the workflows defined on the default branch of repo, by path.
+func (repo *repository) workflows() []*workflow {
+	sha, _ := repo.resolve(repo.DefaultBranch)
+	var workflows []*workflow
+	for file, blob := range repo.files(sha) {
+		if !strings.HasPrefix(file, workflowsDir) || strings.Contains(file[len(workflowsDir):], "/") ||
+			(path.Ext(file) != ".yml" && path.Ext(file) != ".yaml") {
+			continue
+		}
+		id, ok := repo.workflowIDs[file]
+		if !ok {
+			id = int64(len(repo.workflowIDs) + 1)
+			repo.workflowIDs[file] = id
+		}
+		workflows = append(workflows, parseWorkflow(id, file, repo.blobs[blob]))
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].ID < workflows[j].ID })
+	return workflows
+}
+
+// parseWorkflow reads the name and jobs of the workflow at path from its definition, falling back on the path and
+// a single job for definitions that do not parse.
+func parseWorkflow(id int64, path string, data []byte) *workflow {
+	var definition struct {
+		Name string `yaml:"name"`
+		Jobs yaml.Node
+	}
+	wf := &workflow{ID: id, Path: path, Name: path}
+	if err := yaml.Unmarshal(data, &definition); err == nil && definition.Name != "" {
+		wf.Name = definition.Name
+	}
+	// Mapping nodes alternate keys and values
+	for i := 0; i+1 < len(definition.Jobs.Content); i += 2 {
+		name := definition.Jobs.Content[i].Value
+		for j := 0; j+1 < len(definition.Jobs.Content[i+1].Content); j += 2 {
+			if definition.Jobs.Content[i+1].Content[j].Value == "name" {
+				name = definition.Jobs.Content[i+1].Content[j+1].Value
+			}
+		}
+		wf.Jobs = append(wf.Jobs, name)
+	}
+	if len(wf.Jobs) == 0 {
+		wf.Jobs = []string{"build"}
+	}
+	return wf
+}
+
+// findWorkflow returns the workflow of repo with the ID or file name the workflow path parameter holds.
+func (repo *repository) findWorkflow(r *http.Request) *workflow {
+	name := r.PathValue("workflow")
+	for _, wf := range repo.workflows() {
+		if strconv.FormatInt(wf.ID, 10) == name || path.Base(wf.Path) == name {
+			return wf
+		}
+	}
+	return nil
+}
+
+func (repo *repository) run(id int64) *workflowRun {
+	for _, run := range repo.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+// withRun adapts h to be handed the workflow run the run path parameter names, answering with a 404 if there is
+// none.
+func (s *Server) withRun(h func(http.ResponseWriter, *http.Request, *repository, *workflowRun)) http.HandlerFunc {
+	return s.withRepo(func(w http.ResponseWriter, r *http.Request, repo *repository) {
+		id, ok := pathInt(w, r, "run")
+		if !ok {
+			return
+		}
+		run := repo.run(id)
+		if run == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		h(w, r, repo, run)
+	})
+}
+
+func (repo *repository) workflowJSON(r *http.Request, wf *workflow) map[string]any {
+	id := strconv.FormatInt(wf.ID, 10)
+	return map[string]any{
+		"id":         wf.ID,
+		"node_id":    "W_" + id,
+		"name":       wf.Name,
+		"path":       wf.Path,
+		"state":      "active",
+		"created_at": timeJSON(repo.CreatedAt),
+		"updated_at": timeJSON(repo.CreatedAt),
+		"url":        repo.apiURL(r, "actions", "workflows", id),
+		"html_url":   repo.htmlURL(r, "blob", repo.DefaultBranch, wf.Path),
+		"badge_url":  repo.htmlURL(r, "workflows", path.Base(wf.Path), "badge.svg"),
+	}
+}
+
+func (repo *repository) runJSON(r *http.Request, run *workflowRun) map[string]any {
+	id := strconv.FormatInt(run.ID, 10)
+	var conclusion any
+	if run.Conclusion != "" {
+		conclusion = run.Conclusion
+	}
+	return map[string]any{
+		"id":               run.ID,
+		"node_id":          nodeID("WFR", run.ID),
+		"name":             run.Workflow.Name,
+		"display_title":    run.Workflow.Name,
+		"path":             run.Workflow.Path,
+		"run_number":       run.Number,
+		"run_attempt":      run.Attempt,
+		"event":            run.Event,
+		"status":           run.Status,
+		"conclusion":       conclusion,
+		"workflow_id":      run.Workflow.ID,
+		"head_branch":      run.HeadBranch,
+		"head_sha":         run.HeadSHA,
+		"actor":            run.Actor.json(r),
+		"triggering_actor": run.Actor.json(r),
+		"created_at":       timeJSON(run.CreatedAt),
+		"updated_at":       timeJSON(run.UpdatedAt),
+		"run_started_at":   timeJSON(run.CreatedAt),
+		"url":              repo.apiURL(r, "actions", "runs", id),
+		"html_url":         repo.htmlURL(r, "actions", "runs", id),
+		"jobs_url":         repo.apiURL(r, "actions", "runs", id, "jobs"),
+		"logs_url":         repo.apiURL(r, "actions", "runs", id, "logs"),
+		"artifacts_url":    repo.apiURL(r, "actions", "runs", id, "artifacts"),
+		"cancel_url":       repo.apiURL(r, "actions", "runs", id, "cancel"),
+		"rerun_url":        repo.apiURL(r, "actions", "runs", id, "rerun"),
+		"workflow_url":     repo.apiURL(r, "actions", "workflows", strconv.FormatInt(run.Workflow.ID, 10)),
+		"repository":       repo.json(r),
+	}
+}
+
+func (repo *repository) jobJSON(r *http.Request, run *workflowRun, job *workflowJob) map[string]any {
+	var conclusion any
+	if job.Conclusion != "" {
+		conclusion = job.Conclusion
+	}
+	runID, id := strconv.FormatInt(run.ID, 10), strconv.FormatInt(job.ID, 10)
+	return map[string]any{
+		"id":            job.ID,
+		"run_id":        run.ID,
+		"node_id":       nodeID("CR", job.ID),
+		"name":          job.Name,
+		"workflow_name": run.Workflow.Name,
+		"head_branch":   run.HeadBranch,
+		"head_sha":      run.HeadSHA,
+		"run_attempt":   job.Attempt,
+		"status":        job.Status,
+		"conclusion":    conclusion,
+		"started_at":    timeJSON(job.StartedAt),
+		"completed_at":  timeJSON(job.CompletedAt),
+		"labels":        []string{"ubuntu-latest"},
+		"steps": []map[string]any{{
+			"name": "Run " + job.Name, "number": 1, "status": job.Status, "conclusion": conclusion,
+			"started_at": timeJSON(job.StartedAt), "completed_at": timeJSON(job.CompletedAt),
+		}},
+		"url":      repo.apiURL(r, "actions", "jobs", id),
+		"html_url": repo.htmlURL(r, "actions", "runs", runID, "job", id),
+		"run_url":  repo.apiURL(r, "actions", "runs", runID),
+	}
+}
+
+func (s *Server) listWorkflows(w http.ResponseWriter, r *http.Request, repo *repository) {
+	workflows := []map[string]any{}
+	for _, wf := range repo.workflows() {
+		workflows = append(workflows, repo.workflowJSON(r, wf))
+	}
+	total := len(workflows)
+	writeJSON(w, http.StatusOK, map[string]any{"total_count": total, "workflows": paginate(w, r, workflows)})
+}
+
+func (s *Server) listWorkflowRuns(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var wf *workflow
+	if r.PathValue("workflow") != "" {
+		if wf = repo.findWorkflow(r); wf == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+	}
+	query := r.URL.Query()
+	runs := []map[string]any{}
+	for i := len(repo.runs) - 1; i >= 0; i-- {
+		run := repo.runs[i]
+		if (wf != nil && run.Workflow.ID != wf.ID) ||
+			(query.Get("actor") != "" && !strings.EqualFold(run.Actor.Login, query.Get("actor"))) ||
+			(query.Get("branch") != "" && run.HeadBranch != query.Get("branch")) ||
+			(query.Get("event") != "" && run.Event != query.Get("event")) ||
+			(query.Get("status") != "" && run.Status != query.Get("status") && run.Conclusion != query.Get("status")) {
+			continue
+		}
+		runs = append(runs, repo.runJSON(r, run))
+	}
+	total := len(runs)
+	writeJSON(w, http.StatusOK, map[string]any{"total_count": total, "workflow_runs": paginate(w, r, runs)})
+}
+
+func (s *Server) dispatchWorkflow(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Ref    string         `json:"ref"`
+		Inputs map[string]any `json:"inputs"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	wf := repo.findWorkflow(r)
+	if wf == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	sha, ok := repo.resolve(body.Ref)
+	if !ok || body.Ref == "" {
+		writeError(w, http.StatusUnprocessableEntity, "No ref found for: "+body.Ref)
+		return
+	}
+
+	number := 1
+	for _, run := range repo.runs {
+		if run.Workflow.ID == wf.ID {
+			number++
+		}
+	}
+	now := s.now()
+	run := &workflowRun{
+		ID:         s.newID(),
+		Number:     number,
+		Attempt:    1,
+		Workflow:   wf,
+		HeadBranch: strings.TrimPrefix(strings.TrimPrefix(body.Ref, "refs/heads/"), "refs/tags/"),
+		HeadSHA:    sha,
+		Event:      "workflow_dispatch",
+		Status:     "in_progress",
+		Actor:      s.viewer,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	for _, name := range wf.Jobs {
+		run.Jobs = append(run.Jobs, &workflowJob{ID: s.newID(), Name: name, Attempt: 1, Status: "in_progress", StartedAt: now})
+	}
+	repo.runs = append(repo.runs, run)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getWorkflowRun(w http.ResponseWriter, r *http.Request, repo *repository, run *workflowRun) {
+	writeJSON(w, http.StatusOK, repo.runJSON(r, run))
+}
+
+func (s *Server) cancelWorkflowRun(w http.ResponseWriter, _ *http.Request, _ *repository, run *workflowRun) {
+	if run.Status == "completed" {
+		writeError(w, http.StatusConflict, "Cannot cancel a workflow run that is completed.")
+		return
+	}
+	s.completeRun(run, "cancelled")
+	writeJSON(w, http.StatusAccepted, map[string]any{})
+}
+
+// rerunWorkflow starts a new attempt of a completed run, re-running all of its jobs, or only those of the last
+// attempt that did not succeed when asked to rerun failed jobs.
+func (s *Server) rerunWorkflow(w http.ResponseWriter, r *http.Request, _ *repository, run *workflowRun) {
+	if run.Status != "completed" {
+		writeError(w, http.StatusForbidden, "This workflow run is not completed")
+		return
+	}
+	failedOnly := strings.HasSuffix(r.URL.Path, "/rerun-failed-jobs")
+	latest := latestJobs(run)
+	if failedOnly && run.Conclusion == "success" {
+		writeError(w, http.StatusForbidden, "This workflow run has no failed jobs")
+		return
+	}
+
+	now := s.now()
+	run.Attempt++
+	run.Status, run.Conclusion, run.UpdatedAt = "in_progress", "", now
+	for _, job := range latest {
+		if failedOnly && job.Conclusion == "success" {
+			continue
+		}
+		run.Jobs = append(run.Jobs, &workflowJob{ID: s.newID(), Name: job.Name, Attempt: run.Attempt, Status: "in_progress", StartedAt: now})
+	}
+	writeJSON(w, http.StatusCreated, map[string]any{})
+}
+
+// latestJobs returns the most recent attempt at each job of run.
+func latestJobs(run *workflowRun) []*workflowJob {
+	var jobs []*workflowJob
+	index := map[string]int{}
+	for _, job := range run.Jobs {
+		if i, ok := index[job.Name]; ok {
+			jobs[i] = job
+			continue
+		}
+		index[job.Name] = len(jobs)
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func (s *Server) listWorkflowJobs(w http.ResponseWriter, r *http.Request, repo *repository, run *workflowRun) {
+	selected := latestJobs(run)
+	if r.URL.Query().Get("filter") == "all" {
+		selected = run.Jobs
+	}
+	jobs := []map[string]any{}
+	for _, job := range selected {
+		jobs = append(jobs, repo.jobJSON(r, run, job))
+	}
+	total := len(jobs)
+	writeJSON(w, http.StatusOK, map[string]any{"total_count": total, "jobs": paginate(w, r, jobs)})
+}
+
+func (s *Server) getWorkflowRunUsage(w http.ResponseWriter, _ *http.Request, _ *repository, run *workflowRun) {
+	end := run.UpdatedAt
+	if run.Status != "completed" {
+		end = s.now()
+	}
+	duration := end.Sub(run.CreatedAt).Milliseconds()
+	jobRuns := []map[string]any{}
+	for _, job := range latestJobs(run) {
+		jobRuns = append(jobRuns, map[string]any{"job_id": job.ID, "duration_ms": duration})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"billable": map[string]any{
+			"UBUNTU": map[string]any{"total_ms": duration * int64(len(jobRuns)), "jobs": len(jobRuns), "job_runs": jobRuns},
+		},
+		"run_duration_ms": duration,
+	})
+}
+
+func (s *Server) listWorkflowRunArtifacts(w http.ResponseWriter, _ *http.Request, _ *repository, _ *workflowRun) {
+	// Nothing is executed, so no artifacts are ever uploaded
+	writeJSON(w, http.StatusOK, map[string]any{"total_count": 0, "artifacts": []any{}})
+}
+
+// jobLog returns the log of job, which only records what the fake did with it.
+func jobLog(run *workflowRun, job *workflowJob) string {
+	log := fmt.Sprintf("%s Run %s\n%s Started job %s of %s, attempt %d\n",
+		job.StartedAt.Format(time.RFC3339), job.Name, job.StartedAt.Format(time.RFC3339), job.Name, run.Workflow.Name, job.Attempt)
+	if job.Status == "completed" {
+		log += fmt.Sprintf("%s Job completed with conclusion %s\n", job.CompletedAt.Format(time.RFC3339), job.Conclusion)
+	}
+	return log
+}
+
+// getWorkflowRunLogs redirects to the archive of the logs of a run, as GitHub does to its storage.
+func (s *Server) getWorkflowRunLogs(w http.ResponseWriter, r *http.Request, repo *repository, run *workflowRun) {
+	http.Redirect(w, r, baseURL(r)+storagePrefix+repo.fullName()+"/runs/"+strconv.FormatInt(run.ID, 10)+"/logs.zip", http.StatusFound)
+}
+
+func (s *Server) downloadWorkflowRunLogs(w http.ResponseWriter, _ *http.Request, _ *repository, run *workflowRun) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for i, job := range latestJobs(run) {
+		f, err := archive.Create(fmt.Sprintf("%d_%s.txt", i, job.Name))
+		if err == nil {
+			_, err = f.Write([]byte(jobLog(run, job)))
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (s *Server) deleteWorkflowRunLogs(w http.ResponseWriter, _ *http.Request, _ *repository, run *workflowRun) {
+	if run.Status != "completed" {
+		writeError(w, http.StatusInternalServerError, "Failed to delete logs")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findJob returns the job the job path parameter names, and the run it belongs to.
+func (repo *repository) findJob(r *http.Request) (*workflowRun, *workflowJob) {
+	for _, run := range repo.runs {
+		for _, job := range run.Jobs {
+			if strconv.FormatInt(job.ID, 10) == r.PathValue("job") {
+				return run, job
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getWorkflowJobLogs redirects to the log of a job, as GitHub does to its storage.
+func (s *Server) getWorkflowJobLogs(w http.ResponseWriter, r *http.Request, repo *repository) {
+	if _, job := repo.findJob(r); job == nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	http.Redirect(w, r, baseURL(r)+storagePrefix+repo.fullName()+"/jobs/"+r.PathValue("job")+"/logs.txt", http.StatusFound)
+}
+
+func (s *Server) downloadWorkflowJobLogs(w http.ResponseWriter, r *http.Request, repo *repository) {
+	run, job := repo.findJob(r)
+	if job == nil {
+		http.Error(w, "404: Not Found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(jobLog(run, job)))
+}
@@ -0,0 +1,269 @@
+// Package fakegithub provides an in-process fake of the GitHub REST and GraphQL APIs, holding repositories, git
+// data, issues, pull requests and Actions workflow runs in memory, so that the server can be exercised end to end
+// without network access.
+//
+// The fake is laid out as GitHub Enterprise Server is, serving the REST API under /api/v3/, the GraphQL API at
+// /api/graphql and raw file contents under /raw/, so the URL it is served at can be used as the host of the
+// server. Only the endpoints the tools use are implemented, and only as far as they need.
+package fakegithub
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLogin is the login of the user the fake authenticates requests as, unless Options.Login is set.
+const DefaultLogin = "octocat"
+
+const (
+	// Copilot goes by different logins when requested as a reviewer and when assigned issues, both referring to
+	// the Copilot bot
+	copilotReviewerLogin = "copilot-pull-request-reviewer[bot]"
+	copilotAgentLogin    = "copilot-swe-agent"
+
+	// storagePrefix is where the fake serves what GitHub redirects to its storage for, such as logs, which is
+	// reached without authentication
+	storagePrefix = "/storage/"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Login of the authenticated user, defaulting to DefaultLogin
+	Login string
+
+	// Token requests must authenticate with, any being accepted if empty
+	Token string
+
+	// Scopes reported in the X-OAuth-Scopes header of responses, as for a classic token, which is left out if nil
+	Scopes []string
+}
+
+// Server is a fake GitHub, serving the REST API, the GraphQL API and raw file contents. It is safe for concurrent
+// use, requests being handled one at a time.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu      sync.Mutex
+	nextID  int64
+	viewer  *user
+	copilot *user
+	users   map[string]*user
+	repos   map[string]*repository
+	now     func() time.Time
+}
+
+// New returns a fake GitHub with no repositories.
+func New(opts Options) *Server {
+	if opts.Login == "" {
+		opts.Login = DefaultLogin
+	}
+	s := &Server{
+		opts:  opts,
+		mux:   http.NewServeMux(),
+		users: map[string]*user{},
+		repos: map[string]*repository{},
+		now:   func() time.Time { return time.Now().UTC().Truncate(time.Second) },
+	}
+	s.viewer = s.user(opts.Login)
+	s.copilot = &user{ID: s.newID(), Login: "Copilot", Type: "Bot"}
+	s.copilot.NodeID = nodeID("BOT", s.copilot.ID)
+	for _, login := range []string{"Copilot", copilotReviewerLogin, copilotAgentLogin} {
+		s.users[strings.ToLower(login)] = s.copilot
+	}
+
+	s.routeRepos()
+	s.routeGit()
+	s.routeIssues()
+	s.routePulls()
+	s.routeActions()
+	s.mux.Handle("POST /api/graphql", s.graphqlHandler())
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, storagePrefix) && !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+	if s.opts.Scopes != nil {
+		w.Header().Set("X-OAuth-Scopes", strings.Join(s.opts.Scopes, ", "))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.opts.Token == "" {
+		return true
+	}
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	return (strings.EqualFold(scheme, "bearer") || strings.EqualFold(scheme, "token")) && token == s.opts.Token
+}
+
+// CreateRepository adds a repository owned by owner, with a commit adding files on its default branch, main, unless
+// files is empty.
+func (s *Server) CreateRepository(owner, name string, files map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.repos[repoKey(owner, name)]; ok {
+		return fmt.Errorf("repository %s/%s already exists", owner, name)
+	}
+	repo := s.newRepository(s.user(owner), name, false, "")
+	if len(files) > 0 {
+		changes := make(map[string]*string, len(files))
+		for path, content := range files {
+			changes[path] = &content
+		}
+		if _, err := s.commitChanges(repo, repo.DefaultBranch, "Initial commit", changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type user struct {
+	ID     int64
+	NodeID string
+	Login  string
+	Type   string
+}
+
+// user returns the user with login, creating it if need be.
+func (s *Server) user(login string) *user {
+	if u, ok := s.users[strings.ToLower(login)]; ok {
+		return u
+	}
+	u := &user{ID: s.newID(), Login: login, Type: "User"}
+	u.NodeID = nodeID("U", u.ID)
+	s.users[strings.ToLower(login)] = u
+	return u
+}
+
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// nodeID returns the GraphQL node ID of the object of kind with id, e.g. PR_12.
+func nodeID(kind string, id int64) string {
+	return kind + "_" + strconv.FormatInt(id, 10)
+}
+
+// baseURL returns the URL the fake is served at, as seen by the client making r.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func apiURL(r *http.Request, path ...string) string {
+	return baseURL(r) + "/api/v3/" + strings.Join(path, "/")
+}
+
+func htmlURL(r *http.Request, path ...string) string {
+	return baseURL(r) + "/" + strings.Join(path, "/")
+}
+
+func (u *user) json(r *http.Request) map[string]any {
+	return map[string]any{
+		"login":    u.Login,
+		"id":       u.ID,
+		"node_id":  u.NodeID,
+		"type":     u.Type,
+		"url":      apiURL(r, "users", u.Login),
+		"html_url": htmlURL(r, u.Login),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error the way GitHub does, as a JSON object with a message.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"message":           message,
+		"documentation_url": "https://docs.github.com/rest",
+	})
+}
+
+// readJSON decodes the body of r into v, writing an error and returning false if it is not valid JSON.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return false
+	}
+	return true
+}
+
+// paginate returns the page of items asked for by the page and per_page query parameters, setting the Link header
+// to the next and last pages as GitHub does.
+func paginate[T any](w http.ResponseWriter, r *http.Request, items []T) []T {
+	query := r.URL.Query()
+	perPage, err := strconv.Atoi(query.Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = 30
+	}
+	perPage = min(perPage, 100)
+	page, err := strconv.Atoi(query.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	last := max((len(items)+perPage-1)/perPage, 1)
+	if page < last {
+		link := func(p int, rel string) string {
+			u := *r.URL
+			q := u.Query()
+			q.Set("page", strconv.Itoa(p))
+			q.Set("per_page", strconv.Itoa(perPage))
+			u.RawQuery = q.Encode()
+			return fmt.Sprintf(`<%s%s>; rel="%s"`, baseURL(r), u.RequestURI(), rel)
+		}
+		w.Header().Set("Link", link(page+1, "next")+", "+link(last, "last"))
+	}
+
+	start := min((page-1)*perPage, len(items))
+	end := min(start+perPage, len(items))
+	return items[start:end]
+}
+
+// pathInt returns the path parameter name of r as an integer, writing a 404 and returning false if it is not one.
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	n, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return 0, false
+	}
+	return n, true
+}
+
+// timeJSON formats t as GitHub does, or returns nil for the zero time.
+func timeJSON(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
+
+// escapePath escapes the segments of a slash separated path for use in a URL.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,284 @@
+package fakegithub
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v72/github"
+	"github.com/shurcooL/githubv4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClients(t *testing.T, opts Options) (*Server, *github.Client, *githubv4.Client) {
+	t.Helper()
+	fake := New(opts)
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+
+	client, err := github.NewClient(nil).WithAuthToken("token").WithEnterpriseURLs(ts.URL, ts.URL)
+	require.NoError(t, err)
+	gqlClient := githubv4.NewEnterpriseClient(ts.URL+"/api/graphql", client.Client())
+	return fake, client, gqlClient
+}
+
+// createBranch creates branch from main in octocat/hello.
+func createBranch(t *testing.T, client *github.Client, branch string) {
+	t.Helper()
+	main, _, err := client.Git.GetRef(context.Background(), "octocat", "hello", "refs/heads/main")
+	require.NoError(t, err)
+	_, _, err = client.Git.CreateRef(context.Background(), "octocat", "hello", &github.Reference{Ref: github.Ptr("refs/heads/" + branch), Object: main.Object})
+	require.NoError(t, err)
+}
+
+func Test_Authentication(t *testing.T) {
+	_, client, _ := newTestClients(t, Options{Token: "secret", Scopes: []string{"repo"}})
+	_, resp, err := client.Users.Get(context.Background(), "")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, client, _ = newTestClients(t, Options{Login: "hubot", Scopes: []string{"repo", "read:org"}})
+	me, resp, err := client.Users.Get(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "hubot", me.GetLogin())
+	assert.Equal(t, "repo, read:org", resp.Header.Get("X-OAuth-Scopes"))
+}
+
+func Test_Contents(t *testing.T) {
+	ctx := context.Background()
+	fake, client, _ := newTestClients(t, Options{})
+	require.NoError(t, fake.CreateRepository("octo", "hello", map[string]string{"README.md": "# hello\n", "docs/guide.md": "Guide\n"}))
+
+	file, _, _, err := client.Repositories.GetContents(ctx, "octo", "hello", "README.md", nil)
+	require.NoError(t, err)
+	content, err := file.GetContent()
+	require.NoError(t, err)
+	assert.Equal(t, "# hello\n", content)
+
+	_, dir, _, err := client.Repositories.GetContents(ctx, "octo", "hello", "", nil)
+	require.NoError(t, err)
+	require.Len(t, dir, 2)
+	assert.Equal(t, "README.md", dir[0].GetName())
+	assert.Equal(t, "docs", dir[1].GetName())
+	assert.Equal(t, "dir", dir[1].GetType())
+
+	// Updating a file needs the SHA of its blob
+	_, resp, err := client.Repositories.CreateFile(ctx, "octo", "hello", "README.md", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Update README"),
+		Content: []byte("# hello, world\n"),
+	})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	updated, _, err := client.Repositories.CreateFile(ctx, "octo", "hello", "README.md", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Update README"),
+		Content: []byte("# hello, world\n"),
+		SHA:     file.SHA,
+	})
+	require.NoError(t, err)
+
+	commit, _, err := client.Repositories.GetCommit(ctx, "octo", "hello", updated.GetSHA(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Update README", commit.GetCommit().GetMessage())
+	require.Len(t, commit.Files, 1)
+	assert.Equal(t, "modified", commit.Files[0].GetStatus())
+	assert.Equal(t, 1, commit.Files[0].GetAdditions())
+	assert.Equal(t, 1, commit.Files[0].GetDeletions())
+
+	commits, _, err := client.Repositories.ListCommits(ctx, "octo", "hello", &github.CommitsListOptions{Path: "docs"})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "Initial commit", commits[0].GetCommit().GetMessage())
+
+	raw, err := client.Client().Get(client.BaseURL.Scheme + "://" + client.BaseURL.Host + "/raw/octo/hello/main/README.md")
+	require.NoError(t, err)
+	defer func() { _ = raw.Body.Close() }()
+	data, err := io.ReadAll(raw.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "# hello, world\n", string(data))
+}
+
+func Test_PullRequests(t *testing.T) {
+	ctx := context.Background()
+	fake, client, _ := newTestClients(t, Options{})
+	require.NoError(t, fake.CreateRepository("octocat", "hello", map[string]string{"README.md": "# hello\n"}))
+
+	createBranch(t, client, "feature")
+
+	_, resp, err := client.PullRequests.Create(ctx, "octocat", "hello", &github.NewPullRequest{
+		Title: github.Ptr("Nothing yet"), Head: github.Ptr("feature"), Base: github.Ptr("main"),
+	})
+	require.Error(t, err, "there are no commits between the branches")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	_, _, err = client.Repositories.CreateFile(ctx, "octocat", "hello", "feature.txt", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Add feature"),
+		Content: []byte("feature\n"),
+		Branch:  github.Ptr("feature"),
+	})
+	require.NoError(t, err)
+	pr, _, err := client.PullRequests.Create(ctx, "octocat", "hello", &github.NewPullRequest{
+		Title: github.Ptr("Add feature"), Head: github.Ptr("feature"), Base: github.Ptr("main"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, pr.GetNumber())
+	assert.Equal(t, 1, pr.GetChangedFiles())
+	assert.True(t, pr.GetMergeable())
+
+	files, _, err := client.PullRequests.ListFiles(ctx, "octocat", "hello", 1, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "@@ -0,0 +1,1 @@\n+feature\n", files[0].GetPatch())
+
+	diff, _, err := client.PullRequests.GetRaw(ctx, "octocat", "hello", 1, github.RawOptions{Type: github.Diff})
+	require.NoError(t, err)
+	assert.Equal(t, "diff --git a/feature.txt b/feature.txt\nnew file mode 100644\n--- /dev/null\n+++ b/feature.txt\n@@ -0,0 +1,1 @@\n+feature\n", diff)
+
+	_, _, err = client.PullRequests.RequestReviewers(ctx, "octocat", "hello", 1, github.ReviewersRequest{Reviewers: []string{copilotReviewerLogin}})
+	require.NoError(t, err)
+	reviewers, _, err := client.PullRequests.ListReviewers(ctx, "octocat", "hello", 1, nil)
+	require.NoError(t, err)
+	require.Len(t, reviewers.Users, 1)
+	assert.Equal(t, "Copilot", reviewers.Users[0].GetLogin())
+	assert.Equal(t, "Bot", reviewers.Users[0].GetType())
+
+	merge, _, err := client.PullRequests.Merge(ctx, "octocat", "hello", 1, "", &github.PullRequestOptions{MergeMethod: "squash"})
+	require.NoError(t, err)
+	assert.True(t, merge.GetMerged())
+
+	pr, _, err = client.PullRequests.Get(ctx, "octocat", "hello", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "closed", pr.GetState())
+	assert.True(t, pr.GetMerged())
+	assert.Equal(t, merge.GetSHA(), pr.GetMergeCommitSHA())
+	assert.Equal(t, 1, pr.GetChangedFiles(), "merged pull requests keep their changes")
+
+	_, _, _, err = client.Repositories.GetContents(ctx, "octocat", "hello", "feature.txt", nil)
+	assert.NoError(t, err, "the change is merged into main")
+}
+
+func Test_PullRequestReviews(t *testing.T) {
+	ctx := context.Background()
+	fake, client, gqlClient := newTestClients(t, Options{})
+	require.NoError(t, fake.CreateRepository("octocat", "hello", map[string]string{"README.md": "# hello\n"}))
+	createBranch(t, client, "feature")
+	_, _, err := client.Repositories.CreateFile(ctx, "octocat", "hello", "feature.txt", &github.RepositoryContentFileOptions{
+		Message: github.Ptr("Add feature"),
+		Content: []byte("feature\n"),
+		Branch:  github.Ptr("feature"),
+	})
+	require.NoError(t, err)
+	_, _, err = client.PullRequests.Create(ctx, "octocat", "hello", &github.NewPullRequest{
+		Title: github.Ptr("Add feature"), Head: github.Ptr("feature"), Base: github.Ptr("main"),
+	})
+	require.NoError(t, err)
+
+	var prQuery pullRequestIDQuery
+	require.NoError(t, gqlClient.Query(ctx, &prQuery, map[string]any{
+		"owner": githubv4.String("octocat"), "repo": githubv4.String("hello"), "prNum": githubv4.Int(1),
+	}))
+
+	var add addReviewMutation
+	require.NoError(t, gqlClient.Mutate(ctx, &add, githubv4.AddPullRequestReviewInput{PullRequestID: prQuery.Repository.PullRequest.ID}, nil))
+	reviewID := add.AddPullRequestReview.PullRequestReview.ID
+
+	var thread addReviewThreadMutation
+	err = gqlClient.Mutate(ctx, &thread, githubv4.AddPullRequestReviewThreadInput{
+		PullRequestReviewID: &reviewID, Path: "README.md", Body: "Not part of the change",
+	}, nil)
+	assert.EqualError(t, err, "Path could not be resolved")
+	require.NoError(t, gqlClient.Mutate(ctx, &thread, githubv4.AddPullRequestReviewThreadInput{
+		PullRequestReviewID: &reviewID, Path: "feature.txt", Body: "Nice", Line: githubv4.NewInt(1),
+	}, nil))
+
+	var submit submitReviewMutation
+	err = gqlClient.Mutate(ctx, &submit, githubv4.SubmitPullRequestReviewInput{PullRequestReviewID: &reviewID, Event: githubv4.PullRequestReviewEventApprove}, nil)
+	assert.EqualError(t, err, "Can not approve your own pull request")
+	require.NoError(t, gqlClient.Mutate(ctx, &submit, githubv4.SubmitPullRequestReviewInput{PullRequestReviewID: &reviewID, Event: githubv4.PullRequestReviewEventComment}, nil))
+
+	reviews, _, err := client.PullRequests.ListReviews(ctx, "octocat", "hello", 1, nil)
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, "COMMENTED", reviews[0].GetState())
+	comments, _, err := client.PullRequests.ListReviewComments(ctx, "octocat", "hello", 1, reviews[0].GetID(), nil)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "Nice", comments[0].GetBody())
+	assert.Equal(t, 1, comments[0].GetLine())
+}
+
+func Test_AssignCopilot(t *testing.T) {
+	ctx := context.Background()
+	fake, client, gqlClient := newTestClients(t, Options{})
+	require.NoError(t, fake.CreateRepository("octocat", "hello", nil))
+	_, _, err := client.Issues.Create(ctx, "octocat", "hello", &github.IssueRequest{Title: github.Ptr("Fix it")})
+	require.NoError(t, err)
+
+	var actors suggestedActorsQuery
+	require.NoError(t, gqlClient.Query(ctx, &actors, map[string]any{
+		"owner": githubv4.String("octocat"), "name": githubv4.String("hello"), "endCursor": (*githubv4.String)(nil),
+	}))
+	require.Len(t, actors.Repository.SuggestedActors.Nodes, 1)
+	copilot := actors.Repository.SuggestedActors.Nodes[0].Bot
+	assert.Equal(t, copilotAgentLogin, copilot.Login)
+
+	var issue issueAssigneesQuery
+	require.NoError(t, gqlClient.Query(ctx, &issue, map[string]any{
+		"owner": githubv4.String("octocat"), "name": githubv4.String("hello"), "number": githubv4.Int(1),
+	}))
+	var replace replaceActorsMutation
+	require.NoError(t, gqlClient.Mutate(ctx, &replace, ReplaceActorsForAssignableInput{
+		AssignableID: issue.Repository.Issue.ID,
+		ActorIDs:     []githubv4.ID{copilot.ID},
+	}, nil))
+
+	assigned, _, err := client.Issues.Get(ctx, "octocat", "hello", 1)
+	require.NoError(t, err)
+	require.Len(t, assigned.Assignees, 1)
+	assert.Equal(t, "Copilot", assigned.Assignees[0].GetLogin())
+}
+
+func Test_Actions(t *testing.T) {
+	ctx := context.Background()
+	fake, client, _ := newTestClients(t, Options{})
+	require.NoError(t, fake.CreateRepository("octocat", "hello", map[string]string{
+		".github/workflows/ci.yml": "name: CI\non: workflow_dispatch\njobs:\n  test:\n    runs-on: ubuntu-latest\n  lint:\n    name: Lint code\n",
+	}))
+
+	workflows, _, err := client.Actions.ListWorkflows(ctx, "octocat", "hello", nil)
+	require.NoError(t, err)
+	require.Len(t, workflows.Workflows, 1)
+	assert.Equal(t, "CI", workflows.Workflows[0].GetName())
+
+	_, err = client.Actions.CreateWorkflowDispatchEventByFileName(ctx, "octocat", "hello", "ci.yml", github.CreateWorkflowDispatchEventRequest{Ref: "main"})
+	require.NoError(t, err)
+	runs, _, err := client.Actions.ListWorkflowRunsByFileName(ctx, "octocat", "hello", "ci.yml", nil)
+	require.NoError(t, err)
+	require.Len(t, runs.WorkflowRuns, 1)
+	run := runs.WorkflowRuns[0]
+	assert.Equal(t, "in_progress", run.GetStatus())
+
+	require.NoError(t, fake.CompleteWorkflowRun("octocat", "hello", run.GetID(), "failure"))
+	_, err = client.Actions.RerunFailedJobsByID(ctx, "octocat", "hello", run.GetID())
+	require.NoError(t, err)
+
+	jobs, _, err := client.Actions.ListWorkflowJobs(ctx, "octocat", "hello", run.GetID(), &github.ListWorkflowJobsOptions{Filter: "all"})
+	require.NoError(t, err)
+	var names []string
+	for _, job := range jobs.Jobs {
+		names = append(names, job.GetName()+" "+job.GetStatus())
+	}
+	assert.Equal(t, []string{"test completed", "Lint code completed", "test in_progress", "Lint code in_progress"}, names)
+
+	logURL, _, err := client.Actions.GetWorkflowJobLogs(ctx, "octocat", "hello", jobs.Jobs[0].GetID(), 1)
+	require.NoError(t, err)
+	resp, err := http.Get(logURL.String())
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	log, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "Job completed with conclusion failure")
+}
@@ -0,0 +1,760 @@
+package fakegithub
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // git names objects by their SHA-1
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Git data is kept the way git keeps it, as content addressed blobs, trees, commits and tags, except that trees
+// list every file below them by its full path rather than nesting other trees.
+
+type signature struct {
+	Name  string
+	Email string
+	Date  time.Time
+}
+
+type commit struct {
+	SHA       string
+	Tree      string
+	Parents   []string
+	Message   string
+	Author    signature
+	Committer signature
+}
+
+type tag struct {
+	SHA     string
+	Tag     string
+	Message string
+	Object  string
+	Type    string
+	Tagger  signature
+}
+
+// fileChange is a file added, removed or modified between two trees.
+type fileChange struct {
+	Filename  string
+	Status    string
+	SHA       string
+	Additions int
+	Deletions int
+}
+
+func hashObject(kind string, data []byte) string {
+	h := sha1.New() //nolint:gosec // git names objects by their SHA-1
+	_, _ = fmt.Fprintf(h, "%s %d\x00", kind, len(data))
+	_, _ = h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (repo *repository) putBlob(data []byte) string {
+	sha := hashObject("blob", data)
+	repo.blobs[sha] = data
+	return sha
+}
+
+// putTree stores the tree listing files, which maps paths to the SHAs of their blobs.
+func (repo *repository) putTree(files map[string]string) string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var buf bytes.Buffer
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "100644 %s\x00%s\n", path, files[path])
+	}
+	sha := hashObject("tree", buf.Bytes())
+	repo.trees[sha] = files
+	return sha
+}
+
+func (repo *repository) putCommit(c *commit) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "tree %s\n", c.Tree)
+	for _, parent := range c.Parents {
+		fmt.Fprintf(&buf, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&buf, "author %s <%s> %d\ncommitter %s <%s> %d\n\n%s",
+		c.Author.Name, c.Author.Email, c.Author.Date.Unix(), c.Committer.Name, c.Committer.Email, c.Committer.Date.Unix(), c.Message)
+	c.SHA = hashObject("commit", buf.Bytes())
+	repo.commits[c.SHA] = c
+}
+
+func (repo *repository) putTag(t *tag) {
+	data := fmt.Sprintf("object %s\ntype %s\ntag %s\ntagger %s <%s> %d\n\n%s",
+		t.Object, t.Type, t.Tag, t.Tagger.Name, t.Tagger.Email, t.Tagger.Date.Unix(), t.Message)
+	t.SHA = hashObject("tag", []byte(data))
+	repo.tags[t.SHA] = t
+}
+
+// objectType returns the type of the object named sha, or "" if there is none.
+func (repo *repository) objectType(sha string) string {
+	switch {
+	case repo.commits[sha] != nil:
+		return "commit"
+	case repo.tags[sha] != nil:
+		return "tag"
+	case repo.trees[sha] != nil:
+		return "tree"
+	case repo.blobs[sha] != nil:
+		return "blob"
+	default:
+		return ""
+	}
+}
+
+// peel follows tags to the commit they point at.
+func (repo *repository) peel(sha string) (string, bool) {
+	for {
+		if t, ok := repo.tags[sha]; ok {
+			sha = t.Object
+			continue
+		}
+		_, ok := repo.commits[sha]
+		return sha, ok
+	}
+}
+
+// resolve returns the commit named by name, which may be HEAD, a ref, a branch, a tag or a commit SHA, in full or
+// abbreviated.
+func (repo *repository) resolve(name string) (string, bool) {
+	switch {
+	case name == "" || name == "HEAD":
+		name = "refs/heads/" + repo.DefaultBranch
+	case strings.HasPrefix(name, "heads/") || strings.HasPrefix(name, "tags/"):
+		name = "refs/" + name
+	}
+	for _, ref := range []string{name, "refs/heads/" + name, "refs/tags/" + name} {
+		if sha, ok := repo.refs[ref]; ok {
+			return repo.peel(sha)
+		}
+	}
+	if len(name) >= 7 {
+		for sha := range repo.commits {
+			if strings.HasPrefix(sha, name) {
+				return sha, true
+			}
+		}
+	}
+	return "", false
+}
+
+// files returns the files of the tree of the commit sha, which are not to be modified.
+func (repo *repository) files(sha string) map[string]string {
+	if c, ok := repo.commits[sha]; ok {
+		return repo.trees[c.Tree]
+	}
+	return map[string]string{}
+}
+
+func (s *Server) signature() signature {
+	return signature{Name: s.viewer.Login, Email: s.viewer.Login + "@users.noreply.github.com", Date: s.now()}
+}
+
+// commitChanges commits changes, which map paths to their new content, or to nil for files, or directories, to be
+// deleted, on top of branch, creating the branch if it does not exist yet.
+func (s *Server) commitChanges(repo *repository, branch, message string, changes map[string]*string) (*commit, error) {
+	var parents []string
+	files := map[string]string{}
+	if sha, ok := repo.refs["refs/heads/"+branch]; ok {
+		parents = []string{sha}
+		for path, blob := range repo.files(sha) {
+			files[path] = blob
+		}
+	}
+	for path, content := range changes {
+		path = strings.Trim(path, "/")
+		if path == "" {
+			return nil, fmt.Errorf("invalid path")
+		}
+		if content == nil {
+			deletePath(files, path)
+			continue
+		}
+		files[path] = repo.putBlob([]byte(*content))
+	}
+
+	c := &commit{Tree: repo.putTree(files), Parents: parents, Message: message, Author: s.signature(), Committer: s.signature()}
+	repo.putCommit(c)
+	repo.refs["refs/heads/"+branch] = c.SHA
+	return c, nil
+}
+
+// deletePath deletes the file at path, or every file under it if it is a directory, reporting whether there was any.
+func deletePath(files map[string]string, path string) bool {
+	deleted := false
+	for file := range files {
+		if file == path || strings.HasPrefix(file, path+"/") {
+			delete(files, file)
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// diff returns the changes made to the files of from to get those of to.
+func (repo *repository) diff(from, to map[string]string) []fileChange {
+	var changes []fileChange
+	for path, sha := range to {
+		old, ok := from[path]
+		switch {
+		case !ok:
+			changes = append(changes, fileChange{Filename: path, Status: "added", SHA: sha, Additions: countLines(repo.blobs[sha])})
+		case old != sha:
+			additions, deletions := diffLines(repo.blobs[old], repo.blobs[sha])
+			changes = append(changes, fileChange{Filename: path, Status: "modified", SHA: sha, Additions: additions, Deletions: deletions})
+		}
+	}
+	for path, sha := range from {
+		if _, ok := to[path]; !ok {
+			changes = append(changes, fileChange{Filename: path, Status: "removed", SHA: sha, Deletions: countLines(repo.blobs[sha])})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Filename < changes[j].Filename })
+	return changes
+}
+
+func lines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func countLines(data []byte) int {
+	return len(lines(data))
+}
+
+// diffLines counts the lines added and deleted between old and new, as those of either missing from the other.
+func diffLines(old, new []byte) (additions, deletions int) {
+	remaining := map[string]int{}
+	for _, line := range lines(old) {
+		remaining[line]++
+	}
+	for _, line := range lines(new) {
+		if remaining[line] > 0 {
+			remaining[line]--
+			continue
+		}
+		additions++
+	}
+	for _, n := range remaining {
+		deletions += n
+	}
+	return additions, deletions
+}
+
+// ancestors returns sha and the commits it descends from, most recent first.
+func (repo *repository) ancestors(sha string) []*commit {
+	var result []*commit
+	seen := map[string]bool{}
+	queue := []string{sha}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		c, ok := repo.commits[next]
+		if !ok || seen[next] {
+			continue
+		}
+		seen[next] = true
+		result = append(result, c)
+		queue = append(queue, c.Parents...)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Committer.Date.After(result[j].Committer.Date) })
+	return result
+}
+
+// mergeBase returns the most recent commit both a and b descend from, or "" if they have none in common.
+func (repo *repository) mergeBase(a, b string) string {
+	inA := map[string]bool{}
+	for _, c := range repo.ancestors(a) {
+		inA[c.SHA] = true
+	}
+	for _, c := range repo.ancestors(b) {
+		if inA[c.SHA] {
+			return c.SHA
+		}
+	}
+	return ""
+}
+
+// merge returns the files of base with the changes made to them by head since they diverged, and whether they
+// conflict.
+func (repo *repository) merge(base, head string) (map[string]string, bool) {
+	ancestor := repo.files(repo.mergeBase(base, head))
+	ours, theirs := repo.files(base), repo.files(head)
+	merged := map[string]string{}
+	for path, sha := range ours {
+		merged[path] = sha
+	}
+	conflict := false
+	paths := map[string]bool{}
+	for path := range ancestor {
+		paths[path] = true
+	}
+	for path := range theirs {
+		paths[path] = true
+	}
+	for path := range paths {
+		original, inAncestor := ancestor[path]
+		their, inTheirs := theirs[path]
+		if inAncestor == inTheirs && original == their {
+			continue
+		}
+		our, inOurs := ours[path]
+		if (inAncestor != inOurs || original != our) && (inOurs != inTheirs || our != their) {
+			conflict = true
+			continue
+		}
+		if inTheirs {
+			merged[path] = their
+		} else {
+			delete(merged, path)
+		}
+	}
+	return merged, conflict
+}
+
+func (s *Server) routeGit() {
+	const prefix = "/api/v3/repos/{owner}/{repo}/git/"
+	s.mux.HandleFunc("GET "+prefix+"ref/{ref...}", s.withRepo(s.getRef))
+	s.mux.HandleFunc("GET "+prefix+"matching-refs/{ref...}", s.withRepo(s.listMatchingRefs))
+	s.mux.HandleFunc("POST "+prefix+"refs", s.withRepo(s.createRef))
+	s.mux.HandleFunc("PATCH "+prefix+"refs/{ref...}", s.withRepo(s.updateRef))
+	s.mux.HandleFunc("DELETE "+prefix+"refs/{ref...}", s.withRepo(s.deleteRef))
+	s.mux.HandleFunc("GET "+prefix+"commits/{sha}", s.withRepo(s.getGitCommit))
+	s.mux.HandleFunc("POST "+prefix+"commits", s.withRepo(s.createGitCommit))
+	s.mux.HandleFunc("GET "+prefix+"trees/{sha}", s.withRepo(s.getTree))
+	s.mux.HandleFunc("POST "+prefix+"trees", s.withRepo(s.createTree))
+	s.mux.HandleFunc("GET "+prefix+"blobs/{sha}", s.withRepo(s.getBlob))
+	s.mux.HandleFunc("POST "+prefix+"blobs", s.withRepo(s.createBlob))
+	s.mux.HandleFunc("GET "+prefix+"tags/{sha}", s.withRepo(s.getTag))
+	s.mux.HandleFunc("POST "+prefix+"tags", s.withRepo(s.createTag))
+	s.mux.HandleFunc("GET /raw/{owner}/{repo}/{rest...}", s.withRepo(s.getRaw))
+}
+
+func (repo *repository) refJSON(r *http.Request, ref string) map[string]any {
+	sha := repo.refs[ref]
+	kind := repo.objectType(sha)
+	return map[string]any{
+		"ref":     ref,
+		"node_id": "REF_" + hashObject("ref", []byte(repo.fullName()+ref))[:12],
+		"url":     repo.apiURL(r, "git", ref),
+		"object": map[string]any{
+			"sha":  sha,
+			"type": kind,
+			"url":  repo.apiURL(r, "git", kind+"s", sha),
+		},
+	}
+}
+
+func (s *Server) getRef(w http.ResponseWriter, r *http.Request, repo *repository) {
+	ref := "refs/" + strings.TrimPrefix(r.PathValue("ref"), "refs/")
+	if _, ok := repo.refs[ref]; !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, repo.refJSON(r, ref))
+}
+
+func (s *Server) listMatchingRefs(w http.ResponseWriter, r *http.Request, repo *repository) {
+	prefix := "refs/" + strings.TrimPrefix(r.PathValue("ref"), "refs/")
+	refs := []map[string]any{}
+	for _, ref := range repo.sortedRefs(prefix) {
+		refs = append(refs, repo.refJSON(r, ref))
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, refs))
+}
+
+// sortedRefs returns the refs starting with prefix, in order.
+func (repo *repository) sortedRefs(prefix string) []string {
+	var refs []string
+	for ref := range repo.refs {
+		if strings.HasPrefix(ref, prefix) {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func (s *Server) createRef(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if !strings.HasPrefix(body.Ref, "refs/") || strings.Count(body.Ref, "/") < 2 {
+		writeError(w, http.StatusUnprocessableEntity, "Reference name must start with 'refs/' and have at least two slashes.")
+		return
+	}
+	if _, ok := repo.refs[body.Ref]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference already exists")
+		return
+	}
+	if repo.objectType(body.SHA) == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	repo.refs[body.Ref] = body.SHA
+	writeJSON(w, http.StatusCreated, repo.refJSON(r, body.Ref))
+}
+
+func (s *Server) updateRef(w http.ResponseWriter, r *http.Request, repo *repository) {
+	ref := "refs/" + strings.TrimPrefix(r.PathValue("ref"), "refs/")
+	var body struct {
+		SHA   string `json:"sha"`
+		Force bool   `json:"force"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	current, ok := repo.refs[ref]
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	if repo.objectType(body.SHA) == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	if !body.Force && repo.mergeBase(current, body.SHA) != current {
+		writeError(w, http.StatusUnprocessableEntity, "Update is not a fast forward")
+		return
+	}
+	repo.refs[ref] = body.SHA
+	writeJSON(w, http.StatusOK, repo.refJSON(r, ref))
+}
+
+func (s *Server) deleteRef(w http.ResponseWriter, r *http.Request, repo *repository) {
+	ref := "refs/" + strings.TrimPrefix(r.PathValue("ref"), "refs/")
+	if _, ok := repo.refs[ref]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Reference does not exist")
+		return
+	}
+	delete(repo.refs, ref)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func signatureJSON(sig signature) map[string]any {
+	return map[string]any{"name": sig.Name, "email": sig.Email, "date": timeJSON(sig.Date)}
+}
+
+func (repo *repository) gitCommitJSON(r *http.Request, c *commit) map[string]any {
+	parents := []map[string]any{}
+	for _, parent := range c.Parents {
+		parents = append(parents, map[string]any{
+			"sha":      parent,
+			"url":      repo.apiURL(r, "git", "commits", parent),
+			"html_url": repo.htmlURL(r, "commit", parent),
+		})
+	}
+	return map[string]any{
+		"sha":       c.SHA,
+		"node_id":   "C_" + c.SHA[:12],
+		"url":       repo.apiURL(r, "git", "commits", c.SHA),
+		"html_url":  repo.htmlURL(r, "commit", c.SHA),
+		"author":    signatureJSON(c.Author),
+		"committer": signatureJSON(c.Committer),
+		"message":   c.Message,
+		"tree": map[string]any{
+			"sha": c.Tree,
+			"url": repo.apiURL(r, "git", "trees", c.Tree),
+		},
+		"parents":      parents,
+		"verification": map[string]any{"verified": false, "reason": "unsigned"},
+	}
+}
+
+func (s *Server) getGitCommit(w http.ResponseWriter, r *http.Request, repo *repository) {
+	c, ok := repo.commits[r.PathValue("sha")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, repo.gitCommitJSON(r, c))
+}
+
+func (s *Server) createGitCommit(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Message string   `json:"message"`
+		Tree    string   `json:"tree"`
+		Parents []string `json:"parents"`
+		Author  *struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if _, ok := repo.trees[body.Tree]; !ok {
+		writeError(w, http.StatusUnprocessableEntity, "Tree SHA does not exist")
+		return
+	}
+	for _, parent := range body.Parents {
+		if _, ok := repo.commits[parent]; !ok {
+			writeError(w, http.StatusUnprocessableEntity, "Parent SHA does not exist or is not a commit object")
+			return
+		}
+	}
+	c := &commit{Tree: body.Tree, Parents: body.Parents, Message: body.Message, Author: s.signature(), Committer: s.signature()}
+	if body.Author != nil {
+		c.Author.Name, c.Author.Email = body.Author.Name, body.Author.Email
+		if !body.Author.Date.IsZero() {
+			c.Author.Date = body.Author.Date
+		}
+	}
+	repo.putCommit(c)
+	writeJSON(w, http.StatusCreated, repo.gitCommitJSON(r, c))
+}
+
+// treeEntries returns the entries of the tree listing files, those of the files and directories at its root unless
+// recursive, with the trees of directories being stored.
+func (repo *repository) treeEntries(r *http.Request, files map[string]string, recursive bool) []map[string]any {
+	entries := []map[string]any{}
+	dirs := map[string]map[string]string{}
+	for path, sha := range files {
+		if recursive || !strings.Contains(path, "/") {
+			entries = append(entries, map[string]any{"path": path, "mode": "100644", "type": "blob", "sha": sha,
+				"size": len(repo.blobs[sha]), "url": repo.apiURL(r, "git", "blobs", sha)})
+		}
+		// Every directory holding the file is listed if recursive, only the one at the root otherwise
+		for i := strings.Index(path, "/"); i >= 0; i = next(path, i) {
+			dir := path[:i]
+			if dirs[dir] == nil {
+				dirs[dir] = map[string]string{}
+			}
+			dirs[dir][path[i+1:]] = sha
+			if !recursive {
+				break
+			}
+		}
+	}
+	for dir, dirFiles := range dirs {
+		sha := repo.putTree(dirFiles)
+		entries = append(entries, map[string]any{"path": dir, "mode": "040000", "type": "tree", "sha": sha,
+			"url": repo.apiURL(r, "git", "trees", sha)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i]["path"].(string) < entries[j]["path"].(string) })
+	return entries
+}
+
+// next returns the index of the slash after the one at i in path, or -1.
+func next(path string, i int) int {
+	j := strings.Index(path[i+1:], "/")
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+func (s *Server) getTree(w http.ResponseWriter, r *http.Request, repo *repository) {
+	sha := r.PathValue("sha")
+	files, ok := repo.trees[sha]
+	if !ok {
+		// A commit, or a branch, stands for its tree
+		commitSHA, found := repo.resolve(sha)
+		if !found {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		sha = repo.commits[commitSHA].Tree
+		files = repo.trees[sha]
+	}
+	recursive := r.URL.Query().Get("recursive") != ""
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sha":       sha,
+		"url":       repo.apiURL(r, "git", "trees", sha),
+		"tree":      repo.treeEntries(r, files, recursive),
+		"truncated": false,
+	})
+}
+
+func (s *Server) createTree(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		BaseTree string `json:"base_tree"`
+		Tree     []struct {
+			Path    string          `json:"path"`
+			Mode    string          `json:"mode"`
+			Type    string          `json:"type"`
+			SHA     json.RawMessage `json:"sha"`
+			Content *string         `json:"content"`
+		} `json:"tree"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	files := map[string]string{}
+	if body.BaseTree != "" {
+		base, ok := repo.trees[body.BaseTree]
+		if !ok {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid tree info")
+			return
+		}
+		for path, sha := range base {
+			files[path] = sha
+		}
+	}
+	for _, entry := range body.Tree {
+		path := strings.Trim(entry.Path, "/")
+		var sha *string
+		if len(entry.SHA) > 0 {
+			if err := json.Unmarshal(entry.SHA, &sha); err != nil {
+				writeError(w, http.StatusUnprocessableEntity, "Invalid tree info")
+				return
+			}
+		}
+		switch {
+		case entry.Content != nil:
+			files[path] = repo.putBlob([]byte(*entry.Content))
+		case sha == nil:
+			// A null SHA deletes the file, or directory, at the path
+			if !deletePath(files, path) {
+				writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("GitRPC::BadObjectState: %s not found", path))
+				return
+			}
+		case entry.Type == "tree":
+			subtree, ok := repo.trees[*sha]
+			if !ok {
+				writeError(w, http.StatusUnprocessableEntity, "Invalid tree info")
+				return
+			}
+			deletePath(files, path)
+			for subpath, blob := range subtree {
+				files[path+"/"+subpath] = blob
+			}
+		default:
+			if _, ok := repo.blobs[*sha]; !ok {
+				writeError(w, http.StatusUnprocessableEntity, "Invalid tree info")
+				return
+			}
+			files[path] = *sha
+		}
+	}
+	sha := repo.putTree(files)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"sha":       sha,
+		"url":       repo.apiURL(r, "git", "trees", sha),
+		"tree":      repo.treeEntries(r, files, false),
+		"truncated": false,
+	})
+}
+
+func (s *Server) getBlob(w http.ResponseWriter, r *http.Request, repo *repository) {
+	sha := r.PathValue("sha")
+	data, ok := repo.blobs[sha]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sha":      sha,
+		"node_id":  "B_" + sha[:12],
+		"size":     len(data),
+		"url":      repo.apiURL(r, "git", "blobs", sha),
+		"content":  base64.StdEncoding.EncodeToString(data),
+		"encoding": "base64",
+	})
+}
+
+func (s *Server) createBlob(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	data := []byte(body.Content)
+	if body.Encoding == "base64" {
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(body.Content); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid base64 content")
+			return
+		}
+	}
+	sha := repo.putBlob(data)
+	writeJSON(w, http.StatusCreated, map[string]any{"sha": sha, "url": repo.apiURL(r, "git", "blobs", sha)})
+}
+
+func (repo *repository) tagJSON(r *http.Request, t *tag) map[string]any {
+	return map[string]any{
+		"sha":     t.SHA,
+		"node_id": "TAG_" + t.SHA[:12],
+		"url":     repo.apiURL(r, "git", "tags", t.SHA),
+		"tag":     t.Tag,
+		"message": t.Message,
+		"tagger":  signatureJSON(t.Tagger),
+		"object": map[string]any{
+			"sha":  t.Object,
+			"type": t.Type,
+			"url":  repo.apiURL(r, "git", t.Type+"s", t.Object),
+		},
+		"verification": map[string]any{"verified": false, "reason": "unsigned"},
+	}
+}
+
+func (s *Server) getTag(w http.ResponseWriter, r *http.Request, repo *repository) {
+	t, ok := repo.tags[r.PathValue("sha")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, repo.tagJSON(r, t))
+}
+
+func (s *Server) createTag(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Tag     string `json:"tag"`
+		Message string `json:"message"`
+		Object  string `json:"object"`
+		Type    string `json:"type"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	kind := repo.objectType(body.Object)
+	if kind == "" || (body.Type != "" && body.Type != kind) {
+		writeError(w, http.StatusUnprocessableEntity, "Object does not exist")
+		return
+	}
+	t := &tag{Tag: body.Tag, Message: body.Message, Object: body.Object, Type: kind, Tagger: s.signature()}
+	repo.putTag(t)
+	writeJSON(w, http.StatusCreated, repo.tagJSON(r, t))
+}
+
+// getRaw serves the contents of a file as raw.githubusercontent.com does, at /raw/{owner}/{repo}/{ref}/{path},
+// where the ref, which may hold slashes itself, is HEAD, a ref, a branch, a tag or a commit SHA.
+func (s *Server) getRaw(w http.ResponseWriter, r *http.Request, repo *repository) {
+	rest := r.PathValue("rest")
+	for i := strings.Index(rest, "/"); i >= 0; i = next(rest, i) {
+		sha, ok := repo.resolve(rest[:i])
+		if !ok {
+			continue
+		}
+		blob, ok := repo.files(sha)[rest[i+1:]]
+		if !ok {
+			continue
+		}
+		data := repo.blobs[blob]
+		w.Header().Set("Content-Type", http.DetectContentType(data))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(data)
+		return
+	}
+	http.Error(w, "404: Not Found", http.StatusNotFound)
+}
@@ -0,0 +1,462 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/github/github-mcp-server/internal/githubv4mock"
+	"github.com/shurcooL/githubv4"
+)
+
+// The GraphQL API only answers the queries and mutations the tools make, which are matched by their shape, the
+// structs below mirroring those the tools declare.
+
+type pullRequestIDQuery struct {
+	Repository struct {
+		PullRequest struct {
+			ID githubv4.ID
+		} `graphql:"pullRequest(number: $prNum)"`
+	} `graphql:"repository(owner: $owner, name: $repo)"`
+}
+
+type viewerQuery struct {
+	Viewer struct {
+		Login githubv4.String
+	}
+}
+
+type latestReviewQuery struct {
+	Repository struct {
+		PullRequest struct {
+			Reviews struct {
+				Nodes []struct {
+					ID    githubv4.ID
+					State githubv4.PullRequestReviewState
+					URL   githubv4.URI
+				}
+			} `graphql:"reviews(first: 1, author: $author)"`
+		} `graphql:"pullRequest(number: $prNum)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type reviewPayload struct {
+	PullRequestReview struct {
+		ID githubv4.ID
+	}
+}
+
+type addReviewMutation struct {
+	AddPullRequestReview reviewPayload `graphql:"addPullRequestReview(input: $input)"`
+}
+
+type addReviewThreadMutation struct {
+	AddPullRequestReviewThread struct {
+		Thread struct {
+			ID githubv4.ID
+		}
+	} `graphql:"addPullRequestReviewThread(input: $input)"`
+}
+
+type submitReviewMutation struct {
+	SubmitPullRequestReview reviewPayload `graphql:"submitPullRequestReview(input: $input)"`
+}
+
+type deleteReviewMutation struct {
+	DeletePullRequestReview reviewPayload `graphql:"deletePullRequestReview(input: $input)"`
+}
+
+type suggestedActorsQuery struct {
+	Repository struct {
+		SuggestedActors struct {
+			Nodes []struct {
+				Bot struct {
+					ID       githubv4.ID
+					Login    string
+					TypeName string `graphql:"__typename"`
+				} `graphql:"... on Bot"`
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+		} `graphql:"suggestedActors(first: 100, after: $endCursor, capabilities: CAN_BE_ASSIGNED)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type issueAssigneesQuery struct {
+	Repository struct {
+		Issue struct {
+			ID        githubv4.ID
+			Assignees struct {
+				Nodes []struct {
+					ID githubv4.ID
+				}
+			} `graphql:"assignees(first: 100)"`
+		} `graphql:"issue(number: $number)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+type replaceActorsMutation struct {
+	ReplaceActorsForAssignable struct {
+		Typename string `graphql:"__typename"`
+	} `graphql:"replaceActorsForAssignable(input: $input)"`
+}
+
+// ReplaceActorsForAssignableInput is named as the input type of replaceActorsForAssignable, which githubv4 lacks.
+type ReplaceActorsForAssignableInput struct {
+	AssignableID githubv4.ID   `json:"assignableId"`
+	ActorIDs     []githubv4.ID `json:"actorIds"`
+}
+
+// graphqlHandler answers GraphQL requests, the matchers being set up for each so that they can refer to it.
+func (s *Server) graphqlHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.graphqlMatchers(r).ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) graphqlMatchers(r *http.Request) http.Handler {
+	repoVariables := func(name string) map[string]any {
+		return map[string]any{"owner": githubv4.String(""), name: githubv4.String(""), "prNum": githubv4.Int(0)}
+	}
+	reviewVariables := repoVariables("name")
+	reviewVariables["author"] = githubv4.String("")
+
+	return githubv4mock.NewHandler(
+		githubv4mock.NewQueryResolver(viewerQuery{}, nil, func(map[string]any) githubv4mock.GQLResponse {
+			return githubv4mock.DataResponse(map[string]any{"viewer": map[string]any{"login": s.viewer.Login}})
+		}),
+		githubv4mock.NewQueryResolver(pullRequestIDQuery{}, repoVariables("repo"), s.resolvePullRequestID),
+		githubv4mock.NewQueryResolver(latestReviewQuery{}, reviewVariables, func(variables map[string]any) githubv4mock.GQLResponse {
+			return s.resolveLatestReview(r, variables)
+		}),
+		githubv4mock.NewMutationResolver(addReviewMutation{}, githubv4.AddPullRequestReviewInput{}, nil, s.resolveAddReview),
+		githubv4mock.NewMutationResolver(addReviewThreadMutation{}, githubv4.AddPullRequestReviewThreadInput{}, nil, s.resolveAddReviewThread),
+		githubv4mock.NewMutationResolver(submitReviewMutation{}, githubv4.SubmitPullRequestReviewInput{}, nil, s.resolveSubmitReview),
+		githubv4mock.NewMutationResolver(deleteReviewMutation{}, githubv4.DeletePullRequestReviewInput{}, nil, s.resolveDeleteReview),
+		githubv4mock.NewQueryResolver(suggestedActorsQuery{}, map[string]any{
+			"owner":     githubv4.String(""),
+			"name":      githubv4.String(""),
+			"endCursor": (*githubv4.String)(nil),
+		}, s.resolveSuggestedActors),
+		githubv4mock.NewQueryResolver(issueAssigneesQuery{}, map[string]any{
+			"owner":  githubv4.String(""),
+			"name":   githubv4.String(""),
+			"number": githubv4.Int(0),
+		}, s.resolveIssueAssignees),
+		githubv4mock.NewMutationResolver(replaceActorsMutation{}, ReplaceActorsForAssignableInput{}, nil, s.resolveReplaceActors),
+	)
+}
+
+func stringVariable(variables map[string]any, name string) string {
+	value, _ := variables[name].(string)
+	return value
+}
+
+func intVariable(variables map[string]any, name string) int {
+	// JSON numbers are decoded as floats
+	value, _ := variables[name].(float64)
+	return int(value)
+}
+
+func inputVariable(variables map[string]any) map[string]any {
+	input, _ := variables["input"].(map[string]any)
+	return input
+}
+
+// notFound returns the error GraphQL answers with for an object of kind that does not exist with the ID, or the
+// number, id.
+func notFound(kind string, id any) githubv4mock.GQLResponse {
+	if number, ok := id.(float64); ok {
+		return githubv4mock.ErrorResponse(fmt.Sprintf("Could not resolve to a %s with the number of %s.", kind, strconv.FormatFloat(number, 'f', -1, 64)))
+	}
+	return githubv4mock.ErrorResponse(fmt.Sprintf("Could not resolve to a %s with the global id of '%v'.", kind, id))
+}
+
+// repositoryNotFound returns the error GraphQL answers with for a repository, named by the owner and nameVariable
+// variables, that does not exist.
+func repositoryNotFound(variables map[string]any, nameVariable string) githubv4mock.GQLResponse {
+	return githubv4mock.ErrorResponse(fmt.Sprintf("Could not resolve to a Repository with the name '%s/%s'.",
+		stringVariable(variables, "owner"), stringVariable(variables, nameVariable)))
+}
+
+// lookupPull returns the pull request with the number of the prNum variable in the repository of the owner and
+// the name or repo variables.
+func (s *Server) lookupPull(variables map[string]any, nameVariable string) (*repository, *issue, *githubv4mock.GQLResponse) {
+	repo, ok := s.repos[repoKey(stringVariable(variables, "owner"), stringVariable(variables, nameVariable))]
+	if !ok {
+		response := repositoryNotFound(variables, nameVariable)
+		return nil, nil, &response
+	}
+	number := intVariable(variables, "prNum")
+	if number < 1 || number > len(repo.issues) || repo.issues[number-1].pull == nil {
+		response := notFound("PullRequest", variables["prNum"])
+		return nil, nil, &response
+	}
+	return repo, repo.issues[number-1], nil
+}
+
+func (s *Server) resolvePullRequestID(variables map[string]any) githubv4mock.GQLResponse {
+	_, iss, errResponse := s.lookupPull(variables, "repo")
+	if errResponse != nil {
+		return *errResponse
+	}
+	return githubv4mock.DataResponse(map[string]any{
+		"repository": map[string]any{"pullRequest": map[string]any{"id": iss.NodeID}},
+	})
+}
+
+func (s *Server) resolveLatestReview(r *http.Request, variables map[string]any) githubv4mock.GQLResponse {
+	repo, iss, errResponse := s.lookupPull(variables, "name")
+	if errResponse != nil {
+		return *errResponse
+	}
+	nodes := []map[string]any{}
+	for _, rev := range iss.pull.Reviews {
+		if strings.EqualFold(rev.User.Login, stringVariable(variables, "author")) {
+			nodes = append(nodes, map[string]any{
+				"id":    rev.NodeID,
+				"state": rev.State,
+				"url":   repo.reviewJSON(r, iss, rev)["html_url"],
+			})
+			break
+		}
+	}
+	return githubv4mock.DataResponse(map[string]any{
+		"repository": map[string]any{"pullRequest": map[string]any{"reviews": map[string]any{"nodes": nodes}}},
+	})
+}
+
+// findPull returns the pull request with the node ID id.
+func (s *Server) findPull(id string) (*repository, *issue) {
+	for _, repo := range s.repos {
+		for _, iss := range repo.issues {
+			if iss.NodeID == id {
+				return repo, iss
+			}
+		}
+	}
+	return nil, nil
+}
+
+// findReview returns the review with the node ID id, and the pull request it is of.
+func (s *Server) findReview(id string) (*repository, *issue, *review) {
+	for _, repo := range s.repos {
+		for _, iss := range repo.issues {
+			if iss.pull == nil {
+				continue
+			}
+			for _, rev := range iss.pull.Reviews {
+				if rev.NodeID == id {
+					return repo, iss, rev
+				}
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// reviewStates maps the events submitting a review to the state they leave it in.
+var reviewStates = map[string]string{
+	"COMMENT":         "COMMENTED",
+	"APPROVE":         "APPROVED",
+	"REQUEST_CHANGES": "CHANGES_REQUESTED",
+}
+
+// submitReview submits rev on the pull request iss with event, unless it is not allowed to.
+func (s *Server) submitReview(iss *issue, rev *review, event string) *githubv4mock.GQLResponse {
+	state, ok := reviewStates[event]
+	var message string
+	switch {
+	case !ok:
+		message = "Invalid review event: " + event
+	case event == "APPROVE" && rev.User == iss.User:
+		message = "Can not approve your own pull request"
+	case event == "REQUEST_CHANGES" && rev.User == iss.User:
+		message = "Can not request changes on your own pull request"
+	}
+	if message != "" {
+		response := githubv4mock.ErrorResponse(message)
+		return &response
+	}
+	rev.State, rev.SubmittedAt = state, s.now()
+	return nil
+}
+
+func reviewResponse(mutation string, rev *review) githubv4mock.GQLResponse {
+	return githubv4mock.DataResponse(map[string]any{
+		mutation: map[string]any{"pullRequestReview": map[string]any{"id": rev.NodeID}},
+	})
+}
+
+func (s *Server) resolveAddReview(variables map[string]any) githubv4mock.GQLResponse {
+	input := inputVariable(variables)
+	repo, iss := s.findPull(stringVariable(input, "pullRequestId"))
+	if iss == nil || iss.pull == nil {
+		return notFound("PullRequest", input["pullRequestId"])
+	}
+	for _, rev := range iss.pull.Reviews {
+		if rev.User == s.viewer && rev.State == "PENDING" {
+			return githubv4mock.ErrorResponse("User can only have one pending review per pull request")
+		}
+	}
+	commitID := stringVariable(input, "commitOID")
+	if commitID == "" {
+		commitID = repo.headSHA(iss.pull)
+	}
+	rev := &review{ID: s.newID(), User: s.viewer, Body: stringVariable(input, "body"), State: "PENDING", CommitID: commitID}
+	rev.NodeID = nodeID("PRR", rev.ID)
+	if event := stringVariable(input, "event"); event != "" {
+		if errResponse := s.submitReview(iss, rev, event); errResponse != nil {
+			return *errResponse
+		}
+	}
+	iss.pull.Reviews = append(iss.pull.Reviews, rev)
+	return reviewResponse("addPullRequestReview", rev)
+}
+
+func (s *Server) resolveAddReviewThread(variables map[string]any) githubv4mock.GQLResponse {
+	input := inputVariable(variables)
+	repo, iss, rev := s.findReview(stringVariable(input, "pullRequestReviewId"))
+	if rev == nil {
+		return notFound("PullRequestReview", input["pullRequestReviewId"])
+	}
+	path := stringVariable(input, "path")
+	changes, _ := repo.pullChanges(iss.pull)
+	inDiff := false
+	for _, change := range changes {
+		inDiff = inDiff || change.Filename == path
+	}
+	if !inDiff {
+		return githubv4mock.ErrorResponse("Path could not be resolved")
+	}
+
+	c := &reviewComment{
+		ID:          s.newID(),
+		User:        s.viewer,
+		Body:        stringVariable(input, "body"),
+		Path:        path,
+		SubjectType: stringVariable(input, "subjectType"),
+		Line:        intVariable(input, "line"),
+		Side:        stringVariable(input, "side"),
+		StartLine:   intVariable(input, "startLine"),
+		StartSide:   stringVariable(input, "startSide"),
+		CommitID:    rev.CommitID,
+		CreatedAt:   s.now(),
+	}
+	if c.SubjectType == "" {
+		c.SubjectType = "LINE"
+	}
+	c.NodeID = nodeID("PRRC", c.ID)
+	rev.Comments = append(rev.Comments, c)
+	return githubv4mock.DataResponse(map[string]any{
+		"addPullRequestReviewThread": map[string]any{"thread": map[string]any{"id": nodeID("PRRT", c.ID)}},
+	})
+}
+
+func (s *Server) resolveSubmitReview(variables map[string]any) githubv4mock.GQLResponse {
+	input := inputVariable(variables)
+	_, iss, rev := s.findReview(stringVariable(input, "pullRequestReviewId"))
+	if rev == nil {
+		return notFound("PullRequestReview", input["pullRequestReviewId"])
+	}
+	if rev.State != "PENDING" {
+		return githubv4mock.ErrorResponse("Can not submit a non-pending pull request review")
+	}
+	if body := stringVariable(input, "body"); body != "" {
+		rev.Body = body
+	}
+	if errResponse := s.submitReview(iss, rev, stringVariable(input, "event")); errResponse != nil {
+		return *errResponse
+	}
+	return reviewResponse("submitPullRequestReview", rev)
+}
+
+func (s *Server) resolveDeleteReview(variables map[string]any) githubv4mock.GQLResponse {
+	input := inputVariable(variables)
+	_, iss, rev := s.findReview(stringVariable(input, "pullRequestReviewId"))
+	if rev == nil {
+		return notFound("PullRequestReview", input["pullRequestReviewId"])
+	}
+	if rev.State != "PENDING" {
+		return githubv4mock.ErrorResponse("Can not delete a non-pending pull request review")
+	}
+	for i, other := range iss.pull.Reviews {
+		if other == rev {
+			iss.pull.Reviews = append(iss.pull.Reviews[:i], iss.pull.Reviews[i+1:]...)
+			break
+		}
+	}
+	return reviewResponse("deletePullRequestReview", rev)
+}
+
+// resolveSuggestedActors suggests Copilot as an assignee for the issues of every repository.
+func (s *Server) resolveSuggestedActors(variables map[string]any) githubv4mock.GQLResponse {
+	if _, ok := s.repos[repoKey(stringVariable(variables, "owner"), stringVariable(variables, "name"))]; !ok {
+		return repositoryNotFound(variables, "name")
+	}
+	return githubv4mock.DataResponse(map[string]any{
+		"repository": map[string]any{
+			"suggestedActors": map[string]any{
+				"nodes": []map[string]any{
+					{"id": s.copilot.NodeID, "login": copilotAgentLogin, "__typename": "Bot"},
+				},
+				"pageInfo": map[string]any{"hasNextPage": false, "endCursor": ""},
+			},
+		},
+	})
+}
+
+func (s *Server) resolveIssueAssignees(variables map[string]any) githubv4mock.GQLResponse {
+	repo, ok := s.repos[repoKey(stringVariable(variables, "owner"), stringVariable(variables, "name"))]
+	if !ok {
+		return repositoryNotFound(variables, "name")
+	}
+	number := intVariable(variables, "number")
+	if number < 1 || number > len(repo.issues) {
+		return notFound("Issue", variables["number"])
+	}
+	iss := repo.issues[number-1]
+	assignees := []map[string]any{}
+	for _, u := range iss.Assignees {
+		assignees = append(assignees, map[string]any{"id": u.NodeID})
+	}
+	return githubv4mock.DataResponse(map[string]any{
+		"repository": map[string]any{
+			"issue": map[string]any{"id": iss.NodeID, "assignees": map[string]any{"nodes": assignees}},
+		},
+	})
+}
+
+func (s *Server) resolveReplaceActors(variables map[string]any) githubv4mock.GQLResponse {
+	input := inputVariable(variables)
+	_, iss := s.findPull(stringVariable(input, "assignableId"))
+	if iss == nil {
+		return notFound("Assignable", input["assignableId"])
+	}
+	actorIDs, _ := input["actorIds"].([]any)
+	var assignees []*user
+	for _, id := range actorIDs {
+		actor := s.userByNodeID(id)
+		if actor == nil {
+			return notFound("Actor", id)
+		}
+		assignees = append(assignees, actor)
+	}
+	iss.Assignees = assignees
+	iss.UpdatedAt = s.now()
+	return githubv4mock.DataResponse(map[string]any{
+		"replaceActorsForAssignable": map[string]any{"__typename": "ReplaceActorsForAssignablePayload"},
+	})
+}
+
+func (s *Server) userByNodeID(id any) *user {
+	for _, u := range s.users {
+		if u.NodeID == id {
+			return u
+		}
+	}
+	return nil
+}
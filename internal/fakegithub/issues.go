@@ -0,0 +1,340 @@
+package fakegithub
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// issue is an issue or, if pull is set, a pull request, the two sharing their numbers and most of their fields.
+type issue struct {
+	ID          int64
+	NodeID      string
+	Number      int
+	Title       string
+	Body        string
+	State       string
+	StateReason string
+	User        *user
+	Labels      []string
+	Assignees   []*user
+	Comments    []*comment
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	ClosedAt    time.Time
+
+	pull *pullRequest
+}
+
+type comment struct {
+	ID        int64
+	NodeID    string
+	User      *user
+	Body      string
+	CreatedAt time.Time
+}
+
+func (s *Server) routeIssues() {
+	const prefix = "/api/v3/repos/{owner}/{repo}/issues"
+	s.mux.HandleFunc("GET "+prefix, s.withRepo(s.listIssues))
+	s.mux.HandleFunc("POST "+prefix, s.withRepo(s.createIssue))
+	s.mux.HandleFunc("GET "+prefix+"/{number}", s.withIssue(s.getIssue))
+	s.mux.HandleFunc("PATCH "+prefix+"/{number}", s.withIssue(s.updateIssue))
+	s.mux.HandleFunc("GET "+prefix+"/{number}/comments", s.withIssue(s.listIssueComments))
+	s.mux.HandleFunc("POST "+prefix+"/{number}/comments", s.withIssue(s.createIssueComment))
+}
+
+// newIssue adds an issue to repo, opened by the authenticated user.
+func (s *Server) newIssue(repo *repository, title, body string) *issue {
+	now := s.now()
+	iss := &issue{
+		ID:        s.newID(),
+		Number:    len(repo.issues) + 1,
+		Title:     title,
+		Body:      body,
+		State:     "open",
+		User:      s.viewer,
+		Labels:    []string{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	iss.NodeID = nodeID("I", iss.ID)
+	repo.issues = append(repo.issues, iss)
+	return iss
+}
+
+// withIssue adapts h to be handed the issue, or pull request, the number path parameter names, answering with a
+// 404 if there is none.
+func (s *Server) withIssue(h func(http.ResponseWriter, *http.Request, *repository, *issue)) http.HandlerFunc {
+	return s.withRepo(func(w http.ResponseWriter, r *http.Request, repo *repository) {
+		number, ok := pathInt(w, r, "number")
+		if !ok {
+			return
+		}
+		if number < 1 || number > int64(len(repo.issues)) {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		h(w, r, repo, repo.issues[number-1])
+	})
+}
+
+// setAssignees replaces the assignees of iss with the users of logins.
+func (s *Server) setAssignees(iss *issue, logins []string) {
+	iss.Assignees = nil
+	for _, login := range logins {
+		iss.Assignees = append(iss.Assignees, s.user(login))
+	}
+}
+
+// setState opens or closes iss.
+func (s *Server) setState(iss *issue, state, reason string) {
+	if state == iss.State {
+		return
+	}
+	iss.State = state
+	if state == "closed" {
+		iss.ClosedAt = s.now()
+		iss.StateReason = reason
+		if iss.StateReason == "" {
+			iss.StateReason = "completed"
+		}
+	} else {
+		iss.ClosedAt = time.Time{}
+		iss.StateReason = "reopened"
+	}
+}
+
+// labelJSON returns the label name, which is created on first use, with an ID derived from its name.
+func labelJSON(name string) map[string]any {
+	hash := hashObject("label", []byte(strings.ToLower(name)))
+	id, _ := strconv.ParseInt(hash[:8], 16, 64)
+	return map[string]any{
+		"id":      id,
+		"node_id": "LA_" + hash[:12],
+		"name":    name,
+		"color":   "ededed",
+		"default": false,
+	}
+}
+
+func (repo *repository) issueJSON(r *http.Request, iss *issue) map[string]any {
+	labels := []map[string]any{}
+	for _, label := range iss.Labels {
+		labels = append(labels, labelJSON(label))
+	}
+	assignees := []map[string]any{}
+	for _, u := range iss.Assignees {
+		assignees = append(assignees, u.json(r))
+	}
+	var assignee any
+	if len(assignees) > 0 {
+		assignee = assignees[0]
+	}
+	var stateReason any
+	if iss.StateReason != "" {
+		stateReason = iss.StateReason
+	}
+	number := strconv.Itoa(iss.Number)
+	result := map[string]any{
+		"id":             iss.ID,
+		"node_id":        iss.NodeID,
+		"number":         iss.Number,
+		"title":          iss.Title,
+		"body":           iss.Body,
+		"state":          iss.State,
+		"state_reason":   stateReason,
+		"user":           iss.User.json(r),
+		"labels":         labels,
+		"assignee":       assignee,
+		"assignees":      assignees,
+		"comments":       len(iss.Comments),
+		"locked":         false,
+		"created_at":     timeJSON(iss.CreatedAt),
+		"updated_at":     timeJSON(iss.UpdatedAt),
+		"closed_at":      timeJSON(iss.ClosedAt),
+		"url":            repo.apiURL(r, "issues", number),
+		"html_url":       repo.htmlURL(r, "issues", number),
+		"repository_url": repo.apiURL(r),
+		"comments_url":   repo.apiURL(r, "issues", number, "comments"),
+	}
+	if iss.pull != nil {
+		result["html_url"] = repo.htmlURL(r, "pull", number)
+		result["pull_request"] = map[string]any{
+			"url":       repo.apiURL(r, "pulls", number),
+			"html_url":  repo.htmlURL(r, "pull", number),
+			"diff_url":  repo.htmlURL(r, "pull", number) + ".diff",
+			"patch_url": repo.htmlURL(r, "pull", number) + ".patch",
+		}
+	}
+	return result
+}
+
+func (repo *repository) commentJSON(r *http.Request, iss *issue, c *comment) map[string]any {
+	id := strconv.FormatInt(c.ID, 10)
+	return map[string]any{
+		"id":         c.ID,
+		"node_id":    c.NodeID,
+		"user":       c.User.json(r),
+		"body":       c.Body,
+		"created_at": timeJSON(c.CreatedAt),
+		"updated_at": timeJSON(c.CreatedAt),
+		"url":        repo.apiURL(r, "issues", "comments", id),
+		"html_url":   repo.htmlURL(r, "issues", strconv.Itoa(iss.Number)) + "#issuecomment-" + id,
+		"issue_url":  repo.apiURL(r, "issues", strconv.Itoa(iss.Number)),
+	}
+}
+
+// sortIssues orders issues by the sort and direction query parameters, most recently created first by default.
+func sortIssues(issues []*issue, field, direction string) {
+	key := func(iss *issue) time.Time { return iss.CreatedAt }
+	if field == "updated" {
+		key = func(iss *issue) time.Time { return iss.UpdatedAt }
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := key(issues[i]), key(issues[j])
+		if direction == "asc" {
+			return a.Before(b) || a.Equal(b) && issues[i].Number < issues[j].Number
+		}
+		return a.After(b) || a.Equal(b) && issues[i].Number > issues[j].Number
+	})
+}
+
+func (s *Server) listIssues(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	var labels []string
+	if query.Get("labels") != "" {
+		labels = strings.Split(query.Get("labels"), ",")
+	}
+	var since time.Time
+	if query.Get("since") != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, query.Get("since")); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+	}
+
+	var matching []*issue
+	for _, iss := range repo.issues {
+		if (state != "all" && iss.State != state) || iss.UpdatedAt.Before(since) || !hasLabels(iss, labels) {
+			continue
+		}
+		matching = append(matching, iss)
+	}
+	sortIssues(matching, query.Get("sort"), query.Get("direction"))
+
+	issues := []map[string]any{}
+	for _, iss := range matching {
+		issues = append(issues, repo.issueJSON(r, iss))
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, issues))
+}
+
+func hasLabels(iss *issue, labels []string) bool {
+	for _, label := range labels {
+		found := false
+		for _, l := range iss.Labels {
+			if strings.EqualFold(l, strings.TrimSpace(label)) {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Title     string   `json:"title"`
+		Body      string   `json:"body"`
+		Labels    []string `json:"labels"`
+		Assignees []string `json:"assignees"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Title == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+	iss := s.newIssue(repo, body.Title, body.Body)
+	if body.Labels != nil {
+		iss.Labels = body.Labels
+	}
+	s.setAssignees(iss, body.Assignees)
+	writeJSON(w, http.StatusCreated, repo.issueJSON(r, iss))
+}
+
+func (s *Server) getIssue(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	writeJSON(w, http.StatusOK, repo.issueJSON(r, iss))
+}
+
+func (s *Server) updateIssue(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	var body struct {
+		Title       *string   `json:"title"`
+		Body        *string   `json:"body"`
+		State       *string   `json:"state"`
+		StateReason string    `json:"state_reason"`
+		Labels      *[]string `json:"labels"`
+		Assignees   *[]string `json:"assignees"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Title != nil {
+		iss.Title = *body.Title
+	}
+	if body.Body != nil {
+		iss.Body = *body.Body
+	}
+	if body.State != nil {
+		if *body.State != "open" && *body.State != "closed" {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		s.setState(iss, *body.State, body.StateReason)
+	}
+	if body.Labels != nil {
+		iss.Labels = append([]string{}, *body.Labels...)
+	}
+	if body.Assignees != nil {
+		s.setAssignees(iss, *body.Assignees)
+	}
+	iss.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, repo.issueJSON(r, iss))
+}
+
+func (s *Server) listIssueComments(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	comments := []map[string]any{}
+	for _, c := range iss.Comments {
+		comments = append(comments, repo.commentJSON(r, iss, c))
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, comments))
+}
+
+func (s *Server) createIssueComment(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	var body struct {
+		Body string `json:"body"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Body == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	}
+	c := &comment{ID: s.newID(), User: s.viewer, Body: body.Body, CreatedAt: s.now()}
+	c.NodeID = nodeID("IC", c.ID)
+	iss.Comments = append(iss.Comments, c)
+	iss.UpdatedAt = c.CreatedAt
+	writeJSON(w, http.StatusCreated, repo.commentJSON(r, iss, c))
+}
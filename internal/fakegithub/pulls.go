@@ -0,0 +1,574 @@
+package fakegithub
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type pullRequest struct {
+	// Head and Base are the names of the branches the pull request merges from and into, in the same repository
+	Head string
+	Base string
+
+	// HeadSHA is the commit the head branch was at when last seen, for once it is deleted
+	HeadSHA string
+
+	Draft               bool
+	MaintainerCanModify bool
+	MergedAt            time.Time
+	MergedBy            *user
+	MergeCommitSHA      string
+	RequestedReviewers  []*user
+	Reviews             []*review
+}
+
+type review struct {
+	ID          int64
+	NodeID      string
+	User        *user
+	Body        string
+	State       string
+	CommitID    string
+	SubmittedAt time.Time
+	Comments    []*reviewComment
+}
+
+type reviewComment struct {
+	ID          int64
+	NodeID      string
+	User        *user
+	Body        string
+	Path        string
+	SubjectType string
+	Line        int
+	Side        string
+	StartLine   int
+	StartSide   string
+	CommitID    string
+	CreatedAt   time.Time
+}
+
+func (s *Server) routePulls() {
+	const prefix = "/api/v3/repos/{owner}/{repo}/pulls"
+	s.mux.HandleFunc("GET "+prefix, s.withRepo(s.listPulls))
+	s.mux.HandleFunc("POST "+prefix, s.withRepo(s.createPull))
+	s.mux.HandleFunc("GET "+prefix+"/{number}", s.withPull(s.getPull))
+	s.mux.HandleFunc("PATCH "+prefix+"/{number}", s.withPull(s.updatePull))
+	s.mux.HandleFunc("GET "+prefix+"/{number}/files", s.withPull(s.listPullFiles))
+	s.mux.HandleFunc("PUT "+prefix+"/{number}/merge", s.withPull(s.mergePull))
+	s.mux.HandleFunc("PUT "+prefix+"/{number}/update-branch", s.withPull(s.updatePullBranch))
+	s.mux.HandleFunc("GET "+prefix+"/{number}/requested_reviewers", s.withPull(s.listRequestedReviewers))
+	s.mux.HandleFunc("POST "+prefix+"/{number}/requested_reviewers", s.withPull(s.requestReviewers))
+	s.mux.HandleFunc("GET "+prefix+"/{number}/reviews", s.withPull(s.listReviews))
+	s.mux.HandleFunc("GET "+prefix+"/{number}/reviews/{review}/comments", s.withPull(s.listReviewComments))
+	s.mux.HandleFunc("GET "+prefix+"/{number}/comments", s.withPull(s.listReviewComments))
+}
+
+// withPull adapts h to be handed the pull request the number path parameter names, answering with a 404 if there
+// is none.
+func (s *Server) withPull(h func(http.ResponseWriter, *http.Request, *repository, *issue)) http.HandlerFunc {
+	return s.withIssue(func(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+		if iss.pull == nil {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		h(w, r, repo, iss)
+	})
+}
+
+// headSHA returns the commit the head branch of pr is at.
+func (repo *repository) headSHA(pr *pullRequest) string {
+	if sha, ok := repo.refs["refs/heads/"+pr.Head]; ok {
+		pr.HeadSHA = sha
+	}
+	return pr.HeadSHA
+}
+
+// baseSHA returns the commit the base branch of pr is at, or was at when pr was merged.
+func (repo *repository) baseSHA(pr *pullRequest) string {
+	if c, ok := repo.commits[pr.MergeCommitSHA]; ok {
+		return c.Parents[0]
+	}
+	return repo.refs["refs/heads/"+pr.Base]
+}
+
+// pullChanges returns the changes pr makes to its base branch, and the commits it adds to it.
+func (repo *repository) pullChanges(pr *pullRequest) ([]fileChange, []*commit) {
+	head, base := repo.headSHA(pr), repo.baseSHA(pr)
+	inBase := map[string]bool{}
+	for _, c := range repo.ancestors(base) {
+		inBase[c.SHA] = true
+	}
+	var commits []*commit
+	for _, c := range repo.ancestors(head) {
+		if !inBase[c.SHA] {
+			commits = append(commits, c)
+		}
+	}
+	return repo.diff(repo.files(repo.mergeBase(base, head)), repo.files(head)), commits
+}
+
+func (repo *repository) branchJSON(r *http.Request, branch, sha string) map[string]any {
+	return map[string]any{
+		"label": repo.Owner.Login + ":" + branch,
+		"ref":   branch,
+		"sha":   sha,
+		"user":  repo.Owner.json(r),
+		"repo":  repo.json(r),
+	}
+}
+
+func (repo *repository) reviewersJSON(r *http.Request, pr *pullRequest) []map[string]any {
+	reviewers := []map[string]any{}
+	for _, u := range pr.RequestedReviewers {
+		reviewers = append(reviewers, u.json(r))
+	}
+	return reviewers
+}
+
+func (repo *repository) pullJSON(r *http.Request, iss *issue) map[string]any {
+	pr := iss.pull
+	result := repo.issueJSON(r, iss)
+	delete(result, "pull_request")
+	delete(result, "repository_url")
+	number := strconv.Itoa(iss.Number)
+
+	changes, commits := repo.pullChanges(pr)
+	additions, deletions := 0, 0
+	for _, change := range changes {
+		additions += change.Additions
+		deletions += change.Deletions
+	}
+	reviewComments := 0
+	for _, rev := range pr.Reviews {
+		if rev.State != "PENDING" {
+			reviewComments += len(rev.Comments)
+		}
+	}
+
+	var mergedBy, mergeCommitSHA any
+	if pr.MergedBy != nil {
+		mergedBy, mergeCommitSHA = pr.MergedBy.json(r), pr.MergeCommitSHA
+	}
+	var mergeable any
+	mergeableState := "unknown"
+	if iss.State == "open" {
+		_, conflict := repo.merge(repo.refs["refs/heads/"+pr.Base], repo.headSHA(pr))
+		mergeable, mergeableState = !conflict, "clean"
+		if conflict {
+			mergeableState = "dirty"
+		}
+	}
+
+	for key, value := range map[string]any{
+		"url":                   repo.apiURL(r, "pulls", number),
+		"html_url":              repo.htmlURL(r, "pull", number),
+		"diff_url":              repo.htmlURL(r, "pull", number) + ".diff",
+		"patch_url":             repo.htmlURL(r, "pull", number) + ".patch",
+		"issue_url":             repo.apiURL(r, "issues", number),
+		"comments":              len(iss.Comments),
+		"review_comments":       reviewComments,
+		"commits":               len(commits),
+		"additions":             additions,
+		"deletions":             deletions,
+		"changed_files":         len(changes),
+		"draft":                 pr.Draft,
+		"merged":                !pr.MergedAt.IsZero(),
+		"merged_at":             timeJSON(pr.MergedAt),
+		"merged_by":             mergedBy,
+		"merge_commit_sha":      mergeCommitSHA,
+		"mergeable":             mergeable,
+		"mergeable_state":       mergeableState,
+		"maintainer_can_modify": pr.MaintainerCanModify,
+		"requested_reviewers":   repo.reviewersJSON(r, pr),
+		"requested_teams":       []any{},
+		"head":                  repo.branchJSON(r, pr.Head, repo.headSHA(pr)),
+		"base":                  repo.branchJSON(r, pr.Base, repo.refs["refs/heads/"+pr.Base]),
+	} {
+		result[key] = value
+	}
+	return result
+}
+
+func (s *Server) listPulls(w http.ResponseWriter, r *http.Request, repo *repository) {
+	query := r.URL.Query()
+	state := query.Get("state")
+	if state == "" {
+		state = "open"
+	}
+	head := query.Get("head")
+	if owner, branch, ok := strings.Cut(head, ":"); ok && strings.EqualFold(owner, repo.Owner.Login) {
+		head = branch
+	}
+
+	var matching []*issue
+	for _, iss := range repo.issues {
+		pr := iss.pull
+		if pr == nil || (state != "all" && iss.State != state) ||
+			(head != "" && pr.Head != head) || (query.Get("base") != "" && pr.Base != query.Get("base")) {
+			continue
+		}
+		matching = append(matching, iss)
+	}
+	sortIssues(matching, query.Get("sort"), query.Get("direction"))
+
+	pulls := []map[string]any{}
+	for _, iss := range matching {
+		pulls = append(pulls, repo.pullJSON(r, iss))
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, pulls))
+}
+
+func (s *Server) createPull(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Title               string `json:"title"`
+		Body                string `json:"body"`
+		Head                string `json:"head"`
+		Base                string `json:"base"`
+		Draft               bool   `json:"draft"`
+		MaintainerCanModify *bool  `json:"maintainer_can_modify"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	head := body.Head
+	if owner, branch, ok := strings.Cut(head, ":"); ok {
+		if !strings.EqualFold(owner, repo.Owner.Login) {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed: pull requests from other repositories are not supported")
+			return
+		}
+		head = branch
+	}
+	headSHA, headOK := repo.refs["refs/heads/"+head]
+	baseSHA, baseOK := repo.refs["refs/heads/"+body.Base]
+	switch {
+	case body.Title == "" || !headOK || !baseOK:
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+		return
+	case repo.mergeBase(baseSHA, headSHA) == headSHA:
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation Failed: No commits between %s and %s", body.Base, head))
+		return
+	}
+	for _, iss := range repo.issues {
+		if iss.pull != nil && iss.State == "open" && iss.pull.Head == head && iss.pull.Base == body.Base {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation Failed: A pull request already exists for %s:%s.", repo.Owner.Login, head))
+			return
+		}
+	}
+
+	iss := s.newIssue(repo, body.Title, body.Body)
+	iss.NodeID = nodeID("PR", iss.ID)
+	iss.pull = &pullRequest{Head: head, Base: body.Base, HeadSHA: headSHA, Draft: body.Draft, MaintainerCanModify: true}
+	if body.MaintainerCanModify != nil {
+		iss.pull.MaintainerCanModify = *body.MaintainerCanModify
+	}
+	writeJSON(w, http.StatusCreated, repo.pullJSON(r, iss))
+}
+
+func (s *Server) getPull(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	if accept := r.Header.Get("Accept"); strings.Contains(accept, ".diff") || strings.Contains(accept, ".patch") {
+		changes, _ := repo.pullChanges(iss.pull)
+		base := repo.pullBaseFiles(iss.pull)
+		var diff strings.Builder
+		for _, change := range changes {
+			diff.WriteString(repo.unifiedDiff(change, base[change.Filename]))
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(diff.String()))
+		return
+	}
+	writeJSON(w, http.StatusOK, repo.pullJSON(r, iss))
+}
+
+// pullBaseFiles returns the files pr is compared against, those of the commit its branches diverged at.
+func (repo *repository) pullBaseFiles(pr *pullRequest) map[string]string {
+	return repo.files(repo.mergeBase(repo.baseSHA(pr), repo.headSHA(pr)))
+}
+
+// patch returns the hunk of a unified diff of change to the blob oldSHA, which shows every line of the file as
+// removed or added rather than only those that differ.
+func (repo *repository) patch(change fileChange, oldSHA string) string {
+	oldLines, newLines := lines(repo.blobs[oldSHA]), lines(repo.blobs[change.SHA])
+	if change.Status == "removed" {
+		newLines = nil
+	}
+	oldStart, newStart := min(1, len(oldLines)), min(1, len(newLines))
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, len(oldLines), newStart, len(newLines))
+	for _, line := range oldLines {
+		b.WriteString("-" + line + "\n")
+	}
+	for _, line := range newLines {
+		b.WriteString("+" + line + "\n")
+	}
+	return b.String()
+}
+
+// unifiedDiff returns change to the blob oldSHA as git diff shows it.
+func (repo *repository) unifiedDiff(change fileChange, oldSHA string) string {
+	name := change.Filename
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", name, name)
+	switch change.Status {
+	case "added":
+		fmt.Fprintf(&b, "new file mode 100644\n--- /dev/null\n+++ b/%s\n", name)
+	case "removed":
+		fmt.Fprintf(&b, "deleted file mode 100644\n--- a/%s\n+++ /dev/null\n", name)
+	default:
+		fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+	}
+	b.WriteString(repo.patch(change, oldSHA))
+	return b.String()
+}
+
+func (s *Server) updatePull(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	var body struct {
+		Title               *string `json:"title"`
+		Body                *string `json:"body"`
+		State               *string `json:"state"`
+		Base                *string `json:"base"`
+		MaintainerCanModify *bool   `json:"maintainer_can_modify"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	if body.Base != nil {
+		if _, ok := repo.refs["refs/heads/"+*body.Base]; !ok {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		iss.pull.Base = *body.Base
+	}
+	if body.State != nil {
+		if *body.State != "open" && *body.State != "closed" {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed")
+			return
+		}
+		if !iss.pull.MergedAt.IsZero() && *body.State == "open" {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed: merged pull requests cannot be reopened")
+			return
+		}
+		s.setState(iss, *body.State, "")
+	}
+	if body.Title != nil {
+		iss.Title = *body.Title
+	}
+	if body.Body != nil {
+		iss.Body = *body.Body
+	}
+	if body.MaintainerCanModify != nil {
+		iss.pull.MaintainerCanModify = *body.MaintainerCanModify
+	}
+	iss.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, repo.pullJSON(r, iss))
+}
+
+func (s *Server) listPullFiles(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	changes, _ := repo.pullChanges(iss.pull)
+	base := repo.pullBaseFiles(iss.pull)
+	files := []map[string]any{}
+	for _, change := range changes {
+		file := repo.fileChangeJSON(r, repo.headSHA(iss.pull), change)
+		file["patch"] = repo.patch(change, base[change.Filename])
+		files = append(files, file)
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, files))
+}
+
+func (s *Server) mergePull(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	var body struct {
+		CommitTitle   string `json:"commit_title"`
+		CommitMessage string `json:"commit_message"`
+		MergeMethod   string `json:"merge_method"`
+		SHA           string `json:"sha"`
+	}
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	pr := iss.pull
+	head, base := repo.headSHA(pr), repo.refs["refs/heads/"+pr.Base]
+	if iss.State != "open" {
+		writeError(w, http.StatusMethodNotAllowed, "Pull Request is not mergeable")
+		return
+	}
+	if body.SHA != "" && body.SHA != head {
+		writeError(w, http.StatusConflict, "Head branch was modified. Review and try the merge again.")
+		return
+	}
+	files, conflict := repo.merge(base, head)
+	if conflict {
+		writeError(w, http.StatusMethodNotAllowed, "Merge conflict")
+		return
+	}
+
+	title := body.CommitTitle
+	parents := []string{base}
+	switch body.MergeMethod {
+	case "", "merge":
+		if title == "" {
+			title = fmt.Sprintf("Merge pull request #%d from %s/%s", iss.Number, repo.Owner.Login, pr.Head)
+		}
+		parents = append(parents, head)
+	case "squash", "rebase":
+		if title == "" {
+			title = fmt.Sprintf("%s (#%d)", iss.Title, iss.Number)
+		}
+	default:
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed: merge_method is not one of merge, squash or rebase")
+		return
+	}
+	message := title
+	if body.CommitMessage != "" {
+		message += "\n\n" + body.CommitMessage
+	}
+	c := &commit{Tree: repo.putTree(files), Parents: parents, Message: message, Author: s.signature(), Committer: s.signature()}
+	repo.putCommit(c)
+	repo.refs["refs/heads/"+pr.Base] = c.SHA
+
+	pr.MergedAt, pr.MergedBy, pr.MergeCommitSHA = s.now(), s.viewer, c.SHA
+	s.setState(iss, "closed", "")
+	iss.UpdatedAt = pr.MergedAt
+	writeJSON(w, http.StatusOK, map[string]any{"sha": c.SHA, "merged": true, "message": "Pull Request successfully merged"})
+}
+
+func (s *Server) updatePullBranch(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	var body struct {
+		ExpectedHeadSHA string `json:"expected_head_sha"`
+	}
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	pr := iss.pull
+	head, base := repo.headSHA(pr), repo.refs["refs/heads/"+pr.Base]
+	switch {
+	case body.ExpectedHeadSHA != "" && body.ExpectedHeadSHA != head:
+		writeError(w, http.StatusUnprocessableEntity, "expected head sha didn't match current head ref.")
+		return
+	case repo.mergeBase(head, base) == base:
+		writeError(w, http.StatusUnprocessableEntity, "There are no new commits on the base branch.")
+		return
+	}
+	files, conflict := repo.merge(head, base)
+	if conflict {
+		writeError(w, http.StatusUnprocessableEntity, "merge conflict between base and head")
+		return
+	}
+	c := &commit{
+		Tree:      repo.putTree(files),
+		Parents:   []string{head, base},
+		Message:   fmt.Sprintf("Merge branch '%s' into %s", pr.Base, pr.Head),
+		Author:    s.signature(),
+		Committer: s.signature(),
+	}
+	repo.putCommit(c)
+	repo.refs["refs/heads/"+pr.Head] = c.SHA
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"message": "Updating pull request branch.",
+		"url":     repo.htmlURL(r, "pull", strconv.Itoa(iss.Number)),
+	})
+}
+
+func (s *Server) listRequestedReviewers(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	writeJSON(w, http.StatusOK, map[string]any{"users": repo.reviewersJSON(r, iss.pull), "teams": []any{}})
+}
+
+func (s *Server) requestReviewers(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	var body struct {
+		Reviewers []string `json:"reviewers"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	pr := iss.pull
+	for _, login := range body.Reviewers {
+		reviewer := s.user(login)
+		if reviewer == iss.User {
+			writeError(w, http.StatusUnprocessableEntity, "Review cannot be requested from pull request author.")
+			return
+		}
+		requested := false
+		for _, u := range pr.RequestedReviewers {
+			requested = requested || u == reviewer
+		}
+		if !requested {
+			pr.RequestedReviewers = append(pr.RequestedReviewers, reviewer)
+		}
+	}
+	writeJSON(w, http.StatusCreated, repo.pullJSON(r, iss))
+}
+
+func (repo *repository) reviewJSON(r *http.Request, iss *issue, rev *review) map[string]any {
+	number := strconv.Itoa(iss.Number)
+	return map[string]any{
+		"id":               rev.ID,
+		"node_id":          rev.NodeID,
+		"user":             rev.User.json(r),
+		"body":             rev.Body,
+		"state":            rev.State,
+		"commit_id":        rev.CommitID,
+		"submitted_at":     timeJSON(rev.SubmittedAt),
+		"html_url":         repo.htmlURL(r, "pull", number) + "#pullrequestreview-" + strconv.FormatInt(rev.ID, 10),
+		"pull_request_url": repo.apiURL(r, "pulls", number),
+	}
+}
+
+func (s *Server) listReviews(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	reviews := []map[string]any{}
+	for _, rev := range iss.pull.Reviews {
+		reviews = append(reviews, repo.reviewJSON(r, iss, rev))
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, reviews))
+}
+
+func (repo *repository) reviewCommentJSON(r *http.Request, iss *issue, rev *review, c *reviewComment) map[string]any {
+	number := strconv.Itoa(iss.Number)
+	id := strconv.FormatInt(c.ID, 10)
+	result := map[string]any{
+		"id":                     c.ID,
+		"node_id":                c.NodeID,
+		"pull_request_review_id": rev.ID,
+		"user":                   c.User.json(r),
+		"body":                   c.Body,
+		"path":                   c.Path,
+		"subject_type":           strings.ToLower(c.SubjectType),
+		"commit_id":              c.CommitID,
+		"original_commit_id":     c.CommitID,
+		"created_at":             timeJSON(c.CreatedAt),
+		"updated_at":             timeJSON(c.CreatedAt),
+		"url":                    repo.apiURL(r, "pulls", "comments", id),
+		"html_url":               repo.htmlURL(r, "pull", number) + "#discussion_r" + id,
+		"pull_request_url":       repo.apiURL(r, "pulls", number),
+	}
+	if c.Line > 0 {
+		result["line"], result["side"] = c.Line, c.Side
+	}
+	if c.StartLine > 0 {
+		result["start_line"], result["start_side"] = c.StartLine, c.StartSide
+	}
+	return result
+}
+
+// listReviewComments lists the comments of the submitted reviews of a pull request, or those of the review the
+// review path parameter names.
+func (s *Server) listReviewComments(w http.ResponseWriter, r *http.Request, repo *repository, iss *issue) {
+	comments := []map[string]any{}
+	found := r.PathValue("review") == ""
+	for _, rev := range iss.pull.Reviews {
+		if r.PathValue("review") == "" && rev.State == "PENDING" || r.PathValue("review") != "" && r.PathValue("review") != strconv.FormatInt(rev.ID, 10) {
+			continue
+		}
+		found = true
+		for _, c := range rev.Comments {
+			comments = append(comments, repo.reviewCommentJSON(r, iss, rev, c))
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, comments))
+}
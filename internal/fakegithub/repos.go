@@ -0,0 +1,581 @@
+package fakegithub
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+type repository struct {
+	ID            int64
+	NodeID        string
+	Owner         *user
+	Name          string
+	Private       bool
+	Description   string
+	DefaultBranch string
+	CreatedAt     time.Time
+	Fork          bool
+
+	blobs   map[string][]byte
+	trees   map[string]map[string]string
+	commits map[string]*commit
+	tags    map[string]*tag
+	refs    map[string]string
+
+	// issues holds the issues and pull requests of the repository, which share their numbers, by number - 1
+	issues []*issue
+
+	workflowIDs map[string]int64
+	runs        []*workflowRun
+}
+
+func repoKey(owner, name string) string {
+	return strings.ToLower(owner + "/" + name)
+}
+
+func (s *Server) newRepository(owner *user, name string, private bool, description string) *repository {
+	repo := &repository{
+		ID:            s.newID(),
+		Owner:         owner,
+		Name:          name,
+		Private:       private,
+		Description:   description,
+		DefaultBranch: "main",
+		CreatedAt:     s.now(),
+		blobs:         map[string][]byte{},
+		trees:         map[string]map[string]string{},
+		commits:       map[string]*commit{},
+		tags:          map[string]*tag{},
+		refs:          map[string]string{},
+		workflowIDs:   map[string]int64{},
+	}
+	repo.NodeID = nodeID("R", repo.ID)
+	s.repos[repoKey(owner.Login, name)] = repo
+	return repo
+}
+
+func (repo *repository) fullName() string {
+	return repo.Owner.Login + "/" + repo.Name
+}
+
+func (repo *repository) apiURL(r *http.Request, path ...string) string {
+	return apiURL(r, append([]string{"repos", repo.Owner.Login, repo.Name}, path...)...)
+}
+
+func (repo *repository) htmlURL(r *http.Request, path ...string) string {
+	return htmlURL(r, append([]string{repo.Owner.Login, repo.Name}, path...)...)
+}
+
+// withRepo adapts h to be handed the repository the owner and repo path parameters name, answering with a 404 if
+// there is none.
+func (s *Server) withRepo(h func(http.ResponseWriter, *http.Request, *repository)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repo, ok := s.repos[repoKey(r.PathValue("owner"), r.PathValue("repo"))]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		h(w, r, repo)
+	}
+}
+
+func (s *Server) routeRepos() {
+	s.mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.viewer.json(r))
+	})
+	s.mux.HandleFunc("GET /api/v3/users/{login}", func(w http.ResponseWriter, r *http.Request) {
+		u, ok := s.users[strings.ToLower(r.PathValue("login"))]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeJSON(w, http.StatusOK, u.json(r))
+	})
+	s.mux.HandleFunc("POST /api/v3/user/repos", s.createRepository)
+	s.mux.HandleFunc("POST /api/v3/orgs/{org}/repos", s.createRepository)
+
+	const prefix = "/api/v3/repos/{owner}/{repo}"
+	s.mux.HandleFunc("GET "+prefix, s.withRepo(func(w http.ResponseWriter, r *http.Request, repo *repository) {
+		writeJSON(w, http.StatusOK, repo.json(r))
+	}))
+	s.mux.HandleFunc("DELETE "+prefix, s.withRepo(func(w http.ResponseWriter, _ *http.Request, repo *repository) {
+		delete(s.repos, repoKey(repo.Owner.Login, repo.Name))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	s.mux.HandleFunc("POST "+prefix+"/forks", s.withRepo(s.forkRepository))
+	s.mux.HandleFunc("GET "+prefix+"/branches", s.withRepo(s.listBranches))
+	s.mux.HandleFunc("GET "+prefix+"/branches/{branch...}", s.withRepo(s.getBranch))
+	s.mux.HandleFunc("GET "+prefix+"/tags", s.withRepo(s.listTags))
+	s.mux.HandleFunc("GET "+prefix+"/commits", s.withRepo(s.listCommits))
+	s.mux.HandleFunc("GET "+prefix+"/commits/{sha}", s.withRepo(s.getCommit))
+	s.mux.HandleFunc("GET "+prefix+"/commits/{sha}/status", s.withRepo(s.getCombinedStatus))
+	s.mux.HandleFunc("GET "+prefix+"/contents/{path...}", s.withRepo(s.getContents))
+	s.mux.HandleFunc("PUT "+prefix+"/contents/{path...}", s.withRepo(s.putContents))
+	s.mux.HandleFunc("DELETE "+prefix+"/contents/{path...}", s.withRepo(s.deleteContents))
+}
+
+func (repo *repository) json(r *http.Request) map[string]any {
+	visibility := "public"
+	if repo.Private {
+		visibility = "private"
+	}
+	return map[string]any{
+		"id":             repo.ID,
+		"node_id":        repo.NodeID,
+		"name":           repo.Name,
+		"full_name":      repo.fullName(),
+		"owner":          repo.Owner.json(r),
+		"private":        repo.Private,
+		"visibility":     visibility,
+		"description":    repo.Description,
+		"fork":           repo.Fork,
+		"archived":       false,
+		"url":            repo.apiURL(r),
+		"html_url":       repo.htmlURL(r),
+		"clone_url":      repo.htmlURL(r) + ".git",
+		"default_branch": repo.DefaultBranch,
+		"created_at":     timeJSON(repo.CreatedAt),
+		"permissions":    map[string]any{"admin": true, "maintain": true, "push": true, "triage": true, "pull": true},
+	}
+}
+
+func (s *Server) createRepository(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Private     bool   `json:"private"`
+		AutoInit    bool   `json:"auto_init"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	owner := s.viewer
+	if org := r.PathValue("org"); org != "" {
+		owner = s.user(org)
+		owner.Type = "Organization"
+	}
+	if body.Name == "" {
+		writeError(w, http.StatusUnprocessableEntity, "Repository creation failed.")
+		return
+	}
+	if _, ok := s.repos[repoKey(owner.Login, body.Name)]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "Repository creation failed: name already exists on this account")
+		return
+	}
+
+	repo := s.newRepository(owner, body.Name, body.Private, body.Description)
+	if body.AutoInit {
+		readme := "# " + body.Name + "\n"
+		if body.Description != "" {
+			readme += body.Description + "\n"
+		}
+		if _, err := s.commitChanges(repo, repo.DefaultBranch, "Initial commit", map[string]*string{"README.md": &readme}); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	writeJSON(w, http.StatusCreated, repo.json(r))
+}
+
+func (s *Server) forkRepository(w http.ResponseWriter, r *http.Request, source *repository) {
+	var body struct {
+		Organization      string `json:"organization"`
+		Name              string `json:"name"`
+		DefaultBranchOnly bool   `json:"default_branch_only"`
+	}
+	if r.ContentLength != 0 && !readJSON(w, r, &body) {
+		return
+	}
+	owner := s.viewer
+	if body.Organization != "" {
+		owner = s.user(body.Organization)
+		owner.Type = "Organization"
+	}
+	name := body.Name
+	if name == "" {
+		name = source.Name
+	}
+	if existing, ok := s.repos[repoKey(owner.Login, name)]; ok {
+		// Forking again returns the existing fork
+		writeJSON(w, http.StatusAccepted, existing.json(r))
+		return
+	}
+
+	fork := s.newRepository(owner, name, source.Private, source.Description)
+	fork.Fork = true
+	fork.DefaultBranch = source.DefaultBranch
+	// Git objects are never modified, so they can be shared
+	for sha, data := range source.blobs {
+		fork.blobs[sha] = data
+	}
+	for sha, files := range source.trees {
+		fork.trees[sha] = files
+	}
+	for sha, c := range source.commits {
+		fork.commits[sha] = c
+	}
+	for sha, t := range source.tags {
+		fork.tags[sha] = t
+	}
+	for ref, sha := range source.refs {
+		if !body.DefaultBranchOnly || ref == "refs/heads/"+source.DefaultBranch {
+			fork.refs[ref] = sha
+		}
+	}
+	writeJSON(w, http.StatusAccepted, fork.json(r))
+}
+
+func (s *Server) listBranches(w http.ResponseWriter, r *http.Request, repo *repository) {
+	branches := []map[string]any{}
+	for _, ref := range repo.sortedRefs("refs/heads/") {
+		sha := repo.refs[ref]
+		branches = append(branches, map[string]any{
+			"name":      strings.TrimPrefix(ref, "refs/heads/"),
+			"commit":    map[string]any{"sha": sha, "url": repo.apiURL(r, "commits", sha)},
+			"protected": false,
+		})
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, branches))
+}
+
+func (s *Server) getBranch(w http.ResponseWriter, r *http.Request, repo *repository) {
+	name := r.PathValue("branch")
+	sha, ok := repo.refs["refs/heads/"+name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Branch not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"name":      name,
+		"commit":    s.commitJSON(r, repo, repo.commits[sha], false),
+		"protected": false,
+	})
+}
+
+func (s *Server) listTags(w http.ResponseWriter, r *http.Request, repo *repository) {
+	tags := []map[string]any{}
+	for _, ref := range repo.sortedRefs("refs/tags/") {
+		name := strings.TrimPrefix(ref, "refs/tags/")
+		sha, _ := repo.peel(repo.refs[ref])
+		tags = append(tags, map[string]any{
+			"name":        name,
+			"commit":      map[string]any{"sha": sha, "url": repo.apiURL(r, "commits", sha)},
+			"zipball_url": repo.apiURL(r, "zipball", name),
+			"tarball_url": repo.apiURL(r, "tarball", name),
+			"node_id":     "REF_" + hashObject("ref", []byte(repo.fullName()+ref))[:12],
+		})
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, tags))
+}
+
+// author returns the user whose commit email is email, if any.
+func (s *Server) author(r *http.Request, email string) any {
+	login, ok := strings.CutSuffix(email, "@users.noreply.github.com")
+	if !ok {
+		return nil
+	}
+	if u, ok := s.users[strings.ToLower(login)]; ok {
+		return u.json(r)
+	}
+	return nil
+}
+
+// commitJSON returns c as the commits API does, with the files it changed if withFiles.
+func (s *Server) commitJSON(r *http.Request, repo *repository, c *commit, withFiles bool) map[string]any {
+	git := repo.gitCommitJSON(r, c)
+	delete(git, "sha")
+	delete(git, "node_id")
+	delete(git, "html_url")
+	delete(git, "parents")
+	git["comment_count"] = 0
+
+	result := map[string]any{
+		"sha":       c.SHA,
+		"node_id":   "C_" + c.SHA[:12],
+		"commit":    git,
+		"url":       repo.apiURL(r, "commits", c.SHA),
+		"html_url":  repo.htmlURL(r, "commit", c.SHA),
+		"author":    s.author(r, c.Author.Email),
+		"committer": s.author(r, c.Committer.Email),
+		"parents":   repo.gitCommitJSON(r, c)["parents"],
+	}
+	if !withFiles {
+		return result
+	}
+
+	var parentFiles map[string]string
+	if len(c.Parents) > 0 {
+		parentFiles = repo.files(c.Parents[0])
+	}
+	files := []map[string]any{}
+	additions, deletions := 0, 0
+	for _, change := range repo.diff(parentFiles, repo.trees[c.Tree]) {
+		files = append(files, repo.fileChangeJSON(r, c.SHA, change))
+		additions += change.Additions
+		deletions += change.Deletions
+	}
+	result["files"] = files
+	result["stats"] = map[string]any{"total": additions + deletions, "additions": additions, "deletions": deletions}
+	return result
+}
+
+func (repo *repository) fileChangeJSON(r *http.Request, sha string, change fileChange) map[string]any {
+	return map[string]any{
+		"sha":          change.SHA,
+		"filename":     change.Filename,
+		"status":       change.Status,
+		"additions":    change.Additions,
+		"deletions":    change.Deletions,
+		"changes":      change.Additions + change.Deletions,
+		"blob_url":     repo.htmlURL(r, "blob", sha, escapePath(change.Filename)),
+		"raw_url":      repo.htmlURL(r, "raw", sha, escapePath(change.Filename)),
+		"contents_url": repo.apiURL(r, "contents", escapePath(change.Filename)) + "?ref=" + sha,
+	}
+}
+
+func (s *Server) listCommits(w http.ResponseWriter, r *http.Request, repo *repository) {
+	if len(repo.refs) == 0 {
+		writeError(w, http.StatusConflict, "Git Repository is empty.")
+		return
+	}
+	query := r.URL.Query()
+	sha, ok := repo.resolve(query.Get("sha"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	filePath := strings.Trim(query.Get("path"), "/")
+	author := strings.ToLower(query.Get("author"))
+
+	commits := []map[string]any{}
+	for _, c := range repo.ancestors(sha) {
+		if author != "" && strings.ToLower(c.Author.Email) != author && strings.ToLower(c.Author.Name) != author {
+			continue
+		}
+		if filePath != "" {
+			var parentFiles map[string]string
+			if len(c.Parents) > 0 {
+				parentFiles = repo.files(c.Parents[0])
+			}
+			touched := false
+			for _, change := range repo.diff(parentFiles, repo.trees[c.Tree]) {
+				if change.Filename == filePath || strings.HasPrefix(change.Filename, filePath+"/") {
+					touched = true
+				}
+			}
+			if !touched {
+				continue
+			}
+		}
+		commits = append(commits, s.commitJSON(r, repo, c, false))
+	}
+	writeJSON(w, http.StatusOK, paginate(w, r, commits))
+}
+
+func (s *Server) getCommit(w http.ResponseWriter, r *http.Request, repo *repository) {
+	sha, ok := repo.resolve(r.PathValue("sha"))
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "No commit found for SHA: "+r.PathValue("sha"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.commitJSON(r, repo, repo.commits[sha], true))
+}
+
+func (s *Server) getCombinedStatus(w http.ResponseWriter, r *http.Request, repo *repository) {
+	sha, ok := repo.resolve(r.PathValue("sha"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"state":       "pending",
+		"sha":         sha,
+		"total_count": 0,
+		"statuses":    []any{},
+		"repository":  repo.json(r),
+	})
+}
+
+func (repo *repository) contentJSON(r *http.Request, filePath, kind, sha, ref string, withContent bool) map[string]any {
+	result := map[string]any{
+		"type":     kind,
+		"name":     path.Base(filePath),
+		"path":     filePath,
+		"sha":      sha,
+		"url":      repo.apiURL(r, "contents", escapePath(filePath)) + "?ref=" + ref,
+		"html_url": repo.htmlURL(r, webSegment(kind), ref, escapePath(filePath)),
+	}
+	if kind == "file" {
+		result["size"] = len(repo.blobs[sha])
+		result["git_url"] = repo.apiURL(r, "git", "blobs", sha)
+		result["download_url"] = baseURL(r) + "/raw/" + repo.fullName() + "/" + ref + "/" + escapePath(filePath)
+	} else {
+		result["size"] = 0
+		result["git_url"] = repo.apiURL(r, "git", "trees", sha)
+		result["download_url"] = nil
+	}
+	if withContent {
+		result["encoding"] = "base64"
+		result["content"] = base64.StdEncoding.EncodeToString(repo.blobs[sha])
+	}
+	return result
+}
+
+// webSegment returns the segment of the web URL of a file or directory.
+func webSegment(kind string) string {
+	if kind == "dir" {
+		return "tree"
+	}
+	return "blob"
+}
+
+func (s *Server) getContents(w http.ResponseWriter, r *http.Request, repo *repository) {
+	ref := r.URL.Query().Get("ref")
+	sha, ok := repo.resolve(ref)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No commit found for the ref %s", ref))
+		return
+	}
+	if ref == "" {
+		ref = repo.DefaultBranch
+	}
+	files := repo.files(sha)
+	filePath := strings.Trim(r.PathValue("path"), "/")
+
+	if blob, ok := files[filePath]; ok {
+		writeJSON(w, http.StatusOK, repo.contentJSON(r, filePath, "file", blob, ref, true))
+		return
+	}
+
+	prefix := ""
+	if filePath != "" {
+		prefix = filePath + "/"
+	}
+	entries := map[string]map[string]any{}
+	dirs := map[string]map[string]string{}
+	for file, blob := range files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		name, below, nested := strings.Cut(rest, "/")
+		if !nested {
+			entries[name] = repo.contentJSON(r, prefix+name, "file", blob, ref, false)
+			continue
+		}
+		if dirs[name] == nil {
+			dirs[name] = map[string]string{}
+		}
+		dirs[name][below] = blob
+	}
+	if len(entries) == 0 && len(dirs) == 0 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	for name, dirFiles := range dirs {
+		entries[name] = repo.contentJSON(r, prefix+name, "dir", repo.putTree(dirFiles), ref, false)
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	listing := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		listing = append(listing, entries[name])
+	}
+	writeJSON(w, http.StatusOK, listing)
+}
+
+// checkFileUpdate checks that the file at filePath on branch may be changed, given the SHA of the blob the client
+// believes it has, writing an error and returning false if not.
+func checkFileUpdate(w http.ResponseWriter, repo *repository, branch, filePath, sha string, mustExist bool) bool {
+	head, ok := repo.refs["refs/heads/"+branch]
+	if !ok && len(repo.refs) > 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Branch %s not found", branch))
+		return false
+	}
+	current, exists := repo.files(head)[filePath]
+	switch {
+	case !exists && mustExist:
+		writeError(w, http.StatusNotFound, "Not Found")
+		return false
+	case exists && sha == "":
+		writeError(w, http.StatusUnprocessableEntity, "Invalid request.\n\n\"sha\" wasn't supplied.")
+		return false
+	case exists && sha != current:
+		writeError(w, http.StatusConflict, fmt.Sprintf("%s does not match %s", filePath, sha))
+		return false
+	}
+	return true
+}
+
+func (s *Server) putContents(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Message string `json:"message"`
+		Content string `json:"content"`
+		SHA     string `json:"sha"`
+		Branch  string `json:"branch"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(body.Content)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "content is not valid Base64")
+		return
+	}
+	branch := body.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	if !checkFileUpdate(w, repo, branch, filePath, body.SHA, false) {
+		return
+	}
+
+	status := http.StatusCreated
+	if body.SHA != "" {
+		status = http.StatusOK
+	}
+	text := string(content)
+	c, err := s.commitChanges(repo, branch, body.Message, map[string]*string{filePath: &text})
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, status, map[string]any{
+		"content": repo.contentJSON(r, filePath, "file", repo.files(c.SHA)[filePath], branch, false),
+		"commit":  repo.gitCommitJSON(r, c),
+	})
+}
+
+func (s *Server) deleteContents(w http.ResponseWriter, r *http.Request, repo *repository) {
+	var body struct {
+		Message string `json:"message"`
+		SHA     string `json:"sha"`
+		Branch  string `json:"branch"`
+	}
+	if !readJSON(w, r, &body) {
+		return
+	}
+	branch := body.Branch
+	if branch == "" {
+		branch = repo.DefaultBranch
+	}
+	filePath := strings.Trim(r.PathValue("path"), "/")
+	if !checkFileUpdate(w, repo, branch, filePath, body.SHA, true) {
+		return
+	}
+	c, err := s.commitChanges(repo, branch, body.Message, map[string]*string{filePath: nil})
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"content": nil, "commit": repo.gitCommitJSON(r, c)})
+}
@@ -0,0 +1,250 @@
+package ghmcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/github/github-mcp-server/pkg/errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sirupsen/logrus"
+)
+
+// FormatText prints the content of the result of a call as text.
+const FormatText = "text"
+
+// CallConfig configures a single call to a tool, made by a server configured as the stdio server would be.
+type CallConfig struct {
+	StdioServerConfig
+
+	// Tool is the name of the tool to call
+	Tool string
+
+	// ArgsJSON is a JSON object holding the arguments of the call, if not empty
+	ArgsJSON string
+
+	// Args are key=value pairs, each setting an argument of the call over those in ArgsJSON. Values are converted
+	// to the type the tool's input schema gives the argument.
+	Args []string
+
+	// Format is FormatText, printing the content of the result, or FormatJSON, printing the result as the server
+	// sends it
+	Format string
+
+	// Out is where the result is printed
+	Out io.Writer
+
+	// ErrOut is where the content of a result reporting an error is printed, with FormatText
+	ErrOut io.Writer
+}
+
+// RunCall makes a single call to a tool through the same server, middleware and handlers an agent's calls go through,
+// printing its result. It reports whether the tool returned an error result.
+func RunCall(cfg CallConfig) (bool, error) {
+	if cfg.Format != FormatText && cfg.Format != FormatJSON {
+		return false, fmt.Errorf("unknown format %q, expected %s or %s", cfg.Format, FormatText, FormatJSON)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ghServer, err := newLocalServer(ctx, cfg.LocalServerConfig)
+	if err != nil {
+		return false, err
+	}
+	defer ghServer.close()
+	if cfg.LogFilePath == "" {
+		// Keep what the server finds out about the token from cluttering the output of the call
+		ghServer.logger.SetLevel(logrus.WarnLevel)
+	}
+
+	tool := ghServer.GetTool(cfg.Tool)
+	if tool == nil {
+		return false, fmt.Errorf("unknown tool %q, or not offered with the current configuration", cfg.Tool)
+	}
+	args, err := callArguments(tool.Tool, cfg.ArgsJSON, cfg.Args)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := callTool(ctx, ghServer.MCPServer, cfg.Version, cfg.Tool, args)
+	if err != nil {
+		return false, err
+	}
+	if err := printCallResult(cfg, result); err != nil {
+		return false, err
+	}
+	return result.IsError, ghServer.finishCassette()
+}
+
+// callTool initializes a session with s, as a client would, and makes a single tools/call request in it.
+func callTool(ctx context.Context, s *server.MCPServer, version, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	ctx = errors.ContextWithGitHubErrors(ctx)
+
+	initialize := mcp.InitializeRequest{}
+	initialize.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initialize.Params.ClientInfo = mcp.Implementation{Name: "github-mcp-server-call", Version: version}
+	if _, err := handleRequest(ctx, s, 1, mcp.MethodInitialize, initialize.Params); err != nil {
+		return nil, err
+	}
+
+	call := mcp.CallToolRequest{}
+	call.Params.Name = name
+	call.Params.Arguments = args
+	response, err := handleRequest(ctx, s, 2, mcp.MethodToolsCall, call.Params)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := response.Result.(mcp.CallToolResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result of %s: %T", mcp.MethodToolsCall, response.Result)
+	}
+	return &result, nil
+}
+
+// handleRequest sends a JSON-RPC request to s, returning its response, or the error it answered with.
+func handleRequest(ctx context.Context, s *server.MCPServer, id int, method mcp.MCPMethod, params any) (mcp.JSONRPCResponse, error) {
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return mcp.JSONRPCResponse{}, fmt.Errorf("failed to marshal %s request: %w", method, err)
+	}
+
+	switch response := s.HandleMessage(ctx, message).(type) {
+	case mcp.JSONRPCResponse:
+		return response, nil
+	case mcp.JSONRPCError:
+		return mcp.JSONRPCResponse{}, fmt.Errorf("%s failed: %s", method, response.Error.Message)
+	default:
+		return mcp.JSONRPCResponse{}, fmt.Errorf("unexpected response to %s: %T", method, response)
+	}
+}
+
+// callArguments returns the arguments of a call to tool, read from the JSON object argsJSON and then the key=value
+// pairs.
+func callArguments(tool mcp.Tool, argsJSON string, pairs []string) (map[string]any, error) {
+	args := map[string]any{}
+	if argsJSON != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return nil, fmt.Errorf("invalid --args-json, expected a JSON object: %w", err)
+		}
+		if args == nil {
+			return nil, fmt.Errorf("invalid --args-json, expected a JSON object, got null")
+		}
+	}
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected key=value", pair)
+		}
+		v, err := argumentValue(tool, key, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %s: %w", key, err)
+		}
+		args[key] = v
+	}
+	return args, nil
+}
+
+// argumentValue converts value to the type the input schema of tool gives the argument key. Values of arguments the
+// schema does not describe are taken as JSON if they parse as such, and as strings otherwise.
+func argumentValue(tool mcp.Tool, key, value string) (any, error) {
+	property, _ := tool.InputSchema.Properties[key].(map[string]any)
+	switch kind, _ := property["type"].(string); kind {
+	case "string":
+		return value, nil
+	case "number", "integer":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", value)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected a boolean, got %q", value)
+		}
+		return b, nil
+	case "array":
+		if strings.HasPrefix(strings.TrimSpace(value), "[") {
+			var items []any
+			if err := json.Unmarshal([]byte(value), &items); err != nil {
+				return nil, fmt.Errorf("expected a JSON array or comma separated values: %w", err)
+			}
+			return items, nil
+		}
+		items := []any{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case "object":
+		var object map[string]any
+		if err := json.Unmarshal([]byte(value), &object); err != nil {
+			return nil, fmt.Errorf("expected a JSON object: %w", err)
+		}
+		return object, nil
+	default:
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return value, nil
+		}
+		return v, nil
+	}
+}
+
+// printCallResult prints result as configured: as JSON, or its content as text, to ErrOut if it reports an error.
+func printCallResult(cfg CallConfig, result *mcp.CallToolResult) error {
+	if cfg.Format == FormatJSON {
+		return writeIndentedJSON(cfg.Out, result)
+	}
+
+	out := cfg.Out
+	if result.IsError {
+		out = cfg.ErrOut
+	}
+	for _, content := range result.Content {
+		if _, err := fmt.Fprintln(out, contentText(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentText returns the text of content, or a description of it if it is binary.
+func contentText(content mcp.Content) string {
+	switch c := content.(type) {
+	case mcp.TextContent:
+		return c.Text
+	case mcp.ImageContent:
+		return fmt.Sprintf("[image %s, %d bytes]", c.MIMEType, base64.StdEncoding.DecodedLen(len(c.Data)))
+	case mcp.AudioContent:
+		return fmt.Sprintf("[audio %s, %d bytes]", c.MIMEType, base64.StdEncoding.DecodedLen(len(c.Data)))
+	case mcp.ResourceLink:
+		return fmt.Sprintf("[resource %s]", c.URI)
+	case mcp.EmbeddedResource:
+		switch r := c.Resource.(type) {
+		case mcp.TextResourceContents:
+			return r.Text
+		case mcp.BlobResourceContents:
+			return fmt.Sprintf("[resource %s, %s, %d bytes]", r.URI, r.MIMEType, base64.StdEncoding.DecodedLen(len(r.Blob)))
+		}
+	}
+	data, _ := json.Marshal(content)
+	return string(data)
+}
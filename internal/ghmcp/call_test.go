@@ -0,0 +1,156 @@
+package ghmcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/github/github-mcp-server/internal/fakegithub"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_callArguments(t *testing.T) {
+	tool := mcp.NewTool("search",
+		mcp.WithString("query"),
+		mcp.WithNumber("page"),
+		mcp.WithBoolean("draft"),
+		mcp.WithArray("labels"),
+		mcp.WithObject("filter"),
+	)
+
+	args, err := callArguments(tool, `{"query": "bug", "page": 1, "extra": true}`, []string{
+		"page=2",
+		"draft=true",
+		"labels=bug, help wanted",
+		`filter={"state": "open"}`,
+		"unknown=[1, 2]",
+		"other=plain text",
+		"query=a=b",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"query":   "a=b",
+		"page":    float64(2),
+		"draft":   true,
+		"labels":  []any{"bug", "help wanted"},
+		"filter":  map[string]any{"state": "open"},
+		"extra":   true,
+		"unknown": []any{float64(1), float64(2)},
+		"other":   "plain text",
+	}, args)
+
+	args, err = callArguments(tool, "", []string{`labels=["a,b"]`, "query=42"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"labels": []any{"a,b"}, "query": "42"}, args)
+
+	tests := []struct {
+		name     string
+		argsJSON string
+		pair     string
+		wantErr  string
+	}{
+		{name: "args JSON not an object", argsJSON: `["a"]`, wantErr: "invalid --args-json"},
+		{name: "args JSON null", argsJSON: "null", pair: "page=2", wantErr: "invalid --args-json, expected a JSON object, got null"},
+		{name: "missing value", pair: "query", wantErr: `invalid argument "query", expected key=value`},
+		{name: "missing key", pair: "=1", wantErr: `invalid argument "=1", expected key=value`},
+		{name: "not a number", pair: "page=two", wantErr: `invalid argument page: expected a number, got "two"`},
+		{name: "not a boolean", pair: "draft=maybe", wantErr: `invalid argument draft: expected a boolean, got "maybe"`},
+		{name: "not an object", pair: "filter=open", wantErr: "invalid argument filter: expected a JSON object"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var pairs []string
+			if tc.pair != "" {
+				pairs = []string{tc.pair}
+			}
+			_, err := callArguments(tool, tc.argsJSON, pairs)
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func Test_contentText(t *testing.T) {
+	assert.Equal(t, "hello", contentText(mcp.NewTextContent("hello")))
+	assert.Equal(t, "[image image/png, 3 bytes]", contentText(mcp.NewImageContent("YWJj", "image/png")))
+	assert.Equal(t, "[resource repo://a/b]", contentText(mcp.NewResourceLink("repo://a/b", "b", "", "")))
+	assert.Equal(t, "file contents", contentText(mcp.NewEmbeddedResource(mcp.TextResourceContents{
+		URI:  "repo://a/b/contents/README.md",
+		Text: "file contents",
+	})))
+	assert.Equal(t, "[resource repo://a/b/contents/logo.png, image/png, 3 bytes]", contentText(mcp.NewEmbeddedResource(mcp.BlobResourceContents{
+		URI:      "repo://a/b/contents/logo.png",
+		MIMEType: "image/png",
+		Blob:     "YWJj",
+	})))
+}
+
+func Test_RunCall(t *testing.T) {
+	fake := fakegithub.New(fakegithub.Options{Token: "test-token"})
+	require.NoError(t, fake.CreateRepository(fakegithub.DefaultLogin, "hello-world", map[string]string{"README.md": "# Hello"}))
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	cfg := func(tool, format string, args ...string) (CallConfig, *bytes.Buffer, *bytes.Buffer) {
+		var out, errOut bytes.Buffer
+		return CallConfig{
+			StdioServerConfig: StdioServerConfig{LocalServerConfig: LocalServerConfig{ServerOptions: ServerOptions{
+				Version:         "test",
+				Host:            ts.URL,
+				Token:           "test-token",
+				EnabledToolsets: []string{"context", "repos"},
+			}}},
+			Tool:   tool,
+			Args:   args,
+			Format: format,
+			Out:    &out,
+			ErrOut: &errOut,
+		}, &out, &errOut
+	}
+
+	t.Run("prints the content of the result", func(t *testing.T) {
+		c, out, errOut := cfg("get_me", FormatText)
+		isError, err := RunCall(c)
+		require.NoError(t, err)
+		assert.False(t, isError)
+		assert.Contains(t, out.String(), `"login":"octocat"`)
+		assert.Empty(t, errOut.String())
+	})
+
+	t.Run("prints the result as JSON", func(t *testing.T) {
+		c, out, _ := cfg("get_file_contents", FormatJSON, "owner=octocat", "repo=hello-world", "path=README.md")
+		isError, err := RunCall(c)
+		require.NoError(t, err)
+		assert.False(t, isError)
+
+		var result struct {
+			Content []map[string]any `json:"content"`
+		}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+		require.NotEmpty(t, result.Content)
+		assert.Equal(t, "text", result.Content[0]["type"])
+	})
+
+	t.Run("prints error results to ErrOut", func(t *testing.T) {
+		c, out, errOut := cfg("get_file_contents", FormatText, "owner=octocat", "repo=missing", "path=README.md")
+		isError, err := RunCall(c)
+		require.NoError(t, err)
+		assert.True(t, isError)
+		assert.Empty(t, out.String())
+		assert.NotEmpty(t, errOut.String())
+	})
+
+	t.Run("rejects tools not offered", func(t *testing.T) {
+		c, _, _ := cfg("create_issue", FormatText)
+		_, err := RunCall(c)
+		assert.ErrorContains(t, err, `unknown tool "create_issue"`)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		c, _, _ := cfg("get_me", "yaml")
+		_, err := RunCall(c)
+		assert.ErrorContains(t, err, `unknown format "yaml"`)
+	})
+}
@@ -0,0 +1,41 @@
+package ghmcp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/cassette"
+	"github.com/sirupsen/logrus"
+)
+
+// cassetteTransport returns the transport reaching GitHub for a session recorded into the record directory, or
+// replayed from the replay directory, in place of transport. The function returned reports the requests that could
+// not be replayed, once the session is over.
+func cassetteTransport(transport http.RoundTripper, record, replay string, logger *logrus.Logger) (http.RoundTripper, func() error, error) {
+	switch {
+	case record != "" && replay != "":
+		return nil, nil, fmt.Errorf("a session cannot be recorded and replayed at once")
+	case replay != "":
+		replayer, err := cassette.NewReplayer(replay)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Infof("replaying GitHub responses from %s", replay)
+		return replayer, func() error {
+			if unmatched := replayer.Unmatched(); len(unmatched) > 0 {
+				return fmt.Errorf("%d requests had no response recorded in %s: %s", len(unmatched), replay, strings.Join(unmatched, ", "))
+			}
+			return nil
+		}, nil
+	case record != "":
+		recorder, err := cassette.NewRecorder(transport, record)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Infof("recording GitHub requests and responses into %s", record)
+		return recorder, func() error { return nil }, nil
+	default:
+		return transport, func() error { return nil }, nil
+	}
+}
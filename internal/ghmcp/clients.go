@@ -0,0 +1,312 @@
+package ghmcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/hosts"
+	"github.com/github/github-mcp-server/pkg/raw"
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/shurcooL/githubv4"
+)
+
+// defaultClientIdleTimeout is how long clients built for a session are kept around without being used.
+const defaultClientIdleTimeout = 30 * time.Minute
+
+// ErrNoToken is returned when a client is requested but neither the request nor the server provides a token.
+var ErrNoToken = errors.New("no GitHub token provided: authenticate with an Authorization: Bearer <token> header")
+
+type tokenCtxKey struct{}
+
+type hostTokensCtxKey struct{}
+
+// ContextWithToken returns a context carrying the GitHub token to use for requests made on its behalf to the
+// default host, taking precedence over the token the server was configured with.
+func ContextWithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenCtxKey{}, token)
+}
+
+// TokenFromContext returns the GitHub token carried by the context, if any.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenCtxKey{}).(string)
+	return token, ok && token != ""
+}
+
+// ContextWithHostTokens returns a context carrying GitHub tokens, by host name, to use for requests made on its
+// behalf to hosts other than the default one.
+func ContextWithHostTokens(ctx context.Context, tokens map[string]string) context.Context {
+	return context.WithValue(ctx, hostTokensCtxKey{}, tokens)
+}
+
+// HostTokenFromContext returns the GitHub token carried by the context for the named host, if any.
+func HostTokenFromContext(ctx context.Context, host string) (string, bool) {
+	tokens, _ := ctx.Value(hostTokensCtxKey{}).(map[string]string)
+	token, ok := tokens[host]
+	return token, ok && token != ""
+}
+
+// sessionClients holds the clients built for one session and token.
+type sessionClients struct {
+	rest     *gogithub.Client
+	gql      *githubv4.Client
+	raw      *raw.Client
+	lastUsed time.Time
+}
+
+// clientFactory builds GitHub clients on demand, keyed by the MCP session and the token in use, so that
+// sessions authenticated with different tokens never share a client.
+type clientFactory struct {
+	version     string
+	host        apiHost
+	credentials auth.TokenSource
+	transport   http.RoundTripper
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	// hostName names the host the clients are built for, empty for the default host. Clients of other hosts use
+	// the token the request context carries for the host, or else credentials, but never those of a request that
+	// carries its own token for the default host only, so that the server's credentials are not lent to it.
+	hostName string
+
+	mu         sync.Mutex
+	clients    map[string]*sessionClients
+	userAgents map[string]string
+}
+
+// newClientFactory creates a clientFactory building clients for host, which send requests with transport. Clients
+// authenticate with the token carried by the request context if there is one, falling back to credentials, which
+// may be nil.
+func newClientFactory(version string, host apiHost, credentials auth.TokenSource, transport http.RoundTripper) *clientFactory {
+	return &clientFactory{
+		version:     version,
+		host:        host,
+		credentials: credentials,
+		transport:   transport,
+		idleTimeout: defaultClientIdleTimeout,
+		now:         time.Now,
+		clients:     make(map[string]*sessionClients),
+		userAgents:  make(map[string]string),
+	}
+}
+
+// sessionIDFromContext returns the ID of the MCP session the request belongs to, or an empty string.
+func sessionIDFromContext(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// serverCredentialsKey identifies the clients using the server's own credentials within a session.
+const serverCredentialsKey = "server"
+
+// clientKey identifies a set of clients by session and credentials. Request tokens are identified by a digest, so
+// the token itself is not retained as a map key.
+func clientKey(sessionID, credentials string) string {
+	return sessionID + "/" + credentials
+}
+
+func tokenDigest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetUserAgent records the user agent to use for requests made by a session, and drops any clients already
+// built for it so that they pick it up.
+func (f *clientFactory) SetUserAgent(sessionID, userAgent string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.userAgents[sessionID] = userAgent
+	f.forgetLocked(sessionID)
+}
+
+// Forget drops all clients and state held for a session.
+func (f *clientFactory) Forget(sessionID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.userAgents, sessionID)
+	f.forgetLocked(sessionID)
+}
+
+func (f *clientFactory) forgetLocked(sessionID string) {
+	prefix := sessionID + "/"
+	for key := range f.clients {
+		if strings.HasPrefix(key, prefix) {
+			delete(f.clients, key)
+		}
+	}
+}
+
+func (f *clientFactory) get(ctx context.Context) (*sessionClients, error) {
+	sessionID := sessionIDFromContext(ctx)
+
+	token, ok := TokenFromContext(ctx)
+	if f.hostName != "" {
+		hostToken, hostOK := HostTokenFromContext(ctx, f.hostName)
+		if !hostOK && ok {
+			return nil, fmt.Errorf("no GitHub token provided for host %s: send one in an %s: %s=<token> header", f.hostName, HostTokenHeader, f.hostName)
+		}
+		token, ok = hostToken, hostOK
+	}
+
+	var key string
+	var credentials auth.TokenSource
+	if ok {
+		key = clientKey(sessionID, tokenDigest(token))
+		credentials = auth.StaticTokenSource(token)
+	} else if f.credentials != nil {
+		key = clientKey(sessionID, serverCredentialsKey)
+		credentials = f.credentials
+	} else {
+		return nil, ErrNoToken
+	}
+	now := f.now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c, ok := f.clients[key]; ok {
+		c.lastUsed = now
+		return c, nil
+	}
+
+	// Evict clients that have not been used for a while, as not every transport tells us when a
+	// session ends.
+	for k, c := range f.clients {
+		if now.Sub(c.lastUsed) > f.idleTimeout {
+			delete(f.clients, k)
+		}
+	}
+
+	userAgent, ok := f.userAgents[sessionID]
+	if !ok {
+		userAgent = fmt.Sprintf("github-mcp-server/%s", f.version)
+	}
+
+	c := f.newSessionClients(credentials, userAgent)
+	c.lastUsed = now
+	f.clients[key] = c
+	return c, nil
+}
+
+func (f *clientFactory) newSessionClients(credentials auth.TokenSource, userAgent string) *sessionClients {
+	httpClient := &http.Client{
+		Transport: &userAgentTransport{
+			transport: &bearerAuthTransport{
+				transport: f.transport,
+				token:     credentials,
+				hosts:     f.host.hosts(),
+			},
+			agent: userAgent,
+		},
+	}
+
+	// Construct our REST client
+	restClient := gogithub.NewClient(httpClient)
+	restClient.UserAgent = userAgent
+	restClient.BaseURL = f.host.baseRESTURL
+	restClient.UploadURL = f.host.uploadURL
+
+	// Construct our GraphQL client
+	// We're using NewEnterpriseClient here unconditionally as opposed to NewClient because we already
+	// did the necessary API host parsing so that github.com will return the correct URL anyway.
+	gqlClient := githubv4.NewEnterpriseClient(f.host.graphqlURL.String(), httpClient)
+
+	return &sessionClients{
+		rest: restClient,
+		gql:  gqlClient,
+		raw:  raw.NewClient(restClient, f.host.rawURL),
+	}
+}
+
+// GetClient implements github.GetClientFn.
+func (f *clientFactory) GetClient(ctx context.Context) (*gogithub.Client, error) {
+	c, err := f.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.rest, nil
+}
+
+// GetGQLClient implements github.GetGQLClientFn.
+func (f *clientFactory) GetGQLClient(ctx context.Context) (*githubv4.Client, error) {
+	c, err := f.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.gql, nil
+}
+
+// GetRawClient implements raw.GetRawClientFn.
+func (f *clientFactory) GetRawClient(ctx context.Context) (*raw.Client, error) {
+	c, err := f.get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub client: %w", err)
+	}
+	return c.raw, nil
+}
+
+// hostClients holds a clientFactory for each host, by name, building the clients of the host each call was routed
+// to (see hosts.Router).
+type hostClients map[string]*clientFactory
+
+func (c hostClients) factory(ctx context.Context) (*clientFactory, error) {
+	name := hosts.FromContext(ctx)
+	f, ok := c[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown host %q", name)
+	}
+	return f, nil
+}
+
+// SetUserAgent records the user agent to use for requests made by a session, to every host.
+func (c hostClients) SetUserAgent(sessionID, userAgent string) {
+	for _, f := range c {
+		f.SetUserAgent(sessionID, userAgent)
+	}
+}
+
+// Forget drops all clients and state held for a session.
+func (c hostClients) Forget(sessionID string) {
+	for _, f := range c {
+		f.Forget(sessionID)
+	}
+}
+
+// GetClient implements github.GetClientFn.
+func (c hostClients) GetClient(ctx context.Context) (*gogithub.Client, error) {
+	f, err := c.factory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return f.GetClient(ctx)
+}
+
+// GetGQLClient implements github.GetGQLClientFn.
+func (c hostClients) GetGQLClient(ctx context.Context) (*githubv4.Client, error) {
+	f, err := c.factory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return f.GetGQLClient(ctx)
+}
+
+// GetRawClient implements raw.GetRawClientFn.
+func (c hostClients) GetRawClient(ctx context.Context) (*raw.Client, error) {
+	f, err := c.factory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub client: %w", err)
+	}
+	return f.GetRawClient(ctx)
+}
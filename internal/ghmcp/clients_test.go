@@ -0,0 +1,123 @@
+package ghmcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSession struct {
+	id string
+}
+
+func (s fakeSession) SessionID() string                                   { return s.id }
+func (s fakeSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (s fakeSession) Initialize()                                         {}
+func (s fakeSession) Initialized() bool                                   { return true }
+
+func contextWithSession(id string) context.Context {
+	return server.NewMCPServer("test", "0.0.0").WithContext(context.Background(), fakeSession{id: id})
+}
+
+func testAPIHost(t *testing.T, rawURL string) apiHost {
+	u, err := url.Parse(rawURL + "/")
+	require.NoError(t, err)
+	return apiHost{
+		baseRESTURL: u,
+		graphqlURL:  u.JoinPath("graphql"),
+		uploadURL:   u,
+		rawURL:      u.JoinPath("raw"),
+	}
+}
+
+func Test_ClientFactory(t *testing.T) {
+	host := testAPIHost(t, "https://api.example.com")
+
+	t.Run("requires a token", func(t *testing.T) {
+		f := newClientFactory("1.0.0", host, nil, http.DefaultTransport)
+		_, err := f.GetClient(contextWithSession("a"))
+		require.ErrorIs(t, err, ErrNoToken)
+	})
+
+	t.Run("reuses clients within a session", func(t *testing.T) {
+		f := newClientFactory("1.0.0", host, auth.StaticTokenSource("server-token"), http.DefaultTransport)
+		ctx := contextWithSession("a")
+
+		first, err := f.GetClient(ctx)
+		require.NoError(t, err)
+		second, err := f.GetClient(ctx)
+		require.NoError(t, err)
+		assert.Same(t, first, second)
+	})
+
+	t.Run("never shares clients across sessions or tokens", func(t *testing.T) {
+		f := newClientFactory("1.0.0", host, auth.StaticTokenSource("server-token"), http.DefaultTransport)
+
+		serverA, err := f.GetClient(contextWithSession("a"))
+		require.NoError(t, err)
+		serverB, err := f.GetClient(contextWithSession("b"))
+		require.NoError(t, err)
+		userA, err := f.GetClient(ContextWithToken(contextWithSession("a"), "user-token"))
+		require.NoError(t, err)
+
+		assert.NotSame(t, serverA, serverB)
+		assert.NotSame(t, serverA, userA)
+	})
+
+	t.Run("forgets clients when the session ends", func(t *testing.T) {
+		f := newClientFactory("1.0.0", host, auth.StaticTokenSource("server-token"), http.DefaultTransport)
+		ctx := contextWithSession("a")
+
+		first, err := f.GetGQLClient(ctx)
+		require.NoError(t, err)
+		f.Forget("a")
+		second, err := f.GetGQLClient(ctx)
+		require.NoError(t, err)
+		assert.NotSame(t, first, second)
+	})
+
+	t.Run("evicts idle clients", func(t *testing.T) {
+		f := newClientFactory("1.0.0", host, auth.StaticTokenSource("server-token"), http.DefaultTransport)
+		now := time.Now()
+		f.now = func() time.Time { return now }
+
+		_, err := f.GetClient(contextWithSession("a"))
+		require.NoError(t, err)
+
+		now = now.Add(2 * defaultClientIdleTimeout)
+		_, err = f.GetClient(contextWithSession("b"))
+		require.NoError(t, err)
+
+		assert.Len(t, f.clients, 1)
+	})
+}
+
+func Test_ClientFactory_SendsTokenAndUserAgent(t *testing.T) {
+	var gotAuth, gotUserAgent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotUserAgent = r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	defer ts.Close()
+
+	f := newClientFactory("1.0.0", testAPIHost(t, ts.URL), auth.StaticTokenSource("server-token"), http.DefaultTransport)
+	f.SetUserAgent("a", "github-mcp-server/1.0.0 (test-client/2.0.0)")
+
+	client, err := f.GetClient(ContextWithToken(contextWithSession("a"), "user-token"))
+	require.NoError(t, err)
+	_, _, err = client.Users.Get(context.Background(), "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer user-token", gotAuth)
+	assert.Equal(t, "github-mcp-server/1.0.0 (test-client/2.0.0)", gotUserAgent)
+}
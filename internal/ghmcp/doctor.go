@@ -0,0 +1,600 @@
+package ghmcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/network"
+	"github.com/github/github-mcp-server/pkg/scopes"
+)
+
+const (
+	// doctorRequestTimeout bounds each request the doctor command sends.
+	doctorRequestTimeout = 15 * time.Second
+
+	// tokenExpiryWarning is how long before its expiry a token is flagged as about to expire.
+	tokenExpiryWarning = 7 * 24 * time.Hour
+
+	// rateLimitWarning is the share of a rate limit left below which it is flagged as running low.
+	rateLimitWarning = 0.1
+
+	// tokenExpirationHeader is the response header GitHub reports the expiry of the token a request authenticated
+	// with in, for tokens that expire.
+	tokenExpirationHeader = "GitHub-Authentication-Token-Expiration"
+
+	// ssoHeader is the response header GitHub reports organizations requiring SAML single sign-on the token is not
+	// authorized for in.
+	ssoHeader = "X-GitHub-SSO"
+)
+
+// Statuses of the checks the doctor command makes.
+const (
+	CheckOK      = "ok"
+	CheckWarning = "warning"
+	CheckError   = "error"
+	CheckSkipped = "skipped"
+)
+
+// DoctorConfig configures the doctor command, which checks a server configured alike can reach GitHub and use the
+// enabled tools.
+type DoctorConfig struct {
+	// Version of the server
+	Version string
+
+	// Host is the GitHub instance to check, github.com if empty
+	Host string
+
+	// Endpoints overrides URLs otherwise derived from Host
+	Endpoints Endpoints
+
+	// Network configures how GitHub is connected to
+	Network network.Config
+
+	// Token is the token the server authenticates with, if any
+	Token string
+
+	// GitHubApp configures authentication as a GitHub App installation, used instead of Token when set
+	GitHubApp *auth.AppConfig
+
+	// EnabledToolsets is a list of toolsets to enable
+	EnabledToolsets []string
+
+	// Tools restricts the tools offered within the enabled toolsets to those listed, if not empty
+	Tools []string
+
+	// ExcludeTools lists tools that are not offered even if their toolset is enabled
+	ExcludeTools []string
+
+	// ReadOnly indicates if only read-only tools are offered
+	ReadOnly bool
+
+	// Format is FormatText, printing a report for people to read, or FormatJSON
+	Format string
+
+	// Out is where the report is printed
+	Out io.Writer
+}
+
+// doctorReport is what the doctor command found, as printed with FormatJSON.
+type doctorReport struct {
+	Host      string          `json:"host"`
+	Endpoints doctorEndpoints `json:"endpoints"`
+	Checks    []doctorCheck   `json:"checks"`
+}
+
+type doctorEndpoints struct {
+	REST    string `json:"rest"`
+	GraphQL string `json:"graphql"`
+	Upload  string `json:"upload"`
+	Raw     string `json:"raw"`
+}
+
+// doctorCheck is the outcome of a check, along with what to do about it if it did not pass.
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// doctor holds what the checks find out as they go, later checks depending on earlier ones.
+type doctor struct {
+	cfg    DoctorConfig
+	host   apiHost
+	tools  []toolEntry
+	client *http.Client
+	now    func() time.Time
+	report doctorReport
+
+	// token is the token requests authenticate with, empty if there is none or it could not be obtained
+	token string
+
+	// user is the response to the request for the authenticated user, nil unless it succeeded
+	user *http.Response
+
+	// rateLimits is the body of the response to the request for the rate limits, nil unless it succeeded, and
+	// rateLimitsStatus the status it was answered with, 0 if it was not
+	rateLimits       []byte
+	rateLimitsStatus int
+}
+
+// RunDoctor checks that GitHub can be reached and the token used as cfg configures, and that the token can use the
+// enabled tools, printing a report with what to do about any problem found. It reports whether every check passed,
+// warnings aside, returning an error only when the checks could not be made.
+func RunDoctor(cfg DoctorConfig) (bool, error) {
+	if cfg.Format != FormatText && cfg.Format != FormatJSON {
+		return false, fmt.Errorf("unknown format %q, expected %s or %s", cfg.Format, FormatText, FormatJSON)
+	}
+	// The format is that of listings, which the selection does not depend on
+	tools, err := selectTools(ToolsConfig{
+		EnabledToolsets: cfg.EnabledToolsets,
+		Tools:           cfg.Tools,
+		ExcludeTools:    cfg.ExcludeTools,
+		ReadOnly:        cfg.ReadOnly,
+		Format:          FormatTable,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	host, err := newAPIHost(cfg.Host, cfg.Endpoints)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse API host: %w", err)
+	}
+	transport, err := network.NewTransport(cfg.Network)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := &doctor{cfg: cfg, host: host, tools: tools, now: time.Now}
+	d.report.Host = cfg.Host
+	if d.report.Host == "" {
+		d.report.Host = "https://github.com"
+	}
+	d.report.Endpoints = doctorEndpoints{
+		REST:    host.baseRESTURL.String(),
+		GraphQL: host.graphqlURL.String(),
+		Upload:  host.uploadURL.String(),
+		Raw:     host.rawURL.String(),
+	}
+
+	d.checkCredentials(ctx, transport)
+	d.checkREST(ctx)
+	d.checkGraphQL(ctx)
+	d.checkReachable(ctx, "Raw content", host.rawURL.String(), "--raw-url")
+	d.checkReachable(ctx, "Uploads", host.uploadURL.String(), "--upload-url")
+	d.checkToken(ctx)
+	d.checkTokenExpiry()
+	d.checkSSO(ctx)
+	d.checkRateLimits()
+	d.checkScopes()
+
+	if err := d.print(); err != nil {
+		return false, err
+	}
+	for _, check := range d.report.Checks {
+		if check.Status == CheckError {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (d *doctor) add(name, status, message, fix string) {
+	d.report.Checks = append(d.report.Checks, doctorCheck{Name: name, Status: status, Message: message, Fix: fix})
+}
+
+// checkCredentials obtains the token requests authenticate with, minting an installation token for a GitHub App.
+// The client requests are sent with is set up whether or not there is one, so that GitHub can be reached all the
+// same.
+func (d *doctor) checkCredentials(ctx context.Context, transport http.RoundTripper) {
+	switch {
+	case d.cfg.GitHubApp != nil:
+		source, err := auth.NewAppTokenSource(*d.cfg.GitHubApp, d.host.baseRESTURL, transport)
+		if err == nil {
+			reqCtx, cancel := context.WithTimeout(ctx, doctorRequestTimeout)
+			d.token, err = source.Token(reqCtx)
+			cancel()
+		}
+		if err != nil {
+			d.add("Credentials", CheckError, fmt.Sprintf("failed to authenticate as the GitHub App: %v", err),
+				"Check the app ID, private key and installation given with --app-id, --app-private-key-file and --app-installation-id or --app-installation-owner.")
+		} else {
+			d.add("Credentials", CheckOK, fmt.Sprintf("authenticated as an installation of GitHub App %d", d.cfg.GitHubApp.AppID), "")
+		}
+	case d.cfg.Token != "":
+		d.token = d.cfg.Token
+		d.add("Credentials", CheckOK, fmt.Sprintf("using a %s", tokenKind(d.token)), "")
+	default:
+		d.add("Credentials", CheckError, "no token is configured",
+			"Set GITHUB_PERSONAL_ACCESS_TOKEN, or run `github-mcp-server login`.")
+	}
+
+	transport = &userAgentTransport{transport: transport, agent: fmt.Sprintf("github-mcp-server/%s", d.cfg.Version)}
+	if d.token != "" {
+		transport = &bearerAuthTransport{transport: transport, token: auth.StaticTokenSource(d.token), hosts: d.host.hosts()}
+	}
+	d.client = &http.Client{Transport: transport}
+}
+
+// tokenKind describes the kind of token from its prefix.
+func tokenKind(token string) string {
+	switch {
+	case strings.HasPrefix(token, "ghp_"):
+		return "classic personal access token"
+	case strings.HasPrefix(token, "github_pat_"):
+		return "fine-grained personal access token"
+	case strings.HasPrefix(token, "gho_"):
+		return "OAuth token"
+	case strings.HasPrefix(token, "ghu_"):
+		return "GitHub App user access token"
+	case strings.HasPrefix(token, "ghs_"):
+		return "GitHub App installation token"
+	default:
+		return "token"
+	}
+}
+
+// send sends a request, returning the response with its body read.
+func (d *doctor) send(ctx context.Context, method, url string, body []byte) (*http.Response, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, doctorRequestTimeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, data, nil
+}
+
+// unreachable records that the endpoint named name, overridden by flag, could not be reached.
+func (d *doctor) unreachable(name, url, flag string, err error) {
+	// The URL is already in the message
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	d.add(name, CheckError, fmt.Sprintf("cannot reach %s: %v", url, err),
+		fmt.Sprintf("Check --gh-host, or %s if the endpoint is elsewhere, and the proxy and certificate settings.", flag))
+}
+
+// checkREST checks the REST API can be reached, asking for the rate limits, which are reported later.
+func (d *doctor) checkREST(ctx context.Context) {
+	url := d.host.baseRESTURL.String() + "rate_limit"
+	resp, body, err := d.send(ctx, http.MethodGet, url, nil)
+	switch {
+	case err != nil:
+		d.unreachable("REST API", d.host.baseRESTURL.String(), "--api-url", err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		d.add("REST API", CheckError, fmt.Sprintf("%s answered with %s", d.host.baseRESTURL, resp.Status),
+			"Check the status of the GitHub instance, and try again later.")
+	default:
+		d.rateLimitsStatus = resp.StatusCode
+		if resp.StatusCode == http.StatusOK {
+			d.rateLimits = body
+		}
+		d.add("REST API", CheckOK, fmt.Sprintf("reachable at %s", d.host.baseRESTURL), "")
+	}
+}
+
+// checkGraphQL checks the GraphQL API can be reached and answers queries.
+func (d *doctor) checkGraphQL(ctx context.Context) {
+	url := d.host.graphqlURL.String()
+	resp, body, err := d.send(ctx, http.MethodPost, url, []byte(`{"query":"query { rateLimit { remaining } }"}`))
+	switch {
+	case err != nil:
+		d.unreachable("GraphQL API", url, "--graphql-url", err)
+		return
+	case resp.StatusCode >= http.StatusInternalServerError:
+		d.add("GraphQL API", CheckError, fmt.Sprintf("%s answered with %s", url, resp.Status),
+			"Check the status of the GitHub instance, and try again later.")
+		return
+	case resp.StatusCode != http.StatusOK:
+		// Left for the token check to report when it is the token that was refused
+		d.add("GraphQL API", CheckOK, fmt.Sprintf("reachable at %s", url), "")
+		return
+	}
+
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		d.add("GraphQL API", CheckError, fmt.Sprintf("%s did not answer with JSON, and may not be a GitHub GraphQL API", url),
+			"Check --gh-host, or --graphql-url if the endpoint is elsewhere.")
+		return
+	}
+	if len(result.Errors) > 0 {
+		d.add("GraphQL API", CheckWarning, fmt.Sprintf("%s answered a query with an error: %s", url, result.Errors[0].Message),
+			"Tools using the GraphQL API, such as those for discussions and projects, may fail.")
+		return
+	}
+	d.add("GraphQL API", CheckOK, fmt.Sprintf("reachable at %s", url), "")
+}
+
+// checkReachable checks the endpoint named name, at url and overridden by flag, answers requests.
+func (d *doctor) checkReachable(ctx context.Context, name, url, flag string) {
+	resp, _, err := d.send(ctx, http.MethodGet, url, nil)
+	switch {
+	case err != nil:
+		d.unreachable(name, url, flag, err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		d.add(name, CheckWarning, fmt.Sprintf("%s answered with %s", url, resp.Status),
+			"Check the status of the GitHub instance, and try again later.")
+	default:
+		d.add(name, CheckOK, fmt.Sprintf("reachable at %s", url), "")
+	}
+}
+
+// checkToken checks GitHub accepts the token, looking up the user it belongs to. Installation tokens belong to no
+// user, and were checked as they were obtained.
+func (d *doctor) checkToken(ctx context.Context) {
+	if d.token == "" || d.cfg.GitHubApp != nil {
+		return
+	}
+
+	resp, body, err := d.send(ctx, http.MethodGet, d.host.baseRESTURL.String()+"user", nil)
+	switch {
+	case err != nil:
+		d.add("Token", CheckSkipped, "GitHub could not be reached to check the token", "")
+	case resp.StatusCode == http.StatusUnauthorized:
+		d.add("Token", CheckError, fmt.Sprintf("GitHub refused the token: %s", apiMessage(resp, body)),
+			"The token is invalid, expired or revoked: create a new one and set GITHUB_PERSONAL_ACCESS_TOKEN to it, or run `github-mcp-server login`.")
+	case resp.StatusCode != http.StatusOK:
+		d.add("Token", CheckError, fmt.Sprintf("looking up the user the token belongs to failed: %s", apiMessage(resp, body)),
+			"Check the token is a personal access token or OAuth token of a user.")
+	default:
+		var user struct {
+			Login string `json:"login"`
+		}
+		_ = json.Unmarshal(body, &user)
+		d.user = resp
+		d.add("Token", CheckOK, fmt.Sprintf("authenticated as %s", user.Login), "")
+	}
+}
+
+// apiMessage returns the message of an error GitHub answered with, or the status if it has none.
+func apiMessage(resp *http.Response, body []byte) string {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		return fmt.Sprintf("%s (%s)", apiErr.Message, resp.Status)
+	}
+	return resp.Status
+}
+
+// checkTokenExpiry reports when the token expires, from the header GitHub sets for tokens that do.
+func (d *doctor) checkTokenExpiry() {
+	if d.user == nil {
+		return
+	}
+	value := d.user.Header.Get(tokenExpirationHeader)
+	if value == "" {
+		d.add("Token expiry", CheckOK, "the token does not expire", "")
+		return
+	}
+
+	var expires time.Time
+	var err error
+	for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+		if expires, err = time.Parse(layout, value); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		d.add("Token expiry", CheckWarning, fmt.Sprintf("GitHub reported an expiry the server does not understand: %s", value), "")
+		return
+	}
+
+	left := expires.Sub(d.now())
+	message := fmt.Sprintf("the token expires on %s", expires.UTC().Format("2006-01-02 15:04 MST"))
+	if left < tokenExpiryWarning {
+		d.add("Token expiry", CheckWarning, fmt.Sprintf("%s, in %s", message, left.Round(time.Minute)),
+			"Regenerate the token, or create a new one, before it expires.")
+		return
+	}
+	d.add("Token expiry", CheckOK, message, "")
+}
+
+// checkSSO checks whether organizations requiring SAML single sign-on have not authorized the token, which GitHub
+// reports when listing the organizations of the user.
+func (d *doctor) checkSSO(ctx context.Context) {
+	if d.user == nil {
+		return
+	}
+
+	resp, body, err := d.send(ctx, http.MethodGet, d.host.baseRESTURL.String()+"user/orgs?per_page=100", nil)
+	if err != nil {
+		d.add("Single sign-on", CheckSkipped, fmt.Sprintf("listing the organizations of the user failed: %v", err), "")
+		return
+	}
+
+	fix := fmt.Sprintf("Authorize the token for single sign-on with the organizations in the token settings at %ssettings/tokens, or sign in with single sign-on and run `github-mcp-server login` again.", d.host.webURL)
+	directive, params, _ := strings.Cut(resp.Header.Get(ssoHeader), ";")
+	params = strings.TrimSpace(params)
+	switch strings.TrimSpace(directive) {
+	case "required":
+		url := strings.TrimPrefix(params, "url=")
+		d.add("Single sign-on", CheckError, "an organization requires SAML single sign-on the token is not authorized for",
+			fmt.Sprintf("Authorize the token at %s.", url))
+	case "partial-results":
+		organizations := strings.Split(strings.TrimPrefix(params, "organizations="), ",")
+		d.add("Single sign-on", CheckWarning,
+			fmt.Sprintf("the token is not authorized for single sign-on with %d organizations, whose resources tools cannot access (organization IDs %s)",
+				len(organizations), strings.Join(organizations, ", ")),
+			fix)
+	default:
+		if resp.StatusCode != http.StatusOK {
+			d.add("Single sign-on", CheckSkipped, fmt.Sprintf("listing the organizations of the user failed: %s", apiMessage(resp, body)), "")
+			return
+		}
+		d.add("Single sign-on", CheckOK, "no organization requires single sign-on the token is not authorized for", "")
+	}
+}
+
+// rateLimit is a rate limit as the REST API reports it.
+type rateLimit struct {
+	Limit     int   `json:"limit"`
+	Remaining int   `json:"remaining"`
+	Reset     int64 `json:"reset"`
+}
+
+// checkRateLimits reports how much is left of the core REST and GraphQL rate limits.
+func (d *doctor) checkRateLimits() {
+	switch {
+	case d.rateLimitsStatus == http.StatusNotFound:
+		d.add("Rate limits", CheckOK, "rate limiting is not enabled on the GitHub instance", "")
+		return
+	case d.rateLimits == nil:
+		if d.rateLimitsStatus != 0 {
+			d.add("Rate limits", CheckSkipped, fmt.Sprintf("GitHub answered the request for the rate limits with HTTP %d", d.rateLimitsStatus), "")
+		}
+		return
+	}
+
+	var limits struct {
+		Resources map[string]rateLimit `json:"resources"`
+	}
+	if err := json.Unmarshal(d.rateLimits, &limits); err != nil {
+		d.add("Rate limits", CheckSkipped, fmt.Sprintf("GitHub answered the request for the rate limits with invalid JSON: %v", err), "")
+		return
+	}
+
+	status := CheckOK
+	var summaries []string
+	var fix string
+	for _, resource := range []string{"core", "graphql"} {
+		limit, ok := limits.Resources[resource]
+		if !ok || limit.Limit == 0 {
+			continue
+		}
+		reset := time.Unix(limit.Reset, 0).UTC().Format("15:04 MST")
+		summaries = append(summaries, fmt.Sprintf("%s %d of %d left, reset at %s", resource, limit.Remaining, limit.Limit, reset))
+		if float64(limit.Remaining) < rateLimitWarning*float64(limit.Limit) {
+			status = CheckWarning
+			fix = "Calls may be held back until the rate limits reset. Authenticate with a token if none is set, or wait for the reset."
+		}
+	}
+	if len(summaries) == 0 {
+		d.add("Rate limits", CheckSkipped, "GitHub did not report the core or GraphQL rate limits", "")
+		return
+	}
+	d.add("Rate limits", status, strings.Join(summaries, ", "), fix)
+}
+
+// checkScopes reports the scopes of the token, and checks they allow the tools of each enabled toolset. GitHub
+// reports the scopes of classic personal access tokens and OAuth tokens only, so other tokens are not checked.
+func (d *doctor) checkScopes() {
+	if d.user == nil {
+		if d.cfg.GitHubApp != nil && d.token != "" {
+			d.add("Token scopes", CheckSkipped, "the permissions of GitHub App installations cannot be looked up ahead of a call, and calls fail with 403 errors where the installation lacks one", "")
+		}
+		return
+	}
+	values, ok := d.user.Header[http.CanonicalHeaderKey(scopes.Header)]
+	if !ok {
+		d.add("Token scopes", CheckSkipped, "GitHub does not report the permissions of fine-grained personal access tokens, and calls fail with 403 errors where the token lacks one", "")
+		return
+	}
+	granted := scopes.Parse(strings.Join(values, ","))
+	if len(granted) == 0 {
+		d.add("Token scopes", CheckWarning, "the token has no scopes, and can only read public data", "Grant the token the scopes the enabled tools need, listed below.")
+	} else {
+		d.add("Token scopes", CheckOK, strings.Join(values, ", "), "")
+	}
+
+	var toolsets []string
+	counts := map[string]int{}
+	unavailable := map[string][]string{}
+	needed := map[string]map[string]bool{}
+	for _, entry := range d.tools {
+		if _, ok := counts[entry.toolset]; !ok {
+			toolsets = append(toolsets, entry.toolset)
+			needed[entry.toolset] = map[string]bool{}
+		}
+		counts[entry.toolset]++
+		if missing := granted.Missing(entry.tool); missing != nil {
+			unavailable[entry.toolset] = append(unavailable[entry.toolset], entry.tool.Name)
+			needed[entry.toolset][strings.Join(missing, " or ")] = true
+		}
+	}
+
+	for _, toolset := range toolsets {
+		name := fmt.Sprintf("Toolset %s", toolset)
+		names := unavailable[toolset]
+		if len(names) == 0 {
+			d.add(name, CheckOK, fmt.Sprintf("the token's scopes allow all %d tools", counts[toolset]), "")
+			continue
+		}
+		missing := make([]string, 0, len(needed[toolset]))
+		for scope := range needed[toolset] {
+			missing = append(missing, scope)
+		}
+		sort.Strings(missing)
+		d.add(name, CheckWarning,
+			fmt.Sprintf("the token's scopes do not allow %d of %d tools: %s", len(names), counts[toolset], strings.Join(names, ", ")),
+			fmt.Sprintf("Grant the token the %s scope, or leave the toolset out of --toolsets.", strings.Join(missing, ", ")))
+	}
+}
+
+// print prints the report as configured.
+func (d *doctor) print() error {
+	if d.cfg.Format == FormatJSON {
+		return writeIndentedJSON(d.cfg.Out, d.report)
+	}
+
+	out := d.cfg.Out
+	e := d.report.Endpoints
+	_, _ = fmt.Fprintf(out, "Host: %s\n", d.report.Host)
+	_, _ = fmt.Fprintf(out, "  REST API:    %s\n", e.REST)
+	_, _ = fmt.Fprintf(out, "  GraphQL API: %s\n", e.GraphQL)
+	_, _ = fmt.Fprintf(out, "  Uploads:     %s\n", e.Upload)
+	_, _ = fmt.Fprintf(out, "  Raw content: %s\n\n", e.Raw)
+
+	counts := map[string]int{}
+	for _, check := range d.report.Checks {
+		counts[check.Status]++
+		_, _ = fmt.Fprintf(out, "%-9s %s: %s\n", "["+check.Status+"]", check.Name, check.Message)
+		if check.Fix != "" {
+			_, _ = fmt.Fprintf(out, "          Fix: %s\n", check.Fix)
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "\nErrors: %d, warnings: %d\n", counts[CheckError], counts[CheckWarning])
+	return err
+}
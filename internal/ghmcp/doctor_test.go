@@ -0,0 +1,212 @@
+package ghmcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDoctorGitHub serves what the doctor command asks GitHub, laid out as GitHub Enterprise Server is, adding
+// userHeaders and orgsHeaders to the responses listing the user and their organizations.
+func fakeDoctorGitHub(t *testing.T, token string, userHeaders, orgsHeaders http.Header) *httptest.Server {
+	mux := http.NewServeMux()
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "Bad credentials"}`))
+			return false
+		}
+		return true
+	}
+	mux.HandleFunc("GET /api/v3/rate_limit", func(w http.ResponseWriter, _ *http.Request) {
+		reset := time.Now().Add(time.Hour).Unix()
+		_, _ = fmt.Fprintf(w, `{"resources": {"core": {"limit": 5000, "remaining": 100, "reset": %d}, "graphql": {"limit": 5000, "remaining": 4999, "reset": %d}}}`, reset, reset)
+	})
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			_, _ = w.Write([]byte(`{"data": {"rateLimit": {"remaining": 4999}}}`))
+		}
+	})
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			for key, values := range userHeaders {
+				w.Header()[key] = values
+			}
+			_, _ = w.Write([]byte(`{"login": "octocat"}`))
+		}
+	})
+	mux.HandleFunc("GET /api/v3/user/orgs", func(w http.ResponseWriter, r *http.Request) {
+		if authorized(w, r) {
+			for key, values := range orgsHeaders {
+				w.Header()[key] = values
+			}
+			_, _ = w.Write([]byte(`[]`))
+		}
+	})
+	mux.HandleFunc("GET /raw/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /api/uploads/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// runDoctor runs the doctor command with the JSON format, returning whether it passed and its checks by name.
+func runDoctor(t *testing.T, cfg DoctorConfig) (bool, map[string]doctorCheck) {
+	t.Helper()
+	var out bytes.Buffer
+	cfg.Version = "test"
+	cfg.Format = FormatJSON
+	cfg.Out = &out
+	ok, err := RunDoctor(cfg)
+	require.NoError(t, err)
+
+	var report doctorReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	checks := map[string]doctorCheck{}
+	for _, check := range report.Checks {
+		checks[check.Name] = check
+	}
+	return ok, checks
+}
+
+func Test_RunDoctor(t *testing.T) {
+	expires := time.Now().Add(48 * time.Hour).UTC().Format("2006-01-02 15:04:05 MST")
+	ts := fakeDoctorGitHub(t, "ghp_test",
+		http.Header{"X-Oauth-Scopes": {"read:org, notifications"}, "Github-Authentication-Token-Expiration": {expires}},
+		http.Header{"X-Github-Sso": {"partial-results; organizations=21955855,20582480"}})
+
+	ok, checks := runDoctor(t, DoctorConfig{
+		Host:            ts.URL,
+		Token:           "ghp_test",
+		EnabledToolsets: []string{"context", "issues"},
+	})
+	assert.True(t, ok, "warnings should not fail the checks")
+
+	assert.Equal(t, doctorCheck{Name: "Credentials", Status: CheckOK, Message: "using a classic personal access token"}, checks["Credentials"])
+	for _, name := range []string{"REST API", "GraphQL API", "Raw content", "Uploads"} {
+		assert.Equal(t, CheckOK, checks[name].Status, name)
+	}
+	assert.Equal(t, "authenticated as octocat", checks["Token"].Message)
+
+	assert.Equal(t, CheckWarning, checks["Token expiry"].Status)
+	assert.Contains(t, checks["Token expiry"].Message, "in 48h0m0s")
+
+	assert.Equal(t, CheckWarning, checks["Single sign-on"].Status)
+	assert.Contains(t, checks["Single sign-on"].Message, "2 organizations")
+	assert.Contains(t, checks["Single sign-on"].Fix, ts.URL+"/settings/tokens")
+
+	assert.Equal(t, CheckWarning, checks["Rate limits"].Status)
+	assert.Contains(t, checks["Rate limits"].Message, "core 100 of 5000 left")
+
+	assert.Equal(t, doctorCheck{Name: "Token scopes", Status: CheckOK, Message: "read:org, notifications"}, checks["Token scopes"])
+	assert.Equal(t, CheckOK, checks["Toolset context"].Status)
+	assert.Equal(t, CheckWarning, checks["Toolset issues"].Status)
+	assert.Contains(t, checks["Toolset issues"].Message, "create_issue")
+	assert.Equal(t, "Grant the token the public_repo scope, or leave the toolset out of --toolsets.", checks["Toolset issues"].Fix)
+}
+
+func Test_RunDoctor_ReadOnlyTokenWithoutScopes(t *testing.T) {
+	ts := fakeDoctorGitHub(t, "github_pat_test", nil, nil)
+
+	ok, checks := runDoctor(t, DoctorConfig{
+		Host:            ts.URL,
+		Token:           "github_pat_test",
+		EnabledToolsets: []string{"issues"},
+		ReadOnly:        true,
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "using a fine-grained personal access token", checks["Credentials"].Message)
+	assert.Equal(t, CheckOK, checks["Token expiry"].Status)
+	assert.Equal(t, CheckOK, checks["Single sign-on"].Status)
+	assert.Equal(t, CheckSkipped, checks["Token scopes"].Status)
+	assert.NotContains(t, checks, "Toolset issues", "toolsets should not be checked without scopes")
+}
+
+func Test_RunDoctor_Failures(t *testing.T) {
+	ts := fakeDoctorGitHub(t, "ghp_valid", nil, http.Header{"X-Github-Sso": {"required; url=https://github.com/orgs/acme/sso?authorization_request=abc"}})
+
+	t.Run("invalid token", func(t *testing.T) {
+		ok, checks := runDoctor(t, DoctorConfig{Host: ts.URL, Token: "ghp_revoked", EnabledToolsets: []string{"context"}})
+		assert.False(t, ok)
+		assert.Equal(t, CheckError, checks["Token"].Status)
+		assert.Equal(t, "GitHub refused the token: Bad credentials (401 Unauthorized)", checks["Token"].Message)
+		assert.Equal(t, CheckOK, checks["GraphQL API"].Status, "the refused token should be reported by the token check only")
+		assert.NotContains(t, checks, "Token expiry")
+		assert.NotContains(t, checks, "Single sign-on")
+	})
+
+	t.Run("no token", func(t *testing.T) {
+		ok, checks := runDoctor(t, DoctorConfig{Host: ts.URL, EnabledToolsets: []string{"context"}})
+		assert.False(t, ok)
+		assert.Equal(t, CheckError, checks["Credentials"].Status)
+		assert.Contains(t, checks["Credentials"].Fix, "GITHUB_PERSONAL_ACCESS_TOKEN")
+		assert.Equal(t, CheckOK, checks["REST API"].Status)
+	})
+
+	t.Run("single sign-on required", func(t *testing.T) {
+		ok, checks := runDoctor(t, DoctorConfig{Host: ts.URL, Token: "ghp_valid", EnabledToolsets: []string{"context"}})
+		assert.False(t, ok)
+		assert.Equal(t, CheckError, checks["Single sign-on"].Status)
+		assert.Equal(t, "Authorize the token at https://github.com/orgs/acme/sso?authorization_request=abc.", checks["Single sign-on"].Fix)
+	})
+
+	t.Run("unreachable endpoints", func(t *testing.T) {
+		ok, checks := runDoctor(t, DoctorConfig{
+			Host:            ts.URL,
+			Endpoints:       Endpoints{GraphQL: "http://127.0.0.1:1/graphql"},
+			Token:           "ghp_valid",
+			EnabledToolsets: []string{"context"},
+		})
+		assert.False(t, ok)
+		assert.Equal(t, CheckError, checks["GraphQL API"].Status)
+		assert.Contains(t, checks["GraphQL API"].Message, "cannot reach http://127.0.0.1:1/graphql")
+		assert.Contains(t, checks["GraphQL API"].Fix, "--graphql-url")
+	})
+}
+
+func Test_RunDoctor_Text(t *testing.T) {
+	ts := fakeDoctorGitHub(t, "ghp_test", http.Header{"X-Oauth-Scopes": {"repo"}}, nil)
+
+	var out bytes.Buffer
+	ok, err := RunDoctor(DoctorConfig{
+		Version:         "test",
+		Host:            ts.URL,
+		Token:           "ghp_test",
+		EnabledToolsets: []string{"context"},
+		Format:          FormatText,
+		Out:             &out,
+	})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "Host: "+ts.URL, lines[0])
+	assert.Equal(t, "  REST API:    "+ts.URL+"/api/v3/", lines[1])
+	assert.Contains(t, lines, "[ok]      Token: authenticated as octocat")
+	assert.Contains(t, out.String(), "\n[warning] Rate limits: core 100 of 5000 left")
+	assert.Contains(t, out.String(), "\n          Fix: Calls may be held back")
+	assert.Contains(t, out.String(), "\nErrors: 0, warnings: 1\n")
+}
+
+func Test_RunDoctor_RejectsInvalidConfig(t *testing.T) {
+	_, err := RunDoctor(DoctorConfig{Format: "yaml"})
+	assert.ErrorContains(t, err, `unknown format "yaml"`)
+
+	_, err = RunDoctor(DoctorConfig{Format: FormatText, EnabledToolsets: []string{"gists"}})
+	assert.ErrorContains(t, err, "failed to enable toolsets")
+
+	_, err = RunDoctor(DoctorConfig{Format: FormatText, Host: "github.example.com"})
+	assert.ErrorContains(t, err, "host must have a scheme")
+}
@@ -0,0 +1,96 @@
+package ghmcp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// HostTokenHeader carries the tokens of a request for hosts other than the default one, as name=token pairs, which
+// may be repeated or separated by commas.
+const HostTokenHeader = "X-GitHub-Host-Token"
+
+// hostTokensFromRequest extracts the tokens for other hosts from the HostTokenHeader headers of the request,
+// returning an error message if they are malformed.
+func hostTokensFromRequest(r *http.Request) (map[string]string, string) {
+	var tokens map[string]string
+	for _, value := range r.Header.Values(HostTokenHeader) {
+		for _, pair := range strings.Split(value, ",") {
+			name, token, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || name == "" || token == "" {
+				return nil, HostTokenHeader + " header must hold name=token pairs"
+			}
+			if tokens == nil {
+				tokens = make(map[string]string)
+			}
+			tokens[name] = token
+		}
+	}
+	return tokens, ""
+}
+
+// tokenFromRequest extracts the token from an "Authorization: Bearer <token>" or "Authorization: token <token>"
+// header. It reports false if the header is absent, and returns an error message if it is malformed.
+func tokenFromRequest(r *http.Request) (token string, ok bool, errMsg string) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", false, ""
+	}
+
+	scheme, credentials, found := strings.Cut(header, " ")
+	if !found || (!strings.EqualFold(scheme, "bearer") && !strings.EqualFold(scheme, "token")) {
+		return "", false, "Authorization header must use the Bearer or token scheme"
+	}
+
+	credentials = strings.TrimSpace(credentials)
+	if credentials == "" {
+		return "", false, "Authorization header is missing a token"
+	}
+
+	return credentials, true, ""
+}
+
+// withRequestToken attaches the token from the request's Authorization header to the request context, so that
+// tool calls are made on behalf of the caller rather than with the server's own token, along with its tokens for
+// other hosts. When the server has no credentials of its own, requests without a token are rejected.
+func withRequestToken(next http.Handler, hasServerToken bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok, errMsg := tokenFromRequest(r)
+		hostTokens, hostErrMsg := hostTokensFromRequest(r)
+		if errMsg == "" {
+			errMsg = hostErrMsg
+		}
+		if hostTokens != nil {
+			r = r.WithContext(ContextWithHostTokens(r.Context(), hostTokens))
+		}
+		switch {
+		case errMsg != "":
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+			http.Error(w, errMsg, http.StatusUnauthorized)
+			return
+		case ok:
+			r = r.WithContext(ContextWithToken(r.Context(), token))
+		case !hasServerToken:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Authorization header with a GitHub token is required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withStreamsClosedOnDone ends the GET requests to next, the streams clients of the streamable HTTP transport listen
+// for server notifications on, once ctx is done. The streamable HTTP server keeps them open until clients disconnect,
+// even when shut down, which would hold up shutting down the HTTP server serving them until its timeout.
+func withStreamsClosedOnDone(ctx context.Context, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			streamCtx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			stop := context.AfterFunc(ctx, cancel)
+			defer stop()
+			r = r.WithContext(streamCtx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
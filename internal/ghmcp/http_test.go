@@ -0,0 +1,205 @@
+package ghmcp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithRequestToken(t *testing.T) {
+	tests := []struct {
+		name              string
+		authorization     string
+		hasServerToken    bool
+		hostTokens        string
+		expectedStatus    int
+		expectedBody      string
+		expectedToken     string
+		expectedHostToken string
+	}{
+		{
+			name:           "bearer token is attached to the context",
+			authorization:  "Bearer user-token",
+			expectedStatus: http.StatusOK,
+			expectedToken:  "user-token",
+		},
+		{
+			name:           "token scheme is accepted",
+			authorization:  "token user-token",
+			expectedStatus: http.StatusOK,
+			expectedToken:  "user-token",
+		},
+		{
+			name:           "missing header is rejected without a server token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing header falls back to the server token",
+			hasServerToken: true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unsupported scheme is rejected",
+			authorization:  "Basic dXNlcjpwYXNz",
+			hasServerToken: true,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   "Authorization header must use the Bearer or token scheme",
+		},
+		{
+			name:              "tokens for other hosts are attached to the context",
+			authorization:     "Bearer user-token",
+			hostTokens:        "other=other-token, ghes=ghes-token",
+			expectedStatus:    http.StatusOK,
+			expectedToken:     "user-token",
+			expectedHostToken: "ghes-token",
+		},
+		{
+			name:           "malformed host tokens are rejected",
+			authorization:  "Bearer user-token",
+			hostTokens:     "ghes",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "empty token is rejected",
+			authorization:  "Bearer ",
+			hasServerToken: true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotToken, gotHostToken string
+			handler := withRequestToken(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				gotToken, _ = TokenFromContext(r.Context())
+				gotHostToken, _ = HostTokenFromContext(r.Context(), "ghes")
+			}), tc.hasServerToken)
+
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			if tc.hostTokens != "" {
+				req.Header.Set(HostTokenHeader, tc.hostTokens)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+			assert.Equal(t, tc.expectedToken, gotToken)
+			assert.Equal(t, tc.expectedHostToken, gotHostToken)
+		})
+	}
+}
+
+// startHTTPServer serves cfg on a free port, returning the URL of its MCP endpoint and a function shutting it down
+// and returning what serving returned.
+func startHTTPServer(t *testing.T, cfg HTTPServerConfig) (string, func() error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if cfg.ShutdownTimeout == 0 {
+		cfg.ShutdownTimeout = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errC := make(chan error, 1)
+	go func() { errC <- serveHTTP(ctx, cfg, listener) }()
+	t.Cleanup(cancel)
+
+	return "http://" + listener.Addr().String() + "/mcp", func() error {
+		cancel()
+		select {
+		case err := <-errC:
+			return err
+		case <-time.After(cfg.ShutdownTimeout + 5*time.Second):
+			t.Fatal("the server did not shut down")
+			return nil
+		}
+	}
+}
+
+// postMCP sends message to the MCP endpoint at url within session, if not empty, returning the response.
+func postMCP(t *testing.T, url, session, message string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(message))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if session != "" {
+		req.Header.Set("Mcp-Session-Id", session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+const initializeMessage = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+
+func Test_serveHTTP_OwnerTokensOnly(t *testing.T) {
+	var authorization []string
+	github := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"number":1}`))
+	}))
+	defer github.Close()
+
+	url, stop := startHTTPServer(t, HTTPServerConfig{LocalServerConfig: LocalServerConfig{ServerOptions: ServerOptions{
+		Version:         "test",
+		Host:            github.URL,
+		OwnerTokens:     []auth.OwnerToken{{Owner: "corp", Token: "corp-token"}},
+		EnabledToolsets: []string{"issues"},
+	}}})
+
+	// Requests without an Authorization header are served with the owner tokens
+	resp := postMCP(t, url, "", initializeMessage)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session := resp.Header.Get("Mcp-Session-Id")
+
+	resp = postMCP(t, url, session, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_issue","arguments":{"owner":"corp","repo":"api","issue_number":1}}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `\"number\":1`)
+	assert.Equal(t, []string{"Bearer corp-token"}, authorization)
+
+	require.NoError(t, stop())
+}
+
+func Test_serveHTTP_ShutsDownWithOpenStreams(t *testing.T) {
+	url, stop := startHTTPServer(t, HTTPServerConfig{
+		LocalServerConfig: LocalServerConfig{ServerOptions: ServerOptions{
+			Version: "test",
+			Host:    "https://github.example.com",
+			Token:   "test-token",
+		}},
+		ShutdownTimeout: 2 * time.Second,
+	})
+
+	resp := postMCP(t, url, "", initializeMessage)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session := resp.Header.Get("Mcp-Session-Id")
+
+	// Listen for notifications of the session, leaving the stream open
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Mcp-Session-Id", session)
+	stream, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = stream.Body.Close() }()
+	require.Equal(t, http.StatusOK, stream.StatusCode)
+
+	start := time.Now()
+	require.NoError(t, stop())
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,240 @@
+package ghmcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/network"
+	gogithub "github.com/google/go-github/v72/github"
+)
+
+// DefaultLoginScopes are the OAuth scopes requested by login, covering the tools the server offers.
+var DefaultLoginScopes = []string{"repo", "read:org", "notifications", "workflow", "security_events"}
+
+type LoginConfig struct {
+	// Version of the server
+	Version string
+
+	// GitHub Host to log in to (e.g. github.com or github.enterprise.com)
+	Host string
+
+	// Endpoints overrides the API URLs derived from Host
+	Endpoints Endpoints
+
+	// Network configures the proxy, certificate authorities and client certificate used to connect to GitHub
+	Network network.Config
+
+	// ClientID of the OAuth app to authorize
+	ClientID string
+
+	// Scopes to request, defaulting to DefaultLoginScopes
+	Scopes []string
+
+	// Store to save the resulting credential to
+	Store *auth.CredentialStore
+
+	// Out is where instructions for the user are written
+	Out io.Writer
+}
+
+// credentialHost returns the key credentials for host are stored under, which is the hostname of the GitHub
+// instance, e.g. github.com.
+func credentialHost(host string) (string, error) {
+	apiHost, err := parseAPIHost(host)
+	if err != nil {
+		return "", err
+	}
+	return apiHost.webURL.Host, nil
+}
+
+// StoredToken returns the token saved by login for host, or an empty string if there is none.
+func StoredToken(store *auth.CredentialStore, host string) (string, error) {
+	key, err := credentialHost(host)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse API host: %w", err)
+	}
+	cred, ok, err := store.Get(key)
+	if err != nil || !ok {
+		return "", err
+	}
+	return cred.Token, nil
+}
+
+// RunLogin authorizes the server with the OAuth device flow and stores the resulting token.
+func RunLogin(cfg LoginConfig) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.ClientID == "" {
+		return fmt.Errorf("an OAuth client ID is required to log in")
+	}
+
+	apiHost, err := newAPIHost(cfg.Host, cfg.Endpoints)
+	if err != nil {
+		return fmt.Errorf("failed to parse API host: %w", err)
+	}
+
+	transport, err := network.NewTransport(cfg.Network)
+	if err != nil {
+		return err
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultLoginScopes
+	}
+
+	flow := &auth.DeviceFlow{
+		ClientID:   cfg.ClientID,
+		Scopes:     scopes,
+		BaseURL:    apiHost.webURL,
+		HTTPClient: &http.Client{Transport: transport},
+	}
+
+	code, err := flow.RequestCode(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cfg.Out, "First copy your one-time code: %s\n", code.UserCode)
+	_, _ = fmt.Fprintf(cfg.Out, "Then open %s in your browser to authorize the GitHub MCP Server.\n", code.VerificationURI)
+	_, _ = fmt.Fprintf(cfg.Out, "Waiting for authorization...\n")
+
+	pollCtx, cancel := context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+	defer cancel()
+
+	token, err := flow.PollToken(pollCtx, code)
+	if err != nil {
+		return err
+	}
+
+	user, grantedScopes, err := lookupUser(ctx, apiHost, transport, cfg.Version, token)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Store.Set(apiHost.webURL.Host, auth.Credential{
+		Host:      cfg.Host,
+		Token:     token,
+		User:      user,
+		Scopes:    grantedScopes,
+		CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(cfg.Out, "Logged in to %s as %s\n", apiHost.webURL.Host, user)
+	return nil
+}
+
+// RunLogout removes the token stored for host.
+func RunLogout(store *auth.CredentialStore, host string, out io.Writer) error {
+	key, err := credentialHost(host)
+	if err != nil {
+		return fmt.Errorf("failed to parse API host: %w", err)
+	}
+
+	removed, err := store.Delete(key)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("not logged in to %s", key)
+	}
+
+	_, _ = fmt.Fprintf(out, "Logged out of %s\n", key)
+	return nil
+}
+
+// RunAuthStatus reports the credentials stored for every host, checking that each is still valid by connecting to
+// it as configured by netCfg. The API URLs of host, the configured one, are overridden by endpoints, as they are for
+// login.
+func RunAuthStatus(store *auth.CredentialStore, version, host string, endpoints Endpoints, netCfg network.Config, out io.Writer) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	configured, err := credentialHost(host)
+	if err != nil {
+		return fmt.Errorf("failed to parse API host: %w", err)
+	}
+
+	transport, err := network.NewTransport(netCfg)
+	if err != nil {
+		return err
+	}
+
+	hosts, err := store.Hosts()
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		_, _ = fmt.Fprintf(out, "Not logged in to any hosts. Run `github-mcp-server login` to authenticate.\n")
+		return nil
+	}
+
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cred := hosts[name]
+		_, _ = fmt.Fprintf(out, "%s\n", name)
+		_, _ = fmt.Fprintf(out, "  Credentials: %s\n", store.Path)
+
+		var hostEndpoints Endpoints
+		if name == configured {
+			hostEndpoints = endpoints
+		}
+		apiHost, err := newAPIHost(cred.Host, hostEndpoints)
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "  Error: %v\n", err)
+			continue
+		}
+
+		user, scopes, err := lookupUser(ctx, apiHost, transport, version, cred.Token)
+		if err != nil {
+			_, _ = fmt.Fprintf(out, "  Token is invalid: %v\n", err)
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "  Logged in as %s\n", user)
+		_, _ = fmt.Fprintf(out, "  Token scopes: %s\n", strings.Join(scopes, ", "))
+	}
+	return nil
+}
+
+// lookupUser returns the login of the user the token belongs to, along with the scopes granted to it.
+func lookupUser(ctx context.Context, apiHost apiHost, transport http.RoundTripper, version, token string) (string, []string, error) {
+	client := gogithub.NewClient(&http.Client{
+		Transport: &bearerAuthTransport{
+			transport: transport,
+			token:     auth.StaticTokenSource(token),
+			hosts:     apiHost.hosts(),
+		},
+	})
+	client.UserAgent = fmt.Sprintf("github-mcp-server/%s", version)
+	client.BaseURL = apiHost.baseRESTURL
+
+	user, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var scopes []string
+	for _, scope := range strings.Split(resp.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return user.GetLogin(), scopes, nil
+}
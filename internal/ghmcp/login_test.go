@@ -0,0 +1,29 @@
+package ghmcp
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/github/github-mcp-server/internal/fakegithub"
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RunAuthStatus_UsesEndpoints(t *testing.T) {
+	fake := fakegithub.New(fakegithub.Options{Token: "ghes-token", Scopes: []string{"repo"}})
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	store := &auth.CredentialStore{Path: filepath.Join(t.TempDir(), "credentials.json")}
+	require.NoError(t, store.Set("ghes.example.com", auth.Credential{Host: "https://ghes.example.com", Token: "ghes-token"}))
+
+	var out bytes.Buffer
+	err := RunAuthStatus(store, "test", "https://ghes.example.com", Endpoints{REST: ts.URL + "/api/v3/"}, network.Config{}, &out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Logged in as "+fakegithub.DefaultLogin)
+	assert.Contains(t, out.String(), "Token scopes: repo")
+}
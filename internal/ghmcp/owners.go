@@ -0,0 +1,32 @@
+package ghmcp
+
+import (
+	"context"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ownerToolHandlerMiddleware records the owner argument of tool calls in their context, for the token of the owner
+// to be chosen (see auth.OwnerTokenSource).
+func ownerToolHandlerMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if owner, _ := request.GetArguments()["owner"].(string); owner != "" {
+			ctx = auth.ContextWithOwner(ctx, owner)
+		}
+		return next(ctx, request)
+	}
+}
+
+// ownerResourceTemplateHandlerMiddleware records the owner of resources, such as
+// repo://{owner}/{repo}/contents{/path*}, in the context they are read with.
+func ownerResourceTemplateHandlerMiddleware(next server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if owner := toolsets.TemplateArgument(request, "owner"); owner != "" {
+			ctx = auth.ContextWithOwner(ctx, owner)
+		}
+		return next(ctx, request)
+	}
+}
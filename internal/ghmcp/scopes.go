@@ -0,0 +1,49 @@
+package ghmcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/scopes"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/sirupsen/logrus"
+)
+
+// scopeLookupTimeout bounds the lookup of the scopes of the server's own token as it starts.
+const scopeLookupTimeout = 10 * time.Second
+
+// scopeKey returns a scopes.KeyFn identifying the token calls authenticate with: the one carried by the request, or
+// else the server's own, whose scopes are checked only if it is a token rather than a GitHub App, whose installation
+// tokens have permissions instead.
+func scopeKey(checkServerToken bool) scopes.KeyFn {
+	return func(ctx context.Context) (string, bool) {
+		if token, ok := TokenFromContext(ctx); ok {
+			return tokenDigest(token), true
+		}
+		return serverCredentialsKey, checkServerToken
+	}
+}
+
+// reportTokenScopes looks up the scopes of the server's own token as the server starts, logging the enabled tools
+// they do not allow.
+func reportTokenScopes(checker *scopes.Checker, tsg *toolsets.ToolsetGroup, logger *logrus.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), scopeLookupTimeout)
+	defer cancel()
+
+	var unavailable []string
+	for _, toolset := range tsg.Toolsets {
+		if !toolset.Enabled {
+			continue
+		}
+		for name, reason := range tsg.UnavailableTools(ctx, toolset) {
+			unavailable = append(unavailable, fmt.Sprintf("%s (%s)", name, reason))
+		}
+	}
+	if len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		logger.Warnf("the token cannot use %d of the enabled tools: %s", len(unavailable), strings.Join(unavailable, ", "))
+	}
+}
@@ -2,38 +2,69 @@ package ghmcp
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
+	"github.com/github/github-mcp-server/pkg/audit"
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/confirm"
+	"github.com/github/github-mcp-server/pkg/dryrun"
 	"github.com/github/github-mcp-server/pkg/errors"
 	"github.com/github/github-mcp-server/pkg/github"
+	"github.com/github/github-mcp-server/pkg/hosts"
+	"github.com/github/github-mcp-server/pkg/httpcache"
+	"github.com/github/github-mcp-server/pkg/limits"
 	mcplog "github.com/github/github-mcp-server/pkg/log"
+	"github.com/github/github-mcp-server/pkg/metrics"
+	"github.com/github/github-mcp-server/pkg/network"
+	"github.com/github/github-mcp-server/pkg/policy"
+	"github.com/github/github-mcp-server/pkg/ratelimit"
 	"github.com/github/github-mcp-server/pkg/raw"
+	"github.com/github/github-mcp-server/pkg/scopes"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/github/github-mcp-server/pkg/tracing"
 	"github.com/github/github-mcp-server/pkg/translations"
-	gogithub "github.com/google/go-github/v72/github"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
-	"github.com/shurcooL/githubv4"
 	"github.com/sirupsen/logrus"
 )
 
-type MCPServerConfig struct {
+// ServerOptions configure the tools a server offers and how it calls GitHub, however it is run.
+type ServerOptions struct {
 	// Version of the server
 	Version string
 
 	// GitHub Host to target for API requests (e.g. github.com or github.enterprise.com)
 	Host string
 
-	// GitHub Token to authenticate with the GitHub API
+	// Endpoints overrides the API URLs derived from Host
+	Endpoints Endpoints
+
+	// Hosts are further GitHub instances tools can act on, each call being routed to one of them or Host
+	Hosts []HostConfig
+
+	// GitHub Token to authenticate with the GitHub API, used when the request context does not carry
+	// its own token (see ContextWithToken)
 	Token string
 
+	// GitHubApp configures authentication as a GitHub App installation, used instead of Token when set
+	GitHubApp *auth.AppConfig
+
+	// OwnerTokens authenticate calls acting on the repositories of the owners they match, in place of Token or
+	// GitHubApp, which authenticate the other calls
+	OwnerTokens []auth.OwnerToken
+
 	// EnabledToolsets is a list of toolsets to enable
 	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#tool-configuration
 	EnabledToolsets []string
@@ -42,38 +73,171 @@ type MCPServerConfig struct {
 	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#dynamic-tool-discovery
 	DynamicToolsets bool
 
+	// Tools restricts the tools offered within the enabled toolsets to those listed, if not empty
+	Tools []string
+
+	// ExcludeTools lists tools that are not offered even if their toolset is enabled
+	ExcludeTools []string
+
+	// Repos restricts the repositories tools and resources may act on to those matching these owner/repo
+	// patterns, in order, with a leading ! excluding matches (e.g. myorg/*, !myorg/secrets-*)
+	Repos []string
+
+	// ConfirmTools lists the tools, glob patterns or "destructive" for every destructive tool, whose calls the
+	// user is asked to approve through elicitation before they are made
+	ConfirmTools []string
+
+	// AllowUnconfirmed lets calls to the ConfirmTools go ahead without approval when the client does not support
+	// elicitation, instead of refusing them
+	AllowUnconfirmed bool
+
+	// RateLimitMaxWait is the longest a request to GitHub is held back waiting for a rate limit to reset, or
+	// before being retried
+	RateLimitMaxWait time.Duration
+
+	// MaxRetries is how many times an idempotent request to GitHub is retried after being rate limited or
+	// failing with a server error
+	MaxRetries int
+
+	// Limits bounds how many tool calls run at once, and for how long
+	Limits limits.Config
+
+	// Cache configures caching of responses from GitHub, which is disabled if nil
+	Cache *httpcache.Config
+
 	// ReadOnly indicates if we should only offer read-only tools
 	ReadOnly bool
 
+	// DryRun makes every call to a write tool return the requests it would make to change data on GitHub instead
+	// of making them
+	DryRun bool
+
+	// ScopeCheck is what becomes of the tools the token in use lacks the OAuth scopes for: scopes.ModeHide,
+	// scopes.ModeAnnotate, or scopes.ModeOff if empty. Scopes are not checked when Hosts or OwnerTokens are set, as
+	// calls are then authenticated with more than one token.
+	ScopeCheck string
+}
+
+type MCPServerConfig struct {
+	ServerOptions
+
+	// Transport sends every request to GitHub, defaulting to http.DefaultTransport
+	Transport http.RoundTripper
+
+	// Metrics records tool calls and requests to GitHub, if set
+	Metrics *metrics.Metrics
+
+	// Tracer records spans for MCP requests, tool calls and resource reads among them, and requests to GitHub, if set
+	Tracer *tracing.Tracer
+
+	// AuditLog records every call to a write tool, if set
+	AuditLog *audit.Logger
+
+	// Logger reports what the server finds out about the tokens it uses, if set. With a Logger and a Token, the
+	// scopes of the token are looked up, and the tools they rule out logged, as the server starts.
+	Logger *logrus.Logger
+
 	// Translator provides translated text for the server tooling
 	Translator translations.TranslationHelperFunc
 }
 
+// HostConfig is a GitHub instance tools can act on in addition to the one the server is configured with.
+type HostConfig struct {
+	// Name identifies the host in the host argument of tools and in resource URIs
+	Name string
+
+	// URL of the GitHub instance (e.g. https://ghes.example.com)
+	URL string
+
+	// Endpoints overrides the API URLs derived from URL
+	Endpoints Endpoints
+
+	// Token to authenticate with the GitHub API of the host
+	Token string
+
+	// Owners are the users and organizations, or glob patterns matching them, whose repositories are on this host.
+	// Calls acting on them are routed to the host unless they name another.
+	Owners []string
+}
+
 func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
-	apiHost, err := parseAPIHost(cfg.Host)
+	apiHost, err := newAPIHost(cfg.Host, cfg.Endpoints)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse API host: %w", err)
 	}
 
-	// Construct our REST client
-	restClient := gogithub.NewClient(nil).WithAuthToken(cfg.Token)
-	restClient.UserAgent = fmt.Sprintf("github-mcp-server/%s", cfg.Version)
-	restClient.BaseURL = apiHost.baseRESTURL
-	restClient.UploadURL = apiHost.uploadURL
+	// All requests to GitHub share one transport, so that rate limits are tracked, and responses cached, across
+	// sessions using the same token.
+	transport := cfg.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if cfg.Metrics != nil {
+		// Closest to the network, so that retries are counted and cached responses are not
+		transport = cfg.Metrics.Transport(transport)
+	}
+	if cfg.Tracer != nil {
+		transport = cfg.Tracer.Transport(transport)
+	}
+	transport = ratelimit.NewTransport(transport, ratelimit.Options{
+		MaxWait:    cfg.RateLimitMaxWait,
+		MaxRetries: cfg.MaxRetries,
+	})
+	if cfg.Cache != nil {
+		cacheTransport, err := httpcache.NewTransport(transport, *cfg.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP cache: %w", err)
+		}
+		transport = cacheTransport
+	}
 
-	// Construct our GraphQL client
-	// We're using NewEnterpriseClient here unconditionally as opposed to NewClient because we already
-	// did the necessary API host parsing so that github.com will return the correct URL anyway.
-	gqlHTTPClient := &http.Client{
-		Transport: &bearerAuthTransport{
-			transport: http.DefaultTransport,
-			token:     cfg.Token,
-		},
-	} // We're going to wrap the Transport later in beforeInit
-	gqlClient := githubv4.NewEnterpriseClient(apiHost.graphqlURL.String(), gqlHTTPClient)
+	var credentials auth.TokenSource
+	switch {
+	case cfg.GitHubApp != nil:
+		appTokenSource, err := auth.NewAppTokenSource(*cfg.GitHubApp, apiHost.baseRESTURL, transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure GitHub App authentication: %w", err)
+		}
+		credentials = appTokenSource
+	case cfg.Token != "":
+		credentials = auth.StaticTokenSource(cfg.Token)
+	}
+	if len(cfg.OwnerTokens) > 0 {
+		ownerTokens, err := auth.NewOwnerTokenSource(cfg.OwnerTokens, credentials)
+		if err != nil {
+			return nil, err
+		}
+		credentials = ownerTokens
+	}
+
+	// Records the permissions GitHub asks for when it refuses a call's requests, for the scope check
+	transport = scopes.NewTransport(transport)
+
+	// Outermost, so that nothing is sent on behalf of a dry run. GitHub App installation tokens are still created
+	// with the transport underneath.
+	transport = dryrun.NewTransport(transport)
+
+	clients := hostClients{hosts.Default: newClientFactory(cfg.Version, apiHost, credentials, transport)}
+	for _, h := range cfg.Hosts {
+		hostAPI, err := newAPIHost(h.URL, h.Endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse API host of %s: %w", h.Name, err)
+		}
+		if h.Token == "" {
+			return nil, fmt.Errorf("no token configured for host %s", h.Name)
+		}
+		f := newClientFactory(cfg.Version, hostAPI, auth.StaticTokenSource(h.Token), transport)
+		f.hostName = h.Name
+		clients[h.Name] = f
+	}
+
+	limiter, err := limits.New(cfg.Limits)
+	if err != nil {
+		return nil, err
+	}
 
 	// When a client send an initialize request, update the user agent to include the client info.
-	beforeInit := func(_ context.Context, _ any, message *mcp.InitializeRequest) {
+	beforeInit := func(ctx context.Context, _ any, message *mcp.InitializeRequest) {
 		userAgent := fmt.Sprintf(
 			"github-mcp-server/%s (%s/%s)",
 			cfg.Version,
@@ -81,16 +245,22 @@ func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
 			message.Params.ClientInfo.Version,
 		)
 
-		restClient.UserAgent = userAgent
-
-		gqlHTTPClient.Transport = &userAgentTransport{
-			transport: gqlHTTPClient.Transport,
-			agent:     userAgent,
+		clients.SetUserAgent(sessionIDFromContext(ctx), userAgent)
+		if cfg.AuditLog != nil {
+			cfg.AuditLog.SetClientInfo(sessionIDFromContext(ctx), message.Params.ClientInfo)
 		}
 	}
 
 	hooks := &server.Hooks{
 		OnBeforeInitialize: []server.OnBeforeInitializeFunc{beforeInit},
+		OnUnregisterSession: []server.OnUnregisterSessionHookFunc{
+			func(_ context.Context, session server.ClientSession) {
+				clients.Forget(session.SessionID())
+				if cfg.AuditLog != nil {
+					cfg.AuditLog.Forget(session.SessionID())
+				}
+			},
+		},
 		OnBeforeAny: []server.BeforeAnyHookFunc{
 			func(ctx context.Context, _ any, _ mcp.MCPMethod, _ any) {
 				// Ensure the context is cleared of any previous errors
@@ -98,48 +268,110 @@ func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
 				errors.ContextWithGitHubErrors(ctx)
 			},
 		},
+		OnBeforeCallTool: []server.OnBeforeCallToolFunc{limiter.BeforeCallTool},
+		OnError:          []server.OnErrorHookFunc{limiter.OnError},
+	}
+	if cfg.Tracer != nil {
+		hooks.AddBeforeAny(cfg.Tracer.BeforeAny)
+		hooks.AddOnSuccess(cfg.Tracer.OnSuccess)
+		hooks.AddOnError(cfg.Tracer.OnError)
 	}
 
-	ghServer := github.NewServer(cfg.Version, server.WithHooks(hooks))
-
-	enabledToolsets := cfg.EnabledToolsets
-	if cfg.DynamicToolsets {
-		// filter "all" from the enabled toolsets
-		enabledToolsets = make([]string, 0, len(cfg.EnabledToolsets))
-		for _, toolset := range cfg.EnabledToolsets {
-			if toolset != "all" {
-				enabledToolsets = append(enabledToolsets, toolset)
+	repoScope, err := policy.NewRepoScope(cfg.Repos)
+	if err != nil {
+		return nil, err
+	}
+	if repoScope != nil {
+		repoScope.SetThreadLookup(func(ctx context.Context, threadID string) (string, error) {
+			client, err := clients.GetClient(ctx)
+			if err != nil {
+				return "", err
 			}
-		}
+			thread, _, err := client.Activity.GetThread(ctx, threadID)
+			if err != nil {
+				return "", err
+			}
+			return thread.GetRepository().GetFullName(), nil
+		})
 	}
 
-	getClient := func(_ context.Context) (*gogithub.Client, error) {
-		return restClient, nil // closing over client
+	scopeCheck := cfg.ScopeCheck
+	if len(cfg.Hosts) > 0 || len(cfg.OwnerTokens) > 0 {
+		// Which token a call uses depends on its arguments, which tools/list knows nothing of
+		scopeCheck = scopes.ModeOff
+	}
+	checkServerToken := cfg.GitHubApp == nil && cfg.Token != ""
+	checker, err := scopes.NewChecker(scopeCheck, clients[hosts.Default].GetClient, scopeKey(checkServerToken), cfg.Logger)
+	if err != nil {
+		return nil, err
 	}
 
-	getGQLClient := func(_ context.Context) (*githubv4.Client, error) {
-		return gqlClient, nil // closing over client
+	confirmation, err := confirm.NewPolicy(cfg.ConfirmTools, cfg.AllowUnconfirmed)
+	if err != nil {
+		return nil, err
 	}
 
-	getRawClient := func(ctx context.Context) (*raw.Client, error) {
-		client, err := getClient(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get GitHub client: %w", err)
-		}
-		return raw.NewClient(client, apiHost.rawURL), nil // closing over client
+	opts := []server.ServerOption{
+		server.WithHooks(hooks),
+		// Outermost, as it finds the request of each call by the context the server passes to middleware
+		server.WithToolHandlerMiddleware(limiter.ToolHandlerMiddleware),
+	}
+	if confirmation != nil {
+		opts = append(opts, server.WithElicitation())
+	}
+	if cfg.Metrics != nil {
+		// Installed first so that calls rejected by the repository policy are counted too
+		opts = append(opts, server.WithToolHandlerMiddleware(cfg.Metrics.ToolHandlerMiddleware))
+	}
+	if cfg.Tracer != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(cfg.Tracer.ToolHandlerMiddleware))
+	}
+	if repoScope != nil {
+		opts = append(opts, server.WithToolHandlerMiddleware(repoScope.ToolHandlerMiddleware))
+	}
+	if len(cfg.OwnerTokens) > 0 {
+		opts = append(opts, server.WithToolHandlerMiddleware(ownerToolHandlerMiddleware))
+	}
+	if checker != nil {
+		opts = append(opts, server.WithToolFilter(checker.FilterTools))
 	}
 
-	// Create default toolsets
-	tsg := github.DefaultToolsetGroup(cfg.ReadOnly, getClient, getGQLClient, getRawClient, cfg.Translator)
-	err = tsg.EnableToolsets(enabledToolsets)
+	ghServer := github.NewServer(cfg.Version, opts...)
+	ghServer.AddNotificationHandler(limits.MethodNotificationCancelled, limiter.HandleCancelled)
 
+	tsg, router, err := newToolsetGroup(cfg, clients.GetClient, clients.GetGQLClient, clients.GetRawClient)
 	if err != nil {
-		return nil, fmt.Errorf("failed to enable toolsets: %w", err)
+		return nil, err
+	}
+	if cfg.Tracer != nil {
+		tsg.UseResourceTemplateMiddleware(cfg.Tracer.ResourceTemplateHandlerMiddleware)
+	}
+	if checker != nil {
+		tsg.WrapTools(checker.WrapTool)
+		tsg.SetToolAvailability(checker.Unavailable)
+	}
+	tsg.UseResourceTemplateMiddleware(router.ResourceTemplateHandlerMiddleware)
+	if len(cfg.OwnerTokens) > 0 {
+		tsg.UseResourceTemplateMiddleware(ownerResourceTemplateHandlerMiddleware)
+	}
+	if cfg.AuditLog != nil {
+		tsg.UseWriteToolMiddleware(cfg.AuditLog.ToolHandlerMiddleware)
+	}
+	if confirmation != nil {
+		// Inside the audit log, so that calls the user did not approve are recorded as refused
+		tsg.WrapWriteTools(confirmation.WrapTool)
+	}
+	if repoScope != nil {
+		tsg.UseResourceTemplateMiddleware(repoScope.ResourceTemplateHandlerMiddleware)
 	}
 
 	// Register all mcp functionality with the server
 	tsg.RegisterAll(ghServer)
 
+	if checker != nil && checkServerToken && cfg.Logger != nil {
+		reportTokenScopes(checker, tsg, cfg.Logger)
+	}
+
 	if cfg.DynamicToolsets {
 		dynamic := github.InitDynamicToolset(ghServer, tsg, cfg.Translator)
 		dynamic.RegisterTools(ghServer)
@@ -148,36 +380,177 @@ func NewMCPServer(cfg MCPServerConfig) (*server.MCPServer, error) {
 	return ghServer, nil
 }
 
-type StdioServerConfig struct {
-	// Version of the server
-	Version string
+// newToolsetGroup returns the toolsets of a server configured with cfg, calling GitHub with the given clients, with
+// the tools enabled and described as the server offers them: with the host parameter of tools and resource templates
+// when cfg.Hosts are set, and the dry_run parameter of write tools. It also returns the router choosing the host of
+// each call. Wrappers and middleware added to the group afterwards are applied inside of these.
+func newToolsetGroup(cfg MCPServerConfig, getClient github.GetClientFn, getGQLClient github.GetGQLClientFn, getRawClient raw.GetRawClientFn) (*toolsets.ToolsetGroup, *hosts.Router, error) {
+	routes := make([]hosts.Host, 0, len(cfg.Hosts))
+	for _, h := range cfg.Hosts {
+		routes = append(routes, hosts.Host{Name: h.Name, Owners: h.Owners})
+	}
+	router, err := hosts.NewRouter(routes)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// GitHub Host to target for API requests (e.g. github.com or github.enterprise.com)
-	Host string
+	enabledToolsets := cfg.EnabledToolsets
+	if cfg.DynamicToolsets {
+		// filter "all" from the enabled toolsets
+		enabledToolsets = make([]string, 0, len(cfg.EnabledToolsets))
+		for _, toolset := range cfg.EnabledToolsets {
+			if toolset != "all" {
+				enabledToolsets = append(enabledToolsets, toolset)
+			}
+		}
+	}
 
-	// GitHub Token to authenticate with the GitHub API
-	Token string
+	// Create default toolsets
+	tsg := github.DefaultToolsetGroup(cfg.ReadOnly, getClient, getGQLClient, getRawClient, cfg.Translator)
+	if len(cfg.Tools) > 0 || len(cfg.ExcludeTools) > 0 {
+		filter, err := toolsets.NewToolFilter(cfg.Tools, cfg.ExcludeTools)
+		if err != nil {
+			return nil, nil, err
+		}
+		tsg.SetToolFilter(filter)
+	}
+	tsg.WrapTools(router.WrapTool)
+	tsg.WrapResourceTemplates(router.WrapResourceTemplate)
+	// Wraps the audit log, which records that calls were dry runs
+	tsg.WrapWriteTools(dryrun.WrapTool(cfg.DryRun))
+	if err := tsg.EnableToolsets(enabledToolsets); err != nil {
+		return nil, nil, fmt.Errorf("failed to enable toolsets: %w", err)
+	}
+	return tsg, router, nil
+}
 
-	// EnabledToolsets is a list of toolsets to enable
-	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#tool-configuration
-	EnabledToolsets []string
+// LocalServerConfig configures a server run by this process, along with what it sets up for itself: its logs,
+// metrics, traces, audit log and connection to GitHub.
+type LocalServerConfig struct {
+	ServerOptions
 
-	// Whether to enable dynamic toolsets
-	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#dynamic-tool-discovery
-	DynamicToolsets bool
+	// Network configures the proxy, certificate authorities and client certificate used to connect to GitHub
+	Network network.Config
 
-	// ReadOnly indicates if we should only register read-only tools
-	ReadOnly bool
+	// MetricsAddress is the TCP address Prometheus metrics are served on at /metrics, disabled if empty
+	MetricsAddress string
+
+	// Tracing configures exporting OpenTelemetry spans, which is disabled if nil
+	Tracing *tracing.Config
+
+	// AuditLog configures recording every call to a write tool, which is disabled if nil
+	AuditLog *audit.Config
 
 	// ExportTranslations indicates if we should export translations
 	// See: https://github.com/github/github-mcp-server?tab=readme-ov-file#i18n--overriding-descriptions
 	ExportTranslations bool
 
+	// Path to the log file if not stderr
+	LogFilePath string
+
+	// LogFormat is mcplog.FormatText or mcplog.FormatJSON
+	LogFormat string
+
+	// Record is the directory every request made to GitHub, and its response, is recorded into, if not empty
+	Record string
+
+	// Replay is the directory of recorded responses served in place of GitHub's, if not empty. Requests with no
+	// recorded response fail.
+	Replay string
+}
+
+type StdioServerConfig struct {
+	LocalServerConfig
+
 	// EnableCommandLogging indicates if we should log commands
 	EnableCommandLogging bool
 
-	// Path to the log file if not stderr
-	LogFilePath string
+	// LogRedaction adds to the rules masking secrets in the commands logged
+	LogRedaction mcplog.RedactionConfig
+}
+
+// localServer is an MCP server run by this process, over stdio or HTTP or for a single call, along with what it
+// uses.
+type localServer struct {
+	*server.MCPServer
+
+	metrics          *metrics.Metrics
+	tracer           *tracing.Tracer
+	logger           *logrus.Logger
+	dumpTranslations func()
+	finishCassette   func() error
+	closers          []func()
+}
+
+// newLocalServer creates the MCP server configured by cfg. It must be closed once done with.
+func newLocalServer(ctx context.Context, cfg LocalServerConfig) (_ *localServer, err error) {
+	s := &localServer{finishCassette: func() error { return nil }}
+	defer func() {
+		if err != nil {
+			s.close()
+		}
+	}()
+
+	t, dumpTranslations := translations.TranslationHelper()
+	s.dumpTranslations = dumpTranslations
+
+	if cfg.MetricsAddress != "" {
+		s.metrics = metrics.New()
+	}
+
+	if cfg.Tracing != nil {
+		tracer, err := tracing.New(ctx, *cfg.Tracing, cfg.Version)
+		if err != nil {
+			return nil, err
+		}
+		s.tracer = tracer
+		s.closers = append(s.closers, func() { shutdownTracer(tracer) })
+	}
+
+	var auditLog *audit.Logger
+	if cfg.AuditLog != nil {
+		auditLog, err = audit.Open(*cfg.AuditLog)
+		if err != nil {
+			return nil, err
+		}
+		s.closers = append(s.closers, func() { _ = auditLog.Close() })
+	}
+
+	s.logger, err = newLogger(cfg.LogFilePath, cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	networkTransport, err := network.NewTransport(cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	transport, finishCassette, err := cassetteTransport(networkTransport, cfg.Record, cfg.Replay, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	s.finishCassette = finishCassette
+
+	s.MCPServer, err = NewMCPServer(MCPServerConfig{
+		ServerOptions: cfg.ServerOptions,
+		Transport:     transport,
+		Metrics:       s.metrics,
+		Tracer:        s.tracer,
+		AuditLog:      auditLog,
+		Logger:        s.logger,
+		Translator:    t,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MCP server: %w", err)
+	}
+	return s, nil
+}
+
+// close exports the spans still buffered and closes the audit log, but does not finish the cassette.
+func (s *localServer) close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
 }
 
 // RunStdioServer is not concurrent safe.
@@ -186,39 +559,38 @@ func RunStdioServer(cfg StdioServerConfig) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	t, dumpTranslations := translations.TranslationHelper()
-
-	ghServer, err := NewMCPServer(MCPServerConfig{
-		Version:         cfg.Version,
-		Host:            cfg.Host,
-		Token:           cfg.Token,
-		EnabledToolsets: cfg.EnabledToolsets,
-		DynamicToolsets: cfg.DynamicToolsets,
-		ReadOnly:        cfg.ReadOnly,
-		Translator:      t,
-	})
+	ghServer, err := newLocalServer(ctx, cfg.LocalServerConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create MCP server: %w", err)
+		return err
 	}
+	defer ghServer.close()
+	logrusLogger := ghServer.logger
 
-	stdioServer := server.NewStdioServer(ghServer)
+	stdioServer := server.NewStdioServer(ghServer.MCPServer)
+	server.WithWorkerPoolSize(stdioWorkers(cfg.Limits))(stdioServer)
 
-	logrusLogger := logrus.New()
-	if cfg.LogFilePath != "" {
-		file, err := os.OpenFile(cfg.LogFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	var redactor *mcplog.Redactor
+	if cfg.EnableCommandLogging {
+		redactor, err = mcplog.NewRedactor(cfg.LogRedaction)
 		if err != nil {
-			return fmt.Errorf("failed to open log file: %w", err)
+			return err
 		}
-
-		logrusLogger.SetLevel(logrus.DebugLevel)
-		logrusLogger.SetOutput(file)
 	}
+
 	stdLogger := log.New(logrusLogger.Writer(), "stdioserver", 0)
 	stdioServer.SetErrorLogger(stdLogger)
 
+	if ghServer.metrics != nil {
+		closeMetrics, err := serveMetrics(cfg.MetricsAddress, ghServer.metrics, logrusLogger)
+		if err != nil {
+			return err
+		}
+		defer closeMetrics()
+	}
+
 	if cfg.ExportTranslations {
 		// Once server is initialized, all translations are loaded
-		dumpTranslations()
+		ghServer.dumpTranslations()
 	}
 
 	// Start listening for messages
@@ -228,6 +600,7 @@ func RunStdioServer(cfg StdioServerConfig) error {
 
 		if cfg.EnableCommandLogging {
 			loggedIO := mcplog.NewIOLogger(in, out, logrusLogger)
+			loggedIO.SetRedactor(redactor)
 			in, out = loggedIO, loggedIO
 		}
 		// enable GitHub errors in the context
@@ -248,7 +621,204 @@ func RunStdioServer(cfg StdioServerConfig) error {
 		}
 	}
 
-	return nil
+	return ghServer.finishCassette()
+}
+
+// HTTPServerConfig configures the HTTP server. Without a Token, GitHubApp or OwnerTokens of its own, every request
+// must carry its own token.
+type HTTPServerConfig struct {
+	LocalServerConfig
+
+	// ListenAddress is the TCP address the HTTP server listens on (e.g. localhost:8082)
+	ListenAddress string
+
+	// BasePath is prepended to every endpoint served (e.g. /github yields /github/mcp)
+	BasePath string
+
+	// ShutdownTimeout bounds how long in-flight requests are given to complete on shutdown
+	ShutdownTimeout time.Duration
+}
+
+// RunHTTPServer serves the MCP server over streamable HTTP at <base-path>/mcp, with the
+// older HTTP+SSE transport available at <base-path>/sse and <base-path>/message for
+// clients that do not support streamable HTTP yet. It blocks until a shutdown signal is
+// received or the listener fails.
+func RunHTTPServer(cfg HTTPServerConfig) error {
+	// Create app context
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+	return serveHTTP(ctx, cfg, listener)
+}
+
+// serveHTTP serves the MCP server configured by cfg on listener until ctx is done, then shuts it down.
+func serveHTTP(ctx context.Context, cfg HTTPServerConfig, listener net.Listener) error {
+	defer func() { _ = listener.Close() }()
+
+	ghServer, err := newLocalServer(ctx, cfg.LocalServerConfig)
+	if err != nil {
+		return err
+	}
+	defer ghServer.close()
+	logrusLogger := ghServer.logger
+	tracer := ghServer.tracer
+
+	if ghServer.metrics != nil {
+		closeMetrics, err := serveMetrics(cfg.MetricsAddress, ghServer.metrics, logrusLogger)
+		if err != nil {
+			return err
+		}
+		defer closeMetrics()
+	}
+
+	if cfg.ExportTranslations {
+		// Once server is initialized, all translations are loaded
+		ghServer.dumpTranslations()
+	}
+
+	basePath := "/" + strings.Trim(cfg.BasePath, "/")
+	if basePath == "/" {
+		basePath = ""
+	}
+
+	// enable GitHub errors in the context of every request
+	contextFunc := func(ctx context.Context, r *http.Request) context.Context {
+		if tracer != nil {
+			// Continue the trace of the client sending the request, if any
+			ctx = tracer.Extract(ctx, r.Header)
+		}
+		return errors.ContextWithGitHubErrors(ctx)
+	}
+
+	httpServer := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	streamableServer := server.NewStreamableHTTPServer(ghServer.MCPServer,
+		server.WithEndpointPath(basePath+"/mcp"),
+		server.WithHTTPContextFunc(contextFunc),
+		server.WithLogger(logrusLogger),
+	)
+	sseServer := server.NewSSEServer(ghServer.MCPServer,
+		server.WithStaticBasePath(basePath),
+		server.WithSSEContextFunc(contextFunc),
+		server.WithHTTPServer(httpServer),
+	)
+
+	streams, closeStreams := context.WithCancel(context.Background())
+	defer closeStreams()
+
+	mux := http.NewServeMux()
+	mux.Handle(basePath+"/mcp", withStreamsClosedOnDone(streams, streamableServer))
+	mux.Handle(sseServer.CompleteSsePath(), sseServer)
+	mux.Handle(sseServer.CompleteMessagePath(), sseServer)
+	httpServer.Handler = withRequestToken(mux, cfg.Token != "" || cfg.GitHubApp != nil || len(cfg.OwnerTokens) > 0)
+
+	// Start serving requests
+	errC := make(chan error, 1)
+	go func() {
+		errC <- httpServer.Serve(listener)
+	}()
+
+	// Output github-mcp-server string
+	_, _ = fmt.Fprintf(os.Stderr, "GitHub MCP Server running on http://%s%s/mcp\n", listener.Addr(), basePath)
+
+	// Wait for shutdown signal
+	select {
+	case <-ctx.Done():
+		logrusLogger.Infof("shutting down server...")
+	case err := <-errC:
+		if err != nil && !goerrors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("error running server: %w", err)
+		}
+		return nil
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Both transports hold long-lived streams open, which the shared HTTP server would wait on until the
+	// timeout. The streamable HTTP server is shut down with its streams closed first, then shutting down the
+	// SSE server closes its own streams before shutting down the shared HTTP server.
+	closeStreams()
+	if err := streamableServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down server: %w", err)
+	}
+	if err := sseServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down server: %w", err)
+	}
+
+	return ghServer.finishCassette()
+}
+
+// serveMetrics serves m at /metrics on address in the background, returning a function that stops serving.
+func serveMetrics(address string, m *metrics.Metrics, logger *logrus.Logger) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics on %s: %w", address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	metricsServer := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := metricsServer.Serve(listener); err != nil && !goerrors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("error serving metrics: %v", err)
+		}
+	}()
+
+	_, _ = fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", listener.Addr())
+	return func() { _ = metricsServer.Close() }, nil
+}
+
+// maxStdioWorkers is the most tool calls the stdio server handles at once.
+const maxStdioWorkers = 100
+
+// stdioWorkers returns how many tool calls the stdio server should handle at once for calls both running and
+// queued within the limits to be handled, so that the server keeps reading messages, such as cancellations,
+// while they wait.
+func stdioWorkers(cfg limits.Config) int {
+	if cfg.MaxConcurrent == 0 {
+		return maxStdioWorkers
+	}
+	return min(cfg.MaxConcurrent+cfg.MaxQueued, maxStdioWorkers)
+}
+
+// shutdownTracer exports the spans still buffered by tracer, giving up after a few seconds if the collector
+// cannot be reached.
+func shutdownTracer(tracer *tracing.Tracer) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to export traces: %v\n", err)
+	}
+}
+
+// newLogger returns a logger writing to stderr, or at debug level to logFilePath if set, in the given format.
+func newLogger(logFilePath, format string) (*logrus.Logger, error) {
+	formatter, err := mcplog.NewFormatter(format)
+	if err != nil {
+		return nil, err
+	}
+	logrusLogger := logrus.New()
+	logrusLogger.SetFormatter(formatter)
+	if logFilePath != "" {
+		file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+
+		logrusLogger.SetLevel(logrus.DebugLevel)
+		logrusLogger.SetOutput(file)
+	}
+	return logrusLogger, nil
 }
 
 type apiHost struct {
@@ -256,6 +826,7 @@ type apiHost struct {
 	graphqlURL  *url.URL
 	uploadURL   *url.URL
 	rawURL      *url.URL
+	webURL      *url.URL
 }
 
 func newDotcomHost() (apiHost, error) {
@@ -279,11 +850,17 @@ func newDotcomHost() (apiHost, error) {
 		return apiHost{}, fmt.Errorf("failed to parse dotcom Raw URL: %w", err)
 	}
 
+	webURL, err := url.Parse("https://github.com/")
+	if err != nil {
+		return apiHost{}, fmt.Errorf("failed to parse dotcom Web URL: %w", err)
+	}
+
 	return apiHost{
 		baseRESTURL: baseRestURL,
 		graphqlURL:  gqlURL,
 		uploadURL:   uploadURL,
 		rawURL:      rawURL,
+		webURL:      webURL,
 	}, nil
 }
 
@@ -318,11 +895,17 @@ func newGHECHost(hostname string) (apiHost, error) {
 		return apiHost{}, fmt.Errorf("failed to parse GHEC Raw URL: %w", err)
 	}
 
+	webURL, err := url.Parse(fmt.Sprintf("https://%s/", u.Hostname()))
+	if err != nil {
+		return apiHost{}, fmt.Errorf("failed to parse GHEC Web URL: %w", err)
+	}
+
 	return apiHost{
 		baseRESTURL: restURL,
 		graphqlURL:  gqlURL,
 		uploadURL:   uploadURL,
 		rawURL:      rawURL,
+		webURL:      webURL,
 	}, nil
 }
 
@@ -332,34 +915,41 @@ func newGHESHost(hostname string) (apiHost, error) {
 		return apiHost{}, fmt.Errorf("failed to parse GHES URL: %w", err)
 	}
 
-	restURL, err := url.Parse(fmt.Sprintf("%s://%s/api/v3/", u.Scheme, u.Hostname()))
+	restURL, err := url.Parse(fmt.Sprintf("%s://%s/api/v3/", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES REST URL: %w", err)
 	}
 
-	gqlURL, err := url.Parse(fmt.Sprintf("%s://%s/api/graphql", u.Scheme, u.Hostname()))
+	gqlURL, err := url.Parse(fmt.Sprintf("%s://%s/api/graphql", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES GraphQL URL: %w", err)
 	}
 
-	uploadURL, err := url.Parse(fmt.Sprintf("%s://%s/api/uploads/", u.Scheme, u.Hostname()))
+	uploadURL, err := url.Parse(fmt.Sprintf("%s://%s/api/uploads/", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES Upload URL: %w", err)
 	}
-	rawURL, err := url.Parse(fmt.Sprintf("%s://%s/raw/", u.Scheme, u.Hostname()))
+	rawURL, err := url.Parse(fmt.Sprintf("%s://%s/raw/", u.Scheme, u.Host))
 	if err != nil {
 		return apiHost{}, fmt.Errorf("failed to parse GHES Raw URL: %w", err)
 	}
 
+	webURL, err := url.Parse(fmt.Sprintf("%s://%s/", u.Scheme, u.Host))
+	if err != nil {
+		return apiHost{}, fmt.Errorf("failed to parse GHES Web URL: %w", err)
+	}
+
 	return apiHost{
 		baseRESTURL: restURL,
 		graphqlURL:  gqlURL,
 		uploadURL:   uploadURL,
 		rawURL:      rawURL,
+		webURL:      webURL,
 	}, nil
 }
 
-// Note that this does not handle ports yet, so development environments are out.
+// parseAPIHost derives the URLs of a GitHub instance from its host URL, which may include a port. Hosts other than
+// github.com and GHE.com are taken to be GitHub Enterprise Server.
 func parseAPIHost(s string) (apiHost, error) {
 	if s == "" {
 		return newDotcomHost()
@@ -374,17 +964,73 @@ func parseAPIHost(s string) (apiHost, error) {
 		return apiHost{}, fmt.Errorf("host must have a scheme (http or https): %s", s)
 	}
 
-	if strings.HasSuffix(u.Hostname(), "github.com") {
+	hostname := u.Hostname()
+	if hostname == "github.com" || strings.HasSuffix(hostname, ".github.com") {
 		return newDotcomHost()
 	}
 
-	if strings.HasSuffix(u.Hostname(), "ghe.com") {
+	if strings.HasSuffix(hostname, ".ghe.com") {
 		return newGHECHost(s)
 	}
 
 	return newGHESHost(s)
 }
 
+// Endpoints overrides URLs otherwise derived from the host, for GitHub instances whose APIs are reached at other
+// addresses, such as through a gateway. Empty URLs are derived from the host.
+type Endpoints struct {
+	REST    string
+	GraphQL string
+	Upload  string
+	Raw     string
+}
+
+// newAPIHost returns the URLs of the GitHub instance at host, with any overridden by endpoints.
+func newAPIHost(host string, endpoints Endpoints) (apiHost, error) {
+	h, err := parseAPIHost(host)
+	if err != nil {
+		return apiHost{}, err
+	}
+
+	overrides := []struct {
+		name  string
+		value string
+		url   **url.URL
+		// dir marks URLs other paths are resolved against, which must end with a slash
+		dir bool
+	}{
+		{"REST API", endpoints.REST, &h.baseRESTURL, true},
+		{"GraphQL API", endpoints.GraphQL, &h.graphqlURL, false},
+		{"upload", endpoints.Upload, &h.uploadURL, true},
+		{"raw content", endpoints.Raw, &h.rawURL, true},
+	}
+	for _, o := range overrides {
+		if o.value == "" {
+			continue
+		}
+		u, err := url.Parse(o.value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return apiHost{}, fmt.Errorf("%s URL must be an absolute http or https URL: %s", o.name, o.value)
+		}
+		if o.dir && !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		*o.url = u
+	}
+	return h, nil
+}
+
+// hosts returns the hosts, with their ports, that requests to the GitHub instance are sent to.
+func (h apiHost) hosts() []string {
+	var hosts []string
+	for _, u := range []*url.URL{h.baseRESTURL, h.graphqlURL, h.uploadURL, h.rawURL} {
+		if u != nil && !slices.Contains(hosts, u.Host) {
+			hosts = append(hosts, u.Host)
+		}
+	}
+	return hosts
+}
+
 type userAgentTransport struct {
 	transport http.RoundTripper
 	agent     string
@@ -396,13 +1042,23 @@ func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error
 	return t.transport.RoundTrip(req)
 }
 
+// bearerAuthTransport authenticates requests to GitHub. Requests to other hosts, such as the storage workflow logs
+// are downloaded from, are sent without the token.
 type bearerAuthTransport struct {
 	transport http.RoundTripper
-	token     string
+	token     auth.TokenSource
+	hosts     []string
 }
 
 func (t *bearerAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !slices.Contains(t.hosts, req.URL.Host) {
+		return t.transport.RoundTrip(req)
+	}
+	token, err := t.token.Token(req.Context())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub token: %w", err)
+	}
 	req = req.Clone(req.Context())
-	req.Header.Set("Authorization", "Bearer "+t.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	return t.transport.RoundTrip(req)
 }
@@ -0,0 +1,517 @@
+package ghmcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/github/github-mcp-server/pkg/auth"
+	"github.com/github/github-mcp-server/pkg/cassette"
+	"github.com/github/github-mcp-server/pkg/scopes"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newAPIHost(t *testing.T) {
+	tests := []struct {
+		name            string
+		host            string
+		endpoints       Endpoints
+		expectedREST    string
+		expectedGraphQL string
+		expectedUpload  string
+		expectedRaw     string
+		expectedError   string
+	}{
+		{
+			name:            "github.com by default",
+			expectedREST:    "https://api.github.com/",
+			expectedGraphQL: "https://api.github.com/graphql",
+			expectedUpload:  "https://uploads.github.com",
+			expectedRaw:     "https://raw.githubusercontent.com/",
+		},
+		{
+			name:            "GHEC",
+			host:            "https://tenant.ghe.com",
+			expectedREST:    "https://api.tenant.ghe.com/",
+			expectedGraphQL: "https://api.tenant.ghe.com/graphql",
+			expectedUpload:  "https://uploads.tenant.ghe.com",
+			expectedRaw:     "https://raw.tenant.ghe.com/",
+		},
+		{
+			name:            "GHES on a non-standard port",
+			host:            "https://ghes.example.com:8443",
+			expectedREST:    "https://ghes.example.com:8443/api/v3/",
+			expectedGraphQL: "https://ghes.example.com:8443/api/graphql",
+			expectedUpload:  "https://ghes.example.com:8443/api/uploads/",
+			expectedRaw:     "https://ghes.example.com:8443/raw/",
+		},
+		{
+			name:            "hosts merely ending in github.com are GHES",
+			host:            "https://notgithub.com",
+			expectedREST:    "https://notgithub.com/api/v3/",
+			expectedGraphQL: "https://notgithub.com/api/graphql",
+			expectedUpload:  "https://notgithub.com/api/uploads/",
+			expectedRaw:     "https://notgithub.com/raw/",
+		},
+		{
+			name: "explicit endpoints",
+			host: "https://ghes.example.com",
+			endpoints: Endpoints{
+				REST:    "https://gateway.example.com/github/api",
+				GraphQL: "https://gateway.example.com/github/graphql",
+				Raw:     "https://raw.example.com:9443/",
+			},
+			expectedREST:    "https://gateway.example.com/github/api/",
+			expectedGraphQL: "https://gateway.example.com/github/graphql",
+			expectedUpload:  "https://ghes.example.com/api/uploads/",
+			expectedRaw:     "https://raw.example.com:9443/",
+		},
+		{
+			name:          "relative endpoints are rejected",
+			host:          "https://ghes.example.com",
+			endpoints:     Endpoints{GraphQL: "/api/graphql"},
+			expectedError: "GraphQL API URL must be an absolute http or https URL: /api/graphql",
+		},
+		{
+			name:          "hosts without a scheme are rejected",
+			host:          "ghes.example.com",
+			expectedError: "host must have a scheme (http or https): ghes.example.com",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := newAPIHost(tc.host, tc.endpoints)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedREST, h.baseRESTURL.String())
+			assert.Equal(t, tc.expectedGraphQL, h.graphqlURL.String())
+			assert.Equal(t, tc.expectedUpload, h.uploadURL.String())
+			assert.Equal(t, tc.expectedRaw, h.rawURL.String())
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func Test_bearerAuthTransport_OnlyAuthenticatesGitHub(t *testing.T) {
+	authorization := map[string]string{}
+	client := &http.Client{Transport: &bearerAuthTransport{
+		transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			authorization[req.URL.Host] = req.Header.Get("Authorization")
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+		token: auth.StaticTokenSource("token"),
+		hosts: []string{"ghes.example.com:8443"},
+	}}
+
+	for _, target := range []string{"https://ghes.example.com:8443/api/v3/user", "https://ghes.example.com/api/v3/user", "https://logs.example.com/job/1"} {
+		resp, err := client.Get(target)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+	assert.Equal(t, map[string]string{
+		"ghes.example.com:8443": "Bearer token",
+		"ghes.example.com":      "",
+		"logs.example.com":      "",
+	}, authorization)
+}
+
+func Test_NewMCPServer_RoutesCallsToHosts(t *testing.T) {
+	var requests []string
+	newHost := func(name string) *httptest.Server {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, name+" "+r.Header.Get("Authorization")+" "+r.URL.Path)
+			_, _ = w.Write([]byte(`{"login":"octocat","number":1}`))
+		}))
+		t.Cleanup(ts.Close)
+		return ts
+	}
+	defaultHost, ghes := newHost("default"), newHost("ghes")
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			Version: "test",
+			Host:    defaultHost.URL,
+			Token:   "default-token",
+			Hosts: []HostConfig{{
+				Name:   "ghes",
+				URL:    ghes.URL,
+				Token:  "ghes-token",
+				Owners: []string{"corp-*"},
+			}},
+			EnabledToolsets: []string{"context", "issues", "repos"},
+		},
+		Translator: translations.NullTranslationHelper,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		tool             string
+		args             map[string]any
+		expectedRequest  string
+		expectedErrorMsg string
+	}{
+		{
+			name:            "default host",
+			tool:            "get_me",
+			args:            map[string]any{},
+			expectedRequest: "default Bearer default-token /api/v3/user",
+		},
+		{
+			name:            "host argument",
+			tool:            "get_me",
+			args:            map[string]any{"host": "ghes"},
+			expectedRequest: "ghes Bearer ghes-token /api/v3/user",
+		},
+		{
+			name:            "owner mapped to a host",
+			tool:            "get_issue",
+			args:            map[string]any{"owner": "corp-platform", "repo": "api", "issue_number": 1},
+			expectedRequest: "ghes Bearer ghes-token /api/v3/repos/corp-platform/api/issues/1",
+		},
+		{
+			name:            "host argument taking precedence over the owner",
+			tool:            "get_issue",
+			args:            map[string]any{"owner": "corp-platform", "repo": "api", "issue_number": 1, "host": "default"},
+			expectedRequest: "default Bearer default-token /api/v3/repos/corp-platform/api/issues/1",
+		},
+		{
+			name:             "unknown host",
+			tool:             "get_me",
+			args:             map[string]any{"host": "gitlab"},
+			expectedErrorMsg: `unknown host "gitlab", expected one of: default, ghes`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests = nil
+			message, err := json.Marshal(map[string]any{
+				"jsonrpc": "2.0",
+				"id":      1,
+				"method":  "tools/call",
+				"params":  map[string]any{"name": tc.tool, "arguments": tc.args},
+			})
+			require.NoError(t, err)
+			response, ok := ghServer.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+			require.True(t, ok)
+			result, ok := response.Result.(mcp.CallToolResult)
+			require.True(t, ok)
+
+			if tc.expectedErrorMsg != "" {
+				require.True(t, result.IsError)
+				assert.Equal(t, tc.expectedErrorMsg, result.Content[0].(mcp.TextContent).Text)
+				assert.Empty(t, requests)
+				return
+			}
+			require.False(t, result.IsError, result.Content)
+			assert.Equal(t, []string{tc.expectedRequest}, requests)
+		})
+	}
+
+	t.Run("resource read naming a host", func(t *testing.T) {
+		requests = nil
+		response, ok := ghServer.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"repo://octo/api/contents/README.md?host=ghes"}}`)).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		require.IsType(t, mcp.ReadResourceResult{}, response.Result)
+		require.NotEmpty(t, requests)
+		assert.Contains(t, requests[0], "ghes Bearer ghes-token ")
+	})
+}
+
+func Test_NewMCPServer_RequestTokensAreNotLentHostTokens(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	defer ts.Close()
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			Version:         "test",
+			Host:            ts.URL,
+			Hosts:           []HostConfig{{Name: "ghes", URL: ts.URL, Token: "operator-ghes-token"}},
+			EnabledToolsets: []string{"context"},
+		},
+		Translator: translations.NullTranslationHelper,
+	})
+	require.NoError(t, err)
+
+	call := func(ctx context.Context) mcp.CallToolResult {
+		message, err := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  "tools/call",
+			"params":  map[string]any{"name": "get_me", "arguments": map[string]any{"host": "ghes"}},
+		})
+		require.NoError(t, err)
+		response, ok := ghServer.HandleMessage(ctx, message).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		result, ok := response.Result.(mcp.CallToolResult)
+		require.True(t, ok)
+		return result
+	}
+
+	// A session authenticated with its own token must not act on another host as the operator
+	result := call(ContextWithToken(context.Background(), "any-string"))
+	require.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "no GitHub token provided for host ghes")
+	assert.Empty(t, requests)
+
+	ctx := ContextWithHostTokens(ContextWithToken(context.Background(), "user-token"), map[string]string{"ghes": "user-ghes-token"})
+	result = call(ctx)
+	require.False(t, result.IsError, result.Content)
+	assert.Equal(t, []string{"Bearer user-ghes-token"}, requests)
+}
+
+func Test_NewMCPServer_OwnerTokens(t *testing.T) {
+	var authorization []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"number":1}`))
+	}))
+	defer ts.Close()
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			Version:         "test",
+			Host:            ts.URL,
+			OwnerTokens:     []auth.OwnerToken{{Owner: "corp", Token: "corp-token"}},
+			EnabledToolsets: []string{"issues"},
+		},
+		Translator: translations.NullTranslationHelper,
+	})
+	require.NoError(t, err)
+
+	callGetIssue := func(owner string) mcp.JSONRPCMessage {
+		message, err := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  "tools/call",
+			"params": map[string]any{
+				"name":      "get_issue",
+				"arguments": map[string]any{"owner": owner, "repo": "api", "issue_number": 1},
+			},
+		})
+		require.NoError(t, err)
+		return ghServer.HandleMessage(context.Background(), message)
+	}
+
+	response, ok := callGetIssue("corp").(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	assert.False(t, response.Result.(mcp.CallToolResult).IsError)
+	assert.Equal(t, []string{"Bearer corp-token"}, authorization)
+
+	// Without a default token, calls acting on other owners fail before reaching GitHub
+	authorization = nil
+	failure, ok := callGetIssue("octocat").(mcp.JSONRPCError)
+	require.True(t, ok)
+	assert.Contains(t, failure.Error.Message, "no GitHub token configured for owner octocat")
+	assert.Empty(t, authorization)
+}
+
+func Test_NewMCPServer_ScopeCheck(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("X-OAuth-Scopes", "public_repo, read:org")
+		_, _ = w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	defer ts.Close()
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			Version:         "test",
+			Host:            ts.URL,
+			Token:           "classic-token",
+			EnabledToolsets: []string{"actions"},
+			DynamicToolsets: true,
+			ScopeCheck:      scopes.ModeHide,
+		},
+		Translator: translations.NullTranslationHelper,
+	})
+	require.NoError(t, err)
+
+	handle := func(method string, params map[string]any) any {
+		message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		require.NoError(t, err)
+		response, ok := ghServer.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		return response.Result
+	}
+
+	list, ok := handle("tools/list", map[string]any{}).(mcp.ListToolsResult)
+	require.True(t, ok)
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.Contains(t, names, "list_workflows")
+	assert.NotContains(t, names, "run_workflow", "the token lacks the repo scope")
+	assert.Equal(t, []string{"GET /api/v3/user"}, requests, "scopes are looked up once per token")
+
+	result := handle("tools/call", map[string]any{
+		"name":      "run_workflow",
+		"arguments": map[string]any{"owner": "octocat", "repo": "hello-world", "workflow_id": "ci.yml", "ref": "main"},
+	}).(mcp.CallToolResult)
+	require.True(t, result.IsError)
+	assert.Equal(t, "run_workflow is not available: the token lacks the repo scope", result.Content[0].(mcp.TextContent).Text)
+
+	result = handle("tools/call", map[string]any{"name": "list_available_toolsets", "arguments": map[string]any{}}).(mcp.CallToolResult)
+	var toolsets []struct {
+		Name             string            `json:"name"`
+		UnavailableTools map[string]string `json:"unavailable_tools"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &toolsets))
+	for _, toolset := range toolsets {
+		if toolset.Name == "actions" {
+			assert.Equal(t, "the token lacks the repo scope", toolset.UnavailableTools["run_workflow"])
+		}
+		if toolset.Name == "context" {
+			assert.Empty(t, toolset.UnavailableTools)
+		}
+	}
+	assert.Len(t, requests, 1)
+}
+
+func Test_NewMCPServer_ScopeCheckRemembersRefusedPermissions(t *testing.T) {
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/api/v3/user" {
+			// Fine-grained tokens have no scopes to report
+			_, _ = w.Write([]byte(`{"login":"octocat"}`))
+			return
+		}
+		w.Header().Set(scopes.PermissionsHeader, "actions=read")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Resource not accessible by personal access token"}`))
+	}))
+	defer ts.Close()
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			Version:         "test",
+			Host:            ts.URL,
+			Token:           "fine-grained-token",
+			EnabledToolsets: []string{"actions"},
+			DynamicToolsets: true,
+			ScopeCheck:      scopes.ModeHide,
+		},
+		Translator: translations.NullTranslationHelper,
+	})
+	require.NoError(t, err)
+
+	handle := func(method string, params map[string]any) any {
+		message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+		require.NoError(t, err)
+		response, ok := ghServer.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		return response.Result
+	}
+	toolNames := func() []string {
+		list, ok := handle("tools/list", map[string]any{}).(mcp.ListToolsResult)
+		require.True(t, ok)
+		var names []string
+		for _, tool := range list.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	listWorkflows := map[string]any{"name": "list_workflows", "arguments": map[string]any{"owner": "octocat", "repo": "hello-world"}}
+
+	assert.Contains(t, toolNames(), "list_workflows", "tools are offered until GitHub refuses them")
+
+	message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": listWorkflows})
+	require.NoError(t, err)
+	_, failed := ghServer.HandleMessage(context.Background(), message).(mcp.JSONRPCError)
+	assert.True(t, failed)
+	assert.Len(t, requests, 2)
+
+	assert.NotContains(t, toolNames(), "list_workflows")
+	result := handle("tools/call", listWorkflows).(mcp.CallToolResult)
+	require.True(t, result.IsError)
+	assert.Equal(t, "list_workflows is not available: GitHub refused the token, which needs the actions=read permissions", result.Content[0].(mcp.TextContent).Text)
+	assert.Len(t, requests, 2, "calls GitHub refused are not sent again")
+
+	result = handle("tools/call", map[string]any{"name": "list_available_toolsets", "arguments": map[string]any{}}).(mcp.CallToolResult)
+	var toolsets []struct {
+		Name             string            `json:"name"`
+		UnavailableTools map[string]string `json:"unavailable_tools"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &toolsets))
+	for _, toolset := range toolsets {
+		if toolset.Name == "actions" {
+			assert.Equal(t, "GitHub refused the token, which needs the actions=read permissions", toolset.UnavailableTools["list_workflows"])
+		}
+	}
+}
+
+func Test_NewMCPServer_RecordAndReplay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/repos/octo/hello/issues/1", r.URL.Path)
+		_, _ = w.Write([]byte(`{"number":1,"title":"Recorded issue","state":"open"}`))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	callTool := func(transport http.RoundTripper, issue int) mcp.JSONRPCMessage {
+		ghServer, err := NewMCPServer(MCPServerConfig{
+			ServerOptions: ServerOptions{
+				Version:         "test",
+				Host:            ts.URL,
+				Token:           "recording-token",
+				EnabledToolsets: []string{"issues"},
+				ScopeCheck:      scopes.ModeOff,
+			},
+			Transport:  transport,
+			Translator: translations.NullTranslationHelper,
+		})
+		require.NoError(t, err)
+		message, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": map[string]any{
+			"name":      "get_issue",
+			"arguments": map[string]any{"owner": "octo", "repo": "hello", "issue_number": issue},
+		}})
+		require.NoError(t, err)
+		return ghServer.HandleMessage(context.Background(), message)
+	}
+
+	recorder, err := cassette.NewRecorder(http.DefaultTransport, dir)
+	require.NoError(t, err)
+	recorded, ok := callTool(recorder, 1).(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	require.False(t, recorded.Result.(mcp.CallToolResult).IsError)
+
+	// GitHub is gone, the replay answers in its place
+	ts.Close()
+	replayer, err := cassette.NewReplayer(dir)
+	require.NoError(t, err)
+	replayed := callTool(replayer, 1)
+	assert.Equal(t, recorded, replayed)
+
+	unmatched, ok := callTool(replayer, 2).(mcp.JSONRPCError)
+	require.True(t, ok, "requests that were not recorded fail the call")
+	assert.Contains(t, unmatched.Error.Message, "no response recorded in "+dir)
+	assert.Equal(t, []string{"GET " + ts.URL + "/api/v3/repos/octo/hello/issues/2"}, replayer.Unmatched())
+}
+
+func Test_cassetteTransport(t *testing.T) {
+	_, _, err := cassetteTransport(http.DefaultTransport, t.TempDir(), t.TempDir(), logrus.New())
+	assert.EqualError(t, err, "a session cannot be recorded and replayed at once")
+
+	dir := t.TempDir()
+	_, _, err = cassetteTransport(http.DefaultTransport, "", dir, logrus.New())
+	assert.EqualError(t, err, "no cassettes found in "+dir)
+}
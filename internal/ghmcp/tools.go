@@ -0,0 +1,277 @@
+package ghmcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/github/github-mcp-server/pkg/github"
+	"github.com/github/github-mcp-server/pkg/raw"
+	"github.com/github/github-mcp-server/pkg/translations"
+	gogithub "github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/shurcooL/githubv4"
+)
+
+const (
+	// FormatTable prints tools as a table for people to read.
+	FormatTable = "table"
+
+	// FormatJSON prints tools as JSON for scripts to read.
+	FormatJSON = "json"
+)
+
+// errNoClient is returned to tools that are described rather than called, and so have no client to call GitHub with.
+var errNoClient = errors.New("tools are not called when described")
+
+// ToolsConfig selects the tools the tools command describes, as they would be offered by a server with the same
+// configuration.
+type ToolsConfig struct {
+	// EnabledToolsets is a list of toolsets to enable
+	EnabledToolsets []string
+
+	// Tools restricts the tools offered within the enabled toolsets to those listed, if not empty
+	Tools []string
+
+	// ExcludeTools lists tools that are not offered even if their toolset is enabled
+	ExcludeTools []string
+
+	// ReadOnly indicates if only read-only tools are offered
+	ReadOnly bool
+
+	// DynamicToolsets offers the tools enabling toolsets on request, with only the toolsets listed by
+	// EnabledToolsets, other than "all", enabled from the start
+	DynamicToolsets bool
+
+	// DryRun leaves out the dry_run parameter of write tools, every call to them being a dry run
+	DryRun bool
+
+	// Hosts add the host parameter to tools. Only their names and owners are used.
+	Hosts []HostConfig
+
+	// All includes the tools of the toolsets that are not enabled
+	All bool
+
+	// Format is FormatTable or FormatJSON
+	Format string
+
+	// Translator provides translated text for the tools, as the server would
+	Translator translations.TranslationHelperFunc
+
+	// Out is where the tools are printed
+	Out io.Writer
+}
+
+// toolEntry is a tool along with the toolset offering it.
+type toolEntry struct {
+	tool    mcp.Tool
+	toolset string
+	enabled bool
+}
+
+// toolSummary is how a tool is listed as JSON.
+type toolSummary struct {
+	Name        string             `json:"name"`
+	Title       string             `json:"title,omitempty"`
+	Toolset     string             `json:"toolset"`
+	Enabled     bool               `json:"enabled"`
+	Annotations mcp.ToolAnnotation `json:"annotations"`
+}
+
+// toolDetails is how a tool is described as JSON, the tool being as the server lists it.
+type toolDetails struct {
+	Toolset string   `json:"toolset"`
+	Enabled bool     `json:"enabled"`
+	Tool    mcp.Tool `json:"tool"`
+}
+
+// selectTools returns the tools the server would offer with cfg, described as it does, or every tool it can offer if
+// cfg.All is set, ordered by toolset and name.
+func selectTools(cfg ToolsConfig) ([]toolEntry, error) {
+	if cfg.Format != FormatTable && cfg.Format != FormatJSON {
+		return nil, fmt.Errorf("unknown format %q, expected %s or %s", cfg.Format, FormatTable, FormatJSON)
+	}
+
+	t := cfg.Translator
+	if t == nil {
+		t = translations.NullTranslationHelper
+	}
+	tsg, _, err := newToolsetGroup(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			EnabledToolsets: cfg.EnabledToolsets,
+			DynamicToolsets: cfg.DynamicToolsets,
+			Tools:           cfg.Tools,
+			ExcludeTools:    cfg.ExcludeTools,
+			Hosts:           cfg.Hosts,
+			ReadOnly:        cfg.ReadOnly,
+			DryRun:          cfg.DryRun,
+		},
+		Translator: t,
+	},
+		func(context.Context) (*gogithub.Client, error) { return nil, errNoClient },
+		func(context.Context) (*githubv4.Client, error) { return nil, errNoClient },
+		func(context.Context) (*raw.Client, error) { return nil, errNoClient },
+	)
+	if err != nil {
+		return nil, err
+	}
+	groups := maps.Clone(tsg.Toolsets)
+	if cfg.DynamicToolsets {
+		dynamic := github.InitDynamicToolset(nil, tsg, t)
+		groups[dynamic.Name] = dynamic
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var entries []toolEntry
+	for _, name := range names {
+		toolset := groups[name]
+		if !toolset.Enabled && !cfg.All {
+			continue
+		}
+		tools := slices.Clone(toolset.GetAvailableTools())
+		sort.Slice(tools, func(i, j int) bool { return tools[i].Tool.Name < tools[j].Tool.Name })
+		for _, tool := range tools {
+			entries = append(entries, toolEntry{tool: tool.Tool, toolset: name, enabled: toolset.Enabled})
+		}
+	}
+	return entries, nil
+}
+
+// ListTools prints the name, title, toolset and annotations of each tool.
+func ListTools(cfg ToolsConfig) error {
+	entries, err := selectTools(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Format == FormatJSON {
+		summaries := make([]toolSummary, 0, len(entries))
+		for _, e := range entries {
+			summaries = append(summaries, toolSummary{
+				Name:        e.tool.Name,
+				Title:       e.tool.Annotations.Title,
+				Toolset:     e.toolset,
+				Enabled:     e.enabled,
+				Annotations: e.tool.Annotations,
+			})
+		}
+		return writeIndentedJSON(cfg.Out, summaries)
+	}
+
+	w := tabwriter.NewWriter(cfg.Out, 0, 4, 2, ' ', 0)
+	header := "NAME\tTOOLSET\tANNOTATIONS\tTITLE"
+	if cfg.All {
+		header += "\tENABLED"
+	}
+	_, _ = fmt.Fprintln(w, header)
+	for _, e := range entries {
+		row := fmt.Sprintf("%s\t%s\t%s\t%s", e.tool.Name, e.toolset, annotationSummary(e.tool.Annotations), e.tool.Annotations.Title)
+		if cfg.All {
+			row += "\t" + yesNo(e.enabled)
+		}
+		_, _ = fmt.Fprintln(w, row)
+	}
+	return w.Flush()
+}
+
+// DescribeTool prints everything there is to know about the tool called name, including the JSON schema of its
+// input, whether or not its toolset is enabled.
+func DescribeTool(cfg ToolsConfig, name string) error {
+	cfg.All = true
+	entries, err := selectTools(cfg)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(entries, func(e toolEntry) bool { return e.tool.Name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown tool %q, or not offered with the current configuration", name)
+	}
+	e := entries[i]
+
+	if cfg.Format == FormatJSON {
+		return writeIndentedJSON(cfg.Out, toolDetails{Toolset: e.toolset, Enabled: e.enabled, Tool: e.tool})
+	}
+
+	schema, err := json.MarshalIndent(toolInputSchema(e.tool), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal input schema of %s: %w", name, err)
+	}
+	_, _ = fmt.Fprintf(cfg.Out, "%s\n", e.tool.Name)
+	if e.tool.Annotations.Title != "" {
+		_, _ = fmt.Fprintf(cfg.Out, "  Title: %s\n", e.tool.Annotations.Title)
+	}
+	_, _ = fmt.Fprintf(cfg.Out, "  Toolset: %s (enabled: %s)\n", e.toolset, yesNo(e.enabled))
+	_, _ = fmt.Fprintf(cfg.Out, "  Annotations: %s\n", annotationSummary(e.tool.Annotations))
+	_, _ = fmt.Fprintf(cfg.Out, "\n%s\n\nInput schema:\n%s\n", e.tool.Description, schema)
+	return nil
+}
+
+// PrintToolSchemas prints the tools as JSON, as the server lists them in reply to tools/list, whatever the format.
+// What the scope check makes of them, which depends on the token in use, is left out.
+func PrintToolSchemas(cfg ToolsConfig) error {
+	entries, err := selectTools(cfg)
+	if err != nil {
+		return err
+	}
+	tools := make([]mcp.Tool, 0, len(entries))
+	for _, e := range entries {
+		tools = append(tools, e.tool)
+	}
+	return writeIndentedJSON(cfg.Out, mcp.ListToolsResult{Tools: tools})
+}
+
+// toolInputSchema returns the input schema of tool as it is sent to clients.
+func toolInputSchema(tool mcp.Tool) any {
+	if tool.RawInputSchema != nil {
+		return tool.RawInputSchema
+	}
+	return tool.InputSchema
+}
+
+// annotationSummary lists the hints that are set on a tool, e.g. "read-only, idempotent".
+func annotationSummary(a mcp.ToolAnnotation) string {
+	var hints []string
+	for _, hint := range []struct {
+		name  string
+		value *bool
+	}{
+		{"read-only", a.ReadOnlyHint},
+		{"destructive", a.DestructiveHint},
+		{"idempotent", a.IdempotentHint},
+		{"open-world", a.OpenWorldHint},
+	} {
+		if hint.value != nil && *hint.value {
+			hints = append(hints, hint.name)
+		}
+	}
+	if len(hints) == 0 {
+		return "-"
+	}
+	return strings.Join(hints, ", ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func writeIndentedJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,173 @@
+package ghmcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/github/github-mcp-server/pkg/scopes"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ListTools(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, ListTools(ToolsConfig{
+		EnabledToolsets: []string{"issues"},
+		ExcludeTools:    []string{"add_*"},
+		ReadOnly:        true,
+		Format:          FormatTable,
+		Out:             &out,
+	}))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, []string{"NAME", "TOOLSET", "ANNOTATIONS", "TITLE"}, strings.Fields(lines[0]))
+	assert.Regexp(t, `^get_issue\s+issues\s+read-only\s+Get issue details$`, lines[1])
+	for _, line := range lines[1:] {
+		assert.Contains(t, line, " issues ")
+		assert.NotContains(t, line, "add_issue_comment", "excluded tools should not be listed")
+		assert.NotContains(t, line, "create_issue", "write tools should not be listed in read-only mode")
+	}
+}
+
+func Test_ListTools_JSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, ListTools(ToolsConfig{
+		EnabledToolsets: []string{"context"},
+		All:             true,
+		Format:          FormatJSON,
+		Out:             &out,
+	}))
+
+	var summaries []toolSummary
+	require.NoError(t, json.Unmarshal(out.Bytes(), &summaries))
+	byName := map[string]toolSummary{}
+	for _, s := range summaries {
+		byName[s.Name] = s
+	}
+
+	getMe := byName["get_me"]
+	assert.Equal(t, "context", getMe.Toolset)
+	assert.True(t, getMe.Enabled)
+	require.NotNil(t, getMe.Annotations.ReadOnlyHint)
+	assert.True(t, *getMe.Annotations.ReadOnlyHint)
+
+	createIssue := byName["create_issue"]
+	assert.Equal(t, "issues", createIssue.Toolset)
+	assert.False(t, createIssue.Enabled, "tools of toolsets that are not enabled should be listed with --all")
+}
+
+func Test_DescribeTool(t *testing.T) {
+	translator := func(key, defaultValue string) string {
+		if key == "TOOL_GET_ISSUE_DESCRIPTION" {
+			return "Translated description"
+		}
+		return defaultValue
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, DescribeTool(ToolsConfig{
+		EnabledToolsets: []string{"repos"},
+		Format:          FormatTable,
+		Translator:      translator,
+		Out:             &out,
+	}, "get_issue"))
+	assert.Contains(t, out.String(), "get_issue\n  Title: Get issue details\n  Toolset: issues (enabled: no)\n  Annotations: read-only\n\nTranslated description\n")
+	assert.Contains(t, out.String(), `"issue_number": {`)
+
+	out.Reset()
+	require.NoError(t, DescribeTool(ToolsConfig{EnabledToolsets: []string{"all"}, Format: FormatJSON, Out: &out}, "get_issue"))
+	var details struct {
+		Toolset string   `json:"toolset"`
+		Enabled bool     `json:"enabled"`
+		Tool    mcp.Tool `json:"tool"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &details))
+	assert.Equal(t, "issues", details.Toolset)
+	assert.True(t, details.Enabled)
+	assert.Equal(t, "get_issue", details.Tool.Name)
+	assert.Contains(t, details.Tool.InputSchema.Required, "issue_number")
+
+	err := DescribeTool(ToolsConfig{EnabledToolsets: []string{"all"}, ReadOnly: true, Format: FormatTable, Out: &out}, "create_issue")
+	assert.ErrorContains(t, err, `unknown tool "create_issue"`)
+}
+
+func Test_PrintToolSchemas(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, PrintToolSchemas(ToolsConfig{
+		EnabledToolsets: []string{"context"},
+		Format:          FormatTable,
+		Translator:      translations.NullTranslationHelper,
+		Out:             &out,
+	}))
+
+	var result mcp.ListToolsResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	require.NotEmpty(t, result.Tools)
+	for _, tool := range result.Tools {
+		assert.Equal(t, "object", tool.InputSchema.Type, tool.Name)
+	}
+	assert.Equal(t, "get_me", result.Tools[0].Name)
+}
+
+func Test_PrintToolSchemas_MatchesToolsList(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, PrintToolSchemas(ToolsConfig{
+		EnabledToolsets: []string{"issues"},
+		DynamicToolsets: true,
+		Hosts:           []HostConfig{{Name: "ghes"}},
+		Format:          FormatJSON,
+		Translator:      translations.NullTranslationHelper,
+		Out:             &out,
+	}))
+	var printed mcp.ListToolsResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+
+	ghServer, err := NewMCPServer(MCPServerConfig{
+		ServerOptions: ServerOptions{
+			Version:         "test",
+			Token:           "test-token",
+			Hosts:           []HostConfig{{Name: "ghes", URL: "https://ghes.example.com", Token: "ghes-token"}},
+			EnabledToolsets: []string{"issues"},
+			DynamicToolsets: true,
+			ScopeCheck:      scopes.ModeOff,
+		},
+		Translator: translations.NullTranslationHelper,
+	})
+	require.NoError(t, err)
+	response, ok := ghServer.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)).(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	listed := response.Result.(mcp.ListToolsResult)
+
+	schemas := func(tools []mcp.Tool) map[string]string {
+		m := make(map[string]string, len(tools))
+		for _, tool := range tools {
+			schema, err := json.Marshal(toolInputSchema(tool))
+			require.NoError(t, err)
+			m[tool.Name] = string(schema)
+		}
+		return m
+	}
+	expected, actual := schemas(listed.Tools), schemas(printed.Tools)
+	for _, name := range []string{"enable_toolset", "get_toolset_tools"} {
+		// Their toolset enums are in no particular order
+		assert.Contains(t, actual, name)
+		delete(expected, name)
+		delete(actual, name)
+	}
+	assert.Equal(t, expected, actual)
+	assert.Contains(t, actual["create_issue"], `"dry_run"`)
+	assert.Contains(t, actual["create_issue"], `"host"`)
+}
+
+func Test_selectTools_RejectsUnknownFormatsAndToolsets(t *testing.T) {
+	_, err := selectTools(ToolsConfig{Format: "yaml"})
+	assert.ErrorContains(t, err, `unknown format "yaml"`)
+
+	_, err = selectTools(ToolsConfig{EnabledToolsets: []string{"gists"}, Format: FormatTable})
+	assert.ErrorContains(t, err, "failed to enable toolsets")
+}
@@ -14,6 +14,10 @@ type Matcher struct {
 	Variables map[string]any
 
 	Response GQLResponse
+
+	// Resolve, when set, computes the response from the variables of each request instead of returning Response,
+	// in which case the variables are not matched against Variables.
+	Resolve func(variables map[string]any) GQLResponse
 }
 
 // NewQueryMatcher constructs a new matcher for the provided query and variables.
@@ -62,6 +66,24 @@ func NewMutationMatcher(mutation any, input any, variables map[string]any, respo
 	}
 }
 
+// NewQueryResolver constructs a new matcher for the provided query, answered with the response resolve computes
+// from the variables of each request. The provided variables are only used to declare their types in the query,
+// so their values do not matter.
+func NewQueryResolver(query any, variables map[string]any, resolve func(variables map[string]any) GQLResponse) Matcher {
+	m := NewQueryMatcher(query, variables, GQLResponse{})
+	m.Resolve = resolve
+	return m
+}
+
+// NewMutationResolver constructs a new matcher for the provided mutation, answered with the response resolve
+// computes from the variables of each request, the input being found under the "input" key. As with
+// NewQueryResolver, the values of input and variables do not matter, only their types.
+func NewMutationResolver(mutation any, input any, variables map[string]any, resolve func(variables map[string]any) GQLResponse) Matcher {
+	m := NewMutationMatcher(mutation, input, variables, GQLResponse{})
+	m.Resolve = resolve
+	return m
+}
+
 type GQLResponse struct {
 	Data   map[string]any `json:"data"`
 	Errors []struct {
@@ -149,13 +171,23 @@ func githubv4InputStructToMap(s any) (map[string]any, error) {
 // This client does not currently provide a mechanism for out-of-band errors e.g. returning a 500,
 // and errors are constrained to GQL errors returned in the response body with a 200 status code.
 func NewMockedHTTPClient(ms ...Matcher) *http.Client {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", NewHandler(ms...))
+
+	return &http.Client{Transport: &localRoundTripper{
+		handler: mux,
+	}}
+}
+
+// NewHandler returns the http.Handler behind NewMockedHTTPClient, answering GraphQL requests made to any path
+// with the matchers, so that it can be served alongside other endpoints.
+func NewHandler(ms ...Matcher) http.Handler {
 	matchers := make(map[string]Matcher, len(ms))
 	for _, m := range ms {
 		matchers[m.Request] = m
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -174,7 +206,10 @@ func NewMockedHTTPClient(ms ...Matcher) *http.Client {
 			return
 		}
 
-		if len(gqlRequest.Variables) > 0 {
+		response := matcher.Response
+		if matcher.Resolve != nil {
+			response = matcher.Resolve(gqlRequest.Variables)
+		} else if len(gqlRequest.Variables) > 0 {
 			if len(gqlRequest.Variables) != len(matcher.Variables) {
 				http.Error(w, "variables do not have the same length", http.StatusBadRequest)
 				return
@@ -188,7 +223,7 @@ func NewMockedHTTPClient(ms ...Matcher) *http.Client {
 			}
 		}
 
-		responseBody, err := json.Marshal(matcher.Response)
+		responseBody, err := json.Marshal(response)
 		if err != nil {
 			http.Error(w, "error marshalling response", http.StatusInternalServerError)
 			return
@@ -198,10 +233,6 @@ func NewMockedHTTPClient(ms ...Matcher) *http.Client {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write(responseBody)
 	})
-
-	return &http.Client{Transport: &localRoundTripper{
-		handler: mux,
-	}}
 }
 
 type gqlRequest struct {
@@ -0,0 +1,297 @@
+// Package audit records every call to a tool that can change data on GitHub in an append-only JSONL file, for
+// reviewing what agents did.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/dryrun"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultMaxBytes is the default size the audit log is rotated at.
+	DefaultMaxBytes = 100 << 20
+
+	// Redacted replaces secrets in the recorded arguments.
+	Redacted = "[REDACTED]"
+
+	// maxValueLength bounds the length of a recorded argument, longer values such as file contents are recorded
+	// by their size and digest.
+	maxValueLength = 64 << 10
+	// maxReferences bounds the number of URLs and SHAs recorded from a result.
+	maxReferences = 20
+)
+
+// Config configures the audit log.
+type Config struct {
+	// Path is the file entries are appended to.
+	Path string
+
+	// MaxBytes is the size the file is rotated at, defaulting to DefaultMaxBytes. Negative values disable rotation.
+	MaxBytes int64
+
+	// MaxBackups is how many rotated files are kept, keeping all of them if zero.
+	MaxBackups int
+}
+
+// Entry records one tool call.
+type Entry struct {
+	Time      time.Time           `json:"time"`
+	Tool      string              `json:"tool"`
+	Owner     string              `json:"owner,omitempty"`
+	Repo      string              `json:"repo,omitempty"`
+	Arguments map[string]any      `json:"arguments"`
+	SessionID string              `json:"session_id,omitempty"`
+	Client    *mcp.Implementation `json:"client,omitempty"`
+	Outcome   string              `json:"outcome"`
+	Error     string              `json:"error,omitempty"`
+	DryRun    bool                `json:"dry_run,omitempty"`
+	URLs      []string            `json:"urls,omitempty"`
+	SHAs      []string            `json:"shas,omitempty"`
+}
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// Logger appends entries to the audit log.
+type Logger struct {
+	mu      sync.Mutex
+	out     io.WriteCloser
+	now     func() time.Time
+	clients map[string]mcp.Implementation
+}
+
+// Open opens the audit log for appending, creating it if needed.
+func Open(cfg Config) (*Logger, error) {
+	if cfg.MaxBytes == 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	out, err := openRotatingFile(cfg.Path, cfg.MaxBytes, cfg.MaxBackups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return newLogger(out), nil
+}
+
+func newLogger(out io.WriteCloser) *Logger {
+	return &Logger{
+		out:     out,
+		now:     time.Now,
+		clients: make(map[string]mcp.Implementation),
+	}
+}
+
+// Close closes the audit log.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.out.Close()
+}
+
+// SetClientInfo records the client a session belongs to, as reported in its initialize request.
+func (l *Logger) SetClientInfo(sessionID string, info mcp.Implementation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clients[sessionID] = info
+}
+
+// Forget drops what is known about a session once it ends.
+func (l *Logger) Forget(sessionID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.clients, sessionID)
+}
+
+// Log appends an entry to the audit log, as a single line of JSON.
+func (l *Logger) Log(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.out.Write(line)
+	return err
+}
+
+// ToolHandlerMiddleware records every call to the tools it wraps, whether it succeeded or not. It is meant for the
+// write tools of the toolsets, see toolsets.ToolsetGroup.UseWriteToolMiddleware.
+func (l *Logger) ToolHandlerMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		entry := Entry{
+			Time:      l.now().UTC(),
+			Tool:      request.Params.Name,
+			Arguments: redactArguments(args),
+			DryRun:    dryrun.Enabled(ctx),
+		}
+		entry.Owner, _ = args["owner"].(string)
+		entry.Repo, _ = args["repo"].(string)
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			entry.SessionID = session.SessionID()
+		}
+		l.mu.Lock()
+		if info, ok := l.clients[entry.SessionID]; ok {
+			entry.Client = &info
+		}
+		l.mu.Unlock()
+
+		result, err := next(ctx, request)
+
+		switch {
+		case err != nil:
+			entry.Outcome = OutcomeError
+			entry.Error = err.Error()
+		case result != nil && result.IsError:
+			entry.Outcome = OutcomeError
+			entry.Error = resultText(result)
+		default:
+			entry.Outcome = OutcomeSuccess
+			// The URLs and SHAs in the result of a dry run are placeholders
+			if result != nil && !entry.DryRun {
+				entry.URLs, entry.SHAs = references(resultText(result))
+			}
+		}
+
+		if logErr := l.Log(entry); logErr != nil {
+			// The call has already been made, so it cannot be refused, but the gap in the audit trail must be
+			// visible
+			_, _ = fmt.Fprintf(os.Stderr, "failed to write audit log entry for %s: %v\n", entry.Tool, logErr)
+		}
+		return result, err
+	}
+}
+
+// secretKeyPattern matches argument names whose values are secrets.
+var secretKeyPattern = regexp.MustCompile(`(?i)token|secret|password|passphrase|private_?key|credential`)
+
+// tokenPattern matches GitHub tokens appearing anywhere in a value.
+var tokenPattern = regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)
+
+func redactArguments(args map[string]any) map[string]any {
+	redacted := make(map[string]any, len(args))
+	for k, v := range args {
+		if secretKeyPattern.MatchString(k) {
+			redacted[k] = Redacted
+			continue
+		}
+		redacted[k] = redactValue(v)
+	}
+	return redacted
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		v = tokenPattern.ReplaceAllString(v, Redacted)
+		if len(v) > maxValueLength {
+			sum := sha256.Sum256([]byte(v))
+			return fmt.Sprintf("[%d bytes, sha256:%s]", len(v), hex.EncodeToString(sum[:]))
+		}
+		return v
+	case map[string]any:
+		return redactArguments(v)
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// skippedKeys hold objects that existed before the call, such as the parents of a new commit or the base of a pull
+// request, whose URLs and SHAs say nothing about what the call changed.
+var skippedKeys = map[string]bool{
+	"base":         true,
+	"owner":        true,
+	"parents":      true,
+	"repo":         true,
+	"repository":   true,
+	"tree":         true,
+	"user":         true,
+	"author":       true,
+	"committer":    true,
+	"merged_by":    true,
+	"assignee":     true,
+	"assignees":    true,
+	"labels":       true,
+	"milestone":    true,
+	"organization": true,
+}
+
+// references returns the html_url and sha values in a JSON tool result, which identify what the call created or
+// changed. Results that are not JSON have none.
+func references(text string) (urls, shas []string) {
+	var result any
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		return nil, nil
+	}
+
+	seen := map[string]bool{}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			// Walked in order, so the same result is always recorded the same way
+			sort.Strings(keys)
+			for _, k := range keys {
+				item := v[k]
+				if skippedKeys[k] {
+					continue
+				}
+				s, ok := item.(string)
+				switch {
+				case ok && k == "html_url" && !seen[s] && len(urls) < maxReferences:
+					seen[s] = true
+					urls = append(urls, s)
+				case ok && k == "sha" && !seen[s] && len(shas) < maxReferences:
+					seen[s] = true
+					shas = append(shas, s)
+				default:
+					walk(item)
+				}
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	walk(result)
+	return urls, shas
+}
+
+// resultText returns the text content of a tool result.
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			parts = append(parts, text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/dryrun"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func newTestLogger() (*Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := newLogger(nopCloser{&buf})
+	l.now = func() time.Time { return time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC) }
+	return l, &buf
+}
+
+func readEntries(t *testing.T, buf *bytes.Buffer) []Entry {
+	t.Helper()
+	var entries []Entry
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry Entry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func call(l *Logger, name string, args map[string]any, result *mcp.CallToolResult, err error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	_, _ = l.ToolHandlerMiddleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return result, err
+	})(context.Background(), request)
+}
+
+func Test_ToolHandlerMiddleware(t *testing.T) {
+	l, buf := newTestLogger()
+	l.SetClientInfo("", mcp.Implementation{Name: "test-client", Version: "1.2.3"})
+
+	token := "ghp_" + strings.Repeat("a", 36)
+	call(l, "create_or_update_file", map[string]any{
+		"owner":   "octo",
+		"repo":    "hello",
+		"path":    "config.yml",
+		"content": "password: hunter2\ntoken: " + token + "\n",
+		"branch":  "main",
+		"message": "Add config",
+	}, mcp.NewToolResultText(`{
+		"content": {"sha": "blob-sha", "html_url": "https://github.com/octo/hello/blob/main/config.yml"},
+		"commit": {"sha": "commit-sha", "html_url": "https://github.com/octo/hello/commit/commit-sha", "parents": [{"sha": "parent-sha"}], "tree": {"sha": "tree-sha"}}
+	}`), nil)
+	call(l, "create_issue", map[string]any{"owner": "octo", "repo": "hello", "title": "x"},
+		mcp.NewToolResultError("failed to create issue: 403 Resource not accessible"), nil)
+	call(l, "merge_pull_request", map[string]any{"owner": "octo", "repo": "hello", "pullNumber": float64(1)},
+		nil, errors.New("missing required parameter"))
+
+	entries := readEntries(t, buf)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, Entry{
+		Time:  time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Tool:  "create_or_update_file",
+		Owner: "octo",
+		Repo:  "hello",
+		Arguments: map[string]any{
+			"owner":   "octo",
+			"repo":    "hello",
+			"path":    "config.yml",
+			"content": "password: hunter2\ntoken: [REDACTED]\n",
+			"branch":  "main",
+			"message": "Add config",
+		},
+		Client:  &mcp.Implementation{Name: "test-client", Version: "1.2.3"},
+		Outcome: OutcomeSuccess,
+		URLs:    []string{"https://github.com/octo/hello/commit/commit-sha", "https://github.com/octo/hello/blob/main/config.yml"},
+		SHAs:    []string{"commit-sha", "blob-sha"},
+	}, entries[0])
+
+	assert.Equal(t, OutcomeError, entries[1].Outcome)
+	assert.Equal(t, "failed to create issue: 403 Resource not accessible", entries[1].Error)
+	assert.Empty(t, entries[1].URLs)
+
+	assert.Equal(t, OutcomeError, entries[2].Outcome)
+	assert.Equal(t, "missing required parameter", entries[2].Error)
+}
+
+func Test_redactArguments(t *testing.T) {
+	large := strings.Repeat("x", maxValueLength+1)
+	redacted := redactArguments(map[string]any{
+		"github_token":  "anything",
+		"client_secret": map[string]any{"nested": "value"},
+		"files": []any{
+			map[string]any{"path": "a.txt", "content": "github_pat_" + strings.Repeat("b", 30)},
+			map[string]any{"path": "big.bin", "content": large},
+		},
+		"draft": true,
+	})
+
+	assert.Equal(t, Redacted, redacted["github_token"])
+	assert.Equal(t, Redacted, redacted["client_secret"])
+	assert.Equal(t, true, redacted["draft"])
+	files := redacted["files"].([]any)
+	assert.Equal(t, Redacted, files[0].(map[string]any)["content"])
+	assert.Regexp(t, `^\[65537 bytes, sha256:[0-9a-f]{64}\]$`, files[1].(map[string]any)["content"])
+}
+
+func Test_references(t *testing.T) {
+	urls, shas := references(`{"sha": "merge-sha", "merged": true, "message": "Pull Request successfully merged"}`)
+	assert.Empty(t, urls)
+	assert.Equal(t, []string{"merge-sha"}, shas)
+
+	urls, shas = references(`{"html_url": "https://github.com/octo/hello/pull/2", "head": {"sha": "head-sha", "repo": {"html_url": "https://github.com/octo/hello"}}, "base": {"sha": "base-sha"}}`)
+	assert.Equal(t, []string{"https://github.com/octo/hello/pull/2"}, urls)
+	assert.Equal(t, []string{"head-sha"}, shas)
+
+	urls, shas = references("not JSON")
+	assert.Empty(t, urls)
+	assert.Empty(t, shas)
+}
+
+func Test_ToolHandlerMiddleware_DryRun(t *testing.T) {
+	l, buf := newTestLogger()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "merge_pull_request"
+	_, _ = l.ToolHandlerMiddleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(`{"sha": "dry-run-1", "merged": true}`), nil
+	})(dryrun.ContextWithDryRun(context.Background()), request)
+
+	entries := readEntries(t, buf)
+	require.Len(t, entries, 1)
+	assert.True(t, entries[0].DryRun)
+	assert.Equal(t, OutcomeSuccess, entries[0].Outcome)
+	assert.Empty(t, entries[0].SHAs, "placeholders are not recorded")
+}
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+
+	f, err := openRotatingFile(path, 20, 2)
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for _, line := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth line\n", string(current), "lines are never split across files")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Only the two most recent rotated files are kept
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	var contents []string
+	for _, backup := range backups {
+		data, err := os.ReadFile(backup)
+		require.NoError(t, err)
+		contents = append(contents, string(data))
+	}
+	assert.Equal(t, []string{"second line\n", "third line\n"}, contents)
+
+	// Reopening appends to the current file
+	f, err = openRotatingFile(path, 100, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("fifth line\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	current, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth line\nfifth line\n", string(current))
+	assert.True(t, strings.HasPrefix(filepath.Base(backups[0]), "audit.jsonl.20250101T120"))
+}
+
+func Test_rotatingFile_PrunesOnlyRotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	others := []string{"audit.jsonl.1", "audit.jsonl.gz", "audit.jsonl.bak", "audit.jsonl.20250101T120000Z"}
+	for _, name := range others {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("keep\n"), 0600))
+	}
+
+	f, err := openRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	for _, line := range []string{"first line\n", "second line\n", "third line\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	for _, name := range others {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, len(others)+2, "the current file, one rotated file and the others")
+	assert.FileExists(t, filepath.Join(dir, "audit.jsonl.20250101T120002.000000000Z"))
+}
@@ -0,0 +1,225 @@
+// Package auth provides the credentials the server uses to authenticate with the GitHub API.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	gogithub "github.com/google/go-github/v72/github"
+)
+
+const (
+	// jwtLifetime is how long the JWTs minted to authenticate as the app are valid for. GitHub allows at most 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew backdates the JWT issue time to allow for clock drift between us and GitHub.
+	jwtClockSkew = time.Minute
+	// tokenRefreshMargin is how long before expiry an installation token is replaced by a new one.
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+// TokenSource provides the token to authenticate a request with.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenSource is a TokenSource that always returns the same token, such as a personal access token.
+type StaticTokenSource string
+
+// Token implements TokenSource.
+func (s StaticTokenSource) Token(_ context.Context) (string, error) {
+	return string(s), nil
+}
+
+// AppConfig configures authentication as an installation of a GitHub App.
+type AppConfig struct {
+	// AppID is the ID of the GitHub App
+	AppID int64
+
+	// PrivateKeyPath is the path to the PEM encoded private key generated for the app
+	PrivateKeyPath string
+
+	// InstallationID is the ID of the installation to authenticate as
+	InstallationID int64
+
+	// InstallationOwner is the user or organization the app is installed on, used to look up the
+	// installation when InstallationID is not set
+	InstallationOwner string
+}
+
+// Validate checks that the config contains everything needed to authenticate.
+func (c AppConfig) Validate() error {
+	if c.AppID <= 0 {
+		return errors.New("GitHub App ID must be set")
+	}
+	if c.PrivateKeyPath == "" {
+		return errors.New("GitHub App private key file must be set")
+	}
+	if c.InstallationID <= 0 && c.InstallationOwner == "" {
+		return errors.New("either a GitHub App installation ID or installation owner must be set")
+	}
+	return nil
+}
+
+// AppTokenSource is a TokenSource that authenticates as a GitHub App installation. It mints JWTs signed with the
+// app's private key, exchanges them for installation access tokens, and refreshes the token before it expires.
+type AppTokenSource struct {
+	appID          int64
+	key            *rsa.PrivateKey
+	owner          string
+	client         *gogithub.Client
+	now            func() time.Time
+	mu             sync.Mutex
+	installationID int64
+	token          string
+	expiresAt      time.Time
+}
+
+// NewAppTokenSource creates an AppTokenSource for the given config, making app API calls against the REST API at
+// baseURL using transport.
+func NewAppTokenSource(cfg AppConfig, baseURL *url.URL, transport http.RoundTripper) (*AppTokenSource, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	pemData, err := os.ReadFile(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GitHub App private key: %w", err)
+	}
+	key, err := parsePrivateKey(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+
+	ts := &AppTokenSource{
+		appID:          cfg.AppID,
+		key:            key,
+		owner:          cfg.InstallationOwner,
+		installationID: cfg.InstallationID,
+		now:            time.Now,
+	}
+
+	// Requests for installation tokens authenticate as the app itself, with a JWT.
+	client := gogithub.NewClient(&http.Client{Transport: &jwtTransport{transport: transport, source: ts}})
+	client.BaseURL = baseURL
+	ts.client = client
+
+	return ts, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an RSA private key, got %T", parsed)
+	}
+	return key, nil
+}
+
+// Token implements TokenSource, returning a cached installation token while it is not close to expiry.
+func (s *AppTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(tokenRefreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	if s.installationID == 0 {
+		id, err := s.findInstallation(ctx)
+		if err != nil {
+			return "", err
+		}
+		s.installationID = id
+	}
+
+	token, _, err := s.client.Apps.CreateInstallationToken(ctx, s.installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token for installation %d: %w", s.installationID, err)
+	}
+
+	s.token = token.GetToken()
+	s.expiresAt = token.GetExpiresAt().Time
+	return s.token, nil
+}
+
+// findInstallation looks up the app's installation on the configured owner, which may be an organization or a user.
+func (s *AppTokenSource) findInstallation(ctx context.Context) (int64, error) {
+	installation, resp, err := s.client.Apps.FindOrganizationInstallation(ctx, s.owner)
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		installation, _, err = s.client.Apps.FindUserInstallation(ctx, s.owner)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find GitHub App installation for %s: %w", s.owner, err)
+	}
+	return installation.GetID(), nil
+}
+
+// JWT returns a newly signed JWT authenticating as the app.
+func (s *AppTokenSource) JWT() (string, error) {
+	now := s.now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// jwtTransport authenticates requests as the app itself.
+type jwtTransport struct {
+	transport http.RoundTripper
+	source    *AppTokenSource
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	jwt, err := t.source.JWT()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return t.transport.RoundTrip(req)
+}
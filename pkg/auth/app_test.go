@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePrivateKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return key, path
+}
+
+// verifyJWT checks the JWT is signed by key and returns its claims.
+func verifyJWT(t *testing.T, key *rsa.PrivateKey, jwt string) map[string]any {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims map[string]any
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	return claims
+}
+
+func Test_AppConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         AppConfig
+		expectedErr string
+	}{
+		{
+			name:        "missing app ID",
+			cfg:         AppConfig{PrivateKeyPath: "key.pem", InstallationID: 1},
+			expectedErr: "GitHub App ID must be set",
+		},
+		{
+			name:        "missing private key",
+			cfg:         AppConfig{AppID: 1, InstallationID: 1},
+			expectedErr: "GitHub App private key file must be set",
+		},
+		{
+			name:        "missing installation",
+			cfg:         AppConfig{AppID: 1, PrivateKeyPath: "key.pem"},
+			expectedErr: "either a GitHub App installation ID or installation owner must be set",
+		},
+		{
+			name: "installation owner instead of ID",
+			cfg:  AppConfig{AppID: 1, PrivateKeyPath: "key.pem", InstallationOwner: "octo-org"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func Test_AppTokenSource(t *testing.T) {
+	key, keyPath := writePrivateKey(t)
+
+	var tokenRequests int
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/octo-user/installation", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	})
+	mux.HandleFunc("GET /users/octo-user/installation", func(w http.ResponseWriter, r *http.Request) {
+		claims := verifyJWT(t, key, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		assert.Equal(t, "42", claims["iss"])
+		_, _ = w.Write([]byte(`{"id":7}`))
+	})
+	mux.HandleFunc("POST /app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		verifyJWT(t, key, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		tokenRequests++
+		expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		_, _ = fmt.Fprintf(w, `{"token":"ghs_token%d","expires_at":%q}`, tokenRequests, expiresAt)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	baseURL, err := url.Parse(ts.URL + "/")
+	require.NoError(t, err)
+
+	source, err := NewAppTokenSource(AppConfig{
+		AppID:             42,
+		PrivateKeyPath:    keyPath,
+		InstallationOwner: "octo-user",
+	}, baseURL, http.DefaultTransport)
+	require.NoError(t, err)
+
+	now := time.Now()
+	source.now = func() time.Time { return now }
+
+	// The installation is looked up and a token created on first use
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token)
+
+	// The token is reused while it is not close to expiry
+	now = now.Add(30 * time.Minute)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token)
+
+	// The token is refreshed before it expires
+	now = now.Add(26 * time.Minute)
+	token, err = source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token2", token)
+}
+
+func Test_NewAppTokenSource_InvalidKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a key"), 0600))
+
+	_, err := NewAppTokenSource(AppConfig{AppID: 1, PrivateKeyPath: path, InstallationID: 1}, &url.URL{}, http.DefaultTransport)
+	require.ErrorContains(t, err, "failed to parse GitHub App private key")
+}
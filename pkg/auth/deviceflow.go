@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// deviceFlowSlowDownIncrement is added to the polling interval each time GitHub asks us to slow down.
+const deviceFlowSlowDownIncrement = 5 * time.Second
+
+// DeviceCode is the response to a device flow authorization request. The user enters UserCode at VerificationURI
+// to authorize the application.
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// DeviceFlow obtains an OAuth token for an OAuth or GitHub App using the device authorization flow.
+// See: https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps#device-flow
+type DeviceFlow struct {
+	// ClientID is the client ID of the OAuth or GitHub App to authorize
+	ClientID string
+
+	// Scopes are the OAuth scopes to request
+	Scopes []string
+
+	// BaseURL is the URL of the GitHub web host, e.g. https://github.com/
+	BaseURL *url.URL
+
+	// HTTPClient is used to make requests, defaulting to http.DefaultClient
+	HTTPClient *http.Client
+
+	// sleep waits between polls, and is replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// RequestCode starts the device flow, returning the code the user needs to enter.
+func (f *DeviceFlow) RequestCode(ctx context.Context) (*DeviceCode, error) {
+	form := url.Values{
+		"client_id": {f.ClientID},
+		"scope":     {strings.Join(f.Scopes, " ")},
+	}
+
+	var code struct {
+		DeviceCode
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := f.post(ctx, "login/device/code", form, &code); err != nil {
+		return nil, err
+	}
+	if code.Error != "" {
+		return nil, fmt.Errorf("failed to request device code: %s", describeOAuthError(code.Error, code.ErrorDescription))
+	}
+	return &code.DeviceCode, nil
+}
+
+// PollToken waits for the user to authorize the device code, returning the resulting access token.
+func (f *DeviceFlow) PollToken(ctx context.Context, code *DeviceCode) (string, error) {
+	sleep := f.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	form := url.Values{
+		"client_id":   {f.ClientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	for {
+		if err := sleep(ctx, interval); err != nil {
+			return "", err
+		}
+
+		var resp struct {
+			AccessToken      string `json:"access_token"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+			Interval         int    `json:"interval"`
+		}
+		if err := f.post(ctx, "login/oauth/access_token", form, &resp); err != nil {
+			return "", err
+		}
+
+		switch resp.Error {
+		case "":
+			if resp.AccessToken == "" {
+				return "", errors.New("no access token in response")
+			}
+			return resp.AccessToken, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			if resp.Interval > 0 {
+				interval = time.Duration(resp.Interval) * time.Second
+			} else {
+				interval += deviceFlowSlowDownIncrement
+			}
+		default:
+			return "", fmt.Errorf("failed to obtain access token: %s", describeOAuthError(resp.Error, resp.ErrorDescription))
+		}
+	}
+}
+
+func (f *DeviceFlow) post(ctx context.Context, path string, form url.Values, v any) error {
+	client := f.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.BaseURL.JoinPath(path).String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", req.URL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", req.URL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", req.URL, err)
+	}
+	return nil
+}
+
+func describeOAuthError(code, description string) string {
+	if description == "" {
+		return code
+	}
+	return fmt.Sprintf("%s (%s)", description, code)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DeviceFlow(t *testing.T) {
+	var polls int
+	var sleeps []time.Duration
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login/device/code", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client-id", r.PostForm.Get("client_id"))
+		assert.Equal(t, "repo read:org", r.PostForm.Get("scope"))
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		_, _ = w.Write([]byte(`{"device_code":"device","user_code":"ABCD-1234","verification_uri":"https://github.com/login/device","expires_in":900,"interval":5}`))
+	})
+	mux.HandleFunc("POST /login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "device", r.PostForm.Get("device_code"))
+		polls++
+		switch polls {
+		case 1:
+			_, _ = w.Write([]byte(`{"error":"authorization_pending"}`))
+		case 2:
+			_, _ = w.Write([]byte(`{"error":"slow_down","interval":10}`))
+		default:
+			_, _ = w.Write([]byte(`{"access_token":"gho_token","token_type":"bearer"}`))
+		}
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	baseURL, err := url.Parse(ts.URL + "/")
+	require.NoError(t, err)
+
+	flow := &DeviceFlow{
+		ClientID: "client-id",
+		Scopes:   []string{"repo", "read:org"},
+		BaseURL:  baseURL,
+		sleep: func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		},
+	}
+
+	code, err := flow.RequestCode(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ABCD-1234", code.UserCode)
+
+	token, err := flow.PollToken(context.Background(), code)
+	require.NoError(t, err)
+	assert.Equal(t, "gho_token", token)
+	assert.Equal(t, []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second}, sleeps)
+}
+
+func Test_DeviceFlow_AccessDenied(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"error":"access_denied","error_description":"The authorization request was denied."}`))
+	}))
+	defer ts.Close()
+
+	baseURL, err := url.Parse(ts.URL + "/")
+	require.NoError(t, err)
+
+	flow := &DeviceFlow{
+		ClientID: "client-id",
+		BaseURL:  baseURL,
+		sleep:    func(_ context.Context, _ time.Duration) error { return nil },
+	}
+
+	_, err = flow.PollToken(context.Background(), &DeviceCode{DeviceCode: "device", Interval: 5})
+	require.EqualError(t, err, "failed to obtain access token: The authorization request was denied. (access_denied)")
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// OwnerToken is the token to authenticate with when acting on the repositories of the users and organizations
+// matching Owner, a login or glob pattern such as corp-*.
+type OwnerToken struct {
+	Owner string
+	Token string
+}
+
+type ownerKey struct{}
+
+// ContextWithOwner returns a context for requests acting on the repositories of owner.
+func ContextWithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext returns the owner requests made with ctx act on, or an empty string if there is none.
+func OwnerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey{}).(string)
+	return owner
+}
+
+// OwnerTokenSource is a TokenSource choosing the token by the owner the request context acts on (see
+// ContextWithOwner), for organizations that require tokens of their own, such as those enforcing SAML single
+// sign-on.
+type OwnerTokenSource struct {
+	tokens   []OwnerToken
+	fallback TokenSource
+}
+
+// NewOwnerTokenSource returns a token source using the token of the first of tokens whose owner matches, or else
+// fallback, which may be nil.
+func NewOwnerTokenSource(tokens []OwnerToken, fallback TokenSource) (*OwnerTokenSource, error) {
+	for _, t := range tokens {
+		if _, err := path.Match(t.Owner, ""); err != nil {
+			return nil, fmt.Errorf("invalid owner pattern %q: %w", t.Owner, err)
+		}
+		if t.Token == "" {
+			return nil, fmt.Errorf("no token given for owner %s", t.Owner)
+		}
+	}
+	return &OwnerTokenSource{tokens: tokens, fallback: fallback}, nil
+}
+
+// Token implements TokenSource.
+func (s *OwnerTokenSource) Token(ctx context.Context) (string, error) {
+	owner := OwnerFromContext(ctx)
+	if owner != "" {
+		for _, t := range s.tokens {
+			// GitHub logins are case insensitive
+			if ok, _ := path.Match(strings.ToLower(t.Owner), strings.ToLower(owner)); ok {
+				return t.Token, nil
+			}
+		}
+	}
+	if s.fallback != nil {
+		return s.fallback.Token(ctx)
+	}
+	if owner == "" {
+		return "", errors.New("no default GitHub token configured, and the call does not act on an owner with a token of its own")
+	}
+	return "", fmt.Errorf("no GitHub token configured for owner %s", owner)
+}
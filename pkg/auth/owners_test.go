@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OwnerTokenSource(t *testing.T) {
+	tokens := []OwnerToken{
+		{Owner: "corp", Token: "corp-token"},
+		{Owner: "corp-*", Token: "corp-subsidiary-token"},
+	}
+	withFallback, err := NewOwnerTokenSource(tokens, StaticTokenSource("default-token"))
+	require.NoError(t, err)
+	withoutFallback, err := NewOwnerTokenSource(tokens, nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		source        *OwnerTokenSource
+		owner         string
+		expectedToken string
+		expectedError string
+	}{
+		{name: "owner with a token", source: withFallback, owner: "corp", expectedToken: "corp-token"},
+		{name: "owners are case insensitive", source: withFallback, owner: "Corp-Labs", expectedToken: "corp-subsidiary-token"},
+		{name: "other owners use the fallback", source: withFallback, owner: "octocat", expectedToken: "default-token"},
+		{name: "calls without an owner use the fallback", source: withFallback, expectedToken: "default-token"},
+		{name: "owner without a token", source: withoutFallback, owner: "octocat", expectedError: "no GitHub token configured for owner octocat"},
+		{
+			name:          "call without an owner or a fallback",
+			source:        withoutFallback,
+			expectedError: "no default GitHub token configured, and the call does not act on an owner with a token of its own",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.owner != "" {
+				ctx = ContextWithOwner(ctx, tc.owner)
+			}
+			token, err := tc.source.Token(ctx)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedToken, token)
+		})
+	}
+
+	_, err = NewOwnerTokenSource([]OwnerToken{{Owner: "corp-[", Token: "token"}}, nil)
+	assert.EqualError(t, err, `invalid owner pattern "corp-[": syntax error in pattern`)
+	_, err = NewOwnerTokenSource([]OwnerToken{{Owner: "corp"}}, nil)
+	assert.EqualError(t, err, "no token given for owner corp")
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Credential is a token stored for a GitHub host.
+type Credential struct {
+	// Host is the host URL the credential was obtained from, as passed to --gh-host
+	Host      string    `json:"host,omitempty"`
+	Token     string    `json:"token"`
+	User      string    `json:"user,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CredentialStore persists credentials keyed by GitHub host in a JSON file that only the current user can access.
+type CredentialStore struct {
+	Path string
+}
+
+type credentialsFile struct {
+	Hosts map[string]Credential `json:"hosts"`
+}
+
+// DefaultCredentialStore returns the store in the user's configuration directory.
+func DefaultCredentialStore() (*CredentialStore, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return &CredentialStore{Path: filepath.Join(dir, "github-mcp-server", "credentials.json")}, nil
+}
+
+// Get returns the credential stored for host, reporting false if there is none.
+func (s *CredentialStore) Get(host string) (Credential, bool, error) {
+	f, err := s.load()
+	if err != nil {
+		return Credential{}, false, err
+	}
+	cred, ok := f.Hosts[host]
+	return cred, ok, nil
+}
+
+// Hosts returns all stored credentials, keyed by host.
+func (s *CredentialStore) Hosts() (map[string]Credential, error) {
+	f, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return f.Hosts, nil
+}
+
+// Set stores the credential for host, replacing any existing one.
+func (s *CredentialStore) Set(host string, cred Credential) error {
+	f, err := s.load()
+	if err != nil {
+		return err
+	}
+	f.Hosts[host] = cred
+	return s.save(f)
+}
+
+// Delete removes the credential for host, reporting false if there was none.
+func (s *CredentialStore) Delete(host string) (bool, error) {
+	f, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := f.Hosts[host]; !ok {
+		return false, nil
+	}
+	delete(f.Hosts, host)
+	return true, s.save(f)
+}
+
+func (s *CredentialStore) load() (*credentialsFile, error) {
+	f := &credentialsFile{Hosts: map[string]Credential{}}
+
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", s.Path, err)
+	}
+	if f.Hosts == nil {
+		f.Hosts = map[string]Credential{}
+	}
+	return f, nil
+}
+
+func (s *CredentialStore) save(f *credentialsFile) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal credentials: %w", err)
+	}
+
+	// Write to a temporary file first so that a failed write never leaves a truncated credentials file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), ".credentials-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := tmp.Chmod(0600); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to restrict credentials file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.Path); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
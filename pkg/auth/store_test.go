@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CredentialStore(t *testing.T) {
+	store := &CredentialStore{Path: filepath.Join(t.TempDir(), "github-mcp-server", "credentials.json")}
+
+	// An empty store has no credentials
+	_, ok, err := store.Get("github.com")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.Set("github.com", Credential{Token: "gho_dotcom", User: "octocat"}))
+	require.NoError(t, store.Set("ghes.example.com", Credential{Token: "gho_ghes"}))
+
+	cred, ok, err := store.Get("github.com")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "gho_dotcom", cred.Token)
+	assert.Equal(t, "octocat", cred.User)
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(store.Path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	removed, err := store.Delete("github.com")
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = store.Delete("github.com")
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	hosts, err := store.Hosts()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Credential{"ghes.example.com": {Token: "gho_ghes"}}, hosts)
+}
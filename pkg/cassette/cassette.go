@@ -0,0 +1,127 @@
+// Package cassette records the requests the server makes to GitHub, and the responses it gets, into a directory of
+// cassette files, and replays them in place of GitHub, so that sessions can be reproduced without network access.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// strippedHeaders are left out of recorded requests and responses, as they hold credentials.
+var strippedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Interaction is a request made to GitHub and the response it got, stored in a cassette file of its own.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request, without its credentials.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body
+}
+
+// Body holds a request or response body as text, or base64 encoded if it is binary, such as a zip archive.
+type Body struct {
+	Text   string `json:"body,omitempty"`
+	Base64 string `json:"body_base64,omitempty"`
+}
+
+func newBody(data []byte) Body {
+	if utf8.Valid(data) {
+		return Body{Text: string(data)}
+	}
+	return Body{Base64: base64.StdEncoding.EncodeToString(data)}
+}
+
+// Bytes returns the body as it was sent.
+func (b Body) Bytes() ([]byte, error) {
+	if b.Base64 != "" {
+		return base64.StdEncoding.DecodeString(b.Base64)
+	}
+	return []byte(b.Text), nil
+}
+
+// readBody reads, and replaces, the body of a request or response so that it can be read again.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	_ = (*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func stripHeaders(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range strippedHeaders {
+		header.Del(name)
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	return header
+}
+
+// Load returns the interactions recorded in dir, in the order they were recorded.
+func Load(dir string) ([]Interaction, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// File names start with a zero padded sequence number
+	sort.Strings(paths)
+
+	interactions := make([]Interaction, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path) //#nosec G304 -- the directory is given by the user running the server
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		var i Interaction
+		if err := json.Unmarshal(data, &i); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+		interactions = append(interactions, i)
+	}
+	return interactions, nil
+}
+
+// fileName names the cassette file of the nth interaction after its request, e.g. 000042-GET-repos-octo-hello.json.
+func fileName(n int, req *http.Request) string {
+	path := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, strings.Trim(req.URL.Path, "/"))
+	if len(path) > 80 {
+		path = path[:80]
+	}
+	return fmt.Sprintf("%06d-%s-%s.json", n, req.Method, path)
+}
@@ -0,0 +1,128 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func do(t *testing.T, transport http.RoundTripper, method, url, body string) (int, string, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data), nil
+}
+
+func Test_RecordAndReplay(t *testing.T) {
+	issueState := "open"
+	formatted := `{
+  "title": "{\"b\": 1, \"a\": 2}",
+  "password": "hunter2"
+}
+`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPatch:
+			issueState = "closed"
+			_, _ = w.Write([]byte(`{"state":"closed"}`))
+		case r.URL.Path == "/app/installations/1/access_tokens":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"token":"ghs_` + strings.Repeat("x", 36) + `"}`))
+		case r.URL.Path == "/raw":
+			_, _ = w.Write([]byte{0x50, 0x4b, 0x03, 0x04, 0xff})
+		case r.URL.Path == "/formatted":
+			_, _ = w.Write([]byte(formatted))
+		default:
+			_, _ = w.Write([]byte(`{"state":"` + issueState + `"}`))
+		}
+	}))
+	defer ts.Close()
+
+	dir := filepath.Join(t.TempDir(), "cassettes")
+	recorder, err := NewRecorder(http.DefaultTransport, dir)
+	require.NoError(t, err)
+
+	steps := []struct {
+		method, path, body, expected string
+	}{
+		{http.MethodGet, "/repos/octo/hello/issues/1?a=1&b=2", "", `{"state":"open"}`},
+		{http.MethodPatch, "/repos/octo/hello/issues/1", `{"state": "closed"}`, `{"state":"closed"}`},
+		{http.MethodGet, "/repos/octo/hello/issues/1?a=1&b=2", "", `{"state":"closed"}`},
+		{http.MethodPost, "/app/installations/1/access_tokens", "", `{"token":"ghs_` + strings.Repeat("x", 36) + `"}`},
+		{http.MethodGet, "/raw", "", "PK\x03\x04\xff"},
+		{http.MethodGet, "/formatted", "", formatted},
+	}
+	for _, step := range steps {
+		_, body, err := do(t, recorder, step.method, ts.URL+step.path, step.body)
+		require.NoError(t, err)
+		assert.Equal(t, step.expected, body, "responses are passed on while recording")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, len(steps))
+	assert.Equal(t, "000002-PATCH-repos-octo-hello-issues-1.json", filepath.Base(files[1]))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "secret-token", "credentials are not recorded")
+		assert.NotContains(t, string(data), "ghs_", "tokens in bodies are masked")
+	}
+
+	// Replaying, the same requests get the same responses in the same order, with the query parameters and JSON
+	// body written differently, and the bodies of responses are returned as they were written
+	replayer, err := NewReplayer(dir)
+	require.NoError(t, err)
+	replayed := []struct {
+		method, path, body, expected string
+	}{
+		{http.MethodGet, "/repos/octo/hello/issues/1?b=2&a=1", "", `{"state":"open"}`},
+		{http.MethodPatch, "/repos/octo/hello/issues/1", `{"state":"closed"}`, `{"state":"closed"}`},
+		{http.MethodGet, "/repos/octo/hello/issues/1?a=1&b=2", "", `{"state":"closed"}`},
+		{http.MethodGet, "/repos/octo/hello/issues/1?a=1&b=2", "", `{"state":"closed"}`},
+		{http.MethodPost, "/app/installations/1/access_tokens", "", `{"token":"[REDACTED]"}`},
+		{http.MethodGet, "/raw", "", "PK\x03\x04\xff"},
+		{http.MethodGet, "/formatted", "", formatted},
+	}
+	ts.Close()
+	for _, step := range replayed {
+		status, body, err := do(t, replayer, step.method, ts.URL+step.path, step.body)
+		require.NoError(t, err)
+		assert.NotZero(t, status)
+		assert.Equal(t, step.expected, body)
+	}
+	assert.Empty(t, replayer.Unmatched())
+
+	_, _, err = do(t, replayer, http.MethodDelete, ts.URL+"/repos/octo/hello", "")
+	require.ErrorContains(t, err, "no response recorded in "+dir+" for DELETE "+ts.URL+"/repos/octo/hello")
+	assert.Equal(t, []string{"DELETE " + ts.URL + "/repos/octo/hello"}, replayer.Unmatched())
+
+	// Recording again into the directory adds to it
+	recorder, err = NewRecorder(http.DefaultTransport, dir)
+	require.NoError(t, err)
+	assert.Equal(t, len(steps)+1, recorder.next)
+}
+
+func Test_NewReplayer_RequiresCassettes(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewReplayer(dir)
+	assert.EqualError(t, err, "no cassettes found in "+dir)
+}
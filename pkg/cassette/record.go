@@ -0,0 +1,126 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	mcplog "github.com/github/github-mcp-server/pkg/log"
+)
+
+// newRedactor returns the redactor masking tokens, such as those of GitHub App installations, in the bodies of
+// recorded requests and responses.
+func newRedactor() *mcplog.Redactor {
+	// The default rules always compile
+	redactor, _ := mcplog.NewRedactor(mcplog.RedactionConfig{})
+	return redactor
+}
+
+// redactBody returns data with the tokens in it masked, unless it is binary. JSON is left as it was written
+// otherwise, so that replays return the same bytes.
+func redactBody(redactor *mcplog.Redactor, data []byte) []byte {
+	if len(data) == 0 || !utf8.Valid(data) {
+		return data
+	}
+	return redactor.RedactText(data)
+}
+
+// Recorder is a transport passing requests on to another, and recording each request and its response, without
+// credentials, into a cassette file of its own.
+type Recorder struct {
+	base     http.RoundTripper
+	dir      string
+	redactor *mcplog.Redactor
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewRecorder returns a transport sending requests with base and recording them in dir, which is created if need
+// be. Interactions already recorded in dir are kept, and those recorded now numbered after them.
+func NewRecorder(base http.RoundTripper, dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	next := 1
+	if len(existing) > 0 {
+		sort.Strings(existing)
+		var last int
+		if _, err := fmt.Sscanf(filepath.Base(existing[len(existing)-1]), "%d", &last); err == nil {
+			next = last + 1
+		}
+	}
+	return &Recorder{base: base, dir: dir, redactor: newRedactor(), next: next}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	header := stripHeaders(resp.Header)
+	// The body may be shorter once redacted
+	header.Del("Content-Length")
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: stripHeaders(req.Header),
+			Body:   newBody(redactBody(r.redactor, body)),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       newBody(redactBody(r.redactor, respBody)),
+		},
+	}
+	if err := r.save(req, interaction); err != nil {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to record %s %s: %w", req.Method, req.URL, err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(req *http.Request, interaction Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path := filepath.Join(r.dir, fileName(r.next, req))
+	r.next++
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
@@ -0,0 +1,132 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	mcplog "github.com/github/github-mcp-server/pkg/log"
+)
+
+// Replayer is a transport answering requests with the responses recorded for them, never sending them on. Requests
+// that were not recorded fail.
+type Replayer struct {
+	dir          string
+	interactions []Interaction
+	keys         []string
+	redactor     *mcplog.Redactor
+
+	mu        sync.Mutex
+	used      []bool
+	unmatched []string
+}
+
+// NewReplayer returns a transport replaying the interactions recorded in dir by a Recorder.
+func NewReplayer(dir string) (*Replayer, error) {
+	interactions, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("no cassettes found in %s", dir)
+	}
+
+	r := &Replayer{
+		dir:          dir,
+		interactions: interactions,
+		keys:         make([]string, len(interactions)),
+		redactor:     newRedactor(),
+		used:         make([]bool, len(interactions)),
+	}
+	for i, interaction := range interactions {
+		body, err := interaction.Request.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid request body in cassette %d of %s: %w", i+1, dir, err)
+		}
+		u, err := url.Parse(interaction.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid request URL in cassette %d of %s: %w", i+1, dir, err)
+		}
+		r.keys[i] = r.key(interaction.Request.Method, u, body)
+	}
+	return r, nil
+}
+
+// key identifies a request by its method, URL and body, regardless of the order of its query parameters and of the
+// formatting of a JSON body.
+func (r *Replayer) key(method string, u *url.URL, body []byte) string {
+	normalized := *u
+	normalized.RawQuery = u.Query().Encode()
+	body = redactBody(r.redactor, body)
+	var compacted bytes.Buffer
+	if json.Compact(&compacted, body) == nil {
+		body = compacted.Bytes()
+	}
+	return method + " " + normalized.String() + "\n" + string(body)
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	key := r.key(req.Method, req.URL, body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Identical requests are answered in the order they were recorded, the last answer being repeated once they run
+	// out, as when polling
+	match := -1
+	for i, k := range r.keys {
+		if k != key {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		r.unmatched = append(r.unmatched, req.Method+" "+req.URL.String())
+		return nil, fmt.Errorf("no response recorded in %s for %s %s", r.dir, req.Method, req.URL)
+	}
+	r.used[match] = true
+
+	recorded := r.interactions[match].Response
+	respBody, err := recorded.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid response body in cassette %d of %s: %w", match+1, r.dir, err)
+	}
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
+
+// Unmatched returns the requests that had no recorded response, as "METHOD URL".
+func (r *Replayer) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.unmatched...)
+}
@@ -0,0 +1,170 @@
+// Package confirm asks the human using an MCP client to approve calls to selected tools, using elicitation, before
+// they are made.
+package confirm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/dryrun"
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Destructive selects every tool annotated as destructive, such as delete_file, in place of a tool pattern.
+const Destructive = "destructive"
+
+// maxValueLength bounds the length of an argument shown in a confirmation request.
+const maxValueLength = 200
+
+// Policy selects the tools whose calls must be confirmed.
+type Policy struct {
+	patterns    []string
+	destructive bool
+
+	// allowUnconfirmed lets calls go ahead without confirmation when the client cannot ask for it
+	allowUnconfirmed bool
+}
+
+// NewPolicy returns a policy requiring confirmation for the tools matching one of the patterns, which are tool
+// names, glob patterns such as delete_*, or Destructive. A nil policy, requiring no confirmation, is returned if
+// there are no patterns.
+//
+// Calls are refused when the client does not support elicitation, unless allowUnconfirmed is set.
+func NewPolicy(patterns []string, allowUnconfirmed bool) (*Policy, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	p := &Policy{allowUnconfirmed: allowUnconfirmed}
+	for _, pattern := range patterns {
+		if pattern == Destructive {
+			p.destructive = true
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+	return p, nil
+}
+
+// Requires reports whether calls to tool must be confirmed.
+func (p *Policy) Requires(tool mcp.Tool) bool {
+	if p == nil {
+		return false
+	}
+	if p.destructive && tool.Annotations.DestructiveHint != nil && *tool.Annotations.DestructiveHint {
+		return true
+	}
+	return toolsets.MatchToolPattern(p.patterns, tool.Name)
+}
+
+// WrapTool makes calls to tool wait for confirmation if the policy requires it. It is meant for the write tools of
+// the toolsets, see toolsets.ToolsetGroup.WrapWriteTools.
+func (p *Policy) WrapTool(tool server.ServerTool) server.ServerTool {
+	if !p.Requires(tool.Tool) {
+		return tool
+	}
+	next := tool.Handler
+	title := tool.Tool.Annotations.Title
+	tool.Handler = func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if dryrun.Enabled(ctx) {
+			// Nothing is changed, so there is nothing to confirm
+			return next(ctx, request)
+		}
+
+		name := request.Params.Name
+		session, ok := server.ClientSessionFromContext(ctx).(server.SessionWithElicitation)
+		if !ok || !supportsElicitation(session) {
+			if p.allowUnconfirmed {
+				return next(ctx, request)
+			}
+			return mcp.NewToolResultError(fmt.Sprintf("%s requires confirmation, but the client does not support elicitation to ask for it", name)), nil
+		}
+
+		result, err := session.RequestElicitation(ctx, mcp.ElicitationRequest{
+			Params: mcp.ElicitationParams{
+				Message:         summary(name, title, request.GetArguments()),
+				RequestedSchema: requestedSchema,
+			},
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to ask for confirmation of %s: %v", name, err)), nil
+		}
+		if !approved(result) {
+			return mcp.NewToolResultError(fmt.Sprintf("the user did not approve the call to %s", name)), nil
+		}
+		return next(ctx, request)
+	}
+	return tool
+}
+
+// supportsElicitation reports whether the client declared support for elicitation when it initialized the session.
+// Sessions that do not keep the client's capabilities are assumed to support it, as they implement it.
+func supportsElicitation(session server.ClientSession) bool {
+	if s, ok := session.(server.SessionWithClientInfo); ok {
+		return s.GetClientCapabilities().Elicitation != nil
+	}
+	return true
+}
+
+// requestedSchema asks for a single checkbox approving the call.
+var requestedSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"approve": map[string]any{
+			"type":        "boolean",
+			"title":       "Approve",
+			"description": "Allow the call to go ahead",
+		},
+	},
+	"required": []string{"approve"},
+}
+
+func approved(result *mcp.ElicitationResult) bool {
+	if result == nil || result.Action != mcp.ElicitationResponseActionAccept {
+		return false
+	}
+	content, _ := result.Content.(map[string]any)
+	approve, _ := content["approve"].(bool)
+	return approve
+}
+
+// summary describes a call for the human approving it, listing its arguments in order.
+func summary(name, title string, args map[string]any) string {
+	var b strings.Builder
+	if title != "" {
+		fmt.Fprintf(&b, "%s (%s)", title, name)
+	} else {
+		b.WriteString(name)
+	}
+	if owner, _ := args["owner"].(string); owner != "" {
+		repo, _ := args["repo"].(string)
+		fmt.Fprintf(&b, " on %s", strings.TrimSuffix(owner+"/"+repo, "/"))
+	}
+	b.WriteString("\n")
+
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, err := json.Marshal(args[k])
+		if err != nil {
+			continue
+		}
+		s := string(value)
+		if len(s) > maxValueLength {
+			s = fmt.Sprintf("%s… (%d bytes)", strings.ToValidUTF8(s[:maxValueLength], ""), len(s))
+		}
+		fmt.Fprintf(&b, "\n%s: %s", k, s)
+	}
+	return b.String()
+}
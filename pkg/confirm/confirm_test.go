@@ -0,0 +1,175 @@
+package confirm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/github/github-mcp-server/pkg/dryrun"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type elicitationFunc func(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error)
+
+func (f elicitationFunc) Elicit(ctx context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+	return f(ctx, request)
+}
+
+// answer returns an elicitation handler giving result, and the requests it received.
+func answer(result *mcp.ElicitationResult, err error) (server.ElicitationHandler, *[]mcp.ElicitationRequest) {
+	var requests []mcp.ElicitationRequest
+	return elicitationFunc(func(_ context.Context, request mcp.ElicitationRequest) (*mcp.ElicitationResult, error) {
+		requests = append(requests, request)
+		return result, err
+	}), &requests
+}
+
+func accept(content any) *mcp.ElicitationResult {
+	return &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionAccept, Content: content}}
+}
+
+func sessionContext(ctx context.Context, handler server.ElicitationHandler, supported bool) context.Context {
+	session := server.NewInProcessSessionWithHandlers("session", nil, handler, nil)
+	if supported {
+		session.SetClientCapabilities(mcp.ClientCapabilities{Elicitation: &struct{}{}})
+	}
+	return server.NewMCPServer("test", "1.0.0").WithContext(ctx, session)
+}
+
+func mergeTool(called *bool) server.ServerTool {
+	return server.ServerTool{
+		Tool: mcp.NewTool("merge_pull_request", mcp.WithToolAnnotation(mcp.ToolAnnotation{
+			Title:           "Merge pull request",
+			DestructiveHint: mcp.ToBoolPtr(false),
+		})),
+		Handler: func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			*called = true
+			return mcp.NewToolResultText("merged"), nil
+		},
+	}
+}
+
+func callTool(ctx context.Context, tool server.ServerTool) *mcp.CallToolResult {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool.Tool.Name
+	request.Params.Arguments = map[string]any{"owner": "octo", "repo": "hello", "pullNumber": 42, "merge_method": "squash"}
+	result, _ := tool.Handler(ctx, request)
+	return result
+}
+
+func Test_Policy_Requires(t *testing.T) {
+	deleteFile := mcp.NewTool("delete_file", mcp.WithToolAnnotation(mcp.ToolAnnotation{DestructiveHint: mcp.ToBoolPtr(true)}))
+	merge := mcp.NewTool("merge_pull_request", mcp.WithToolAnnotation(mcp.ToolAnnotation{DestructiveHint: mcp.ToBoolPtr(false)}))
+
+	policy, err := NewPolicy([]string{Destructive, "merge_*"}, false)
+	require.NoError(t, err)
+	assert.True(t, policy.Requires(deleteFile))
+	assert.True(t, policy.Requires(merge))
+	assert.False(t, policy.Requires(mcp.NewTool("create_issue", mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: mcp.ToBoolPtr(false)}))))
+
+	policy, err = NewPolicy([]string{"merge_pull_request"}, false)
+	require.NoError(t, err)
+	assert.False(t, policy.Requires(deleteFile))
+
+	policy, err = NewPolicy(nil, false)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+	assert.False(t, policy.Requires(deleteFile))
+
+	_, err = NewPolicy([]string{"merge_["}, false)
+	assert.Error(t, err)
+}
+
+func Test_Policy_WrapTool(t *testing.T) {
+	policy, err := NewPolicy([]string{"merge_pull_request"}, false)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		result         *mcp.ElicitationResult
+		err            error
+		supported      bool
+		expectedCalled bool
+		expectedError  string
+	}{
+		{
+			name:           "approved",
+			result:         accept(map[string]any{"approve": true}),
+			supported:      true,
+			expectedCalled: true,
+		},
+		{
+			name:          "accepted without approving",
+			result:        accept(map[string]any{"approve": false}),
+			supported:     true,
+			expectedError: "the user did not approve the call to merge_pull_request",
+		},
+		{
+			name:          "declined",
+			result:        &mcp.ElicitationResult{ElicitationResponse: mcp.ElicitationResponse{Action: mcp.ElicitationResponseActionDecline}},
+			supported:     true,
+			expectedError: "the user did not approve the call to merge_pull_request",
+		},
+		{
+			name:          "elicitation failed",
+			err:           errors.New("timed out"),
+			supported:     true,
+			expectedError: "failed to ask for confirmation of merge_pull_request: timed out",
+		},
+		{
+			name:          "elicitation not supported",
+			supported:     false,
+			expectedError: "merge_pull_request requires confirmation, but the client does not support elicitation to ask for it",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, requests := answer(tc.result, tc.err)
+			var called bool
+			result := callTool(sessionContext(context.Background(), handler, tc.supported), policy.WrapTool(mergeTool(&called)))
+
+			assert.Equal(t, tc.expectedCalled, called)
+			if tc.expectedError != "" {
+				require.True(t, result.IsError)
+				assert.Equal(t, tc.expectedError, result.Content[0].(mcp.TextContent).Text)
+			} else {
+				assert.False(t, result.IsError)
+			}
+			if tc.supported {
+				require.Len(t, *requests, 1)
+				message := (*requests)[0].Params.Message
+				assert.True(t, strings.HasPrefix(message, "Merge pull request (merge_pull_request) on octo/hello\n"), message)
+				assert.Contains(t, message, `merge_method: "squash"`)
+			} else {
+				assert.Empty(t, *requests)
+			}
+		})
+	}
+}
+
+func Test_Policy_WrapTool_Unconfirmed(t *testing.T) {
+	policy, err := NewPolicy([]string{"merge_pull_request"}, true)
+	require.NoError(t, err)
+
+	var called bool
+	result := callTool(context.Background(), policy.WrapTool(mergeTool(&called)))
+	assert.False(t, result.IsError)
+	assert.True(t, called, "calls go ahead when allowed without confirmation")
+}
+
+func Test_Policy_WrapTool_DryRun(t *testing.T) {
+	policy, err := NewPolicy([]string{"merge_pull_request"}, false)
+	require.NoError(t, err)
+
+	handler, requests := answer(nil, errors.New("not expected"))
+	ctx := dryrun.ContextWithDryRun(sessionContext(context.Background(), handler, true))
+	var called bool
+	result := callTool(ctx, policy.WrapTool(mergeTool(&called)))
+	assert.False(t, result.IsError)
+	assert.True(t, called)
+	assert.Empty(t, *requests, "dry runs change nothing, so are not confirmed")
+}
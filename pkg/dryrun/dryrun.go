@@ -0,0 +1,142 @@
+// Package dryrun lets write tools run without changing anything on GitHub. During a dry run the tool's handler runs
+// as usual, validating its inputs and making its read-only requests, but the requests that would change data are
+// recorded instead of sent, and the tool returns the list of them.
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Parameter is the argument asking for a single call to be a dry run.
+const Parameter = "dry_run"
+
+// Mutation is a request that would have changed data on GitHub.
+type Mutation struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+
+	// Placeholder stands in for the SHA, ID and node ID of whatever the request would have created, so that later
+	// mutations using it, such as a commit using a new tree, can be matched up with it.
+	Placeholder string `json:"placeholder,omitempty"`
+}
+
+// Plan is the result of a dry run.
+type Plan struct {
+	DryRun    bool       `json:"dry_run"`
+	Mutations []Mutation `json:"mutations"`
+
+	// Error is why the tool stopped, if it failed after the mutations listed. Mutations depending on the results of
+	// earlier ones may then be missing.
+	Error string `json:"error,omitempty"`
+}
+
+type recorder struct {
+	mu        sync.Mutex
+	mutations []Mutation
+}
+
+func (r *recorder) record(m Mutation) Mutation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m.Placeholder = fmt.Sprintf("dry-run-%d", len(r.mutations)+1)
+	r.mutations = append(r.mutations, m)
+	return m
+}
+
+func (r *recorder) recorded() []Mutation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Mutation{}, r.mutations...)
+}
+
+type recorderKey struct{}
+
+// ContextWithDryRun returns a context in which requests changing data on GitHub are recorded, rather than sent, by
+// the Transport.
+func ContextWithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, recorderKey{}, &recorder{})
+}
+
+// Enabled reports whether ctx belongs to a dry run.
+func Enabled(ctx context.Context) bool {
+	return recorderFromContext(ctx) != nil
+}
+
+// Mutations returns the mutations recorded so far in a dry run.
+func Mutations(ctx context.Context) []Mutation {
+	if r := recorderFromContext(ctx); r != nil {
+		return r.recorded()
+	}
+	return nil
+}
+
+func recorderFromContext(ctx context.Context) *recorder {
+	r, _ := ctx.Value(recorderKey{}).(*recorder)
+	return r
+}
+
+// WrapTool returns a function adapting write tools to dry runs. Calls are dry runs if they set the dry_run
+// parameter, which is added to the tools, or always if always is set.
+func WrapTool(always bool) func(server.ServerTool) server.ServerTool {
+	return func(tool server.ServerTool) server.ServerTool {
+		if !always {
+			tool.Tool = toolsets.WithParameter(tool.Tool, Parameter, map[string]any{
+				"type":        "boolean",
+				"description": "Validate the call and return the requests it would make to change data on GitHub, without making them",
+			})
+		}
+		tool.Handler = handler(always, tool.Handler)
+		return tool
+	}
+}
+
+func handler(always bool, next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if requested, _ := request.GetArguments()[Parameter].(bool); !always && !requested {
+			return next(ctx, request)
+		}
+
+		ctx = ContextWithDryRun(ctx)
+		result, err := next(ctx, request)
+
+		plan := Plan{DryRun: true, Mutations: Mutations(ctx)}
+		switch {
+		case err != nil:
+			plan.Error = err.Error()
+		case result != nil && result.IsError:
+			plan.Error = resultText(result)
+		}
+		if plan.Error != "" && len(plan.Mutations) == 0 {
+			// The call failed validation or one of its lookups, just as it would have for real
+			return result, err
+		}
+
+		r, err := json.Marshal(plan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal dry run: %w", err)
+		}
+		return mcp.NewToolResultText(string(r)), nil
+	}
+}
+
+// resultText returns the text content of a tool result.
+func resultText(result *mcp.CallToolResult) string {
+	var text string
+	for _, content := range result.Content {
+		if c, ok := content.(mcp.TextContent); ok {
+			if text != "" {
+				text += "\n"
+			}
+			text += c.Text
+		}
+	}
+	return text
+}
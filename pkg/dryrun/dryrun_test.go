@@ -0,0 +1,179 @@
+package dryrun
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	ghmcp "github.com/github/github-mcp-server/pkg/github"
+	"github.com/github/github-mcp-server/pkg/translations"
+	"github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitHub serves the lookups push_files makes, recording every other request it receives.
+func newGitHub(t *testing.T) (*github.Client, *[]string) {
+	t.Helper()
+	var sent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/octo/hello/git/ref/heads/main":
+			_, _ = w.Write([]byte(`{"ref": "refs/heads/main", "object": {"sha": "base-sha"}}`))
+		case "GET /repos/octo/hello/git/commits/base-sha":
+			_, _ = w.Write([]byte(`{"sha": "base-sha", "tree": {"sha": "base-tree-sha"}}`))
+		default:
+			sent = append(sent, r.Method+" "+r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	client := github.NewClient(&http.Client{Transport: NewTransport(http.DefaultTransport)})
+	client.BaseURL, _ = url.Parse(ts.URL + "/")
+	return client, &sent
+}
+
+func pushFiles(t *testing.T, always bool) (server.ServerTool, *[]string) {
+	t.Helper()
+	client, sent := newGitHub(t)
+	tool, handler := ghmcp.PushFiles(func(context.Context) (*github.Client, error) {
+		return client, nil
+	}, translations.NullTranslationHelper)
+	return WrapTool(always)(server.ServerTool{Tool: tool, Handler: handler}), sent
+}
+
+func callTool(ctx context.Context, tool server.ServerTool, args map[string]any) (*mcp.CallToolResult, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = tool.Tool.Name
+	request.Params.Arguments = args
+	return tool.Handler(ctx, request)
+}
+
+func Test_WrapTool(t *testing.T) {
+	args := func(dryRun bool) map[string]any {
+		return map[string]any{
+			"owner":   "octo",
+			"repo":    "hello",
+			"branch":  "main",
+			"message": "Add docs",
+			"files":   []any{map[string]any{"path": "README.md", "content": "# Hello"}},
+			Parameter: dryRun,
+		}
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		tool, sent := pushFiles(t, false)
+		assert.Contains(t, tool.Tool.InputSchema.Properties, Parameter)
+
+		result, err := callTool(context.Background(), tool, args(true))
+		require.NoError(t, err)
+		require.False(t, result.IsError, resultText(result))
+		assert.Empty(t, *sent, "nothing is sent to GitHub")
+
+		var plan Plan
+		require.NoError(t, json.Unmarshal([]byte(resultText(result)), &plan))
+		assert.True(t, plan.DryRun)
+		assert.Empty(t, plan.Error)
+		require.Len(t, plan.Mutations, 3)
+
+		tree, commit, ref := plan.Mutations[0], plan.Mutations[1], plan.Mutations[2]
+		assert.Equal(t, http.MethodPost, tree.Method)
+		assert.True(t, strings.HasSuffix(tree.URL, "/repos/octo/hello/git/trees"))
+		assert.JSONEq(t, `{"base_tree": "base-tree-sha", "tree": [{"path": "README.md", "mode": "100644", "type": "blob", "content": "# Hello"}]}`, string(tree.Body))
+		assert.Equal(t, "dry-run-1", tree.Placeholder)
+
+		assert.Equal(t, http.MethodPost, commit.Method)
+		assert.True(t, strings.HasSuffix(commit.URL, "/repos/octo/hello/git/commits"))
+		assert.JSONEq(t, `{"message": "Add docs", "tree": "dry-run-1", "parents": ["base-sha"]}`, string(commit.Body))
+
+		assert.Equal(t, http.MethodPatch, ref.Method)
+		assert.True(t, strings.HasSuffix(ref.URL, "/repos/octo/hello/git/refs/heads/main"))
+		assert.JSONEq(t, `{"sha": "dry-run-2", "force": false}`, string(ref.Body))
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		tool, sent := pushFiles(t, false)
+		invalid := args(true)
+		delete(invalid, "branch")
+
+		result, err := callTool(context.Background(), tool, invalid)
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Equal(t, "missing required parameter: branch", resultText(result))
+		assert.Empty(t, *sent)
+	})
+
+	t.Run("not a dry run", func(t *testing.T) {
+		tool, sent := pushFiles(t, false)
+		_, _ = callTool(context.Background(), tool, args(false))
+		assert.Equal(t, []string{"POST /repos/octo/hello/git/trees"}, *sent)
+	})
+
+	t.Run("always a dry run", func(t *testing.T) {
+		tool, sent := pushFiles(t, true)
+		assert.NotContains(t, tool.Tool.InputSchema.Properties, Parameter)
+
+		result, err := callTool(context.Background(), tool, args(false))
+		require.NoError(t, err)
+		assert.Contains(t, resultText(result), `"dry_run":true`)
+		assert.Empty(t, *sent)
+	})
+}
+
+func Test_Transport(t *testing.T) {
+	var sent []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"login": "octocat"}}}`))
+	}))
+	defer ts.Close()
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport)}
+
+	do := func(ctx context.Context, method, path, body string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	ctx := ContextWithDryRun(context.Background())
+	tests := []struct {
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedSent bool
+	}{
+		{http.MethodGet, "/repos/octo/hello", "", http.StatusOK, true},
+		{http.MethodPost, "/graphql", `{"query": "query { viewer { login } }"}`, http.StatusOK, true},
+		{http.MethodPost, "/graphql", `{"query": "mutation($input: AddCommentInput!) { addComment(input: $input) { clientMutationId } }"}`, http.StatusOK, false},
+		{http.MethodPost, "/repos/octo/hello/issues", `{"title": "Bug"}`, http.StatusCreated, false},
+		{http.MethodPatch, "/repos/octo/hello/issues/1", `{"state": "closed"}`, http.StatusOK, false},
+		{http.MethodPut, "/repos/octo/hello/pulls/1/merge", `{}`, http.StatusOK, false},
+		{http.MethodPut, "/repos/octo/hello/pulls/1/update-branch", `{}`, http.StatusAccepted, false},
+		{http.MethodPost, "/repos/octo/hello/forks", `{}`, http.StatusAccepted, false},
+		{http.MethodPost, "/repos/octo/hello/actions/workflows/ci.yml/dispatches", `{"ref": "main"}`, http.StatusNoContent, false},
+		{http.MethodDelete, "/repos/octo/hello/actions/runs/1/logs", "", http.StatusNoContent, false},
+	}
+	for _, tc := range tests {
+		sent = nil
+		resp := do(ctx, tc.method, tc.path, tc.body)
+		assert.Equal(t, tc.expectedCode, resp.StatusCode, "%s %s", tc.method, tc.path)
+		assert.Equal(t, tc.expectedSent, len(sent) == 1, "%s %s sent", tc.method, tc.path)
+	}
+	assert.Len(t, Mutations(ctx), 8)
+
+	// Outside of dry runs everything is sent
+	sent = nil
+	do(context.Background(), http.MethodPost, "/repos/octo/hello/issues", `{"title": "Bug"}`)
+	assert.Equal(t, []string{"POST /repos/octo/hello/issues"}, sent)
+}
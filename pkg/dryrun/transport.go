@@ -0,0 +1,122 @@
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Transport records the requests that would change data on GitHub, made in a dry run, and answers them with a
+// synthetic success instead of sending them. Other requests, including all requests outside of dry runs, are
+// passed on to the wrapped transport.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps base, which is used for requests that are not recorded.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := recorderFromContext(req.Context())
+	if r == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	graphQL := strings.HasSuffix(req.URL.Path, "/graphql")
+	if graphQL && !isMutation(body) {
+		// GraphQL queries are sent with POST, but only read data
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		return t.base.RoundTrip(req)
+	}
+
+	m := Mutation{Method: req.Method, URL: req.URL.String()}
+	if json.Valid(body) {
+		m.Body = body
+	} else if len(body) > 0 {
+		m.Body, _ = json.Marshal(string(body))
+	}
+	m = r.record(m)
+
+	status := statusCode(req)
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       http.NoBody,
+		Request:    req,
+	}
+	if status != http.StatusNoContent {
+		var payload []byte
+		if graphQL {
+			// Every field of the mutation's result is left empty
+			payload = []byte(`{"data":{}}`)
+		} else {
+			payload, _ = json.Marshal(map[string]string{"sha": m.Placeholder, "node_id": m.Placeholder})
+		}
+		resp.Header.Set("Content-Type", "application/json; charset=utf-8")
+		resp.Body = io.NopCloser(bytes.NewReader(payload))
+		resp.ContentLength = int64(len(payload))
+	}
+	return resp, nil
+}
+
+// statusCode returns the status GitHub answers a successful mutation with, which the tools check for.
+func statusCode(req *http.Request) int {
+	path := req.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/graphql"):
+		return http.StatusOK
+	case strings.HasSuffix(path, "/forks"), strings.HasSuffix(path, "/update-branch"),
+		strings.HasSuffix(path, "/cancel"):
+		// Completed asynchronously
+		return http.StatusAccepted
+	case strings.HasSuffix(path, "/dispatches"):
+		return http.StatusNoContent
+	}
+	switch req.Method {
+	case http.MethodPost:
+		return http.StatusCreated
+	case http.MethodDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+func isMutation(body []byte) bool {
+	var payload struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Treated as a mutation so that it is not sent
+		return true
+	}
+	return strings.HasPrefix(strings.TrimSpace(payload.Query), "mutation")
+}
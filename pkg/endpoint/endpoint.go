@@ -0,0 +1,74 @@
+// Package endpoint labels the GitHub API endpoints requests are sent to, for the metrics and traces recording them.
+package endpoint
+
+import (
+	"net/url"
+	"strings"
+)
+
+// paramSegment replaces path segments that identify a resource rather than an endpoint.
+const paramSegment = "{param}"
+
+// endpointSegments are the path segments of the GitHub REST API that name endpoints rather than resources. Any
+// other segment is replaced, which keeps the number of endpoint labels bounded no matter which repositories, users
+// or files are requested.
+var endpointSegments = map[string]bool{}
+
+func init() {
+	for _, s := range strings.Fields(`
+		access_tokens actions advisories alerts analyses app artifacts assignees attempts blobs branches cancel
+		check-runs check-suites code code-scanning collaborators commits comments compare contents copilot
+		dependabot deployments discussions dispatches emojis enable disable environments events files forks
+		generate gists git global-advisories heads hooks installation installations issues jobs keys labels
+		languages latest license logs markdown members merge meta milestones notifications orgs priority projects
+		pulls rate_limit reactions readme ref refs releases repos repositories requested_reviewers rerun
+		rerun-failed-jobs reviews runs search secret-scanning security-advisories starred stargazers status
+		statuses sub_issue sub_issues subscription tags teams threads timing topics trees update-branch usage
+		user users workflows`) {
+		endpointSegments[s] = true
+	}
+}
+
+// Label returns a label identifying the GitHub API endpoint a URL refers to, such as
+// "/repos/{owner}/{repo}/issues/{param}". Requests for raw file contents are labeled "raw" and GraphQL requests
+// "/graphql". The "/api/v3" prefix used by GitHub Enterprise Server is dropped, so the same endpoint has the same
+// label on every host.
+func Label(u *url.URL) string {
+	p := u.Path
+	switch {
+	case strings.HasPrefix(u.Host, "raw.") || strings.HasPrefix(p, "/raw/"):
+		return "raw"
+	case strings.HasSuffix(p, "/graphql"):
+		return "/graphql"
+	}
+	p = strings.TrimPrefix(p, "/api/v3")
+
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	var b strings.Builder
+	for i := 0; i < len(segments); i++ {
+		s := segments[i]
+		if s == "" {
+			continue
+		}
+		switch {
+		case s == "repos" && i+2 < len(segments):
+			b.WriteString("/repos/{owner}/{repo}")
+			i += 2
+		case s == "contents":
+			// The rest of the path is a file path, which may contain anything
+			b.WriteString("/contents")
+			if i+1 < len(segments) {
+				b.WriteString("/{path}")
+			}
+			return b.String()
+		case endpointSegments[s]:
+			b.WriteString("/" + s)
+		case !strings.HasSuffix(b.String(), paramSegment):
+			b.WriteString("/" + paramSegment)
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
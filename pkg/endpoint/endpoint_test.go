@@ -0,0 +1,37 @@
+package endpoint
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Label(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/user":                                           "/user",
+		"https://api.github.com/repos/octo/hello":                               "/repos/{owner}/{repo}",
+		"https://api.github.com/repos/octo/hello/pulls/42/files":                "/repos/{owner}/{repo}/pulls/{param}/files",
+		"https://api.github.com/repos/octo/hello/contents/docs/a/b.md":          "/repos/{owner}/{repo}/contents/{path}",
+		"https://api.github.com/repos/octo/hello/contents":                      "/repos/{owner}/{repo}/contents",
+		"https://api.github.com/repos/octo/hello/git/ref/heads/feature/x":       "/repos/{owner}/{repo}/git/ref/heads/{param}",
+		"https://api.github.com/repos/octo/hello/actions/runs/7/jobs":           "/repos/{owner}/{repo}/actions/runs/{param}/jobs",
+		"https://api.github.com/users/octocat/repos":                            "/users/{param}/repos",
+		"https://api.github.com/orgs/octo/teams":                                "/orgs/{param}/teams",
+		"https://api.github.com/search/issues?q=is:open":                        "/search/issues",
+		"https://api.github.com/notifications/threads/123/subscription":         "/notifications/threads/{param}/subscription",
+		"https://api.github.com/graphql":                                        "/graphql",
+		"https://ghes.example.com/api/v3/repos/octo/hello/issues":               "/repos/{owner}/{repo}/issues",
+		"https://ghes.example.com/api/graphql":                                  "/graphql",
+		"https://raw.githubusercontent.com/octo/hello/HEAD/README.md":           "raw",
+		"https://ghes.example.com/raw/octo/hello/HEAD/README.md":                "raw",
+		"https://api.github.com/":                                               "/",
+		"https://api.github.com/repos/octo/hello/issues/1/comments/2/reactions": "/repos/{owner}/{repo}/issues/{param}/comments/{param}/reactions",
+	}
+	for rawURL, expected := range tests {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		assert.Equal(t, expected, Label(u), rawURL)
+	}
+}
@@ -3,6 +3,7 @@ package errors
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/google/go-github/v72/github"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -112,7 +113,17 @@ func NewGitHubAPIErrorResponse(ctx context.Context, message string, resp *github
 	if ctx != nil {
 		_, _ = addGitHubAPIErrorToContext(ctx, apiErr) // Explicitly ignore error for graceful handling
 	}
-	return mcp.NewToolResultErrorFromErr(message, err)
+	return mcp.NewToolResultErrorFromErr(withRateLimit(message, resp), err)
+}
+
+// withRateLimit adds the rate limit budget remaining after the request to the message, so the caller can tell
+// whether retrying is worthwhile.
+func withRateLimit(message string, resp *github.Response) string {
+	if resp == nil || resp.Rate.Limit == 0 {
+		return message
+	}
+	return fmt.Sprintf("%s (rate limit: %d of %d requests remaining, resets at %s)",
+		message, resp.Rate.Remaining, resp.Rate.Limit, resp.Rate.Reset.UTC().Format(time.RFC3339))
 }
 
 // NewGitHubGraphQLErrorResponse returns an mcp.NewToolResultError and retains the error in the context for access via middleware
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -183,6 +185,34 @@ func TestGitHubErrorContext(t *testing.T) {
 		assert.Len(t, apiErrors, 0, "Errors should be reset")
 	})
 
+	t.Run("NewGitHubAPIErrorResponse reports the remaining rate limit", func(t *testing.T) {
+		// Given a response carrying rate limit information
+		ctx := ContextWithGitHubErrors(context.Background())
+		resp := &github.Response{
+			Response: &http.Response{StatusCode: 403},
+			Rate: github.Rate{
+				Limit:     5000,
+				Remaining: 0,
+				Reset:     github.Timestamp{Time: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+			},
+		}
+
+		// When we create an API error response
+		result := NewGitHubAPIErrorResponse(ctx, "API call failed", resp, fmt.Errorf("rate limit exceeded"))
+
+		// Then the message should include the budget
+		require.NotNil(t, result)
+		textContent, ok := result.Content[0].(mcp.TextContent)
+		require.True(t, ok)
+		assert.Equal(t, "API call failed (rate limit: 0 of 5000 requests remaining, resets at 2025-01-01T12:00:00Z): rate limit exceeded", textContent.Text)
+
+		// And the stored error should keep the original message
+		apiErrors, err := GetGitHubAPIErrors(ctx)
+		require.NoError(t, err)
+		require.Len(t, apiErrors, 1)
+		assert.Equal(t, "API call failed", apiErrors[0].Message)
+	})
+
 	t.Run("NewGitHubAPIErrorResponse creates MCP error result and stores context error", func(t *testing.T) {
 		// Given a context with GitHub error tracking enabled
 		ctx := ContextWithGitHubErrors(context.Background())
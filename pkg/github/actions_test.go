@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 
 	"github.com/github/github-mcp-server/pkg/translations"
@@ -1051,24 +1050,25 @@ func Test_GetJobLogs(t *testing.T) {
 }
 
 func Test_GetJobLogs_WithContentReturn(t *testing.T) {
-	// Test the return_content functionality with a mock HTTP server
+	// Test the return_content functionality
 	logContent := "2023-01-01T10:00:00.000Z Starting job...\n2023-01-01T10:00:01.000Z Running tests...\n2023-01-01T10:00:02.000Z Job completed successfully"
 
-	// Create a test server to serve log content
-	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(logContent))
-	}))
-	defer testServer.Close()
-
+	// The logs are downloaded with the GitHub client, so the mocked client serves the log storage too
 	mockedClient := mock.NewMockedHTTPClient(
 		mock.WithRequestMatchHandler(
 			mock.GetReposActionsJobsLogsByOwnerByRepoByJobId,
 			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-				w.Header().Set("Location", testServer.URL)
+				w.Header().Set("Location", "https://logs.example.com/jobs/123/logs")
 				w.WriteHeader(http.StatusFound)
 			}),
 		),
+		mock.WithRequestMatchHandler(
+			mock.EndpointPattern{Pattern: "/jobs/123/logs", Method: http.MethodGet},
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(logContent))
+			}),
+		),
 	)
 
 	client := github.NewClient(mockedClient)
@@ -1097,25 +1097,26 @@ func Test_GetJobLogs_WithContentReturn(t *testing.T) {
 }
 
 func Test_GetJobLogs_WithContentReturnAndTailLines(t *testing.T) {
-	// Test the return_content functionality with a mock HTTP server
+	// Test the return_content functionality
 	logContent := "2023-01-01T10:00:00.000Z Starting job...\n2023-01-01T10:00:01.000Z Running tests...\n2023-01-01T10:00:02.000Z Job completed successfully"
 	expectedLogContent := "2023-01-01T10:00:02.000Z Job completed successfully"
 
-	// Create a test server to serve log content
-	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte(logContent))
-	}))
-	defer testServer.Close()
-
+	// The logs are downloaded with the GitHub client, so the mocked client serves the log storage too
 	mockedClient := mock.NewMockedHTTPClient(
 		mock.WithRequestMatchHandler(
 			mock.GetReposActionsJobsLogsByOwnerByRepoByJobId,
 			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-				w.Header().Set("Location", testServer.URL)
+				w.Header().Set("Location", "https://logs.example.com/jobs/123/logs")
 				w.WriteHeader(http.StatusFound)
 			}),
 		),
+		mock.WithRequestMatchHandler(
+			mock.EndpointPattern{Pattern: "/jobs/123/logs", Method: http.MethodGet},
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(logContent))
+			}),
+		),
 	)
 
 	client := github.NewClient(mockedClient)
@@ -67,19 +67,23 @@ func ListAvailableToolsets(toolsetGroup *toolsets.ToolsetGroup, t translations.T
 				ReadOnlyHint: ToBoolPtr(true),
 			}),
 		),
-		func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			// We need to convert the toolsetGroup back to a map for JSON serialization
 
-			payload := []map[string]string{}
+			payload := []map[string]any{}
 
 			for name, ts := range toolsetGroup.Toolsets {
 				{
-					t := map[string]string{
+					t := map[string]any{
 						"name":              name,
 						"description":       ts.Description,
 						"can_enable":        "true",
 						"currently_enabled": fmt.Sprintf("%t", ts.Enabled),
 					}
+					// Tools the token cannot use, such as for want of a scope, with the reason why
+					if unavailable := toolsetGroup.UnavailableTools(ctx, ts); unavailable != nil {
+						t["unavailable_tools"] = unavailable
+					}
 					payload = append(payload, t)
 				}
 			}
@@ -106,7 +110,7 @@ func GetToolsetsTools(toolsetGroup *toolsets.ToolsetGroup, t translations.Transl
 				ToolsetEnum(toolsetGroup),
 			),
 		),
-		func(_ context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 			// We need to convert the toolsetGroup back to a map for JSON serialization
 			toolsetName, err := RequiredParam[string](request, "toolset")
 			if err != nil {
@@ -118,6 +122,7 @@ func GetToolsetsTools(toolsetGroup *toolsets.ToolsetGroup, t translations.Transl
 			}
 			payload := []map[string]string{}
 
+			unavailable := toolsetGroup.UnavailableTools(ctx, toolset)
 			for _, st := range toolset.GetAvailableTools() {
 				tool := map[string]string{
 					"name":        st.Tool.Name,
@@ -125,6 +130,9 @@ func GetToolsetsTools(toolsetGroup *toolsets.ToolsetGroup, t translations.Transl
 					"can_enable":  "true",
 					"toolset":     toolsetName,
 				}
+				if reason, ok := unavailable[st.Tool.Name]; ok {
+					tool["unavailable"] = reason
+				}
 				payload = append(payload, tool)
 			}
 
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	ghErrors "github.com/github/github-mcp-server/pkg/errors"
+	"github.com/github/github-mcp-server/pkg/hosts"
 	"github.com/github/github-mcp-server/pkg/raw"
 	"github.com/github/github-mcp-server/pkg/translations"
 	"github.com/google/go-github/v72/github"
@@ -559,6 +560,8 @@ func GetFileContents(getClient GetClientFn, getRawClient raw.GetRawClientFn, t t
 						}
 					}
 
+					resourceURI = hosts.ResourceURI(ctx, resourceURI)
+
 					if strings.HasPrefix(contentType, "application") || strings.HasPrefix(contentType, "text") {
 						return mcp.NewToolResultResource("successfully downloaded text file", mcp.TextResourceContents{
 							URI:      resourceURI,
@@ -0,0 +1,163 @@
+// Package hosts routes tool calls and resource reads to one of several GitHub instances, such as github.com and a
+// GitHub Enterprise Server, chosen by an argument naming the host or by the owner of the repository acted on.
+package hosts
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Parameter is the argument naming the host a tool call, or resource read, acts on.
+const Parameter = "host"
+
+// Default names the host the server was configured with, which calls act on unless routed elsewhere.
+const Default = "default"
+
+// Host is a GitHub instance calls may be routed to, in addition to the default one.
+type Host struct {
+	// Name identifies the host in the host argument of tools and resource URIs
+	Name string
+
+	// Owners are the users and organizations, or glob patterns matching them (e.g. corp-*), whose repositories are
+	// on this host
+	Owners []string
+}
+
+type route struct {
+	owner string
+	host  string
+}
+
+// Router decides which host each call acts on.
+type Router struct {
+	names  []string
+	routes []route
+}
+
+// NewRouter returns a router choosing between the default host and hosts.
+func NewRouter(hosts []Host) (*Router, error) {
+	r := &Router{names: []string{Default}}
+	for _, h := range hosts {
+		if h.Name == "" || strings.ContainsAny(h.Name, "/?#&= ") {
+			return nil, fmt.Errorf("invalid host name %q", h.Name)
+		}
+		if h.Name == Default {
+			return nil, fmt.Errorf("host name %q is reserved for the host the server is configured with", Default)
+		}
+		if slices.Contains(r.names, h.Name) {
+			return nil, fmt.Errorf("host %q is configured more than once", h.Name)
+		}
+		r.names = append(r.names, h.Name)
+
+		for _, owner := range h.Owners {
+			if _, err := path.Match(owner, ""); err != nil {
+				return nil, fmt.Errorf("invalid owner pattern %q for host %s: %w", owner, h.Name, err)
+			}
+			r.routes = append(r.routes, route{owner: owner, host: h.Name})
+		}
+	}
+	return r, nil
+}
+
+// Names returns the names of the hosts, starting with Default.
+func (r *Router) Names() []string {
+	return r.names
+}
+
+// Resolve returns the host a call acts on: the one named by host if given, otherwise the first host whose owners
+// match owner, falling back to Default.
+func (r *Router) Resolve(host, owner string) (string, error) {
+	if host != "" {
+		if !slices.Contains(r.names, host) {
+			return "", fmt.Errorf("unknown host %q, expected one of: %s", host, strings.Join(r.names, ", "))
+		}
+		return host, nil
+	}
+	if owner == "" {
+		return Default, nil
+	}
+	for _, rt := range r.routes {
+		// GitHub logins are case insensitive
+		if ok, _ := path.Match(strings.ToLower(rt.owner), strings.ToLower(owner)); ok {
+			return rt.host, nil
+		}
+	}
+	return Default, nil
+}
+
+type hostKey struct{}
+
+// ContextWithHost returns a context routed to the named host.
+func ContextWithHost(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, hostKey{}, name)
+}
+
+// FromContext returns the name of the host ctx was routed to, or Default if it was not.
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(hostKey{}).(string); ok {
+		return name
+	}
+	return Default
+}
+
+// WrapTool routes calls to tool to the host they act on. When there is more than one host to choose from, the host
+// parameter is added to the tool so that calls can name it.
+func (r *Router) WrapTool(tool server.ServerTool) server.ServerTool {
+	if len(r.names) > 1 {
+		tool.Tool = toolsets.WithParameter(tool.Tool, Parameter, map[string]any{
+			"type":        "string",
+			"description": "GitHub host to act on, defaulting to the host of the owner",
+			"enum":        r.names,
+		})
+	}
+	next := tool.Handler
+	tool.Handler = func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.GetArguments()
+		host, _ := args[Parameter].(string)
+		owner, _ := args["owner"].(string)
+		name, err := r.Resolve(host, owner)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return next(ContextWithHost(ctx, name), request)
+	}
+	return tool
+}
+
+// WrapResourceTemplate adds the host query parameter to the URI template of template when there is more than one
+// host to choose from, so that reads can name it, as in repo://{owner}/{repo}/contents{/path*}{?host}.
+func (r *Router) WrapResourceTemplate(template mcp.ResourceTemplate) mcp.ResourceTemplate {
+	if len(r.names) > 1 {
+		template.URITemplate = mcp.NewResourceTemplate(template.URITemplate.Raw()+"{?"+Parameter+"}", template.Name).URITemplate
+	}
+	return template
+}
+
+// ResourceTemplateHandlerMiddleware routes reads of resources to the host given by the host query parameter of
+// their URI, or else to the host of their owner.
+func (r *Router) ResourceTemplateHandlerMiddleware(next server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		name, err := r.Resolve(toolsets.TemplateArgument(request, Parameter), toolsets.TemplateArgument(request, "owner"))
+		if err != nil {
+			return nil, err
+		}
+		return next(ContextWithHost(ctx, name), request)
+	}
+}
+
+// ResourceURI returns uri, pointing at the host ctx was routed to if that is not the default one.
+func ResourceURI(ctx context.Context, uri string) string {
+	name := FromContext(ctx)
+	if name == Default {
+		return uri
+	}
+	return uri + "?" + url.Values{Parameter: {name}}.Encode()
+}
@@ -0,0 +1,174 @@
+package hosts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewRouter(t *testing.T) {
+	tests := []struct {
+		name          string
+		hosts         []Host
+		expectedError string
+	}{
+		{
+			name:  "named hosts",
+			hosts: []Host{{Name: "ghes", Owners: []string{"corp-*"}}, {Name: "oss"}},
+		},
+		{
+			name:          "duplicate names",
+			hosts:         []Host{{Name: "ghes"}, {Name: "ghes"}},
+			expectedError: `host "ghes" is configured more than once`,
+		},
+		{
+			name:          "the default name is reserved",
+			hosts:         []Host{{Name: Default}},
+			expectedError: `host name "default" is reserved for the host the server is configured with`,
+		},
+		{
+			name:          "names must fit in a URI query",
+			hosts:         []Host{{Name: "a&b"}},
+			expectedError: `invalid host name "a&b"`,
+		},
+		{
+			name:          "malformed owner patterns",
+			hosts:         []Host{{Name: "ghes", Owners: []string{"corp-["}}},
+			expectedError: `invalid owner pattern "corp-[" for host ghes: syntax error in pattern`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewRouter(tc.hosts)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_Router_Resolve(t *testing.T) {
+	router, err := NewRouter([]Host{{Name: "ghes", Owners: []string{"corp", "corp-*"}}, {Name: "oss"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{Default, "ghes", "oss"}, router.Names())
+
+	tests := []struct {
+		name          string
+		host          string
+		owner         string
+		expectedHost  string
+		expectedError string
+	}{
+		{name: "nothing to go by", expectedHost: Default},
+		{name: "owner of another host", owner: "corp-platform", expectedHost: "ghes"},
+		{name: "owners are case insensitive", owner: "Corp", expectedHost: "ghes"},
+		{name: "unmapped owner", owner: "octocat", expectedHost: Default},
+		{name: "named host", host: "oss", owner: "corp", expectedHost: "oss"},
+		{name: "unknown host", host: "gitlab", expectedError: `unknown host "gitlab", expected one of: default, ghes, oss`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host, err := router.Resolve(tc.host, tc.owner)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedHost, host)
+		})
+	}
+}
+
+func recordHostTool(host *string) server.ServerTool {
+	return server.ServerTool{
+		Tool: mcp.NewTool("get_issue", mcp.WithString("owner")),
+		Handler: func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			*host = FromContext(ctx)
+			return mcp.NewToolResultText(ResourceURI(ctx, "repo://corp/api/contents/README.md")), nil
+		},
+	}
+}
+
+func Test_Router_WrapTool(t *testing.T) {
+	var host string
+	tool := recordHostTool(&host)
+
+	single, err := NewRouter(nil)
+	require.NoError(t, err)
+	assert.NotContains(t, single.WrapTool(tool).Tool.InputSchema.Properties, Parameter, "there is no host to choose with a single one")
+
+	router, err := NewRouter([]Host{{Name: "ghes", Owners: []string{"corp"}}})
+	require.NoError(t, err)
+	wrapped := router.WrapTool(tool)
+	assert.Equal(t, map[string]any{
+		"type":        "string",
+		"description": "GitHub host to act on, defaulting to the host of the owner",
+		"enum":        []string{Default, "ghes"},
+	}, wrapped.Tool.InputSchema.Properties[Parameter])
+	assert.NotContains(t, tool.Tool.InputSchema.Properties, Parameter, "the original tool is left alone")
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"owner": "corp"}
+	result, err := wrapped.Handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "ghes", host)
+	assert.Equal(t, "repo://corp/api/contents/README.md?host=ghes", result.Content[0].(mcp.TextContent).Text)
+
+	request.Params.Arguments = map[string]any{"owner": "corp", "host": Default}
+	result, err = wrapped.Handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, Default, host)
+	assert.Equal(t, "repo://corp/api/contents/README.md", result.Content[0].(mcp.TextContent).Text)
+
+	host = ""
+	request.Params.Arguments = map[string]any{"host": "gitlab"}
+	result, err = wrapped.Handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Empty(t, host, "calls to unknown hosts are not made")
+}
+
+func Test_Router_WrapResourceTemplate(t *testing.T) {
+	template := mcp.NewResourceTemplate("repo://{owner}/{repo}/contents{/path*}", "Repository Content")
+
+	single, err := NewRouter(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "repo://{owner}/{repo}/contents{/path*}", single.WrapResourceTemplate(template).URITemplate.Raw(), "there is no host to choose with a single one")
+
+	router, err := NewRouter([]Host{{Name: "ghes", Owners: []string{"corp"}}})
+	require.NoError(t, err)
+	wrapped := router.WrapResourceTemplate(template)
+	assert.Equal(t, "repo://{owner}/{repo}/contents{/path*}{?host}", wrapped.URITemplate.Raw())
+	assert.Equal(t, "Repository Content", wrapped.Name)
+	assert.Equal(t, "repo://{owner}/{repo}/contents{/path*}", template.URITemplate.Raw(), "the original template is left alone")
+}
+
+func Test_Router_ResourceTemplateHandlerMiddleware(t *testing.T) {
+	router, err := NewRouter([]Host{{Name: "ghes", Owners: []string{"corp"}}})
+	require.NoError(t, err)
+
+	var host string
+	handler := router.ResourceTemplateHandlerMiddleware(func(ctx context.Context, _ mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		host = FromContext(ctx)
+		return nil, nil
+	})
+
+	read := func(args map[string]any) error {
+		request := mcp.ReadResourceRequest{}
+		request.Params.Arguments = args
+		_, err := handler(context.Background(), request)
+		return err
+	}
+
+	require.NoError(t, read(map[string]any{"owner": []string{"corp"}, "repo": []string{"api"}}))
+	assert.Equal(t, "ghes", host)
+	require.NoError(t, read(map[string]any{"owner": []string{"corp"}, "repo": []string{"api"}, "host": []string{Default}}))
+	assert.Equal(t, Default, host)
+	assert.EqualError(t, read(map[string]any{"owner": []string{"corp"}, "host": []string{"gitlab"}}), `unknown host "gitlab", expected one of: default, ghes`)
+}
@@ -0,0 +1,234 @@
+// Package httpcache provides an HTTP transport that caches GET responses and revalidates them with conditional
+// requests, which GitHub does not count against the REST API rate limit when nothing has changed.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxBytes is the default bound on the size of the cache.
+	DefaultMaxBytes = 64 << 20
+	// DefaultTTL is how long responses are kept by default.
+	DefaultTTL = time.Hour
+
+	// maxEntryFraction bounds the size of a single response relative to the size of the cache, so that one large
+	// response cannot evict everything else.
+	maxEntryFraction = 8
+
+	// FromCacheHeader is set on responses served from the cache after GitHub confirmed they are still current.
+	FromCacheHeader = "X-From-Cache"
+)
+
+// Config configures the cache.
+type Config struct {
+	// Dir is the directory responses are stored in. If empty, they are kept in memory.
+	Dir string
+
+	// MaxBytes bounds the total size of the cached responses, defaulting to DefaultMaxBytes
+	MaxBytes int64
+
+	// TTL is how long a response is kept after it was last confirmed current, defaulting to DefaultTTL
+	TTL time.Duration
+}
+
+// Entry is a cached response.
+type Entry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+func (e *Entry) size() int64 {
+	n := int64(len(e.Body))
+	for k, vs := range e.Header {
+		for _, v := range vs {
+			n += int64(len(k) + len(v))
+		}
+	}
+	return n
+}
+
+// Store holds cached responses. Stores are best effort: failures are treated as cache misses.
+type Store interface {
+	Get(key string) (*Entry, bool)
+	Set(key string, entry *Entry)
+	Delete(key string)
+}
+
+// Transport is an http.RoundTripper caching the responses to GET requests that carry an ETag or Last-Modified
+// header. Cached responses are always revalidated with a conditional request, and served from the cache when
+// GitHub answers 304 Not Modified. The cache is partitioned by the Authorization header of the request, so it must
+// sit below the transport adding it, and responses are never shared between tokens.
+type Transport struct {
+	base     http.RoundTripper
+	store    Store
+	ttl      time.Duration
+	maxEntry int64
+	now      func() time.Time
+}
+
+// NewTransport creates a Transport sending requests with base, storing responses on disk if cfg.Dir is set or in
+// memory otherwise.
+func NewTransport(base http.RoundTripper, cfg Config) (*Transport, error) {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = DefaultMaxBytes
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+
+	var store Store
+	if cfg.Dir != "" {
+		disk, err := NewDiskStore(cfg.Dir, cfg.MaxBytes)
+		if err != nil {
+			return nil, err
+		}
+		store = disk
+	} else {
+		store = NewMemoryStore(cfg.MaxBytes)
+	}
+
+	return &Transport{
+		base:     base,
+		store:    store,
+		ttl:      cfg.TTL,
+		maxEntry: cfg.MaxBytes / maxEntryFraction,
+		now:      time.Now,
+	}, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	now := t.now()
+
+	entry, ok := t.store.Get(key)
+	if ok && now.Sub(entry.StoredAt) > t.ttl {
+		t.store.Delete(key)
+		ok = false
+	}
+
+	r := req
+	if ok {
+		r = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			r.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ok && resp.StatusCode == http.StatusNotModified:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		// The 304 carries current values for headers such as the rate limit, which replace the cached ones
+		header := entry.Header.Clone()
+		for k, vs := range resp.Header {
+			if k != "Content-Length" && k != "Transfer-Encoding" {
+				header[k] = vs
+			}
+		}
+		refreshed := &Entry{StatusCode: entry.StatusCode, Header: header, Body: entry.Body, StoredAt: now}
+		t.store.Set(key, refreshed)
+		return cachedResponse(req, resp, refreshed), nil
+	case resp.StatusCode == http.StatusOK && storable(resp):
+		return t.store200(key, resp, now)
+	default:
+		if ok {
+			t.store.Delete(key)
+		}
+		return resp, nil
+	}
+}
+
+// store200 caches a successful response, unless it is too large, returning a response whose body can still be
+// read.
+func (t *Transport) store200(key string, resp *http.Response, now time.Time) (*http.Response, error) {
+	if resp.ContentLength > t.maxEntry {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxEntry+1))
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > t.maxEntry {
+		// Too large to cache, hand back what was read along with the remainder
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+
+	t.store.Set(key, &Entry{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body, StoredAt: now})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func cachedResponse(req *http.Request, notModified *http.Response, entry *Entry) *http.Response {
+	header := entry.Header.Clone()
+	header.Set(FromCacheHeader, "1")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
+
+// cacheable reports whether the cache handles the request. Requests that are already conditional, or only ask for
+// part of a resource, are passed through untouched.
+func cacheable(req *http.Request) bool {
+	return req.Method == http.MethodGet &&
+		req.Header.Get("If-None-Match") == "" &&
+		req.Header.Get("If-Modified-Since") == "" &&
+		req.Header.Get("Range") == ""
+}
+
+// storable reports whether a response can be revalidated later, and may be stored.
+func storable(resp *http.Response) bool {
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return false
+	}
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// cacheKey identifies a response by the token it was requested with, its URL, and the headers selecting its
+// representation. The token is identified by a digest, so it is not retained.
+func cacheKey(req *http.Request) string {
+	token := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return strings.Join([]string{
+		hex.EncodeToString(token[:]),
+		req.URL.String(),
+		req.Header.Get("Accept"),
+		req.Header.Get("X-GitHub-Api-Version"),
+	}, "\n")
+}
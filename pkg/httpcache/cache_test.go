@@ -0,0 +1,150 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// etagServer serves body with an ETag, answering 304 to requests that already have it.
+type etagServer struct {
+	*httptest.Server
+	body        atomic.Value
+	requests    atomic.Int32
+	notModified atomic.Int32
+}
+
+func newETagServer(t *testing.T, body string) *etagServer {
+	t.Helper()
+	s := &etagServer{}
+	s.body.Store(body)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		body := s.body.Load().(string)
+		etag := `"` + body + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		if r.Header.Get("If-None-Match") == etag {
+			s.notModified.Add(1)
+			w.Header().Set("X-RateLimit-Remaining", "4998")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func doRequest(t *testing.T, transport http.RoundTripper, method, url, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func Test_Transport_RevalidatesCachedResponses(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"memory": {},
+		"disk":   {Dir: t.TempDir()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ts := newETagServer(t, "v1")
+			transport, err := NewTransport(http.DefaultTransport, cfg)
+			require.NoError(t, err)
+
+			resp := doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token")
+			assert.Equal(t, "v1", readBody(t, resp))
+			assert.Empty(t, resp.Header.Get(FromCacheHeader))
+
+			// The second request is conditional, and served from the cache
+			resp = doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token")
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "v1", readBody(t, resp))
+			assert.Equal(t, "1", resp.Header.Get(FromCacheHeader))
+			assert.Equal(t, "4998", resp.Header.Get("X-RateLimit-Remaining"), "headers should be refreshed from the 304")
+			assert.Equal(t, int32(1), ts.notModified.Load())
+
+			// Once the resource changes, the new version replaces the cached one
+			ts.body.Store("v2")
+			resp = doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token")
+			assert.Equal(t, "v2", readBody(t, resp))
+			assert.Empty(t, resp.Header.Get(FromCacheHeader))
+
+			resp = doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token")
+			assert.Equal(t, "v2", readBody(t, resp))
+			assert.Equal(t, "1", resp.Header.Get(FromCacheHeader))
+		})
+	}
+}
+
+func Test_Transport_PartitionsByToken(t *testing.T) {
+	ts := newETagServer(t, "v1")
+	transport, err := NewTransport(http.DefaultTransport, Config{})
+	require.NoError(t, err)
+
+	doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token-a")
+	resp := doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token-b")
+
+	assert.Empty(t, resp.Header.Get(FromCacheHeader))
+	assert.Equal(t, int32(0), ts.notModified.Load(), "a response cached for one token must not be revalidated for another")
+}
+
+func Test_Transport_ExpiresEntries(t *testing.T) {
+	ts := newETagServer(t, "v1")
+	transport, err := NewTransport(http.DefaultTransport, Config{TTL: time.Minute})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	transport.now = func() time.Time { return now }
+
+	doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token")
+	now = now.Add(2 * time.Minute)
+	resp := doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r", "token")
+
+	assert.Empty(t, resp.Header.Get(FromCacheHeader))
+	assert.Equal(t, int32(0), ts.notModified.Load())
+}
+
+func Test_Transport_OnlyCachesGETRequests(t *testing.T) {
+	ts := newETagServer(t, "v1")
+	transport, err := NewTransport(http.DefaultTransport, Config{})
+	require.NoError(t, err)
+
+	doRequest(t, transport, http.MethodPost, ts.URL+"/repos/o/r/issues", "token")
+	doRequest(t, transport, http.MethodPost, ts.URL+"/repos/o/r/issues", "token")
+
+	assert.Equal(t, int32(0), ts.notModified.Load())
+}
+
+func Test_Transport_SkipsLargeResponses(t *testing.T) {
+	large := strings.Repeat("x", 2048)
+	ts := newETagServer(t, large)
+	transport, err := NewTransport(http.DefaultTransport, Config{MaxBytes: 8 * 1024})
+	require.NoError(t, err)
+
+	resp := doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r/contents/big", "token")
+	assert.Equal(t, large, readBody(t, resp), "the full body should be returned")
+
+	resp = doRequest(t, transport, http.MethodGet, ts.URL+"/repos/o/r/contents/big", "token")
+	assert.Equal(t, large, readBody(t, resp))
+	assert.Empty(t, resp.Header.Get(FromCacheHeader))
+}
@@ -0,0 +1,156 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskStore is a Store keeping responses as files in a directory only the current user can access, so that they
+// survive restarts. Once its size bound is exceeded, the least recently used responses are removed.
+type DiskStore struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	size int64
+}
+
+// diskEntry is the file a response is stored in. The key is kept to detect hash collisions.
+type diskEntry struct {
+	Key string `json:"key"`
+	*Entry
+}
+
+// NewDiskStore creates a DiskStore holding up to maxBytes of responses in dir, creating it if needed.
+func NewDiskStore(dir string, maxBytes int64) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	s := &DiskStore{dir: dir, maxBytes: maxBytes}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictLocked()
+	return s, nil
+}
+
+func (s *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get implements Store.
+func (s *DiskStore) Get(key string) (*Entry, bool) {
+	path := s.path(key)
+	data, err := os.ReadFile(path) //#nosec G304 -- the path is derived from a hash within the cache directory
+	if err != nil {
+		return nil, false
+	}
+
+	var e diskEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key || e.Entry == nil {
+		return nil, false
+	}
+
+	// Record the access, so that recently used responses are evicted last
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return e.Entry, true
+}
+
+// Set implements Store.
+func (s *DiskStore) Set(key string, entry *Entry) {
+	data, err := json.Marshal(diskEntry{Key: key, Entry: entry})
+	if err != nil || int64(len(data)) > s.maxBytes {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	if info, err := os.Stat(path); err == nil {
+		s.size -= info.Size()
+	}
+
+	// Write to a temporary file first so that readers never see a partially written response.
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return
+	}
+
+	s.size += int64(len(data))
+	if s.size > s.maxBytes {
+		s.evictLocked()
+	}
+}
+
+// Delete implements Store.
+func (s *DiskStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	if info, err := os.Stat(path); err == nil {
+		if os.Remove(path) == nil {
+			s.size -= info.Size()
+		}
+	}
+}
+
+// evictLocked recounts the size of the cache, which other processes may share, and removes the least recently
+// used responses until it is within bounds.
+func (s *DiskStore) evictLocked() {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	s.size = 0
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".json") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{path: filepath.Join(s.dir, de.Name()), size: info.Size(), modTime: info.ModTime()})
+		s.size += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if s.size <= s.maxBytes {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			s.size -= f.size
+		}
+	}
+}
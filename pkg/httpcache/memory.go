@@ -0,0 +1,82 @@
+package httpcache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryStore is a Store keeping responses in memory, evicting the least recently used ones once its size bound
+// is exceeded.
+type MemoryStore struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// NewMemoryStore creates a MemoryStore holding up to maxBytes of responses.
+func NewMemoryStore(maxBytes int64) *MemoryStore {
+	return &MemoryStore{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(el)
+	return el.Value.(*memoryItem).entry, true
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(key string, entry *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteLocked(key)
+
+	item := &memoryItem{key: key, entry: entry, size: entry.size() + int64(len(key))}
+	if item.size > s.maxBytes {
+		return
+	}
+	s.entries[key] = s.lru.PushFront(item)
+	s.size += item.size
+
+	for s.size > s.maxBytes {
+		s.deleteLocked(s.lru.Back().Value.(*memoryItem).key)
+	}
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteLocked(key)
+}
+
+func (s *MemoryStore) deleteLocked(key string) {
+	el, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	s.lru.Remove(el)
+	delete(s.entries, key)
+	s.size -= el.Value.(*memoryItem).size
+}
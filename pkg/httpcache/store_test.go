@@ -0,0 +1,94 @@
+package httpcache
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry(size int) *Entry {
+	return &Entry{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(strings.Repeat("x", size)), StoredAt: time.Now()}
+}
+
+func Test_MemoryStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s := NewMemoryStore(250)
+	s.Set("a", testEntry(100))
+	s.Set("b", testEntry(100))
+
+	// Using a makes b the least recently used
+	_, ok := s.Get("a")
+	require.True(t, ok)
+
+	s.Set("c", testEntry(100))
+	_, ok = s.Get("a")
+	assert.True(t, ok)
+	_, ok = s.Get("b")
+	assert.False(t, ok)
+	_, ok = s.Get("c")
+	assert.True(t, ok)
+
+	// Entries larger than the whole store are not kept
+	s.Set("d", testEntry(1000))
+	_, ok = s.Get("d")
+	assert.False(t, ok)
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+}
+
+func Test_DiskStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, 1024)
+	require.NoError(t, err)
+
+	entry := testEntry(10)
+	entry.Header.Set("ETag", `"abc"`)
+	s.Set("a", entry)
+
+	// Entries survive being reopened
+	s, err = NewDiskStore(dir, 1024)
+	require.NoError(t, err)
+	got, ok := s.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, entry.Body, got.Body)
+	assert.Equal(t, `"abc"`, got.Header.Get("ETag"))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	info, err := files[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+}
+
+func Test_DiskStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s, err := NewDiskStore(t.TempDir(), 1024)
+	require.NoError(t, err)
+
+	s.Set("a", testEntry(300))
+	s.Set("b", testEntry(300))
+
+	// Make a the most recently used
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(s.path("b"), old, old))
+	_, ok := s.Get("a")
+	require.True(t, ok)
+
+	s.Set("c", testEntry(300))
+	_, ok = s.Get("a")
+	assert.True(t, ok)
+	_, ok = s.Get("b")
+	assert.False(t, ok)
+	_, ok = s.Get("c")
+	assert.True(t, ok)
+}
@@ -0,0 +1,323 @@
+// Package limits bounds how many tool calls run at once, and for how long, and stops calls the client cancels.
+//
+// Calls beyond the concurrency limits wait in a bounded queue, and are refused once it is full, so that a client
+// firing many slow calls at once cannot stall the server. Every call runs with a deadline, and its context, which
+// the requests it makes to GitHub are sent with, is cancelled when the client sends a cancellation notification for
+// it.
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// DefaultMaxQueued is how many calls may wait for others to complete by default.
+	DefaultMaxQueued = 100
+
+	// DefaultTimeout is how long calls may take by default.
+	DefaultTimeout = 5 * time.Minute
+
+	// MethodNotificationCancelled is the notification a client sends to cancel one of its requests.
+	MethodNotificationCancelled = "notifications/cancelled"
+)
+
+// errCancelled is the cause of the cancellation of calls the client cancelled.
+var errCancelled = errors.New("cancelled by the client")
+
+// Config sets the limits calls are subject to.
+type Config struct {
+	// MaxConcurrent is how many calls may run at once, any number if 0
+	MaxConcurrent int
+
+	// MaxQueued is how many calls may wait for others to complete before further calls are refused
+	MaxQueued int
+
+	// Timeout is how long a call may take, including the time it waits in the queue, without limit if 0
+	Timeout time.Duration
+
+	// Tools overrides the limits of particular tools, the first match setting each limit
+	Tools []ToolLimit
+}
+
+// ToolLimit limits the calls to the tools matching Pattern.
+type ToolLimit struct {
+	// Pattern is a tool name, or a glob pattern such as search_*
+	Pattern string
+
+	// MaxConcurrent is how many calls to each of the tools may run at once, in addition to the global limit, any
+	// number if 0
+	MaxConcurrent int
+
+	// Timeout replaces Config.Timeout for the tools, if not 0
+	Timeout time.Duration
+}
+
+// ParseToolConcurrency parses a tool=N pair, limiting the tools matching the tool pattern to N concurrent calls.
+func ParseToolConcurrency(value string) (ToolLimit, error) {
+	pattern, n, err := splitPair(value, "tool=N")
+	if err != nil {
+		return ToolLimit{}, err
+	}
+	limit, err := strconv.Atoi(n)
+	if err != nil || limit < 1 {
+		return ToolLimit{}, fmt.Errorf("invalid concurrency limit %q for %s, expected a positive integer", n, pattern)
+	}
+	return ToolLimit{Pattern: pattern, MaxConcurrent: limit}, nil
+}
+
+// ParseToolTimeout parses a tool=duration pair, such as get_job_logs=10m, setting the timeout of the tools matching
+// the tool pattern.
+func ParseToolTimeout(value string) (ToolLimit, error) {
+	pattern, d, err := splitPair(value, "tool=duration")
+	if err != nil {
+		return ToolLimit{}, err
+	}
+	timeout, err := time.ParseDuration(d)
+	if err != nil || timeout <= 0 {
+		return ToolLimit{}, fmt.Errorf("invalid timeout %q for %s, expected a positive duration (e.g. 30s)", d, pattern)
+	}
+	return ToolLimit{Pattern: pattern, Timeout: timeout}, nil
+}
+
+func splitPair(value, format string) (pattern, limit string, err error) {
+	pattern, limit, ok := strings.Cut(value, "=")
+	if !ok || pattern == "" || limit == "" {
+		return "", "", fmt.Errorf("invalid tool limit %q, expected %s", value, format)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", "", fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+	}
+	return pattern, limit, nil
+}
+
+// callKey identifies a tool call by the session it was made in and the ID of its request.
+type callKey struct {
+	session string
+	id      string
+}
+
+// Limiter applies the limits of a Config to tool calls, see ToolHandlerMiddleware.
+type Limiter struct {
+	cfg Config
+
+	// global holds a token for every call running, and is nil if there is no global limit
+	global chan struct{}
+
+	mu     sync.Mutex
+	queued int
+	// tools holds the tokens of each tool with a limit of its own
+	tools map[string]chan struct{}
+	// pending maps the contexts of calls about to reach the middleware to their keys, see BeforeCallTool
+	pending map[context.Context]callKey
+	// running cancels the calls in progress
+	running map[callKey]context.CancelCauseFunc
+}
+
+// New returns a Limiter applying cfg.
+func New(cfg Config) (*Limiter, error) {
+	if cfg.MaxConcurrent < 0 || cfg.MaxQueued < 0 || cfg.Timeout < 0 {
+		return nil, errors.New("concurrency limits and timeouts must not be negative")
+	}
+	for _, t := range cfg.Tools {
+		if _, err := path.Match(t.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tool pattern %q: %w", t.Pattern, err)
+		}
+		if t.MaxConcurrent < 0 || t.Timeout < 0 {
+			return nil, fmt.Errorf("concurrency limit and timeout of %s must not be negative", t.Pattern)
+		}
+	}
+
+	l := &Limiter{
+		cfg:     cfg,
+		tools:   map[string]chan struct{}{},
+		pending: map[context.Context]callKey{},
+		running: map[callKey]context.CancelCauseFunc{},
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.global = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l, nil
+}
+
+// Timeout returns how long calls to tool may take, or 0 if they may take any time.
+func (l *Limiter) Timeout(tool string) time.Duration {
+	for _, t := range l.cfg.Tools {
+		if t.Timeout > 0 && toolsets.MatchToolPattern([]string{t.Pattern}, tool) {
+			return t.Timeout
+		}
+	}
+	return l.cfg.Timeout
+}
+
+// toolTokens returns the tokens of the calls to tool running, or nil if it has no limit of its own.
+func (l *Limiter) toolTokens(tool string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if tokens, ok := l.tools[tool]; ok {
+		return tokens
+	}
+	var tokens chan struct{}
+	for _, t := range l.cfg.Tools {
+		if t.MaxConcurrent > 0 && toolsets.MatchToolPattern([]string{t.Pattern}, tool) {
+			tokens = make(chan struct{}, t.MaxConcurrent)
+			break
+		}
+	}
+	l.tools[tool] = tokens
+	return tokens
+}
+
+// BeforeCallTool is a server.OnBeforeCallToolFunc noting the ID of the request of each call, so that the call can
+// be cancelled. The server calls it with the very context it then passes to the tool handler middleware.
+func (l *Limiter) BeforeCallTool(ctx context.Context, id any, _ *mcp.CallToolRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending[ctx] = callKey{session: sessionID(ctx), id: fmt.Sprint(id)}
+}
+
+// OnError is a server.OnErrorHookFunc forgetting the calls that failed before reaching the middleware, such as
+// calls to unknown tools.
+func (l *Limiter) OnError(ctx context.Context, _ any, method mcp.MCPMethod, _ any, _ error) {
+	if method != mcp.MethodToolsCall {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.pending, ctx)
+}
+
+// HandleCancelled is a server.NotificationHandlerFunc for MethodNotificationCancelled, cancelling the context of
+// the call the notification is for, which aborts the requests it is making to GitHub.
+func (l *Limiter) HandleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	id, ok := notification.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	cancel := l.running[callKey{session: sessionID(ctx), id: fmt.Sprint(id)}]
+	l.mu.Unlock()
+	if cancel != nil {
+		cancel(errCancelled)
+	}
+}
+
+// ToolHandlerMiddleware applies the limits to every tool call, refusing it if the queue is full, and stopping it
+// if it takes too long or the client cancels it. It must be installed before any other tool handler middleware, so
+// that it sees the context BeforeCallTool was called with.
+func (l *Limiter) ToolHandlerMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		name := request.Params.Name
+
+		callCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		key, tracked := l.track(ctx, cancel)
+		if tracked {
+			defer l.untrack(key)
+		}
+		ctx = callCtx
+
+		if timeout := l.Timeout(name); timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+			defer cancelTimeout()
+		}
+
+		release, err := l.acquire(ctx, name)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("%s was not called: %v", name, err)), nil
+		}
+		defer release()
+
+		result, err := next(ctx, request)
+		if failed := err != nil || (result != nil && result.IsError); failed && ctx.Err() != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("%s was stopped: %v", name, context.Cause(ctx))), nil
+		}
+		return result, err
+	}
+}
+
+func (l *Limiter) track(ctx context.Context, cancel context.CancelCauseFunc) (callKey, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key, ok := l.pending[ctx]
+	if ok {
+		delete(l.pending, ctx)
+		l.running[key] = cancel
+	}
+	return key, ok
+}
+
+func (l *Limiter) untrack(key callKey) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.running, key)
+}
+
+// acquire waits for the call to tool to be allowed to run, returning the function to call once it has completed.
+// The tool's own limit is waited for first, so that calls held back by it do not take up the global limit.
+func (l *Limiter) acquire(ctx context.Context, tool string) (func(), error) {
+	var held []chan struct{}
+	release := func() {
+		for _, tokens := range held {
+			<-tokens
+		}
+	}
+	for _, tokens := range []chan struct{}{l.toolTokens(tool), l.global} {
+		if tokens == nil {
+			continue
+		}
+		if err := l.wait(ctx, tokens); err != nil {
+			release()
+			return nil, err
+		}
+		held = append(held, tokens)
+	}
+	return release, nil
+}
+
+// wait takes a token, queueing for one if there is none left.
+func (l *Limiter) wait(ctx context.Context, tokens chan struct{}) error {
+	select {
+	case tokens <- struct{}{}:
+		return nil
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.cfg.MaxQueued {
+		l.mu.Unlock()
+		return fmt.Errorf("too many calls are in progress, with %d more waiting, try again later", l.cfg.MaxQueued)
+	}
+	l.queued++
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+	}()
+
+	select {
+	case tokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func sessionID(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
@@ -0,0 +1,239 @@
+package limits
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func callRequest(name string) mcp.CallToolRequest {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	return request
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	require.Len(t, result.Content, 1)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text
+}
+
+// blockingHandler returns a handler that signals started when called, and returns once release is closed or its
+// context is done.
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) server.ToolHandlerFunc {
+	return func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return mcp.NewToolResultText("done"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func Test_ParseToolLimits(t *testing.T) {
+	limit, err := ParseToolConcurrency("search_*=2")
+	require.NoError(t, err)
+	assert.Equal(t, ToolLimit{Pattern: "search_*", MaxConcurrent: 2}, limit)
+
+	limit, err = ParseToolTimeout("get_job_logs=10m")
+	require.NoError(t, err)
+	assert.Equal(t, ToolLimit{Pattern: "get_job_logs", Timeout: 10 * time.Minute}, limit)
+
+	for _, value := range []string{"search_code", "=2", "search_code=", "search_code=-1", "search_code=two", "search_[=2"} {
+		_, err := ParseToolConcurrency(value)
+		assert.Error(t, err, value)
+	}
+	for _, value := range []string{"get_job_logs=10", "get_job_logs=0s", "get_job_logs"} {
+		_, err := ParseToolTimeout(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func Test_New(t *testing.T) {
+	_, err := New(Config{MaxConcurrent: -1})
+	assert.Error(t, err)
+
+	_, err = New(Config{Tools: []ToolLimit{{Pattern: "get_[", MaxConcurrent: 1}}})
+	assert.Error(t, err)
+}
+
+func Test_Timeout(t *testing.T) {
+	l, err := New(Config{
+		Timeout: time.Minute,
+		Tools: []ToolLimit{
+			{Pattern: "search_*", MaxConcurrent: 2},
+			{Pattern: "search_code", Timeout: 10 * time.Second},
+			{Pattern: "search_*", Timeout: 20 * time.Second},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 10*time.Second, l.Timeout("search_code"))
+	assert.Equal(t, 20*time.Second, l.Timeout("search_issues"))
+	assert.Equal(t, time.Minute, l.Timeout("get_issue"))
+}
+
+func Test_ToolHandlerMiddleware_Timeout(t *testing.T) {
+	l, err := New(Config{Timeout: time.Hour, Tools: []ToolLimit{{Pattern: "get_job_logs", Timeout: 10 * time.Millisecond}}})
+	require.NoError(t, err)
+
+	var deadline time.Time
+	handler := l.ToolHandlerMiddleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		deadline, _ = ctx.Deadline()
+		<-ctx.Done()
+		return mcp.NewToolResultError(ctx.Err().Error()), nil
+	})
+
+	start := time.Now()
+	result, err := handler(context.Background(), callRequest("get_job_logs"))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "get_job_logs was stopped: timed out after 10ms", resultText(t, result))
+	assert.WithinDuration(t, start.Add(10*time.Millisecond), deadline, time.Second)
+}
+
+func Test_ToolHandlerMiddleware_CompletedCallsAreNotRewritten(t *testing.T) {
+	l, err := New(Config{Timeout: time.Hour})
+	require.NoError(t, err)
+
+	handler := l.ToolHandlerMiddleware(func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("not found"), nil
+	})
+	result, err := handler(context.Background(), callRequest("get_issue"))
+	require.NoError(t, err)
+	assert.Equal(t, "not found", resultText(t, result))
+}
+
+func Test_ToolHandlerMiddleware_Concurrency(t *testing.T) {
+	l, err := New(Config{
+		MaxConcurrent: 2,
+		MaxQueued:     1,
+		Tools:         []ToolLimit{{Pattern: "get_job_logs", MaxConcurrent: 1}},
+	})
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	handler := l.ToolHandlerMiddleware(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	results := make(chan *mcp.CallToolResult, 10)
+	call := func(name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, _ := handler(context.Background(), callRequest(name))
+			results <- result
+		}()
+	}
+
+	// The first call to get_job_logs runs, and the second waits for it in the queue
+	call("get_job_logs")
+	<-started
+	call("get_job_logs")
+	require.Eventually(t, func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return l.queued == 1
+	}, time.Second, time.Millisecond)
+
+	// Another tool can still run, taking the last global slot
+	call("search_code")
+	<-started
+
+	// With the queue full, further calls are refused
+	result, err := handler(context.Background(), callRequest("get_issue"))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(t, result), "get_issue was not called: too many calls are in progress")
+
+	close(release)
+	wg.Wait()
+	close(results)
+	for result := range results {
+		assert.Equal(t, "done", resultText(t, result))
+	}
+	assert.Len(t, started, 1, "the queued call should have run once the others completed")
+}
+
+func Test_Cancellation(t *testing.T) {
+	l, err := New(Config{})
+	require.NoError(t, err)
+
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(l.BeforeCallTool)
+	hooks.AddOnError(l.OnError)
+	s := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks), server.WithToolHandlerMiddleware(l.ToolHandlerMiddleware))
+	s.AddNotificationHandler(MethodNotificationCancelled, l.HandleCancelled)
+
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	defer close(release)
+	s.AddTool(mcp.NewTool("get_job_logs"), blockingHandler(started, release))
+
+	responses := make(chan mcp.JSONRPCMessage, 1)
+	go func() {
+		responses <- s.HandleMessage(context.Background(), json.RawMessage(
+			`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"get_job_logs"}}`))
+	}()
+	<-started
+
+	// Cancelling another request leaves the call running
+	s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":8}}`))
+	select {
+	case <-responses:
+		t.Fatal("the call should not have been cancelled")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user aborted"}}`))
+	var response mcp.JSONRPCMessage
+	select {
+	case response = <-responses:
+	case <-time.After(time.Second):
+		t.Fatal("the call was not cancelled")
+	}
+
+	resp, ok := response.(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	result, ok := resp.Result.(mcp.CallToolResult)
+	require.True(t, ok)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "get_job_logs was stopped: cancelled by the client", resultText(t, &result))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Empty(t, l.pending)
+	assert.Empty(t, l.running)
+}
+
+func Test_Cancellation_UnknownTool(t *testing.T) {
+	l, err := New(Config{})
+	require.NoError(t, err)
+
+	hooks := &server.Hooks{}
+	hooks.AddBeforeCallTool(l.BeforeCallTool)
+	hooks.AddOnError(l.OnError)
+	s := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks), server.WithToolHandlerMiddleware(l.ToolHandlerMiddleware))
+	s.AddTool(mcp.NewTool("get_issue"), func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("issue"), nil
+	})
+
+	response := s.HandleMessage(context.Background(), json.RawMessage(
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"delete_everything"}}`))
+	_, ok := response.(mcp.JSONRPCError)
+	require.True(t, ok)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Empty(t, l.pending, "calls that never reach the middleware should be forgotten")
+}
@@ -1,28 +1,76 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// FormatText logs human readable lines.
+	FormatText = "text"
+	// FormatJSON logs one JSON object per line, with the messages exchanged with the client embedded as JSON.
+	FormatJSON = "json"
+)
+
+// maxPendingBytes bounds how much of a message split across reads is held back to be logged whole.
+const maxPendingBytes = 8 << 20
+
+// NewFormatter returns the logrus formatter for format, FormatText if empty.
+func NewFormatter(format string) (log.Formatter, error) {
+	switch format {
+	case "", FormatText:
+		return &log.TextFormatter{}, nil
+	case FormatJSON:
+		return &log.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected %s or %s", format, FormatText, FormatJSON)
+	}
+}
+
+// stream accumulates the data passing one way until it makes up whole messages, which are newline delimited.
+type stream struct {
+	name    string
+	verb    string
+	mu      sync.Mutex
+	pending []byte
+}
+
 // IOLogger is a wrapper around io.Reader and io.Writer that can be used
-// to log the data being read and written from the underlying streams
+// to log the data being read and written from the underlying streams.
+// Secrets are masked in the logged JSON-RPC messages, see Redactor.
 type IOLogger struct {
-	reader io.Reader
-	writer io.Writer
-	logger *log.Logger
+	reader   io.Reader
+	writer   io.Writer
+	logger   *log.Logger
+	redactor *Redactor
+	in       stream
+	out      stream
 }
 
-// NewIOLogger creates a new IOLogger instance
+// NewIOLogger creates a new IOLogger instance, masking secrets with the default redaction rules
 func NewIOLogger(r io.Reader, w io.Writer, logger *log.Logger) *IOLogger {
+	// The default rules always compile
+	redactor, _ := NewRedactor(RedactionConfig{})
 	return &IOLogger{
-		reader: r,
-		writer: w,
-		logger: logger,
+		reader:   r,
+		writer:   w,
+		logger:   logger,
+		redactor: redactor,
+		in:       stream{name: "stdin", verb: "received"},
+		out:      stream{name: "stdout", verb: "sending"},
 	}
 }
 
+// SetRedactor replaces the redactor masking secrets in logged messages.
+func (l *IOLogger) SetRedactor(redactor *Redactor) {
+	l.redactor = redactor
+}
+
 // Read reads data from the underlying io.Reader and logs it.
 func (l *IOLogger) Read(p []byte) (n int, err error) {
 	if l.reader == nil {
@@ -30,7 +78,7 @@ func (l *IOLogger) Read(p []byte) (n int, err error) {
 	}
 	n, err = l.reader.Read(p)
 	if n > 0 {
-		l.logger.Infof("[stdin]: received %d bytes: %s", n, string(p[:n]))
+		l.log(&l.in, p[:n])
 	}
 	return n, err
 }
@@ -40,6 +88,50 @@ func (l *IOLogger) Write(p []byte) (n int, err error) {
 	if l.writer == nil {
 		return 0, io.ErrClosedPipe
 	}
-	l.logger.Infof("[stdout]: sending %d bytes: %s", len(p), string(p))
+	l.log(&l.out, p)
 	return l.writer.Write(p)
 }
+
+// log logs the whole messages in p, holding back the start of a message that has not been read in full, so that
+// every message is redacted as a whole.
+func (l *IOLogger) log(s *stream, p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := append(s.pending, p...)
+	s.pending = nil
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(data[:i]); len(line) > 0 {
+			l.logMessage(s, line)
+		}
+		data = data[i+1:]
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && !json.Valid(trimmed) && len(data) < maxPendingBytes {
+		s.pending = data
+		return
+	}
+	l.logMessage(s, trimmed)
+}
+
+func (l *IOLogger) logMessage(s *stream, message []byte) {
+	redacted := l.redactor.Redact(message)
+	entry := l.logger.WithFields(log.Fields{"stream": s.name, "bytes": len(message)})
+	if _, ok := l.logger.Formatter.(*log.JSONFormatter); ok {
+		var frame any = string(redacted)
+		if json.Valid(redacted) {
+			frame = json.RawMessage(redacted)
+		}
+		entry.WithField("frame", frame).Infof("[%s]: %s %d bytes", s.name, s.verb, len(message))
+		return
+	}
+	entry.Infof("[%s]: %s %d bytes: %s", s.name, s.verb, len(message), redacted)
+}
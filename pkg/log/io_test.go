@@ -2,11 +2,15 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"strings"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoggedReadWriter(t *testing.T) {
@@ -63,3 +67,39 @@ func TestLoggedReadWriter(t *testing.T) {
 		assert.Contains(t, logBuffer.String(), outputData)
 	})
 }
+
+func TestIOLoggerRedactsWholeMessages(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&logBuffer)
+	logger.SetFormatter(&log.JSONFormatter{DisableTimestamp: true})
+
+	token := "ghp_" + strings.Repeat("a", 36)
+	message := `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"{\"number\":1,\"secret\":\"` + token + `\"}"}]}}` + "\n"
+	lrw := NewIOLogger(nil, io.Discard, logger)
+
+	// The message is written in two parts, splitting the secret
+	split := strings.Index(message, token) + 10
+	_, err := lrw.Write([]byte(message[:split]))
+	require.NoError(t, err)
+	assert.Empty(t, logBuffer.String(), "nothing is logged until the message is complete")
+	_, err = lrw.Write([]byte(message[split:]))
+	require.NoError(t, err)
+
+	assert.NotContains(t, logBuffer.String(), token)
+	var entry struct {
+		Msg    string `json:"msg"`
+		Stream string `json:"stream"`
+		Frame  struct {
+			Result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"result"`
+		} `json:"frame"`
+	}
+	require.NoError(t, json.Unmarshal(logBuffer.Bytes(), &entry))
+	assert.Equal(t, "stdout", entry.Stream)
+	assert.Equal(t, fmt.Sprintf("[stdout]: sending %d bytes", len(message)-1), entry.Msg)
+	assert.JSONEq(t, `{"number":1,"secret":"[REDACTED]"}`, entry.Frame.Result.Content[0].Text)
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Masked replaces the values of secret fields and the secrets matched by patterns.
+const Masked = "[REDACTED]"
+
+// defaultFields are the JSON keys whose values are always masked, such as the secret of a secret scanning alert.
+var defaultFields = []string{
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"password",
+	"authorization",
+	"private_key",
+	"client_secret",
+}
+
+// defaultPatterns match the secrets masked wherever they appear in a string.
+var defaultPatterns = []*regexp.Regexp{
+	// Personal access, OAuth, user-to-server, server-to-server and refresh tokens
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	// Fine-grained personal access tokens
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// RedactionConfig adds to the rules deciding what is masked in logged messages.
+type RedactionConfig struct {
+	// Fields are JSON keys, matched case-insensitively, whose values are masked wherever they appear, in addition to
+	// the likes of secret and token
+	Fields []string
+
+	// Patterns are regular expressions matching secrets to mask wherever they appear in a string, in addition to
+	// GitHub tokens, bearer tokens and private keys
+	Patterns []string
+
+	// ResourceContents leaves the contents of resources, such as the files returned by get_file_contents, in the
+	// log instead of masking them
+	ResourceContents bool
+}
+
+// Redactor masks secrets in the JSON-RPC messages exchanged with a client before they are logged.
+type Redactor struct {
+	fields           map[string]bool
+	patterns         []*regexp.Regexp
+	resourceContents bool
+}
+
+// NewRedactor returns a redactor applying the rules of cfg along with the default ones.
+func NewRedactor(cfg RedactionConfig) (*Redactor, error) {
+	r := &Redactor{
+		fields:           make(map[string]bool),
+		patterns:         append([]*regexp.Regexp{}, defaultPatterns...),
+		resourceContents: cfg.ResourceContents,
+	}
+	for _, field := range append(defaultFields, cfg.Fields...) {
+		r.fields[strings.ToLower(field)] = true
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// Redact returns message with its secrets masked. Messages that are not JSON are only searched for the patterns.
+func (r *Redactor) Redact(message []byte) []byte {
+	v, ok := decodeJSON(message)
+	if !ok {
+		return []byte(r.redactString(string(message)))
+	}
+	return encodeJSON(r.redactValue(v))
+}
+
+// RedactText returns text with the secrets matched by the patterns masked, leaving it as it is otherwise, even
+// when it is JSON.
+func (r *Redactor) RedactText(text []byte) []byte {
+	return []byte(r.redactString(string(text)))
+}
+
+func (r *Redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		isResource := false
+		if _, ok := v["uri"].(string); ok && !r.resourceContents {
+			// The contents of a resource, as returned by resources/read or embedded in a tool result
+			isResource = true
+		}
+		for key, value := range v {
+			switch {
+			case r.fields[strings.ToLower(key)]:
+				v[key] = Masked
+			case isResource && (key == "text" || key == "blob"):
+				if s, ok := value.(string); ok {
+					v[key] = fmt.Sprintf("[REDACTED %d bytes]", len(s))
+				}
+			default:
+				v[key] = r.redactValue(value)
+			}
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = r.redactValue(value)
+		}
+		return v
+	case string:
+		// Tool results carry the JSON returned by GitHub as text
+		if nested, ok := decodeJSON([]byte(v)); ok {
+			return string(encodeJSON(r.redactValue(nested)))
+		}
+		return r.redactString(v)
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Masked)
+	}
+	return s
+}
+
+// decodeJSON decodes data if it is a JSON object or array, keeping numbers as they were written.
+func decodeJSON(data []byte) (any, bool) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, false
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return nil, false
+	}
+	return v, true
+}
+
+func encodeJSON(v any) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	// Values decoded from JSON always encode
+	_ = encoder.Encode(v)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
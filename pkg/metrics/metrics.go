@@ -0,0 +1,208 @@
+// Package metrics exposes Prometheus metrics about the tool calls the server handles and the requests it makes to
+// the GitHub API.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/github/github-mcp-server/pkg/endpoint"
+	ghErrors "github.com/github/github-mcp-server/pkg/errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "github_mcp"
+
+// Metrics holds the collectors for the server, registered with their own registry.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	toolCalls    *prometheus.CounterVec
+	toolErrors   *prometheus.CounterVec
+	toolDuration *prometheus.HistogramVec
+
+	githubErrors *prometheus.CounterVec
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	responseBytes    *prometheus.CounterVec
+	rateLimitLimit   *prometheus.GaugeVec
+	rateLimitRemains *prometheus.GaugeVec
+}
+
+// New creates the server's metrics, along with the standard Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_calls_total",
+			Help:      "Number of tool calls handled, by tool.",
+		}, []string{"tool"}),
+		toolErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tool_errors_total",
+			Help:      "Number of tool calls that returned an error, by tool.",
+		}, []string{"tool"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tool_call_duration_seconds",
+			Help:      "Time taken to handle tool calls, by tool.",
+			Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"tool"}),
+		githubErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "github_errors_total",
+			Help:      "Number of errors from the GitHub API reported by tool calls, by tool and API (rest or graphql).",
+		}, []string{"tool", "api"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "github_requests_total",
+			Help:      "Number of requests made to GitHub, by method, endpoint and status code.",
+		}, []string{"method", "endpoint", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "github_request_duration_seconds",
+			Help:      "Time taken for GitHub to respond to requests, by method and endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "endpoint"}),
+		responseBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "github_response_bytes_total",
+			Help:      "Bytes read from GitHub response bodies, by method and endpoint.",
+		}, []string{"method", "endpoint"}),
+		rateLimitLimit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "github_rate_limit_limit",
+			Help:      "Requests allowed per rate limit window, as last reported by GitHub, by resource.",
+		}, []string{"resource"}),
+		rateLimitRemains: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "github_rate_limit_remaining",
+			Help:      "Requests remaining in the current rate limit window, as last reported by GitHub, by resource.",
+		}, []string{"resource"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.toolCalls,
+		m.toolErrors,
+		m.toolDuration,
+		m.githubErrors,
+		m.requests,
+		m.requestDuration,
+		m.responseBytes,
+		m.rateLimitLimit,
+		m.rateLimitRemains,
+	)
+	return m
+}
+
+// Registry returns the registry the metrics are registered with, to add further collectors to.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ToolHandlerMiddleware records the calls, errors and latency of every tool, along with the GitHub API errors the
+// tool reported through the context (see errors.NewGitHubAPIErrorResponse).
+func (m *Metrics) ToolHandlerMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+		start := time.Now()
+		// Errors may already be recorded in the context by other requests in the same session, only count the
+		// ones added by this call
+		apiBefore, gqlBefore := countGitHubErrors(ctx)
+
+		result, err := next(ctx, request)
+
+		m.toolCalls.WithLabelValues(tool).Inc()
+		m.toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+		if err != nil || (result != nil && result.IsError) {
+			m.toolErrors.WithLabelValues(tool).Inc()
+		}
+		apiAfter, gqlAfter := countGitHubErrors(ctx)
+		if apiAfter > apiBefore {
+			m.githubErrors.WithLabelValues(tool, "rest").Add(float64(apiAfter - apiBefore))
+		}
+		if gqlAfter > gqlBefore {
+			m.githubErrors.WithLabelValues(tool, "graphql").Add(float64(gqlAfter - gqlBefore))
+		}
+		return result, err
+	}
+}
+
+// countGitHubErrors returns the number of REST and GraphQL errors recorded in the context.
+func countGitHubErrors(ctx context.Context) (int, int) {
+	apiErrors, _ := ghErrors.GetGitHubAPIErrors(ctx)
+	gqlErrors, _ := ghErrors.GetGitHubGraphQLErrors(ctx)
+	return len(apiErrors), len(gqlErrors)
+}
+
+// Transport returns an http.RoundTripper recording the requests sent with base, and the rate limits reported in
+// the responses. It should sit closest to the network, so that every request actually sent is counted.
+func (m *Metrics) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base, metrics: m}
+}
+
+type transport struct {
+	base    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpoint.Label(req.URL)
+	start := time.Now()
+
+	resp, err := t.base.RoundTrip(req)
+	t.metrics.requestDuration.WithLabelValues(req.Method, endpoint).Observe(time.Since(start).Seconds())
+	if err != nil {
+		status := "error"
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			status = "canceled"
+		}
+		t.metrics.requests.WithLabelValues(req.Method, endpoint, status).Inc()
+		return nil, err
+	}
+
+	t.metrics.requests.WithLabelValues(req.Method, endpoint, strconv.Itoa(resp.StatusCode)).Inc()
+
+	if resource := resp.Header.Get("X-RateLimit-Resource"); resource != "" {
+		if limit, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit")); err == nil {
+			t.metrics.rateLimitLimit.WithLabelValues(resource).Set(float64(limit))
+		}
+		if remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+			t.metrics.rateLimitRemains.WithLabelValues(resource).Set(float64(remaining))
+		}
+	}
+
+	if resp.Body != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, counter: t.metrics.responseBytes.WithLabelValues(req.Method, endpoint)}
+	}
+	return resp, nil
+}
+
+// countingBody adds the bytes read from a response body to a counter.
+type countingBody struct {
+	io.ReadCloser
+	counter prometheus.Counter
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.counter.Add(float64(n))
+	return n, err
+}
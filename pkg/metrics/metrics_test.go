@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ghErrors "github.com/github/github-mcp-server/pkg/errors"
+	"github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape fetches the metrics the way Prometheus would.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	ts := httptest.NewServer(m.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func callTool(ctx context.Context, t *testing.T, m *Metrics, name string, handler func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)) {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	_, _ = m.ToolHandlerMiddleware(handler)(ctx, request)
+}
+
+func Test_ToolHandlerMiddleware(t *testing.T) {
+	m := New()
+	ctx := ghErrors.ContextWithGitHubErrors(context.Background())
+
+	succeed := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	failWithAPIError := func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		resp := &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}
+		return ghErrors.NewGitHubAPIErrorResponse(ctx, "failed to get issue", resp, assert.AnError), nil
+	}
+
+	callTool(ctx, t, m, "get_me", succeed)
+	callTool(ctx, t, m, "get_me", succeed)
+	callTool(ctx, t, m, "get_issue", failWithAPIError)
+	// Errors recorded by earlier calls are not counted again
+	callTool(ctx, t, m, "get_me", succeed)
+
+	metrics := scrape(t, m)
+	assert.Contains(t, metrics, `github_mcp_tool_calls_total{tool="get_me"} 3`)
+	assert.Contains(t, metrics, `github_mcp_tool_calls_total{tool="get_issue"} 1`)
+	assert.Contains(t, metrics, `github_mcp_tool_errors_total{tool="get_issue"} 1`)
+	assert.NotContains(t, metrics, `github_mcp_tool_errors_total{tool="get_me"}`)
+	assert.Contains(t, metrics, `github_mcp_tool_call_duration_seconds_count{tool="get_me"} 3`)
+	assert.Contains(t, metrics, `github_mcp_github_errors_total{api="rest",tool="get_issue"} 1`)
+	assert.NotContains(t, metrics, `github_mcp_github_errors_total{api="rest",tool="get_me"}`)
+}
+
+func Test_Transport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Resource", "core")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4321")
+		if r.URL.Path == "/repos/octo/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer ts.Close()
+
+	m := New()
+	client := &http.Client{Transport: m.Transport(http.DefaultTransport)}
+	for _, path := range []string{"/repos/octo/hello/issues/1", "/repos/octo/hello/issues/2", "/repos/octo/missing"} {
+		resp, err := client.Get(ts.URL + path)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	metrics := scrape(t, m)
+	assert.Contains(t, metrics, `github_mcp_github_requests_total{endpoint="/repos/{owner}/{repo}/issues/{param}",method="GET",status="200"} 2`)
+	assert.Contains(t, metrics, `github_mcp_github_requests_total{endpoint="/repos/{owner}/{repo}",method="GET",status="404"} 1`)
+	assert.Contains(t, metrics, `github_mcp_github_response_bytes_total{endpoint="/repos/{owner}/{repo}/issues/{param}",method="GET"} 16`)
+	assert.Contains(t, metrics, `github_mcp_github_request_duration_seconds_count{endpoint="/repos/{owner}/{repo}",method="GET"} 1`)
+	assert.Contains(t, metrics, `github_mcp_github_rate_limit_limit{resource="core"} 5000`)
+	assert.Contains(t, metrics, `github_mcp_github_rate_limit_remaining{resource="core"} 4321`)
+}
@@ -0,0 +1,74 @@
+// Package network builds the transport every request to GitHub is ultimately sent with, for GitHub instances that
+// are reached through a proxy or serve certificates signed by a private certificate authority.
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Config configures how connections to GitHub are made. The zero value connects like http.DefaultTransport does.
+type Config struct {
+	// CACertFile is a PEM bundle of certificate authorities trusted in addition to those of the system
+	CACertFile string
+
+	// ClientCertFile and ClientKeyFile are the PEM encoded certificate and private key presented to servers
+	// requiring mutual TLS
+	ClientCertFile string
+	ClientKeyFile  string
+}
+
+// NewTransport returns a transport connecting as configured. Requests are sent through the proxy given by the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables, if any.
+func NewTransport(cfg Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+func newTLSConfig(cfg Config) (*tls.Config, error) {
+	if cfg.CACertFile == "" && cfg.ClientCertFile == "" && cfg.ClientKeyFile == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CACertFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			// Not every platform exposes its pool, the bundle is then all that is trusted
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificates: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in %s", cfg.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case cfg.ClientCertFile != "" && cfg.ClientKeyFile != "":
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case cfg.ClientCertFile != "" || cfg.ClientKeyFile != "":
+		return nil, errors.New("a client certificate and its private key must be given together")
+	}
+	return tlsConfig, nil
+}
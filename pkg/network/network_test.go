@@ -0,0 +1,110 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes the DER encoded blocks of the given type to a file in dir.
+func writePEM(t *testing.T, dir, name, blockType string, blocks ...[]byte) string {
+	path := filepath.Join(dir, name)
+	var data []byte
+	for _, b := range blocks {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: b})...)
+	}
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func Test_NewTransport_CACert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	transport, err := NewTransport(Config{})
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(ts.URL)
+	require.Error(t, err, "the test server's certificate is not trusted by default")
+
+	caCert := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", ts.Certificate().Raw)
+	transport, err = NewTransport(Config{CACertFile: caCert})
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+func Test_NewTransport_ClientCert(t *testing.T) {
+	var presented []*x509.Certificate
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented = r.TLS.PeerCertificates
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	ts.StartTLS()
+	defer ts.Close()
+
+	// The test server's own key pair serves as the client certificate
+	dir := t.TempDir()
+	serverCert := ts.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(serverCert.PrivateKey)
+	require.NoError(t, err)
+	cfg := Config{
+		CACertFile:     writePEM(t, dir, "ca.pem", "CERTIFICATE", ts.Certificate().Raw),
+		ClientCertFile: writePEM(t, dir, "client.pem", "CERTIFICATE", serverCert.Certificate...),
+		ClientKeyFile:  writePEM(t, dir, "client-key.pem", "PRIVATE KEY", key),
+	}
+
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(ts.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Len(t, presented, 1)
+	assert.Equal(t, ts.Certificate().Raw, presented[0].Raw)
+}
+
+func Test_NewTransport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0600))
+
+	tests := []struct {
+		name          string
+		cfg           Config
+		expectedError string
+	}{
+		{
+			name:          "CA bundle without certificates",
+			cfg:           Config{CACertFile: notPEM},
+			expectedError: "no PEM encoded certificates found in " + notPEM,
+		},
+		{
+			name:          "client certificate without its key",
+			cfg:           Config{ClientCertFile: "client.pem"},
+			expectedError: "a client certificate and its private key must be given together",
+		},
+		{
+			name:          "client key without its certificate",
+			cfg:           Config{ClientKeyFile: "client-key.pem"},
+			expectedError: "a client certificate and its private key must be given together",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTransport(tc.cfg)
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/github/github-mcp-server/pkg/toolsets"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// searchResultRepo returns the full name of the repository each item of a search tool's results belongs to.
+var searchResultRepo = map[string]func(item map[string]any) string{
+	"search_repositories": func(item map[string]any) string {
+		name, _ := item["full_name"].(string)
+		return name
+	},
+	"search_code":          repoFromRepository,
+	"search_issues":        repoFromRepositoryURL,
+	"search_pull_requests": repoFromRepositoryURL,
+}
+
+// listResultRepo returns the full name of the repository each item of a list tool's results belongs to, for the
+// tools listing across repositories when not given one.
+var listResultRepo = map[string]func(item map[string]any) string{
+	"list_notifications": repoFromRepository,
+}
+
+// threadTools lists the tools acting on a notification thread given by ID, by the argument holding the ID, whose
+// repository must be looked up before they are let through.
+var threadTools = map[string]string{
+	"get_notification_details":         "notificationID",
+	"dismiss_notification":             "threadID",
+	"manage_notification_subscription": "notificationID",
+}
+
+// ThreadRepoFn returns the full name of the repository a notification thread belongs to.
+type ThreadRepoFn func(ctx context.Context, threadID string) (string, error)
+
+// SetThreadLookup sets how the repository of a notification thread is looked up, which the tools acting on a thread
+// given by ID need to be checked. Without it, calls to them are rejected.
+func (s *RepoScope) SetThreadLookup(fn ThreadRepoFn) {
+	s.threadRepo = fn
+}
+
+func repoFromRepository(item map[string]any) string {
+	repo, _ := item["repository"].(map[string]any)
+	name, _ := repo["full_name"].(string)
+	return name
+}
+
+// repoFromRepositoryURL extracts owner/repo from the API URL of an issue's repository, e.g.
+// https://api.github.com/repos/owner/repo.
+func repoFromRepositoryURL(item map[string]any) string {
+	u, _ := item["repository_url"].(string)
+	i := strings.LastIndex(u, "/repos/")
+	if i < 0 {
+		return ""
+	}
+	return u[i+len("/repos/"):]
+}
+
+// OutOfScopeError is returned for calls targeting a repository outside the scope.
+type OutOfScopeError struct {
+	Target string
+	Scope  *RepoScope
+}
+
+func (e *OutOfScopeError) Error() string {
+	return fmt.Sprintf("%s is outside the repositories this server may access (%s)", e.Target, e.Scope)
+}
+
+// CheckToolCall returns an error if the arguments of a call to the named tool target a repository, or owner, that
+// is out of scope.
+func (s *RepoScope) CheckToolCall(name string, args map[string]any) error {
+	if s == nil {
+		return nil
+	}
+
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+
+	switch name {
+	case "create_repository":
+		return fmt.Errorf("create_repository is not available when repositories are restricted (%s), as the repository would be created outside of them", s)
+	case "mark_all_notifications_read":
+		// Without a repository, the notifications of every repository are marked
+		if owner == "" || repo == "" {
+			return fmt.Errorf("mark_all_notifications_read requires an owner and repo when repositories are restricted (%s)", s)
+		}
+	case "fork_repository":
+		// The fork is created in the given organization, or the user's own account if there is none.
+		org, _ := args["organization"].(string)
+		if org == "" {
+			return fmt.Errorf("fork_repository requires an organization when repositories are restricted (%s)", s)
+		}
+		if !s.AllowsRepo(org, repo) {
+			return &OutOfScopeError{Target: "repository " + org + "/" + repo, Scope: s}
+		}
+	}
+
+	switch {
+	case owner != "" && repo != "":
+		if !s.AllowsRepo(owner, repo) {
+			return &OutOfScopeError{Target: "repository " + owner + "/" + repo, Scope: s}
+		}
+	case owner != "":
+		if !s.AllowsOwner(owner) {
+			return &OutOfScopeError{Target: "owner " + owner, Scope: s}
+		}
+	}
+	return nil
+}
+
+// checkThread returns an error if the notification thread a call to the named tool acts on belongs to a repository
+// out of scope, or cannot be looked up.
+func (s *RepoScope) checkThread(ctx context.Context, name string, args map[string]any) error {
+	arg, ok := threadTools[name]
+	if !ok || s == nil {
+		return nil
+	}
+	if s.threadRepo == nil {
+		return fmt.Errorf("%s is not available when repositories are restricted (%s)", name, s)
+	}
+	id, _ := args[arg].(string)
+	fullName, err := s.threadRepo(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to look up the repository of notification %s: %w", id, err)
+	}
+	if !s.AllowsFullName(fullName) {
+		return &OutOfScopeError{Target: "notification " + id, Scope: s}
+	}
+	return nil
+}
+
+// ToolHandlerMiddleware rejects tool calls targeting repositories out of scope before they reach the tool's
+// handler, and removes results from out of scope repositories from the output of the search and list tools.
+func (s *RepoScope) ToolHandlerMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := s.CheckToolCall(request.Params.Name, request.GetArguments()); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := s.checkThread(ctx, request.Params.Name, request.GetArguments()); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result, err := next(ctx, request)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+
+		if repoOf, ok := searchResultRepo[request.Params.Name]; ok {
+			return s.filterSearchResult(result, repoOf)
+		}
+		if repoOf, ok := listResultRepo[request.Params.Name]; ok {
+			return s.filterListResult(result, repoOf)
+		}
+		return result, nil
+	}
+}
+
+// filterSearchResult removes the items belonging to repositories out of scope from search results. Items whose
+// repository cannot be determined are removed too.
+func (s *RepoScope) filterSearchResult(result *mcp.CallToolResult, repoOf func(item map[string]any) string) (*mcp.CallToolResult, error) {
+	if s == nil {
+		return result, nil
+	}
+
+	filtered := *result
+	filtered.Content = make([]mcp.Content, 0, len(result.Content))
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			filtered.Content = append(filtered.Content, content)
+			continue
+		}
+
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(text.Text), &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse search results: %w", err)
+		}
+		items, _ := payload["items"].([]any)
+
+		kept := make([]any, 0, len(items))
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok && s.AllowsFullName(repoOf(m)) {
+				kept = append(kept, item)
+			}
+		}
+		if len(kept) < len(items) {
+			// The total counted by GitHub includes results that were removed, so no longer applies
+			payload["items"] = kept
+			payload["total_count"] = len(kept)
+			payload["incomplete_results"] = true
+		}
+
+		r, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal search results: %w", err)
+		}
+		text.Text = string(r)
+		filtered.Content = append(filtered.Content, text)
+	}
+	return &filtered, nil
+}
+
+// filterListResult removes the items belonging to repositories out of scope from results listing them as a JSON
+// array. Items whose repository cannot be determined are removed too.
+func (s *RepoScope) filterListResult(result *mcp.CallToolResult, repoOf func(item map[string]any) string) (*mcp.CallToolResult, error) {
+	if s == nil {
+		return result, nil
+	}
+
+	filtered := *result
+	filtered.Content = make([]mcp.Content, 0, len(result.Content))
+	for _, content := range result.Content {
+		text, ok := content.(mcp.TextContent)
+		if !ok {
+			filtered.Content = append(filtered.Content, content)
+			continue
+		}
+
+		var items []map[string]any
+		if err := json.Unmarshal([]byte(text.Text), &items); err != nil {
+			return nil, fmt.Errorf("failed to parse results: %w", err)
+		}
+		kept := make([]map[string]any, 0, len(items))
+		for _, item := range items {
+			if s.AllowsFullName(repoOf(item)) {
+				kept = append(kept, item)
+			}
+		}
+
+		r, err := json.Marshal(kept)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal results: %w", err)
+		}
+		text.Text = string(r)
+		filtered.Content = append(filtered.Content, text)
+	}
+	return &filtered, nil
+}
+
+// ResourceTemplateHandlerMiddleware rejects reads of resources belonging to repositories out of scope, such as
+// repo://{owner}/{repo}/contents{/path*}.
+func (s *RepoScope) ResourceTemplateHandlerMiddleware(next server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		owner := toolsets.TemplateArgument(request, "owner")
+		repo := toolsets.TemplateArgument(request, "repo")
+		if owner != "" && repo != "" && !s.AllowsRepo(owner, repo) {
+			return nil, &OutOfScopeError{Target: "repository " + owner + "/" + repo, Scope: s}
+		}
+		return next(ctx, request)
+	}
+}
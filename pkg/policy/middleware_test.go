@@ -0,0 +1,260 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func callTool(t *testing.T, scope *RepoScope, name string, args map[string]any, output any) (*mcp.CallToolResult, bool) {
+	t.Helper()
+
+	called := false
+	handler := scope.ToolHandlerMiddleware(func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		r, err := json.Marshal(output)
+		require.NoError(t, err)
+		return mcp.NewToolResultText(string(r)), nil
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	return result, called
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	require.Len(t, result.Content, 1)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text
+}
+
+func Test_ToolHandlerMiddleware_RejectsOutOfScopeCalls(t *testing.T) {
+	scope, err := NewRepoScope([]string{"myorg/*", "!myorg/secrets"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		tool        string
+		args        map[string]any
+		expectedErr string
+	}{
+		{
+			name: "repository in scope",
+			tool: "get_issue",
+			args: map[string]any{"owner": "myorg", "repo": "api", "issue_number": 1},
+		},
+		{
+			name:        "repository out of scope",
+			tool:        "get_issue",
+			args:        map[string]any{"owner": "evil", "repo": "api", "issue_number": 1},
+			expectedErr: "repository evil/api is outside the repositories this server may access (myorg/*, !myorg/secrets)",
+		},
+		{
+			name:        "excluded repository",
+			tool:        "get_file_contents",
+			args:        map[string]any{"owner": "myorg", "repo": "secrets", "path": "README.md"},
+			expectedErr: "repository myorg/secrets is outside",
+		},
+		{
+			name:        "owner out of scope",
+			tool:        "search_issues",
+			args:        map[string]any{"query": "bug", "owner": "evil"},
+			expectedErr: "owner evil is outside",
+		},
+		{
+			name: "tools without a target",
+			tool: "get_me",
+			args: map[string]any{},
+		},
+		{
+			name:        "forks must go to a repository in scope",
+			tool:        "fork_repository",
+			args:        map[string]any{"owner": "myorg", "repo": "api", "organization": "evil"},
+			expectedErr: "repository evil/api is outside",
+		},
+		{
+			name:        "forks to the user's account cannot be checked",
+			tool:        "fork_repository",
+			args:        map[string]any{"owner": "myorg", "repo": "api"},
+			expectedErr: "fork_repository requires an organization",
+		},
+		{
+			name:        "notifications of every repository cannot be marked read",
+			tool:        "mark_all_notifications_read",
+			args:        map[string]any{},
+			expectedErr: "mark_all_notifications_read requires an owner and repo",
+		},
+		{
+			name: "notifications of a repository in scope can be marked read",
+			tool: "mark_all_notifications_read",
+			args: map[string]any{"owner": "myorg", "repo": "api"},
+		},
+		{
+			name:        "notifications cannot be acted on by ID without a thread lookup",
+			tool:        "dismiss_notification",
+			args:        map[string]any{"threadID": "1", "state": "done"},
+			expectedErr: "dismiss_notification is not available when repositories are restricted",
+		},
+		{
+			name:        "repositories cannot be created",
+			tool:        "create_repository",
+			args:        map[string]any{"name": "new"},
+			expectedErr: "create_repository is not available",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, called := callTool(t, scope, tc.tool, tc.args, map[string]any{})
+			if tc.expectedErr == "" {
+				assert.True(t, called)
+				assert.False(t, result.IsError)
+				return
+			}
+			assert.False(t, called, "handler should not be called")
+			assert.True(t, result.IsError)
+			assert.Contains(t, resultText(t, result), tc.expectedErr)
+		})
+	}
+}
+
+func Test_ToolHandlerMiddleware_ChecksNotificationThreads(t *testing.T) {
+	scope, err := NewRepoScope([]string{"myorg/*"})
+	require.NoError(t, err)
+	scope.SetThreadLookup(func(_ context.Context, threadID string) (string, error) {
+		switch threadID {
+		case "1":
+			return "myorg/api", nil
+		case "2":
+			return "evil/api", nil
+		default:
+			return "", errors.New("not found")
+		}
+	})
+
+	for _, tool := range []string{"get_notification_details", "manage_notification_subscription"} {
+		result, called := callTool(t, scope, tool, map[string]any{"notificationID": "1", "action": "watch"}, map[string]any{})
+		assert.True(t, called, tool)
+		assert.False(t, result.IsError, tool)
+	}
+
+	result, called := callTool(t, scope, "dismiss_notification", map[string]any{"threadID": "2", "state": "done"}, map[string]any{})
+	assert.False(t, called)
+	assert.Equal(t, "notification 2 is outside the repositories this server may access (myorg/*)", resultText(t, result))
+
+	result, called = callTool(t, scope, "get_notification_details", map[string]any{"notificationID": "3"}, map[string]any{})
+	assert.False(t, called)
+	assert.Equal(t, "failed to look up the repository of notification 3: not found", resultText(t, result))
+}
+
+func Test_ToolHandlerMiddleware_FiltersNotifications(t *testing.T) {
+	scope, err := NewRepoScope([]string{"myorg/*"})
+	require.NoError(t, err)
+
+	notifications := []map[string]any{
+		{"id": "1", "repository": map[string]any{"full_name": "myorg/api"}},
+		{"id": "2", "repository": map[string]any{"full_name": "evil/api"}},
+		{"id": "3"},
+	}
+	result, called := callTool(t, scope, "list_notifications", map[string]any{}, notifications)
+	require.True(t, called)
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "1", got[0]["id"])
+}
+
+func Test_ToolHandlerMiddleware_FiltersSearchResults(t *testing.T) {
+	scope, err := NewRepoScope([]string{"myorg/*"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		tool     string
+		items    []map[string]any
+		expected int
+	}{
+		{
+			name: "search_repositories",
+			tool: "search_repositories",
+			items: []map[string]any{
+				{"full_name": "myorg/api"},
+				{"full_name": "evil/api"},
+			},
+			expected: 1,
+		},
+		{
+			name: "search_code",
+			tool: "search_code",
+			items: []map[string]any{
+				{"path": "a.go", "repository": map[string]any{"full_name": "myorg/api"}},
+				{"path": "b.go", "repository": map[string]any{"full_name": "evil/api"}},
+				{"path": "c.go"},
+			},
+			expected: 1,
+		},
+		{
+			name: "search_issues",
+			tool: "search_issues",
+			items: []map[string]any{
+				{"number": 1, "repository_url": "https://api.github.com/repos/myorg/api"},
+				{"number": 2, "repository_url": "https://ghes.example.com/api/v3/repos/myorg/web"},
+				{"number": 3, "repository_url": "https://api.github.com/repos/evil/api"},
+			},
+			expected: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, _ := callTool(t, scope, tc.tool, map[string]any{"query": "x"}, map[string]any{
+				"total_count":        1000,
+				"incomplete_results": false,
+				"items":              tc.items,
+			})
+			require.False(t, result.IsError)
+
+			var payload struct {
+				TotalCount        int              `json:"total_count"`
+				IncompleteResults bool             `json:"incomplete_results"`
+				Items             []map[string]any `json:"items"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &payload))
+			assert.Len(t, payload.Items, tc.expected)
+			assert.Equal(t, tc.expected, payload.TotalCount)
+			assert.True(t, payload.IncompleteResults)
+		})
+	}
+}
+
+func Test_ResourceTemplateHandlerMiddleware(t *testing.T) {
+	scope, err := NewRepoScope([]string{"myorg/*"})
+	require.NoError(t, err)
+
+	handler := scope.ResourceTemplateHandlerMiddleware(func(_ context.Context, _ mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{Text: "content"}}, nil
+	})
+
+	request := mcp.ReadResourceRequest{}
+	request.Params.Arguments = map[string]any{"owner": []string{"myorg"}, "repo": []string{"api"}}
+	contents, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, contents, 1)
+
+	request.Params.Arguments = map[string]any{"owner": []string{"evil"}, "repo": []string{"api"}}
+	_, err = handler(context.Background(), request)
+	var outOfScope *OutOfScopeError
+	require.ErrorAs(t, err, &outOfScope)
+	assert.Equal(t, "repository evil/api", outOfScope.Target)
+}
@@ -0,0 +1,135 @@
+// Package policy restricts what the tools and resources offered by the server may act on.
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// repoPattern matches repositories by owner/name glob, excluding them instead when negated.
+type repoPattern struct {
+	owner  string
+	repo   string
+	negate bool
+}
+
+// RepoScope is the set of repositories tools are allowed to act on, described by an ordered list of glob patterns
+// such as "myorg/*" or "!myorg/secrets-*". As with .gitignore, the last pattern matching a repository decides
+// whether it is in scope. A repository matching no pattern is in scope only if every pattern is negated.
+type RepoScope struct {
+	patterns    []repoPattern
+	hasPositive bool
+	threadRepo  ThreadRepoFn
+}
+
+// NewRepoScope parses the patterns of a scope, returning nil if there are none, meaning every repository is in
+// scope.
+func NewRepoScope(patterns []string) (*RepoScope, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	s := &RepoScope{}
+	for _, raw := range patterns {
+		p, err := parseRepoPattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		s.patterns = append(s.patterns, p)
+		s.hasPositive = s.hasPositive || !p.negate
+	}
+	return s, nil
+}
+
+func parseRepoPattern(raw string) (repoPattern, error) {
+	p := repoPattern{}
+	pattern := strings.ToLower(strings.TrimSpace(raw))
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	}
+
+	owner, repo, ok := strings.Cut(pattern, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return repoPattern{}, fmt.Errorf("invalid repository pattern %q: expected owner/repo, e.g. myorg/*", raw)
+	}
+	for _, part := range []string{owner, repo} {
+		if _, err := path.Match(part, ""); err != nil {
+			return repoPattern{}, fmt.Errorf("invalid repository pattern %q: %w", raw, err)
+		}
+	}
+
+	p.owner, p.repo = owner, repo
+	return p, nil
+}
+
+// AllowsRepo reports whether the repository owner/repo is in scope.
+func (s *RepoScope) AllowsRepo(owner, repo string) bool {
+	if s == nil {
+		return true
+	}
+
+	owner, repo = strings.ToLower(owner), strings.ToLower(repo)
+	allowed := !s.hasPositive
+	for _, p := range s.patterns {
+		if matchPart(p.owner, owner) && matchPart(p.repo, repo) {
+			allowed = !p.negate
+		}
+	}
+	return allowed
+}
+
+// AllowsOwner reports whether owner may hold repositories in scope, which is what is checked for calls naming an
+// owner without a repository.
+func (s *RepoScope) AllowsOwner(owner string) bool {
+	if s == nil {
+		return true
+	}
+
+	owner = strings.ToLower(owner)
+	allowed := !s.hasPositive
+	for _, p := range s.patterns {
+		if !matchPart(p.owner, owner) {
+			continue
+		}
+		switch {
+		case !p.negate:
+			allowed = true
+		case p.repo == "*":
+			// Only a negation covering every repository of the owner takes it out of scope entirely
+			allowed = false
+		}
+	}
+	return allowed
+}
+
+// AllowsFullName reports whether the repository named "owner/repo" is in scope.
+func (s *RepoScope) AllowsFullName(fullName string) bool {
+	owner, repo, ok := strings.Cut(fullName, "/")
+	if !ok {
+		return s == nil
+	}
+	return s.AllowsRepo(owner, repo)
+}
+
+// String returns the patterns of the scope as they were configured.
+func (s *RepoScope) String() string {
+	if s == nil {
+		return "*/*"
+	}
+	patterns := make([]string, 0, len(s.patterns))
+	for _, p := range s.patterns {
+		prefix := ""
+		if p.negate {
+			prefix = "!"
+		}
+		patterns = append(patterns, prefix+p.owner+"/"+p.repo)
+	}
+	return strings.Join(patterns, ", ")
+}
+
+func matchPart(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
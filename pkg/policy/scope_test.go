@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NewRepoScope(t *testing.T) {
+	scope, err := NewRepoScope(nil)
+	require.NoError(t, err)
+	assert.Nil(t, scope)
+	assert.True(t, scope.AllowsRepo("anyone", "anything"))
+	assert.True(t, scope.AllowsOwner("anyone"))
+
+	for _, pattern := range []string{"myorg", "/repo", "myorg/", "myorg/repo/extra", "myorg/[repo"} {
+		_, err := NewRepoScope([]string{pattern})
+		assert.Error(t, err, pattern)
+	}
+}
+
+func Test_RepoScope(t *testing.T) {
+	tests := []struct {
+		name          string
+		patterns      []string
+		allowedRepos  []string
+		deniedRepos   []string
+		allowedOwners []string
+		deniedOwners  []string
+	}{
+		{
+			name:          "only repositories matching a pattern are in scope",
+			patterns:      []string{"myorg/*", "other/docs"},
+			allowedRepos:  []string{"myorg/api", "MyOrg/Web", "other/docs"},
+			deniedRepos:   []string{"other/api", "evil/api"},
+			allowedOwners: []string{"myorg", "other"},
+			deniedOwners:  []string{"evil"},
+		},
+		{
+			name:          "negated patterns exclude matches",
+			patterns:      []string{"myorg/*", "!myorg/secrets-*"},
+			allowedRepos:  []string{"myorg/api"},
+			deniedRepos:   []string{"myorg/secrets-prod", "evil/api"},
+			allowedOwners: []string{"myorg"},
+			deniedOwners:  []string{"evil"},
+		},
+		{
+			name:         "the last matching pattern wins",
+			patterns:     []string{"myorg/*", "!myorg/secrets-*", "myorg/secrets-public"},
+			allowedRepos: []string{"myorg/api", "myorg/secrets-public"},
+			deniedRepos:  []string{"myorg/secrets-prod"},
+		},
+		{
+			name:          "only negated patterns allow everything else",
+			patterns:      []string{"!evil/*", "!myorg/secrets"},
+			allowedRepos:  []string{"myorg/api", "octocat/hello-world"},
+			deniedRepos:   []string{"evil/api", "myorg/secrets"},
+			allowedOwners: []string{"myorg", "octocat"},
+			deniedOwners:  []string{"evil"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scope, err := NewRepoScope(tc.patterns)
+			require.NoError(t, err)
+
+			for _, repo := range tc.allowedRepos {
+				assert.True(t, scope.AllowsFullName(repo), repo)
+			}
+			for _, repo := range tc.deniedRepos {
+				assert.False(t, scope.AllowsFullName(repo), repo)
+			}
+			for _, owner := range tc.allowedOwners {
+				assert.True(t, scope.AllowsOwner(owner), owner)
+			}
+			for _, owner := range tc.deniedOwners {
+				assert.False(t, scope.AllowsOwner(owner), owner)
+			}
+		})
+	}
+}
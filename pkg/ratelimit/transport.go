@@ -0,0 +1,336 @@
+// Package ratelimit provides an HTTP transport that keeps requests to the GitHub API within its rate limits.
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxWait is how long a request may be held back by default waiting for the rate limit to reset.
+	DefaultMaxWait = time.Minute
+	// DefaultMaxRetries is how many times a request is retried by default.
+	DefaultMaxRetries = 3
+
+	// baseBackoff is the delay before the first retry of a failed request, doubling with each further retry.
+	baseBackoff = time.Second
+	// secondaryLimitBackoff is how long to wait after hitting a secondary rate limit without a Retry-After header.
+	// See: https://docs.github.com/en/rest/using-the-rest-api/rate-limits-for-the-rest-api#exceeding-the-rate-limit
+	secondaryLimitBackoff = time.Minute
+)
+
+// Options configures a Transport.
+type Options struct {
+	// MaxWait is the longest a request is held back waiting for the rate limit to reset or for a retry, beyond
+	// which the request fails instead. Zero fails requests as soon as a limit is used up, without retrying them.
+	MaxWait time.Duration
+
+	// MaxRetries is how many times a request is retried after being rate limited, if idempotent, or failing with a
+	// server error, if safe. Zero disables retries.
+	MaxRetries int
+}
+
+// ExhaustedError is returned for requests that would have to wait longer than the maximum wait for the rate limit
+// to reset.
+type ExhaustedError struct {
+	// Resource is the rate limit that was exhausted, e.g. core, search or graphql
+	Resource string
+	// Limit is the number of requests allowed per window, if known
+	Limit int
+	// Until is when requests may be made again
+	Until time.Time
+	// Wait is how long that is from now
+	Wait time.Duration
+	// MaxWait is the longest the request was allowed to wait
+	MaxWait time.Duration
+}
+
+func (e *ExhaustedError) Error() string {
+	budget := ""
+	if e.Limit > 0 {
+		budget = fmt.Sprintf(" (0 of %d requests remaining)", e.Limit)
+	}
+	return fmt.Sprintf("GitHub API %s rate limit exceeded%s, requests are blocked until %s (in %s), longer than the maximum wait of %s",
+		e.Resource, budget, e.Until.UTC().Format(time.RFC3339), e.Wait.Round(time.Second), e.MaxWait)
+}
+
+// block records that requests sharing a rate limit must wait.
+type block struct {
+	resource string
+	limit    int
+	until    time.Time
+}
+
+// Transport is an http.RoundTripper that tracks the rate limits reported by GitHub for each token. Requests made
+// while a limit is exhausted are held until it resets. Idempotent requests that are rate limited, which GitHub did not
+// act on, and safe requests that fail with a server error, which it may have acted on, are retried with jittered
+// exponential backoff, as long as neither takes longer than the maximum wait.
+// It must sit below the transport adding the Authorization header, as limits are tracked per token.
+type Transport struct {
+	base       http.RoundTripper
+	maxWait    time.Duration
+	maxRetries int
+
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+
+	mu     sync.Mutex
+	blocks map[string]block
+}
+
+// NewTransport creates a Transport sending requests with base.
+func NewTransport(base http.RoundTripper, opts Options) *Transport {
+	return &Transport{
+		base:       base,
+		maxWait:    opts.MaxWait,
+		maxRetries: opts.MaxRetries,
+		now:        time.Now,
+		sleep:      sleepContext,
+		jitter:     equalJitter,
+		blocks:     make(map[string]block),
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := resourceFor(req)
+	key := limitKey(req, resource)
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	retryLimited := replayable && isIdempotent(req)
+	retryFailed := replayable && isSafe(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForLimit(req.Context(), key); err != nil {
+			return nil, err
+		}
+
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+
+		delay, retry, limited := t.inspect(resp, key, resource, attempt)
+		retryable := (limited && retryLimited) || (!limited && retryFailed)
+		if !retry || !retryable || attempt >= t.maxRetries || delay > t.maxWait {
+			return resp, nil
+		}
+
+		// The response is discarded in favour of the retry
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		// Rate limited requests are held back by waitForLimit until the limit resets, along with any other
+		// requests counting against it.
+		if !limited {
+			if err := t.sleep(req.Context(), delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// waitForLimit holds the request until the rate limit it counts against is no longer exhausted, failing with an
+// ExhaustedError if that would take longer than the maximum wait.
+func (t *Transport) waitForLimit(ctx context.Context, key string) error {
+	t.mu.Lock()
+	b, ok := t.blocks[key]
+	now := t.now()
+	if ok && !b.until.After(now) {
+		delete(t.blocks, key)
+		ok = false
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	wait := b.until.Sub(now)
+	if wait > t.maxWait {
+		return &ExhaustedError{Resource: b.resource, Limit: b.limit, Until: b.until, Wait: wait, MaxWait: t.maxWait}
+	}
+	return t.sleep(ctx, wait)
+}
+
+// inspect records the rate limit reported by resp, returning how long to wait before retrying the request, whether
+// it should be retried, and whether it was rejected by a rate limit.
+func (t *Transport) inspect(resp *http.Response, key, resource string, attempt int) (time.Duration, bool, bool) {
+	now := t.now()
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	exhausted := resp.Header.Get("X-RateLimit-Remaining") == "0"
+	limited := isRateLimited(resp)
+
+	var until time.Time
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		until = now.Add(retryAfter)
+	} else if exhausted {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			until = time.Unix(reset, 0)
+		}
+	}
+	if limited && until.IsZero() {
+		until = now.Add(t.jitter(secondaryLimitBackoff))
+	}
+
+	if !until.IsZero() && (exhausted || limited) {
+		t.mu.Lock()
+		if existing, ok := t.blocks[key]; !ok || until.After(existing.until) {
+			t.blocks[key] = block{resource: resource, limit: limit, until: until}
+		}
+		t.mu.Unlock()
+	}
+
+	switch {
+	case limited:
+		return until.Sub(now), true, true
+	case resp.StatusCode == http.StatusBadGateway, resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return t.jitter(baseBackoff << attempt), true, false
+	default:
+		return 0, false, false
+	}
+}
+
+// isRateLimited reports whether the request was rejected for exceeding a primary or secondary rate limit.
+func isRateLimited(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "" {
+			return true
+		}
+		return bodyMentionsRateLimit(resp)
+	default:
+		return false
+	}
+}
+
+// bodyMentionsRateLimit checks the error message of a 403 response for a secondary rate limit, leaving the body
+// readable by the caller.
+func bodyMentionsRateLimit(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(data)), "secondary rate limit")
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// resourceFor returns the rate limit a request counts against. GitHub tracks separate limits for the REST API,
+// searches and GraphQL.
+func resourceFor(req *http.Request) string {
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.HasPrefix(req.URL.Path, "/search/") || strings.Contains(req.URL.Path, "/api/v3/search/"):
+		return "search"
+	default:
+		return "core"
+	}
+}
+
+// limitKey identifies the rate limit a request counts against, which is tracked for each token on each host. The
+// token is identified by a digest, so it is not retained.
+func limitKey(req *http.Request, resource string) string {
+	sum := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	return hex.EncodeToString(sum[:]) + " " + req.URL.Host + " " + resource
+}
+
+// isIdempotent reports whether a request can be sent again when GitHub did not act on it.
+func isIdempotent(req *http.Request) bool {
+	return req.Method == http.MethodPut || req.Method == http.MethodDelete || isSafe(req)
+}
+
+// isSafe reports whether a request changes nothing, so that it can be sent again even if GitHub may have acted on
+// it, as after a server error. Changes such as merging a pull request or writing a file are made with PUT, and a
+// repeat could fail, or change data again, after the first was applied. GraphQL queries are sent with POST, but are
+// safe unlike mutations.
+func isSafe(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(req.URL.Path, "/graphql") && isGraphQLQuery(req)
+	default:
+		return false
+	}
+}
+
+func isGraphQLQuery(req *http.Request) bool {
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	defer func() { _ = body.Close() }()
+
+	var payload struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(body).Decode(&payload); err != nil {
+		return false
+	}
+	query := strings.TrimSpace(payload.Query)
+	return query != "" && !strings.HasPrefix(query, "mutation")
+}
+
+// equalJitter returns a random duration between half of d and d, spreading out retries from concurrent requests.
+func equalJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1) // #nosec G404 -- jitter does not need a secure source of randomness
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,349 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock lets tests observe waits without sleeping.
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(_ context.Context, d time.Duration) error {
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func newTestTransport(opts Options) (*Transport, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	transport := NewTransport(http.DefaultTransport, opts)
+	transport.now = clock.Now
+	transport.sleep = clock.Sleep
+	transport.jitter = func(d time.Duration) time.Duration { return d }
+	return transport, clock
+}
+
+// respond serves the given handlers in order, repeating the last one.
+func respond(t *testing.T, calls *atomic.Int32, handlers ...http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(calls.Add(1)) - 1
+		handlers[min(i, len(handlers)-1)](w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func ok(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+	_, _ = w.Write([]byte(`{}`))
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := client.Do(req)
+	if resp != nil {
+		t.Cleanup(func() { _ = resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func Test_Transport_RetriesSecondaryRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	ts := respond(t, &calls,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+		},
+		ok,
+	)
+
+	transport, clock := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 3})
+	resp, err := get(t, &http.Client{Transport: transport}, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, []time.Duration{30 * time.Second}, clock.slept)
+}
+
+func Test_Transport_DetectsSecondaryRateLimitFromBody(t *testing.T) {
+	var calls atomic.Int32
+	ts := respond(t, &calls,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`))
+		},
+	)
+
+	// Without a Retry-After header the wait is a minute, longer than we allow, so the response is returned as is
+	transport, clock := newTestTransport(Options{MaxWait: 10 * time.Second, MaxRetries: 3})
+	resp, err := get(t, &http.Client{Transport: transport}, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Empty(t, clock.slept)
+
+	// The body is still readable
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "secondary rate limit")
+
+	// Further requests fail without reaching GitHub until the limit is lifted
+	_, err = get(t, &http.Client{Transport: transport}, ts.URL+"/repos/o/r")
+	var exhausted *ExhaustedError
+	require.ErrorAs(t, err, &exhausted)
+	assert.Equal(t, "core", exhausted.Resource)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Transport_QueuesRequestsUntilReset(t *testing.T) {
+	var calls atomic.Int32
+	var reset int64
+	ts := respond(t, &calls,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			_, _ = w.Write([]byte(`{}`))
+		},
+		ok,
+	)
+
+	transport, clock := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 3})
+	reset = clock.now.Add(20 * time.Second).Unix()
+	client := &http.Client{Transport: transport}
+
+	// The request using up the budget succeeds
+	resp, err := get(t, client, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, clock.slept)
+
+	// The next one waits for the reset
+	resp, err = get(t, client, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{20 * time.Second}, clock.slept)
+
+	// Requests with another token, or against another limit, are not held back
+	clock.slept = nil
+	reset = clock.now.Add(20 * time.Second).Unix()
+	calls.Store(0)
+	_, err = get(t, client, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	_, err = get(t, client, ts.URL+"/search/code?q=x")
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/repos/o/r", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer other-token")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Empty(t, clock.slept)
+}
+
+func Test_Transport_FailsWhenResetIsTooFarAway(t *testing.T) {
+	var calls atomic.Int32
+	var reset int64
+	ts := respond(t, &calls,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"API rate limit exceeded"}`))
+		},
+	)
+
+	transport, clock := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 3})
+	reset = clock.now.Add(30 * time.Minute).Unix()
+	client := &http.Client{Transport: transport}
+
+	resp, err := get(t, client, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	_, err = get(t, client, ts.URL+"/repos/o/r")
+	var exhausted *ExhaustedError
+	require.ErrorAs(t, err, &exhausted)
+	assert.Equal(t, 5000, exhausted.Limit)
+	assert.Contains(t, err.Error(), "GitHub API core rate limit exceeded (0 of 5000 requests remaining), requests are blocked until 2025-01-01T12:30:00Z (in 30m0s)")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func Test_Transport_FailsFastWithoutMaxWait(t *testing.T) {
+	var calls atomic.Int32
+	ts := respond(t, &calls,
+		func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+		},
+	)
+
+	transport, clock := newTestTransport(Options{MaxRetries: 3})
+	client := &http.Client{Transport: transport}
+
+	resp, err := get(t, client, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode, "rate limited requests are not retried")
+
+	_, err = get(t, client, ts.URL+"/repos/o/r")
+	var exhausted *ExhaustedError
+	require.ErrorAs(t, err, &exhausted)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Empty(t, clock.slept)
+}
+
+func Test_Transport_RetriesServerErrorsWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	unavailable := func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	ts := respond(t, &calls, unavailable, unavailable, ok)
+
+	transport, clock := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 3})
+	resp, err := get(t, &http.Client{Transport: transport}, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, clock.slept)
+}
+
+func Test_Transport_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	ts := respond(t, &calls, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	transport, _ := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 2})
+	resp, err := get(t, &http.Client{Transport: transport}, ts.URL+"/repos/o/r")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func Test_Transport_OnlyRetriesServerErrorsOfSafeRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		expectedCalls int32
+	}{
+		{name: "REST create", method: http.MethodPost, path: "/repos/o/r/issues", body: `{"title":"x"}`, expectedCalls: 1},
+		{name: "REST update", method: http.MethodPatch, path: "/repos/o/r/issues/1", body: `{"title":"x"}`, expectedCalls: 1},
+		{name: "REST replace", method: http.MethodPut, path: "/repos/o/r/contents/x", body: `{"content":"x"}`, expectedCalls: 1},
+		{name: "REST merge", method: http.MethodPut, path: "/repos/o/r/pulls/1/merge", body: `{}`, expectedCalls: 1},
+		{name: "REST delete", method: http.MethodDelete, path: "/repos/o/r/contents/x", body: `{"sha":"x"}`, expectedCalls: 1},
+		{name: "GraphQL query", method: http.MethodPost, path: "/graphql", body: `{"query":"query{viewer{login}}"}`, expectedCalls: 2},
+		{name: "GraphQL mutation", method: http.MethodPost, path: "/graphql", body: `{"query":"mutation($input:X!){x(input:$input){id}}"}`, expectedCalls: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var bodies []string
+			ts := respond(t, &calls,
+				func(w http.ResponseWriter, r *http.Request) {
+					b, _ := io.ReadAll(r.Body)
+					bodies = append(bodies, string(b))
+					w.WriteHeader(http.StatusBadGateway)
+				},
+				func(w http.ResponseWriter, r *http.Request) {
+					b, _ := io.ReadAll(r.Body)
+					bodies = append(bodies, string(b))
+					ok(w, r)
+				},
+			)
+
+			transport, _ := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 3})
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			resp, err := (&http.Client{Transport: transport}).Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+			// Retries send the same body again
+			for _, body := range bodies {
+				assert.Equal(t, tc.body, body)
+			}
+		})
+	}
+}
+
+func Test_Transport_RetriesRateLimitedIdempotentRequests(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		expectedCalls int32
+	}{
+		{name: "REST create", method: http.MethodPost, path: "/repos/o/r/issues", body: `{"title":"x"}`, expectedCalls: 1},
+		{name: "REST replace", method: http.MethodPut, path: "/repos/o/r/contents/x", body: `{"content":"x"}`, expectedCalls: 2},
+		{name: "REST delete", method: http.MethodDelete, path: "/repos/o/r/contents/x", body: `{"sha":"x"}`, expectedCalls: 2},
+		{name: "GraphQL mutation", method: http.MethodPost, path: "/graphql", body: `{"query":"mutation($input:X!){x(input:$input){id}}"}`, expectedCalls: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			ts := respond(t, &calls,
+				func(w http.ResponseWriter, _ *http.Request) {
+					w.Header().Set("Retry-After", "1")
+					w.WriteHeader(http.StatusTooManyRequests)
+				},
+				ok,
+			)
+
+			transport, _ := newTestTransport(Options{MaxWait: time.Minute, MaxRetries: 3})
+			req, err := http.NewRequest(tc.method, ts.URL+tc.path, bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			resp, err := (&http.Client{Transport: transport}).Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+
+			assert.Equal(t, tc.expectedCalls, calls.Load())
+		})
+	}
+}
+
+func Test_resourceFor(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/repos/o/r":                "core",
+		"https://api.github.com/search/issues":            "search",
+		"https://api.github.com/graphql":                  "graphql",
+		"https://ghes.example.com/api/v3/search/code":     "search",
+		"https://ghes.example.com/api/graphql":            "graphql",
+		"https://raw.githubusercontent.com/o/r/HEAD/x.md": "core",
+	}
+	for url, expected := range tests {
+		req, err := http.NewRequest(http.MethodGet, url, strings.NewReader(""))
+		require.NoError(t, err)
+		assert.Equal(t, expected, resourceFor(req), url)
+	}
+}
@@ -0,0 +1,67 @@
+package scopes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// PermissionsHeader is the response header GitHub lists the permissions a request needs in when it refuses a
+// fine-grained personal access token or a GitHub App token lacking them.
+const PermissionsHeader = "X-Accepted-GitHub-Permissions"
+
+// PermissionsReason explains that a token lacks the permissions listed in a PermissionsHeader value.
+func PermissionsReason(permissions string) string {
+	return fmt.Sprintf("GitHub refused the token, which needs the %s permissions", permissions)
+}
+
+// refusal holds the permissions GitHub asked for when it refused a request made during a tool call.
+type refusal struct {
+	mu          sync.Mutex
+	permissions string
+}
+
+func (r *refusal) record(permissions string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.permissions = permissions
+}
+
+func (r *refusal) recorded() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.permissions
+}
+
+type refusalKey struct{}
+
+func contextWithRefusal(ctx context.Context) (context.Context, *refusal) {
+	r := &refusal{}
+	return context.WithValue(ctx, refusalKey{}, r), r
+}
+
+// Transport records the permissions GitHub asks for in the 403 responses to the requests of tool calls, so that the
+// Checker can tell which tools a token whose scopes GitHub does not report cannot use.
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps base, which sends the requests.
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{base: base}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+	if r, ok := req.Context().Value(refusalKey{}).(*refusal); ok {
+		if permissions := strings.TrimSpace(resp.Header.Get(PermissionsHeader)); permissions != "" {
+			r.record(permissions)
+		}
+	}
+	return resp, nil
+}
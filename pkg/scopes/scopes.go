@@ -0,0 +1,364 @@
+// Package scopes works out which tools a token cannot use from the OAuth scopes GitHub reports for it, so that they
+// can be hidden from, or flagged to, the model instead of failing with 403 errors when called.
+//
+// GitHub reports the scopes of classic personal access tokens and OAuth app tokens only. Fine-grained personal
+// access tokens and GitHub App tokens have permissions that cannot be looked up ahead of a request, so their tools
+// are offered until a call to one is refused, after which GitHub's X-Accepted-GitHub-Permissions header, recorded by
+// the Transport, tells why.
+package scopes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ModeHide leaves the tools a token cannot use out of the tools offered, and refuses calls to them.
+	ModeHide = "hide"
+	// ModeAnnotate offers the tools a token cannot use, noting the scopes they need in their description.
+	ModeAnnotate = "annotate"
+	// ModeOff offers every tool whatever the scopes of the token.
+	ModeOff = "off"
+)
+
+// Header is the response header GitHub lists the scopes of the token a request was authenticated with in.
+const Header = "X-OAuth-Scopes"
+
+// DefaultTTL is how long the scopes of a token are remembered before they are looked up again.
+const DefaultTTL = 10 * time.Minute
+
+// implies lists the scopes granted along with a scope.
+var implies = map[string][]string{
+	"repo":             {"public_repo", "repo:status", "repo_deployment", "repo:invite", "security_events"},
+	"admin:org":        {"write:org"},
+	"write:org":        {"read:org"},
+	"admin:repo_hook":  {"write:repo_hook"},
+	"write:repo_hook":  {"read:repo_hook"},
+	"admin:public_key": {"write:public_key"},
+	"write:public_key": {"read:public_key"},
+	"admin:gpg_key":    {"write:gpg_key"},
+	"write:gpg_key":    {"read:gpg_key"},
+	"user":             {"read:user", "user:email", "user:follow"},
+	"write:discussion": {"read:discussion"},
+	"write:packages":   {"read:packages"},
+	"project":          {"read:project"},
+}
+
+var (
+	// Notifications are readable with the repo scope as well as with their own
+	notificationScopes = []string{"notifications", "repo"}
+
+	// Alerts of private repositories need security_events, those of public ones public_repo will do
+	alertScopes = []string{"security_events", "public_repo"}
+
+	// Workflows can only be run, or their runs changed, with the full repo scope
+	workflowScopes = []string{"repo"}
+
+	// Changing a repository, its issues or its pull requests needs public_repo, which repo implies
+	writeScopes = []string{"public_repo"}
+)
+
+// required lists the scopes tools need, any one of which will do, where that differs from what write tools need
+// (writeScopes) and read tools need (nothing, as public data can be read without a scope).
+var required = map[string][]string{
+	"list_notifications":                          notificationScopes,
+	"get_notification_details":                    notificationScopes,
+	"dismiss_notification":                        notificationScopes,
+	"mark_all_notifications_read":                 notificationScopes,
+	"manage_notification_subscription":            notificationScopes,
+	"manage_repository_notification_subscription": notificationScopes,
+	"get_code_scanning_alert":                     alertScopes,
+	"list_code_scanning_alerts":                   alertScopes,
+	"get_secret_scanning_alert":                   alertScopes,
+	"list_secret_scanning_alerts":                 alertScopes,
+	"run_workflow":                                workflowScopes,
+	"rerun_workflow_run":                          workflowScopes,
+	"rerun_failed_jobs":                           workflowScopes,
+	"cancel_workflow_run":                         workflowScopes,
+	"delete_workflow_run_logs":                    workflowScopes,
+}
+
+// Required returns the scopes a token needs to use tool, any one of which will do, or nil if it needs none.
+func Required(tool mcp.Tool) []string {
+	if scopes, ok := required[tool.Name]; ok {
+		return scopes
+	}
+	if tool.Annotations.ReadOnlyHint != nil && !*tool.Annotations.ReadOnlyHint {
+		return writeScopes
+	}
+	return nil
+}
+
+// Set holds the scopes granted to a token, including those implied by the scopes it was given.
+type Set map[string]bool
+
+// Parse returns the scopes listed in the X-OAuth-Scopes header value, such as "repo, read:org".
+func Parse(header string) Set {
+	s := Set{}
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			s.add(scope)
+		}
+	}
+	return s
+}
+
+func (s Set) add(scope string) {
+	if s[scope] {
+		return
+	}
+	s[scope] = true
+	for _, implied := range implies[scope] {
+		s.add(implied)
+	}
+}
+
+// Names returns the scopes in the set, sorted.
+func (s Set) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Missing returns the scopes tool needs if the set has none of them, or nil if the set allows the tool. A nil set,
+// for a token whose scopes are not known, allows every tool.
+func (s Set) Missing(tool mcp.Tool) []string {
+	if s == nil {
+		return nil
+	}
+	needed := Required(tool)
+	for _, scope := range needed {
+		if s[scope] {
+			return nil
+		}
+	}
+	return needed
+}
+
+// Reason explains that a token lacks the missing scopes.
+func Reason(missing []string) string {
+	return fmt.Sprintf("the token lacks the %s scope", strings.Join(missing, " or "))
+}
+
+// Fetch returns the scopes of the token client authenticates with, or nil if GitHub does not report them, as for
+// fine-grained personal access tokens and GitHub App tokens.
+func Fetch(ctx context.Context, client *github.Client) (Set, error) {
+	_, resp, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up token scopes: %w", err)
+	}
+	values, ok := resp.Header[http.CanonicalHeaderKey(Header)]
+	if !ok {
+		return nil, nil
+	}
+	return Parse(strings.Join(values, ",")), nil
+}
+
+// GetClientFn returns a client authenticating with the token requests made with ctx use.
+type GetClientFn func(ctx context.Context) (*github.Client, error)
+
+// KeyFn identifies the token requests made with ctx authenticate with, without revealing it, or reports false if its
+// scopes are not to be checked.
+type KeyFn func(ctx context.Context) (string, bool)
+
+type entry struct {
+	scopes  Set
+	expires time.Time
+}
+
+// refusedTool identifies a tool GitHub refused calls to with a token.
+type refusedTool struct {
+	key  string
+	tool string
+}
+
+type refusedEntry struct {
+	permissions string
+	expires     time.Time
+}
+
+// Checker looks up, and remembers, the scopes of the tokens tools are called with, and hides or annotates the tools
+// a token cannot use.
+type Checker struct {
+	mode      string
+	getClient GetClientFn
+	key       KeyFn
+	logger    *logrus.Logger
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	tokens  map[string]entry
+	refused map[refusedTool]refusedEntry
+}
+
+// NewChecker returns a checker acting in mode, which looks scopes up with the clients of getClient and reports the
+// scopes it finds to logger, which may be nil. A nil checker, leaving every tool alone, is returned for ModeOff or
+// an empty mode.
+func NewChecker(mode string, getClient GetClientFn, key KeyFn, logger *logrus.Logger) (*Checker, error) {
+	switch mode {
+	case "", ModeOff:
+		return nil, nil
+	case ModeHide, ModeAnnotate:
+	default:
+		return nil, fmt.Errorf("unknown scope check mode %q, expected %s, %s or %s", mode, ModeHide, ModeAnnotate, ModeOff)
+	}
+	return &Checker{
+		mode:      mode,
+		getClient: getClient,
+		key:       key,
+		logger:    logger,
+		ttl:       DefaultTTL,
+		now:       time.Now,
+		tokens:    make(map[string]entry),
+		refused:   make(map[refusedTool]refusedEntry),
+	}, nil
+}
+
+// Scopes returns the scopes of the token requests made with ctx authenticate with, looking them up the first time
+// the token is seen. It returns nil if they are not known, in which case the token is taken to allow every tool.
+func (c *Checker) Scopes(ctx context.Context) Set {
+	key, ok := c.key(ctx)
+	if !ok {
+		return nil
+	}
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.scopes
+	}
+
+	client, err := c.getClient(ctx)
+	var scopes Set
+	if err == nil {
+		scopes, err = Fetch(ctx, client)
+	}
+	switch {
+	case err != nil:
+		// Remembered all the same, so that every call does not try again, and offering every tool, as GitHub
+		// still refuses what the token cannot do
+		c.logf(logrus.WarnLevel, "%v, offering every tool", err)
+	case scopes == nil:
+		c.logf(logrus.InfoLevel, "GitHub does not report the scopes of the token, offering every tool")
+	default:
+		c.logf(logrus.InfoLevel, "token scopes: %s", strings.Join(scopes.Names(), ", "))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.tokens {
+		if !now.Before(e.expires) {
+			delete(c.tokens, k)
+		}
+	}
+	c.tokens[key] = entry{scopes: scopes, expires: now.Add(c.ttl)}
+	return scopes
+}
+
+func (c *Checker) logf(level logrus.Level, format string, args ...any) {
+	if c.logger != nil {
+		c.logger.Logf(level, format, args...)
+	}
+}
+
+// refusedPermissions returns the permissions GitHub asked for when it last refused a call to tool made with the
+// token requests made with ctx authenticate with, or an empty string if it has not within the TTL.
+func (c *Checker) refusedPermissions(ctx context.Context, tool string) string {
+	key, ok := c.key(ctx)
+	if !ok {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.refused[refusedTool{key: key, tool: tool}]
+	if !ok || !c.now().Before(e.expires) {
+		return ""
+	}
+	return e.permissions
+}
+
+// recordRefusal remembers, for the TTL, that GitHub refused a call to tool made with the token requests made with
+// ctx authenticate with, asking for permissions.
+func (c *Checker) recordRefusal(ctx context.Context, tool, permissions string) {
+	key, ok := c.key(ctx)
+	if !ok {
+		return
+	}
+	c.logf(logrus.InfoLevel, "%s is not available: %s", tool, PermissionsReason(permissions))
+
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.refused {
+		if !now.Before(e.expires) {
+			delete(c.refused, k)
+		}
+	}
+	c.refused[refusedTool{key: key, tool: tool}] = refusedEntry{permissions: permissions, expires: now.Add(c.ttl)}
+}
+
+// Unavailable returns why the token requests made with ctx authenticate with cannot be used to call tool, or an
+// empty string if it can. It implements toolsets.ToolAvailability.
+func (c *Checker) Unavailable(ctx context.Context, tool mcp.Tool) string {
+	if missing := c.Scopes(ctx).Missing(tool); missing != nil {
+		return Reason(missing)
+	}
+	if permissions := c.refusedPermissions(ctx, tool.Name); permissions != "" {
+		return PermissionsReason(permissions)
+	}
+	return ""
+}
+
+// FilterTools hides, or annotates, the tools the token of the request cannot use. It is meant to be installed with
+// server.WithToolFilter.
+func (c *Checker) FilterTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	filtered := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if reason := c.Unavailable(ctx, tool); reason != "" {
+			if c.mode == ModeHide {
+				continue
+			}
+			tool.Description += fmt.Sprintf("\n\nUnavailable: %s.", reason)
+		}
+		filtered = append(filtered, tool)
+	}
+	return filtered
+}
+
+// WrapTool refuses calls to tool made with a token that cannot use it, in ModeHide, rather than sending GitHub
+// requests bound to be forbidden. In ModeAnnotate calls are left to go ahead. In either mode the permissions GitHub
+// asks for when it refuses a call, as recorded by the Transport, are remembered for the token.
+func (c *Checker) WrapTool(tool server.ServerTool) server.ServerTool {
+	next := tool.Handler
+	definition := tool.Tool
+	tool.Handler = func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if c.mode == ModeHide {
+			if reason := c.Unavailable(ctx, definition); reason != "" {
+				return mcp.NewToolResultError(fmt.Sprintf("%s is not available: %s", definition.Name, reason)), nil
+			}
+		}
+		ctx, refusal := contextWithRefusal(ctx)
+		result, err := next(ctx, request)
+		if permissions := refusal.recorded(); permissions != "" {
+			c.recordRefusal(ctx, definition.Name, permissions)
+		}
+		return result, err
+	}
+	return tool
+}
@@ -0,0 +1,224 @@
+package scopes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v72/github"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTool(name string) mcp.Tool {
+	return mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: github.Ptr(true)}))
+}
+
+func writeTool(name string) mcp.Tool {
+	return mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: github.Ptr(false)}))
+}
+
+func Test_Parse(t *testing.T) {
+	assert.Equal(t, []string{"read:org", "write:org"}, Parse("write:org").Names())
+	assert.Equal(t, []string{"public_repo", "read:org", "repo", "repo:invite", "repo:status", "repo_deployment", "security_events"}, Parse("repo, read:org").Names())
+	assert.Empty(t, Parse(""), "a token may have no scopes at all")
+}
+
+func Test_Set_Missing(t *testing.T) {
+	tests := []struct {
+		name            string
+		scopes          Set
+		tool            mcp.Tool
+		expectedMissing []string
+	}{
+		{
+			name:   "read tools need no scope",
+			scopes: Parse(""),
+			tool:   readTool("get_issue"),
+		},
+		{
+			name:            "write tools need public_repo",
+			scopes:          Parse("read:org"),
+			tool:            writeTool("create_issue"),
+			expectedMissing: []string{"public_repo"},
+		},
+		{
+			name:   "repo implies public_repo",
+			scopes: Parse("repo"),
+			tool:   writeTool("create_issue"),
+		},
+		{
+			name:            "notifications need their own scope or repo",
+			scopes:          Parse("public_repo"),
+			tool:            readTool("list_notifications"),
+			expectedMissing: []string{"notifications", "repo"},
+		},
+		{
+			name:   "secret scanning alerts of public repositories",
+			scopes: Parse("public_repo"),
+			tool:   readTool("list_secret_scanning_alerts"),
+		},
+		{
+			name:            "workflows need the full repo scope",
+			scopes:          Parse("public_repo, workflow"),
+			tool:            writeTool("run_workflow"),
+			expectedMissing: []string{"repo"},
+		},
+		{
+			name:   "unknown scopes allow everything",
+			scopes: nil,
+			tool:   writeTool("run_workflow"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedMissing, tc.scopes.Missing(tc.tool))
+		})
+	}
+}
+
+// newTestChecker returns a checker looking scopes up from a server replying with the given X-OAuth-Scopes header,
+// or none if header is nil, and a pointer to the number of lookups made.
+func newTestChecker(t *testing.T, mode string, header *string) (*Checker, *int) {
+	lookups := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/user", r.URL.Path)
+		lookups++
+		if header != nil {
+			w.Header().Set(Header, *header)
+		}
+		_, _ = w.Write([]byte(`{"login":"octocat"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(ts.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	checker, err := NewChecker(mode,
+		func(context.Context) (*github.Client, error) { return client, nil },
+		func(context.Context) (string, bool) { return "server", true },
+		nil,
+	)
+	require.NoError(t, err)
+	return checker, &lookups
+}
+
+func Test_NewChecker(t *testing.T) {
+	checker, err := NewChecker(ModeOff, nil, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, checker)
+
+	_, err = NewChecker("block", nil, nil, nil)
+	assert.EqualError(t, err, `unknown scope check mode "block", expected hide, annotate or off`)
+}
+
+func Test_Checker_Scopes(t *testing.T) {
+	header := "repo"
+	checker, lookups := newTestChecker(t, ModeHide, &header)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+
+	assert.True(t, checker.Scopes(context.Background())["public_repo"])
+	checker.Scopes(context.Background())
+	assert.Equal(t, 1, *lookups, "scopes are remembered")
+
+	now = now.Add(DefaultTTL)
+	checker.Scopes(context.Background())
+	assert.Equal(t, 2, *lookups, "scopes are looked up again once they expire")
+
+	fineGrained, _ := newTestChecker(t, ModeHide, nil)
+	assert.Nil(t, fineGrained.Scopes(context.Background()), "fine-grained tokens do not report scopes")
+}
+
+func Test_Checker_FilterTools(t *testing.T) {
+	header := "public_repo"
+	tools := []mcp.Tool{readTool("get_issue"), writeTool("create_issue"), writeTool("run_workflow")}
+
+	hide, _ := newTestChecker(t, ModeHide, &header)
+	filtered := hide.FilterTools(context.Background(), tools)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "get_issue", filtered[0].Name)
+	assert.Equal(t, "create_issue", filtered[1].Name)
+
+	annotate, _ := newTestChecker(t, ModeAnnotate, &header)
+	filtered = annotate.FilterTools(context.Background(), tools)
+	require.Len(t, filtered, 3)
+	assert.Equal(t, "\n\nUnavailable: the token lacks the repo scope.", filtered[2].Description)
+	assert.Empty(t, tools[2].Description, "the tools given are left alone")
+
+	unknown, _ := newTestChecker(t, ModeHide, nil)
+	assert.Len(t, unknown.FilterTools(context.Background(), tools), 3)
+}
+
+func Test_Checker_WrapTool(t *testing.T) {
+	header := "public_repo"
+	called := false
+	tool := server.ServerTool{
+		Tool: writeTool("run_workflow"),
+		Handler: func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			called = true
+			return mcp.NewToolResultText("ok"), nil
+		},
+	}
+
+	hide, _ := newTestChecker(t, ModeHide, &header)
+	result, err := hide.WrapTool(tool).Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	assert.Equal(t, "run_workflow is not available: the token lacks the repo scope", result.Content[0].(mcp.TextContent).Text)
+	assert.False(t, called)
+
+	annotate, lookups := newTestChecker(t, ModeAnnotate, &header)
+	result, err = annotate.WrapTool(tool).Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.True(t, called)
+	assert.Zero(t, *lookups, "calls are not checked when tools are only annotated")
+}
+
+func Test_Checker_RemembersRefusedPermissions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(PermissionsHeader, "issues=write")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	calls := 0
+	tool := server.ServerTool{
+		Tool: readTool("list_issue_types"),
+		Handler: func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			calls++
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			resp, err := NewTransport(http.DefaultTransport).RoundTrip(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			return mcp.NewToolResultError("forbidden"), nil
+		},
+	}
+
+	checker, _ := newTestChecker(t, ModeHide, nil)
+	now := time.Now()
+	checker.now = func() time.Time { return now }
+	wrapped := checker.WrapTool(tool)
+
+	_, err := wrapped.Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "GitHub refused the token, which needs the issues=write permissions", checker.Unavailable(context.Background(), tool.Tool))
+	assert.Empty(t, checker.FilterTools(context.Background(), []mcp.Tool{tool.Tool}))
+
+	result, err := wrapped.Handler(context.Background(), mcp.CallToolRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "list_issue_types is not available: GitHub refused the token, which needs the issues=write permissions", result.Content[0].(mcp.TextContent).Text)
+	assert.Equal(t, 1, calls, "calls GitHub refused are not made again")
+
+	now = now.Add(DefaultTTL)
+	assert.Empty(t, checker.Unavailable(context.Background(), tool.Tool), "refusals are forgotten once they expire")
+}
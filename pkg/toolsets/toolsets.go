@@ -1,7 +1,9 @@
 package toolsets
 
 import (
+	"context"
 	"fmt"
+	"path"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -47,6 +49,32 @@ func NewServerPrompt(prompt mcp.Prompt, handler server.PromptHandlerFunc) Server
 	}
 }
 
+// WithParameter returns tool with the parameter name, described by schema, added to its input schema. The properties
+// of the input schema are shared with tool, so they are copied rather than added to.
+func WithParameter(tool mcp.Tool, name string, schema map[string]any) mcp.Tool {
+	properties := make(map[string]any, len(tool.InputSchema.Properties)+1)
+	for k, v := range tool.InputSchema.Properties {
+		properties[k] = v
+	}
+	properties[name] = schema
+	tool.InputSchema.Properties = properties
+	return tool
+}
+
+// TemplateArgument returns the value request gives the variable name of the resource template it matched, or an
+// empty string. The matcher gives each variable as []string, whose first value is returned.
+func TemplateArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
 // ServerResourceTemplate represents a resource template that can be registered with the MCP server.
 type ServerResourceTemplate struct {
 	resourceTemplate mcp.ResourceTemplate
@@ -72,37 +100,86 @@ type Toolset struct {
 	resourceTemplates []ServerResourceTemplate
 	// prompts are also not tools but are namespaced similarly
 	prompts []ServerPrompt
+	// toolFilter decides which individual tools are offered, nil offers all of them
+	toolFilter *ToolFilter
+	// resourceMiddleware wraps the handlers of resource templates as they are registered
+	resourceMiddleware []ResourceTemplateHandlerMiddleware
+	// resourceTemplateWrappers adapt the definitions of resource templates as they are registered
+	resourceTemplateWrappers []ResourceTemplateWrapper
+	// writeToolWrappers adapt write tools, but not read tools, as they are offered
+	writeToolWrappers []ToolWrapper
+	// toolWrappers adapt every tool as it is offered, outside of the write tool wrappers
+	toolWrappers []ToolWrapper
+}
+
+// ResourceTemplateHandlerMiddleware wraps the handler of a resource template, like server.ToolHandlerMiddleware does
+// for tools.
+type ResourceTemplateHandlerMiddleware func(next server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc
+
+// ResourceTemplateWrapper adapts the definition of a resource template as it is registered, such as its URI template.
+type ResourceTemplateWrapper func(template mcp.ResourceTemplate) mcp.ResourceTemplate
+
+// ToolWrapper adapts a tool as it is offered, wrapping its handler or changing its definition.
+type ToolWrapper func(tool server.ServerTool) server.ServerTool
+
+// ToolAvailability returns why tool cannot be used by the request ctx belongs to, such as for want of a token scope,
+// or an empty string if it can.
+type ToolAvailability func(ctx context.Context, tool mcp.Tool) string
+
+// filterTools returns the tools allowed by the toolset's tool filter.
+func (t *Toolset) filterTools(tools []server.ServerTool) []server.ServerTool {
+	if t.toolFilter == nil {
+		return tools
+	}
+	filtered := make([]server.ServerTool, 0, len(tools))
+	for _, tool := range tools {
+		if t.toolFilter.Allows(tool.Tool.Name) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
 }
 
 func (t *Toolset) GetActiveTools() []server.ServerTool {
 	if t.Enabled {
-		if t.readOnly {
-			return t.readTools
-		}
-		return append(t.readTools, t.writeTools...)
+		return t.GetAvailableTools()
 	}
 	return nil
 }
 
 func (t *Toolset) GetAvailableTools() []server.ServerTool {
 	if t.readOnly {
-		return t.readTools
+		return t.filterTools(wrapTools(t.readTools, t.toolWrappers))
 	}
-	return append(t.readTools, t.writeTools...)
+	tools := make([]server.ServerTool, 0, len(t.readTools)+len(t.writeTools))
+	tools = append(tools, t.readTools...)
+	tools = append(tools, wrapTools(t.writeTools, t.writeToolWrappers)...)
+	return t.filterTools(wrapTools(tools, t.toolWrappers))
+}
+
+// wrapTools returns the tools adapted by wrappers.
+func wrapTools(tools []server.ServerTool, wrappers []ToolWrapper) []server.ServerTool {
+	if len(wrappers) == 0 {
+		return tools
+	}
+	wrapped := make([]server.ServerTool, 0, len(tools))
+	for _, tool := range tools {
+		// Apply in reverse so the first wrapper added is the outermost
+		for i := len(wrappers) - 1; i >= 0; i-- {
+			tool = wrappers[i](tool)
+		}
+		wrapped = append(wrapped, tool)
+	}
+	return wrapped
 }
 
 func (t *Toolset) RegisterTools(s *server.MCPServer) {
 	if !t.Enabled {
 		return
 	}
-	for _, tool := range t.readTools {
+	for _, tool := range t.GetAvailableTools() {
 		s.AddTool(tool.Tool, tool.Handler)
 	}
-	if !t.readOnly {
-		for _, tool := range t.writeTools {
-			s.AddTool(tool.Tool, tool.Handler)
-		}
-	}
 }
 
 func (t *Toolset) AddResourceTemplates(templates ...ServerResourceTemplate) *Toolset {
@@ -131,7 +208,16 @@ func (t *Toolset) RegisterResourcesTemplates(s *server.MCPServer) {
 		return
 	}
 	for _, resource := range t.resourceTemplates {
-		s.AddResourceTemplate(resource.resourceTemplate, resource.handler)
+		handler := resource.handler
+		// Apply in reverse so the first middleware added is the outermost
+		for i := len(t.resourceMiddleware) - 1; i >= 0; i-- {
+			handler = t.resourceMiddleware[i](handler)
+		}
+		template := resource.resourceTemplate
+		for _, wrap := range t.resourceTemplateWrappers {
+			template = wrap(template)
+		}
+		s.AddResourceTemplate(template, handler)
 	}
 }
 
@@ -172,10 +258,54 @@ func (t *Toolset) AddReadTools(tools ...server.ServerTool) *Toolset {
 	return t
 }
 
+// ToolFilter selects individual tools within the enabled toolsets. Tools are matched by name or by glob pattern,
+// such as "get_*", using the syntax of path.Match.
+type ToolFilter struct {
+	allow []string
+	deny  []string
+}
+
+// NewToolFilter creates a filter allowing only the tools matching a pattern in allow, or all tools if it is empty,
+// and then excluding any tool matching a pattern in deny.
+func NewToolFilter(allow, deny []string) (*ToolFilter, error) {
+	for _, patterns := range [][]string{allow, deny} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return &ToolFilter{allow: allow, deny: deny}, nil
+}
+
+// Allows reports whether the named tool passes the filter.
+func (f *ToolFilter) Allows(name string) bool {
+	if MatchToolPattern(f.deny, name) {
+		return false
+	}
+	return len(f.allow) == 0 || MatchToolPattern(f.allow, name)
+}
+
+// MatchToolPattern reports whether name matches any of the patterns. Invalid patterns never match.
+func MatchToolPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
 type ToolsetGroup struct {
-	Toolsets     map[string]*Toolset
-	everythingOn bool
-	readOnly     bool
+	Toolsets           map[string]*Toolset
+	everythingOn       bool
+	readOnly           bool
+	toolFilter         *ToolFilter
+	resourceMiddleware []ResourceTemplateHandlerMiddleware
+	templateWrappers   []ResourceTemplateWrapper
+	writeToolWrappers  []ToolWrapper
+	toolWrappers       []ToolWrapper
+	availability       ToolAvailability
 }
 
 func NewToolsetGroup(readOnly bool) *ToolsetGroup {
@@ -190,9 +320,90 @@ func (tg *ToolsetGroup) AddToolset(ts *Toolset) {
 	if tg.readOnly {
 		ts.SetReadOnly()
 	}
+	ts.toolFilter = tg.toolFilter
+	ts.resourceMiddleware = tg.resourceMiddleware
+	ts.resourceTemplateWrappers = tg.templateWrappers
+	ts.writeToolWrappers = tg.writeToolWrappers
+	ts.toolWrappers = tg.toolWrappers
 	tg.Toolsets[ts.Name] = ts
 }
 
+// SetToolFilter restricts the tools offered by every toolset in the group to those allowed by filter.
+func (tg *ToolsetGroup) SetToolFilter(filter *ToolFilter) {
+	tg.toolFilter = filter
+	for _, ts := range tg.Toolsets {
+		ts.toolFilter = filter
+	}
+}
+
+// UseResourceTemplateMiddleware wraps the handlers of the resource templates registered by every toolset in the
+// group with mw.
+func (tg *ToolsetGroup) UseResourceTemplateMiddleware(mw ResourceTemplateHandlerMiddleware) {
+	tg.resourceMiddleware = append(tg.resourceMiddleware, mw)
+	for _, ts := range tg.Toolsets {
+		ts.resourceMiddleware = tg.resourceMiddleware
+	}
+}
+
+// WrapResourceTemplates adapts the definitions of the resource templates registered by every toolset in the group
+// with wrap.
+func (tg *ToolsetGroup) WrapResourceTemplates(wrap ResourceTemplateWrapper) {
+	tg.templateWrappers = append(tg.templateWrappers, wrap)
+	for _, ts := range tg.Toolsets {
+		ts.resourceTemplateWrappers = tg.templateWrappers
+	}
+}
+
+// UseWriteToolMiddleware wraps the handlers of the write tools registered by every toolset in the group with mw.
+// Read tools are not wrapped.
+func (tg *ToolsetGroup) UseWriteToolMiddleware(mw server.ToolHandlerMiddleware) {
+	tg.WrapWriteTools(func(tool server.ServerTool) server.ServerTool {
+		tool.Handler = mw(tool.Handler)
+		return tool
+	})
+}
+
+// WrapWriteTools adapts the write tools registered by every toolset in the group with wrap. Wrappers and middleware
+// added earlier are applied outside of those added later. Read tools are not adapted.
+func (tg *ToolsetGroup) WrapWriteTools(wrap ToolWrapper) {
+	tg.writeToolWrappers = append(tg.writeToolWrappers, wrap)
+	for _, ts := range tg.Toolsets {
+		ts.writeToolWrappers = tg.writeToolWrappers
+	}
+}
+
+// WrapTools adapts every tool registered by every toolset in the group with wrap, outside of the wrappers and
+// middleware of the write tools. Wrappers added earlier are applied outside of those added later.
+func (tg *ToolsetGroup) WrapTools(wrap ToolWrapper) {
+	tg.toolWrappers = append(tg.toolWrappers, wrap)
+	for _, ts := range tg.Toolsets {
+		ts.toolWrappers = tg.toolWrappers
+	}
+}
+
+// SetToolAvailability decides which of the tools of the group UnavailableTools reports.
+func (tg *ToolsetGroup) SetToolAvailability(availability ToolAvailability) {
+	tg.availability = availability
+}
+
+// UnavailableTools returns why each of the tools of toolset that cannot be used by the request ctx belongs to cannot,
+// by tool name. It returns nil if every tool can be used.
+func (tg *ToolsetGroup) UnavailableTools(ctx context.Context, toolset *Toolset) map[string]string {
+	if tg.availability == nil {
+		return nil
+	}
+	var unavailable map[string]string
+	for _, tool := range toolset.GetAvailableTools() {
+		if reason := tg.availability(ctx, tool.Tool); reason != "" {
+			if unavailable == nil {
+				unavailable = make(map[string]string)
+			}
+			unavailable[tool.Tool.Name] = reason
+		}
+	}
+	return unavailable
+}
+
 func NewToolset(name string, description string) *Toolset {
 	return &Toolset{
 		Name:        name,
@@ -1,8 +1,13 @@
 package toolsets
 
 import (
+	"context"
 	"errors"
+	"slices"
 	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 )
 
 func TestNewToolsetGroupIsEmptyWithoutEverythingOn(t *testing.T) {
@@ -250,3 +255,343 @@ func TestToolsetGroup_GetToolset(t *testing.T) {
 		t.Errorf("expected error to be ToolsetDoesNotExistError, got %v", err)
 	}
 }
+
+func TestToolFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "empty filter allows everything",
+			allowed: []string{"get_me", "merge_pull_request"},
+		},
+		{
+			name:    "allow list only offers listed tools",
+			allow:   []string{"get_pull_request"},
+			allowed: []string{"get_pull_request"},
+			denied:  []string{"merge_pull_request"},
+		},
+		{
+			name:    "deny list removes tools",
+			deny:    []string{"merge_pull_request"},
+			allowed: []string{"get_pull_request"},
+			denied:  []string{"merge_pull_request"},
+		},
+		{
+			name:    "allow list supports glob patterns",
+			allow:   []string{"get_*", "list_pull_requests"},
+			allowed: []string{"get_pull_request", "get_issue", "list_pull_requests"},
+			denied:  []string{"merge_pull_request", "list_issues"},
+		},
+		{
+			name:    "deny list supports glob patterns",
+			deny:    []string{"*_pull_request", "delete_*"},
+			allowed: []string{"list_pull_requests", "get_issue"},
+			denied:  []string{"merge_pull_request", "delete_file"},
+		},
+		{
+			name:   "deny wins over allow",
+			allow:  []string{"merge_pull_request"},
+			deny:   []string{"merge_pull_request"},
+			denied: []string{"merge_pull_request"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewToolFilter(tc.allow, tc.deny)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for _, name := range tc.allowed {
+				if !f.Allows(name) {
+					t.Errorf("expected %s to be allowed", name)
+				}
+			}
+			for _, name := range tc.denied {
+				if f.Allows(name) {
+					t.Errorf("expected %s to be denied", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewToolFilterRejectsInvalidPatterns(t *testing.T) {
+	if _, err := NewToolFilter([]string{"get_["}, nil); err == nil {
+		t.Error("expected error for invalid allow pattern, got nil")
+	}
+	if _, err := NewToolFilter(nil, []string{"[-]"}); err == nil {
+		t.Error("expected error for invalid deny pattern, got nil")
+	}
+}
+
+func TestToolFilterAppliesToToolsets(t *testing.T) {
+	readOnly := true
+	readWrite := false
+	readTool := func(name string) server.ServerTool {
+		return NewServerTool(mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: &readOnly})), nil)
+	}
+	writeTool := func(name string) server.ServerTool {
+		return NewServerTool(mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: &readWrite})), nil)
+	}
+
+	tsg := NewToolsetGroup(false)
+	filter, err := NewToolFilter(nil, []string{"merge_*"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	tsg.SetToolFilter(filter)
+
+	toolset := NewToolset("pull_requests", "desc").
+		AddReadTools(readTool("get_pull_request")).
+		AddWriteTools(writeTool("merge_pull_request"), writeTool("create_pull_request"))
+	// Toolsets added after the filter is set are filtered too
+	tsg.AddToolset(toolset)
+
+	var names []string
+	for _, tool := range toolset.GetAvailableTools() {
+		names = append(names, tool.Tool.Name)
+	}
+	if len(names) != 2 || names[0] != "get_pull_request" || names[1] != "create_pull_request" {
+		t.Errorf("expected available tools to exclude merge_pull_request, got %v", names)
+	}
+
+	if len(toolset.GetActiveTools()) != 0 {
+		t.Error("expected no active tools while the toolset is disabled")
+	}
+	if err := tsg.EnableToolset("pull_requests"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(toolset.GetActiveTools()) != 2 {
+		t.Errorf("expected 2 active tools, got %d", len(toolset.GetActiveTools()))
+	}
+
+	s := server.NewMCPServer("test", "1.0.0")
+	tsg.RegisterAll(s)
+	resp := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	result, ok := resp.(mcp.JSONRPCResponse).Result.(mcp.ListToolsResult)
+	if !ok {
+		t.Fatalf("expected a tools/list result, got %#v", resp)
+	}
+	var registered []string
+	for _, tool := range result.Tools {
+		registered = append(registered, tool.Name)
+	}
+	if slices.Contains(registered, "merge_pull_request") {
+		t.Error("expected merge_pull_request not to be registered")
+	}
+	if !slices.Contains(registered, "get_pull_request") {
+		t.Error("expected get_pull_request to be registered")
+	}
+}
+
+func TestResourceTemplateMiddleware(t *testing.T) {
+	tsg := NewToolsetGroup(false)
+	var calls []string
+	tsg.UseResourceTemplateMiddleware(func(next server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+		return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			calls = append(calls, "middleware")
+			return next(ctx, request)
+		}
+	})
+
+	toolset := NewToolset("repos", "desc").AddResourceTemplates(NewServerResourceTemplate(
+		mcp.NewResourceTemplate("test://{name}", "Test"),
+		func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			calls = append(calls, "handler")
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, Text: "content"}}, nil
+		},
+	))
+	tsg.AddToolset(toolset)
+	if err := tsg.EnableToolset("repos"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	s := server.NewMCPServer("test", "1.0.0", server.WithResourceCapabilities(true, true))
+	tsg.RegisterAll(s)
+	resp := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"test://thing"}}`))
+	if _, ok := resp.(mcp.JSONRPCResponse); !ok {
+		t.Fatalf("expected a resources/read result, got %#v", resp)
+	}
+	if !slices.Equal(calls, []string{"middleware", "handler"}) {
+		t.Errorf("expected the middleware to wrap the handler, got calls %v", calls)
+	}
+}
+
+func TestWrapResourceTemplates(t *testing.T) {
+	tsg := NewToolsetGroup(false)
+	tsg.AddToolset(NewToolset("repos", "desc").AddResourceTemplates(NewServerResourceTemplate(
+		mcp.NewResourceTemplate("test://{name}", "Test"),
+		func(_ context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: request.Params.URI, Text: "content"}}, nil
+		},
+	)))
+	tsg.WrapResourceTemplates(func(template mcp.ResourceTemplate) mcp.ResourceTemplate {
+		template.Description = "wrapped"
+		return template
+	})
+	if err := tsg.EnableToolset("repos"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	s := server.NewMCPServer("test", "1.0.0", server.WithResourceCapabilities(true, true))
+	tsg.RegisterAll(s)
+	resp, ok := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/templates/list"}`)).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("expected a resources/templates/list result, got %#v", resp)
+	}
+	result, ok := resp.Result.(mcp.ListResourceTemplatesResult)
+	if !ok || len(result.ResourceTemplates) != 1 || result.ResourceTemplates[0].Description != "wrapped" {
+		t.Errorf("expected the template to be wrapped, got %#v", resp.Result)
+	}
+}
+
+func TestWriteToolMiddleware(t *testing.T) {
+	readOnly, readWrite := true, false
+	var calls []string
+	tool := func(name string, readOnlyHint *bool) server.ServerTool {
+		return NewServerTool(
+			mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: readOnlyHint})),
+			func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				calls = append(calls, name)
+				return mcp.NewToolResultText("ok"), nil
+			},
+		)
+	}
+
+	tsg := NewToolsetGroup(false)
+	tsg.AddToolset(NewToolset("issues", "desc").
+		AddReadTools(tool("get_issue", &readOnly)).
+		AddWriteTools(tool("create_issue", &readWrite)))
+	tsg.UseWriteToolMiddleware(func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			calls = append(calls, "middleware")
+			return next(ctx, request)
+		}
+	})
+	if err := tsg.EnableToolset("issues"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	s := server.NewMCPServer("test", "1.0.0")
+	tsg.RegisterAll(s)
+	for _, name := range []string{"get_issue", "create_issue"} {
+		resp := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`"}}`))
+		if _, ok := resp.(mcp.JSONRPCResponse); !ok {
+			t.Fatalf("expected a tools/call result, got %#v", resp)
+		}
+	}
+	if !slices.Equal(calls, []string{"get_issue", "middleware", "create_issue"}) {
+		t.Errorf("expected the middleware to wrap only the write tool, got calls %v", calls)
+	}
+
+	// Tools added later, as dynamic toolsets do, are wrapped too
+	calls = nil
+	toolset, _ := tsg.GetToolset("issues")
+	for _, tool := range toolset.GetActiveTools() {
+		_, _ = tool.Handler(context.Background(), mcp.CallToolRequest{})
+	}
+	if !slices.Equal(calls, []string{"get_issue", "middleware", "create_issue"}) {
+		t.Errorf("expected the middleware to wrap only the write tool, got calls %v", calls)
+	}
+}
+
+func TestWrapTools(t *testing.T) {
+	readOnly, readWrite := true, false
+	var calls []string
+	tool := func(name string, readOnlyHint *bool) server.ServerTool {
+		return NewServerTool(
+			mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: readOnlyHint})),
+			func(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				calls = append(calls, name)
+				return mcp.NewToolResultText("ok"), nil
+			},
+		)
+	}
+	wrapper := func(label string) ToolWrapper {
+		return func(tool server.ServerTool) server.ServerTool {
+			next := tool.Handler
+			tool.Handler = func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				calls = append(calls, label)
+				return next(ctx, request)
+			}
+			return tool
+		}
+	}
+
+	tsg := NewToolsetGroup(false)
+	tsg.WrapWriteTools(wrapper("write"))
+	tsg.AddToolset(NewToolset("issues", "desc").
+		AddReadTools(tool("get_issue", &readOnly)).
+		AddWriteTools(tool("create_issue", &readWrite)))
+	tsg.WrapTools(wrapper("all"))
+	if err := tsg.EnableToolset("issues"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	toolset, _ := tsg.GetToolset("issues")
+	for _, tool := range toolset.GetActiveTools() {
+		_, _ = tool.Handler(context.Background(), mcp.CallToolRequest{})
+	}
+	if !slices.Equal(calls, []string{"all", "get_issue", "all", "write", "create_issue"}) {
+		t.Errorf("expected every tool to be wrapped, outside of the write tool wrappers, got calls %v", calls)
+	}
+}
+
+func TestUnavailableTools(t *testing.T) {
+	readOnly, readWrite := true, false
+	tool := func(name string, readOnlyHint *bool) server.ServerTool {
+		return NewServerTool(mcp.NewTool(name, mcp.WithToolAnnotation(mcp.ToolAnnotation{ReadOnlyHint: readOnlyHint})), nil)
+	}
+
+	tsg := NewToolsetGroup(false)
+	toolset := NewToolset("issues", "desc").
+		AddReadTools(tool("get_issue", &readOnly)).
+		AddWriteTools(tool("create_issue", &readWrite))
+	tsg.AddToolset(toolset)
+
+	if unavailable := tsg.UnavailableTools(context.Background(), toolset); unavailable != nil {
+		t.Errorf("expected every tool to be available without an availability check, got %v", unavailable)
+	}
+
+	tsg.SetToolAvailability(func(_ context.Context, tool mcp.Tool) string {
+		if *tool.Annotations.ReadOnlyHint {
+			return ""
+		}
+		return "read-only token"
+	})
+	unavailable := tsg.UnavailableTools(context.Background(), toolset)
+	if len(unavailable) != 1 || unavailable["create_issue"] != "read-only token" {
+		t.Errorf("expected only create_issue to be unavailable, got %v", unavailable)
+	}
+}
+
+func TestWithParameter(t *testing.T) {
+	tool := mcp.NewTool("create_issue", mcp.WithString("title"))
+	withHost := WithParameter(tool, "host", map[string]any{"type": "string"})
+
+	if _, ok := withHost.InputSchema.Properties["host"]; !ok {
+		t.Errorf("Expected the host parameter to be added")
+	}
+	if _, ok := withHost.InputSchema.Properties["title"]; !ok {
+		t.Errorf("Expected the title parameter to be kept")
+	}
+	if _, ok := tool.InputSchema.Properties["host"]; ok {
+		t.Errorf("Expected the original tool to be left alone")
+	}
+}
+
+func TestTemplateArgument(t *testing.T) {
+	var request mcp.ReadResourceRequest
+	request.Params.Arguments = map[string]any{"owner": []string{"octocat"}, "repo": "hello-world", "path": []string{}}
+
+	for name, expected := range map[string]string{"owner": "octocat", "repo": "hello-world", "path": "", "sha": ""} {
+		if got := TemplateArgument(request, name); got != expected {
+			t.Errorf("Expected %s to be %q, got %q", name, expected, got)
+		}
+	}
+}
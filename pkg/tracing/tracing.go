@@ -0,0 +1,343 @@
+// Package tracing records OpenTelemetry spans for the MCP requests the server handles, with child spans for the
+// requests it makes to GitHub while handling them.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/github/github-mcp-server/pkg/endpoint"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterOTLP sends spans to an OpenTelemetry collector using OTLP over HTTP.
+	ExporterOTLP = "otlp"
+	// ExporterFile writes spans to a file, one JSON object per line.
+	ExporterFile = "file"
+
+	instrumentationName = "github.com/github/github-mcp-server/pkg/tracing"
+)
+
+// Config configures where spans are exported to.
+type Config struct {
+	// Exporter is ExporterOTLP or ExporterFile.
+	Exporter string
+
+	// Endpoint is the URL of the OTLP/HTTP collector (e.g. http://localhost:4318). If empty, the standard
+	// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables apply.
+	Endpoint string
+
+	// File is the path spans are appended to with ExporterFile.
+	File string
+}
+
+// Tracer records spans for MCP requests and the requests to GitHub they make.
+type Tracer struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	closeFile  func() error
+
+	mu sync.Mutex
+	// requests holds the spans of the MCP requests being handled, by the context the server handles them with
+	requests map[context.Context]trace.Span
+}
+
+// New creates a Tracer exporting spans as configured, identifying the server as the given version.
+func New(ctx context.Context, cfg Config, version string) (*Tracer, error) {
+	var exporter sdktrace.SpanExporter
+	closeFile := func() error { return nil }
+	switch cfg.Exporter {
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		otlp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		exporter = otlp
+	case ExporterFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("a trace file is required with the %s trace exporter", ExporterFile)
+		}
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to create file trace exporter: %w", err)
+		}
+		exporter = stdout
+		closeFile = file.Close
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s or %s", cfg.Exporter, ExporterOTLP, ExporterFile)
+	}
+
+	return newTracer(ctx, exporter, version, closeFile)
+}
+
+func newTracer(ctx context.Context, exporter sdktrace.SpanExporter, version string, closeFile func() error) (*Tracer, error) {
+	// Attributes set through OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME take precedence
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("github-mcp-server"), semconv.ServiceVersion(version)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	return &Tracer{
+		provider:   provider,
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		closeFile:  closeFile,
+		requests:   make(map[context.Context]trace.Span),
+	}, nil
+}
+
+// Shutdown exports the spans that are still buffered and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	err := t.provider.Shutdown(ctx)
+	if closeErr := t.closeFile(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Extract returns ctx with the trace context carried by the headers of an incoming HTTP request, so that spans
+// for the MCP request it carries are part of the client's trace.
+func (t *Tracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// BeforeAny is a server.BeforeAnyHookFunc starting a span for every MCP request, such as initialize, tools/list or
+// prompts/get, but tool calls and resource reads, whose spans ToolHandlerMiddleware and
+// ResourceTemplateHandlerMiddleware record.
+func (t *Tracer) BeforeAny(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+	if method == mcp.MethodToolsCall || method == mcp.MethodResourcesRead {
+		return
+	}
+	name := string(method)
+	attrs := []attribute.KeyValue{
+		attribute.String("mcp.method.name", string(method)),
+		attribute.String("jsonrpc.request.id", fmt.Sprint(id)),
+	}
+	if request, ok := message.(*mcp.GetPromptRequest); ok {
+		name += " " + request.Params.Name
+		attrs = append(attrs, attribute.String("mcp.prompt.name", request.Params.Name))
+	}
+	attrs = append(attrs, sessionAttributes(ctx)...)
+
+	_, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests[ctx] = span
+}
+
+// OnSuccess is a server.OnSuccessHookFunc ending the span of a request.
+func (t *Tracer) OnSuccess(ctx context.Context, _ any, _ mcp.MCPMethod, _ any, _ any) {
+	if span := t.request(ctx); span != nil {
+		span.End()
+	}
+}
+
+// OnError is a server.OnErrorHookFunc ending the span of a request with the error it failed with.
+func (t *Tracer) OnError(ctx context.Context, _ any, _ mcp.MCPMethod, _ any, err error) {
+	if span := t.request(ctx); span != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+	}
+}
+
+// request returns, and forgets, the span of the request handled with ctx, or nil if it has none.
+func (t *Tracer) request(ctx context.Context) trace.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span, ok := t.requests[ctx]
+	if !ok {
+		return nil
+	}
+	delete(t.requests, ctx)
+	return span
+}
+
+// ToolHandlerMiddleware records a span for every tool call, with the repository it targets and whether it failed.
+func (t *Tracer) ToolHandlerMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := request.Params.Name
+		attrs := []attribute.KeyValue{
+			attribute.String("mcp.method.name", string(mcp.MethodToolsCall)),
+			attribute.String("mcp.tool.name", tool),
+		}
+		attrs = append(attrs, sessionAttributes(ctx)...)
+		args := request.GetArguments()
+		if owner, ok := args["owner"].(string); ok && owner != "" {
+			attrs = append(attrs, attribute.String("github.owner", owner))
+		}
+		if repo, ok := args["repo"].(string); ok && repo != "" {
+			attrs = append(attrs, attribute.String("github.repo", repo))
+		}
+
+		ctx, span := t.tracer.Start(ctx, string(mcp.MethodToolsCall)+" "+tool,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		result, err := next(ctx, request)
+		switch {
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case result != nil && result.IsError:
+			span.SetAttributes(attribute.Bool("mcp.tool.is_error", true))
+			span.SetStatus(codes.Error, resultText(result))
+		}
+		return result, err
+	}
+}
+
+// ResourceTemplateHandlerMiddleware records a span for every resource read.
+func (t *Tracer) ResourceTemplateHandlerMiddleware(next server.ResourceTemplateHandlerFunc) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		attrs := []attribute.KeyValue{
+			attribute.String("mcp.method.name", string(mcp.MethodResourcesRead)),
+			attribute.String("mcp.resource.uri", request.Params.URI),
+		}
+		attrs = append(attrs, sessionAttributes(ctx)...)
+
+		ctx, span := t.tracer.Start(ctx, string(mcp.MethodResourcesRead),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		contents, err := next(ctx, request)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return contents, err
+	}
+}
+
+// Transport returns an http.RoundTripper recording a client span for every request sent with base, as a child of
+// the span in the request's context. The trace context is not propagated to GitHub.
+func (t *Tracer) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{base: base, tracer: t.tracer}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := endpoint.Label(req.URL)
+
+	// Query strings may hold search terms, so only the path is recorded
+	u := *req.URL
+	u.RawQuery = ""
+	u.User = nil
+
+	ctx, span := t.tracer.Start(req.Context(), req.Method+" "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.HTTPRoute(endpoint),
+			semconv.URLFull(u.String()),
+			semconv.ServerAddress(req.URL.Hostname()),
+			attribute.String("github.api", apiFor(endpoint)),
+		),
+	)
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetAttributes(semconv.ErrorTypeKey.String(fmt.Sprint(resp.StatusCode)))
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	if resp.Body == nil {
+		span.End()
+		return resp, nil
+	}
+	// The span covers reading the body, which is where most of the time goes for large responses
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+// spanBody ends a span once the response body is closed.
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.span.End()
+	return err
+}
+
+// apiFor returns which of GitHub's APIs an endpoint, as labeled by endpoint.Label, belongs to.
+func apiFor(endpoint string) string {
+	switch endpoint {
+	case "/graphql":
+		return "graphql"
+	case "raw":
+		return "raw"
+	default:
+		return "rest"
+	}
+}
+
+func sessionAttributes(ctx context.Context) []attribute.KeyValue {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return []attribute.KeyValue{attribute.String("mcp.session.id", session.SessionID())}
+	}
+	return nil
+}
+
+// resultText returns the text of an error result, to describe why a tool call failed.
+func resultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
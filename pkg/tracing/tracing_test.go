@@ -0,0 +1,204 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer(t *testing.T) (*Tracer, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tracer, err := newTracer(context.Background(), exporter, "test", func() error { return nil })
+	require.NoError(t, err)
+	return tracer, exporter
+}
+
+// endedSpans flushes the tracer and returns the spans it recorded, by name.
+func endedSpans(t *testing.T, tracer *Tracer, exporter *tracetest.InMemoryExporter) map[string]tracetest.SpanStub {
+	t.Helper()
+	require.NoError(t, tracer.provider.ForceFlush(context.Background()))
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func Test_ToolCallSpans(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/octo/hello/issues/404" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+
+	tracer, exporter := newTestTracer(t)
+	client := &http.Client{Transport: tracer.Transport(http.DefaultTransport)}
+
+	handler := tracer.ToolHandlerMiddleware(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		for _, path := range []string{"/repos/octo/hello/issues/1?state=open", "/repos/octo/hello/issues/404"} {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+path, nil)
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		return mcp.NewToolResultError("failed to get issue: 404 Not Found"), nil
+	})
+
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "get_issue"
+	request.Params.Arguments = map[string]any{"owner": "octo", "repo": "hello", "issue_number": 1}
+	_, err := handler(context.Background(), request)
+	require.NoError(t, err)
+
+	spans := endedSpans(t, tracer, exporter)
+
+	tool, ok := spans["tools/call get_issue"]
+	require.True(t, ok)
+	assert.Equal(t, trace.SpanKindServer, tool.SpanKind)
+	assert.Equal(t, codes.Error, tool.Status.Code)
+	assert.Equal(t, "failed to get issue: 404 Not Found", tool.Status.Description)
+	attrs := attributes(tool)
+	assert.Equal(t, "get_issue", attrs["mcp.tool.name"].AsString())
+	assert.Equal(t, "octo", attrs["github.owner"].AsString())
+	assert.Equal(t, "hello", attrs["github.repo"].AsString())
+
+	all := exporter.GetSpans()
+	require.Len(t, all, 3)
+	for _, span := range all[:2] {
+		assert.Equal(t, "GET /repos/{owner}/{repo}/issues/{param}", span.Name)
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+		assert.Equal(t, tool.SpanContext.SpanID(), span.Parent.SpanID(), "requests to GitHub are children of the tool call")
+		assert.Equal(t, tool.SpanContext.TraceID(), span.SpanContext.TraceID())
+		assert.Equal(t, "rest", attributes(span)["github.api"].AsString())
+		assert.NotContains(t, attributes(span)["url.full"].AsString(), "state=open")
+	}
+	assert.Equal(t, int64(200), attributes(all[0])["http.response.status_code"].AsInt64())
+	assert.Equal(t, codes.Unset, all[0].Status.Code)
+	assert.Equal(t, int64(404), attributes(all[1])["http.response.status_code"].AsInt64())
+	assert.Equal(t, codes.Error, all[1].Status.Code)
+}
+
+func Test_RequestSpans(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+	hooks := &server.Hooks{}
+	hooks.AddBeforeAny(tracer.BeforeAny)
+	hooks.AddOnSuccess(tracer.OnSuccess)
+	hooks.AddOnError(tracer.OnError)
+	s := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks), server.WithPromptCapabilities(true))
+	s.AddPrompt(mcp.NewPrompt("triage"), func(context.Context, mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return mcp.NewGetPromptResult("Triage", nil), nil
+	})
+
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"prompts/get","params":{"name":"triage"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_me"}}`,
+	} {
+		s.HandleMessage(context.Background(), []byte(message))
+	}
+
+	require.NoError(t, tracer.provider.ForceFlush(context.Background()))
+	all := exporter.GetSpans()
+	var names []string
+	for _, span := range all {
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"initialize", "prompts/get triage", "prompts/get missing"}, names, "tool calls get spans of their own from the middleware")
+
+	assert.Equal(t, trace.SpanKindServer, all[0].SpanKind)
+	assert.Equal(t, "initialize", attributes(all[0])["mcp.method.name"].AsString())
+	assert.Equal(t, "1", attributes(all[0])["jsonrpc.request.id"].AsString())
+	assert.Equal(t, codes.Unset, all[1].Status.Code)
+	assert.Equal(t, "triage", attributes(all[1])["mcp.prompt.name"].AsString())
+	assert.Equal(t, codes.Error, all[2].Status.Code)
+	assert.Empty(t, tracer.requests, "ended spans are forgotten")
+}
+
+func Test_Extract(t *testing.T) {
+	tracer, exporter := newTestTracer(t)
+
+	header := http.Header{}
+	header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := tracer.Extract(context.Background(), header)
+
+	handler := tracer.ToolHandlerMiddleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	request := mcp.CallToolRequest{}
+	request.Params.Name = "get_me"
+	_, err := handler(ctx, request)
+	require.NoError(t, err)
+
+	span := endedSpans(t, tracer, exporter)["tools/call get_me"]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent.SpanID().String())
+	assert.Equal(t, codes.Unset, span.Status.Code)
+}
+
+func Test_FileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	tracer, err := New(context.Background(), Config{Exporter: ExporterFile, File: path}, "test")
+	require.NoError(t, err)
+
+	handler := tracer.ToolHandlerMiddleware(func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	for _, name := range []string{"get_me", "list_issues"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = name
+		_, err = handler(context.Background(), request)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span struct{ Name string }
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &span), "each line holds one span")
+		names = append(names, span.Name)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"tools/call get_me", "tools/call list_issues"}, names)
+}
+
+func Test_New_RejectsUnknownExporters(t *testing.T) {
+	_, err := New(context.Background(), Config{Exporter: "jaeger"}, "test")
+	assert.EqualError(t, err, `unknown trace exporter "jaeger", expected otlp or file`)
+
+	_, err = New(context.Background(), Config{Exporter: ExporterFile}, "test")
+	assert.Error(t, err)
+}